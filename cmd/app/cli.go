@@ -0,0 +1,515 @@
+package main
+
+// cli.go — подкоманды бинарника: serve, migrate, seed, user, routes, config.
+// Без аргументов бинарник запускает сервер, как и раньше.
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"myApp/internal/auth"
+	"myApp/internal/core"
+	"myApp/internal/http/server"
+	"myApp/internal/money"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// errUsage — неверные аргументы: печатаем справку и выходим с кодом 2
+var errUsage = errors.New("неверные аргументы")
+
+// command — подкоманда CLI
+type command struct {
+	Name    string
+	Usage   string
+	Summary string
+	Run     func(args []string) error
+}
+
+// commands — порядок соответствует выводу `app help`
+func commands() []command {
+	return []command{
+		{Name: "serve", Usage: "serve", Summary: "Запустить HTTP-сервер (по умолчанию)", Run: cmdServe},
+		{Name: "migrate", Usage: "migrate up | down [N] | status | create NAME", Summary: "Миграции схемы БД", Run: cmdMigrate},
+		{Name: "seed", Usage: "seed [-dir seeds]", Summary: "Загрузить демо-данные", Run: cmdSeed},
+		{Name: "user", Usage: "user create-admin -email E [-name N] | set-role -email E -role R", Summary: "Управление пользователями", Run: cmdUser},
+		{Name: "order", Usage: "order cancel -number N | expire", Summary: "Отмена заказов: по номеру или просроченных", Run: cmdOrder},
+		{Name: "routes", Usage: "routes", Summary: "Показать таблицу маршрутов Gin", Run: cmdRoutes},
+		{Name: "config", Usage: "config check", Summary: "Показать действующую конфигурацию и проверить её", Run: cmdConfig},
+	}
+}
+
+// runCLI — выбирает подкоманду и возвращает код выхода процесса
+func runCLI(args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	for _, cmd := range commands() {
+		if cmd.Name != name {
+			continue
+		}
+		err := cmd.Run(args)
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+			_, _ = fmt.Fprintf(os.Stderr, "Использование: app %s\n", cmd.Usage)
+			return 2
+		default:
+			core.LogError("Ошибка выполнения команды", map[string]interface{}{"command": name, "error": err.Error()})
+			return 1
+		}
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Неизвестная команда %q\n\n", name)
+	printUsage(os.Stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Использование: app <команда> [аргументы]")
+	_, _ = fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands() {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", cmd.Usage, cmd.Summary)
+	}
+	_ = tw.Flush()
+}
+
+// loadConfig — конфиг (в Prod невалидный конфиг завершает процесс) и валюта сумм
+func loadConfig() (core.Config, error) {
+	cfg := core.Load()
+
+	// Валюта и локаль сумм (цены из БД читаются в этой валюте)
+	if !money.IsKnownCurrency(cfg.Currency) {
+		return cfg, fmt.Errorf("неизвестная валюта SHOP_CURRENCY %q", cfg.Currency)
+	}
+	money.DefaultCurrency = cfg.Currency
+	money.DefaultLocale = cfg.Locale
+	return cfg, nil
+}
+
+// cmdServe — запуск сервера: конфиг, логи, storage, graceful shutdown
+func cmdServe(args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	core.LogInfo("Приложение запущено", map[string]interface{}{
+		"env":    cfg.Env,
+		"addr":   cfg.Addr,
+		"secure": cfg.Secure,
+		"app":    cfg.AppName,
+	})
+
+	core.InitDailyLog()
+
+	return run(&cfg)
+}
+
+// cmdMigrate — app migrate up | down [N] | status | create NAME
+func cmdMigrate(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	sub, args := args[0], args[1:]
+	if sub == "create" {
+		if len(args) != 1 {
+			return errUsage
+		}
+		up, down, err := storage.CreateMigration(cfg.MigrationsDir, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println("Создано:", up)
+		fmt.Println("Создано:", down)
+		return nil
+	}
+
+	steps := 1
+	switch {
+	case (sub == "up" || sub == "status") && len(args) == 0:
+	case sub == "down" && len(args) <= 1:
+		if len(args) == 1 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				return errUsage
+			}
+		}
+	default:
+		return errUsage
+	}
+
+	return withDB(cfg, func(db *sqlx.DB) error {
+		ctx := context.Background()
+		migrations := storage.NewMigrations(db, os.DirFS(cfg.MigrationsDir))
+
+		switch sub {
+		case "up":
+			n, err := migrations.Up(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Применено миграций: %d\n", n)
+		case "down":
+			n, err := migrations.Down(ctx, steps)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Откачено миграций: %d\n", n)
+		case "status":
+			list, err := migrations.Status(ctx)
+			if err != nil {
+				return err
+			}
+			printMigrationStatus(os.Stdout, list)
+		}
+		return nil
+	})
+}
+
+func printMigrationStatus(w io.Writer, list []storage.MigrationStatus) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, st := range list {
+		status, at := "pending", ""
+		if st.Applied {
+			status = "applied"
+			at = st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case st.Dirty:
+			status = "DIRTY"
+		case st.Missing:
+			status += ", файл отсутствует"
+		case st.Modified:
+			status += ", файл изменён"
+		}
+		_, _ = fmt.Fprintf(tw, "%03d\t%s\t%s\t%s\n", st.Version, st.Name, status, at)
+	}
+	_ = tw.Flush()
+}
+
+// cmdSeed — демо-данные из каталога seeds (после `app migrate up`)
+func cmdSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dir := fs.String("dir", storage.SeedDir, "каталог с *.sql демо-данных")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		return errUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	return withDB(cfg, func(db *sqlx.DB) error {
+		n, err := storage.Seed(context.Background(), db, os.DirFS(*dir))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Выполнено файлов: %d\n", n)
+		return nil
+	})
+}
+
+// cmdUser — app user create-admin | set-role
+func cmdUser(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "create-admin":
+		return cmdUserCreateAdmin(args[1:])
+	case "set-role":
+		return cmdUserSetRole(args[1:])
+	default:
+		return errUsage
+	}
+}
+
+// cmdUserCreateAdmin — app user create-admin -email E [-name N]. Пароль читается первой
+// строкой stdin (не из аргументов — они видны в ps и истории shell):
+//
+//	read -rs PW && echo "$PW" | app user create-admin -email admin@example.com
+//
+// Уже зарегистрированный пользователь получает роль admin, пароль не меняется.
+func cmdUserCreateAdmin(args []string) error {
+	fs := flag.NewFlagSet("user create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email администратора")
+	name := fs.String("name", "Администратор", "имя")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *email == "" || fs.NArg() > 0 {
+		return errUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	return withDB(cfg, func(db *sqlx.DB) error {
+		ctx := context.Background()
+		users := storage.NewMySQLRepositories(db).Users
+
+		existing, err := users.GetByEmail(ctx, *email)
+		switch {
+		case err == nil:
+			if err := users.SetRole(ctx, existing.ID, storage.RoleAdmin); err != nil {
+				return err
+			}
+			fmt.Printf("Пользователь %s теперь администратор (id %s)\n", existing.Email, existing.ID)
+			return nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		_, _ = fmt.Fprint(os.Stderr, "Пароль (первая строка stdin): ")
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		password = strings.TrimRight(password, "\r\n")
+		if n := utf8.RuneCountInString(password); n < 8 || n > 128 {
+			return errors.New("пароль должен быть от 8 до 128 символов")
+		}
+
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			return err
+		}
+		u := &storage.User{Email: *email, Name: *name, Role: storage.RoleAdmin, PasswordHash: hash}
+		if err := users.Create(ctx, u); err != nil {
+			return err
+		}
+		// Адрес указал сам оператор — подтверждать его письмом незачем
+		if err := users.MarkEmailVerified(ctx, u.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Создан администратор %s (id %s)\n", u.Email, u.ID)
+		return nil
+	})
+}
+
+// cmdUserSetRole — app user set-role -email E -role customer|staff|admin
+func cmdUserSetRole(args []string) error {
+	fs := flag.NewFlagSet("user set-role", flag.ContinueOnError)
+	email := fs.String("email", "", "email пользователя")
+	role := fs.String("role", "", "роль: customer, staff или admin")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *email == "" || *role == "" || fs.NArg() > 0 {
+		return errUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	return withDB(cfg, func(db *sqlx.DB) error {
+		ctx := context.Background()
+		users := storage.NewMySQLRepositories(db).Users
+
+		u, err := users.GetByEmail(ctx, *email)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("пользователь %s не найден", *email)
+		}
+		if err != nil {
+			return err
+		}
+		err = users.SetRole(ctx, u.ID, *role)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("пользователь %s не найден", *email)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Пользователь %s: роль %s → %s\n", u.Email, u.Role, *role)
+		return nil
+	})
+}
+
+// cmdOrder — app order cancel -number N | expire
+func cmdOrder(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "cancel":
+		return cmdOrderCancel(args[1:])
+	case "expire":
+		return cmdOrderExpire(args[1:])
+	default:
+		return errUsage
+	}
+}
+
+// cmdOrderCancel — app order cancel -number N: отмена неоплаченного заказа администратором,
+// резерв товара снимается. Уже отменённый — ошибка "уже отменён" (код 1), оплаченный —
+// ошибка конечного автомата.
+func cmdOrderCancel(args []string) error {
+	fs := flag.NewFlagSet("order cancel", flag.ContinueOnError)
+	number := fs.String("number", "", "номер заказа")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if *number == "" || fs.NArg() > 0 {
+		return errUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	return withDB(cfg, func(db *sqlx.DB) error {
+		ctx := context.Background()
+		orders := storage.NewMySQLRepositories(db).Orders
+
+		order, err := orders.GetByNumber(ctx, *number)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("заказ %s не найден", *number)
+		}
+		if err != nil {
+			return err
+		}
+		// Повторный переход в тот же статус хранилище считает no-op — здесь это ошибка оператора
+		if order.Status == storage.OrderCancelled {
+			return fmt.Errorf("заказ %s уже отменён", order.Number)
+		}
+		if err := orders.UpdateStatus(ctx, order.ID, storage.OrderCancelled, "Отменён администратором"); err != nil {
+			return err
+		}
+		fmt.Printf("Заказ %s: %s → %s\n", order.Number, order.Status, storage.OrderCancelled)
+		return nil
+	})
+}
+
+// cmdOrderExpire — app order expire: разовый проход задачи сервера (server.RunOrderExpiry),
+// например из cron, когда сервер не запущен
+func cmdOrderExpire(args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	return withDB(cfg, func(db *sqlx.DB) error {
+		n, err := storage.NewMySQLRepositories(db).Orders.CancelExpired(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Отменено неоплаченных заказов с истёкшим резервом: %d\n", n)
+		return nil
+	})
+}
+
+// cmdRoutes — таблица маршрутов из того же server.New, что и у сервера.
+// К БД не подключается: обработчики не вызываются, достаточно хранилища в памяти.
+func cmdRoutes(args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Release-режим: Gin не печатает каждый маршрут при регистрации
+	gin.SetMode(gin.ReleaseMode)
+	r, err := server.New(cfg, server.MemoryBackend(), deriveSecureKey(cfg.CSRFKey))
+	if err != nil {
+		return err
+	}
+
+	routes := r.Routes()
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER")
+	for _, rt := range routes {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", rt.Method, rt.Path, strings.TrimPrefix(rt.Handler, "myApp/"))
+	}
+	return tw.Flush()
+}
+
+// cmdConfig — app config check: действующие настройки (секреты замаскированы)
+// и проверка правил; нарушения — ненулевой код выхода.
+func cmdConfig(args []string) error {
+	if len(args) != 1 || args[0] != "check" {
+		return errUsage
+	}
+
+	cfg := core.FromEnv()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, s := range cfg.Settings() {
+		src := ""
+		if s.Default {
+			src = "(по умолчанию)"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, s.Value, src)
+	}
+	_ = tw.Flush()
+
+	problems := cfg.Validate()
+	if !money.IsKnownCurrency(cfg.Currency) {
+		problems = append(problems, core.ConfigError{Key: "SHOP_CURRENCY", Message: fmt.Sprintf("Неизвестная валюта %q.", cfg.Currency)})
+	}
+	if len(problems) == 0 {
+		fmt.Println("\nКонфигурация в порядке.")
+		return nil
+	}
+
+	fmt.Println("\nОшибки конфигурации:")
+	for _, p := range problems {
+		fmt.Printf("  %s: %s\n", p.Key, p.Message)
+	}
+	return fmt.Errorf("ошибок конфигурации: %d", len(problems))
+}
+
+// withDB — подключение к БД на время команды
+func withDB(cfg core.Config, fn func(db *sqlx.DB) error) error {
+	db, err := storage.NewDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = storage.Close(db) }()
+	return fn(db)
+}
@@ -0,0 +1,294 @@
+package apptest
+
+// apptest.go — тестовый стенд: приложение целиком (server.New + хранилище в памяти)
+// и "браузер" посетителя: cookie-сессия между запросами и CSRF-токен в POST-формах.
+//
+//	h := apptest.New(t)
+//	res := h.Get("/catalog")
+//	res = h.PostForm("/cart/add", url.Values{"product_id": {"1"}})
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/http/server"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookSecret — секрет подписи webhook в тестовом конфиге (для payment.Sign)
+const WebhookSecret = "apptest-webhook-secret-0123456789abcdef"
+
+// baseURL — адрес, от имени которого "браузер" хранит cookie
+var baseURL = &url.URL{Scheme: "http", Host: "shop.test", Path: "/"}
+
+// Harness — собранное приложение и клиент по умолчанию (первый посетитель)
+type Harness struct {
+	*Client
+	t      testing.TB
+	Engine *gin.Engine
+	Config core.Config
+	Repos  storage.Repositories // Данные стенда: можно проверить заказ или платёж напрямую
+}
+
+// Option — правка конфига до сборки приложения
+type Option func(*core.Config)
+
+// New — приложение с демо-каталогом (storage.DemoFixtures) в памяти
+func New(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+	setup(t)
+
+	cfg := Config()
+	cfg.Mail.Dir = t.TempDir()   // Письма стенда — .eml-файлы, см. Mails
+	cfg.UploadsDir = t.TempDir() // Загруженные изображения товаров
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	st := server.MemoryBackend()
+	engine, err := server.New(cfg, st, []byte("apptest-session-key-0123456789ab"))
+	if err != nil {
+		t.Fatalf("server.New: %v", err)
+	}
+
+	h := &Harness{t: t, Engine: engine, Config: cfg, Repos: st.Repos}
+	h.Client = h.NewClient()
+	return h
+}
+
+// Config — конфиг тестов: хранилище в памяти, фейковый платёжный шлюз, фиксированные секреты
+func Config() core.Config {
+	return core.Config{
+		AppName:              "myApp-test",
+		Env:                  "test",
+		CSRFKey:              "apptest-csrf-key-0123456789abcdef",
+		RequestTimeout:       5 * time.Second,
+		Currency:             "EUR",
+		Locale:               "ru",
+		PaymentProvider:      "fake",
+		PaymentWebhookSecret: WebhookSecret,
+		Storage:              core.StorageMemory,
+		BaseURL:              "http://shop.test",
+		Mail:                 core.MailConfig{Driver: core.MailDir, From: "Shop <no-reply@shop.test>"},
+		UploadMaxBytes:       2 << 20,
+		ReservationTTL:       15 * time.Minute,
+		PendingOrderTTL:      time.Hour,
+		IdempotencyTTL:       24 * time.Hour,
+		CatalogCacheControl:  "public, max-age=60",
+		ProductCacheControl:  "private, no-cache",
+	}
+}
+
+var setupOnce sync.Once
+
+// setup — один раз на процесс: корень модуля как рабочий каталог (шаблоны и статика
+// ищутся по относительным путям web/...) и тихий Gin без access-лога.
+func setup(t testing.TB) {
+	t.Helper()
+	var err error
+	setupOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+		gin.DefaultWriter = io.Discard
+
+		var dir string
+		if dir, err = os.Getwd(); err != nil {
+			return
+		}
+		for {
+			if _, statErr := os.Stat(filepath.Join(dir, "go.mod")); statErr == nil {
+				err = os.Chdir(dir)
+				return
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				err = os.ErrNotExist
+				return
+			}
+			dir = parent
+		}
+	})
+	if err != nil {
+		t.Fatalf("apptest: корень модуля не найден: %v", err)
+	}
+}
+
+// Client — посетитель сайта со своей cookie-сессией
+type Client struct {
+	h   *Harness
+	jar *cookiejar.Jar
+}
+
+// NewClient — ещё один посетитель (отдельная сессия, общее приложение и данные)
+func (h *Harness) NewClient() *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{h: h, jar: jar}
+}
+
+// Do — выполняет запрос с cookie клиента и запоминает выданные cookie
+func (c *Client) Do(req *http.Request) *Response {
+	for _, ck := range c.jar.Cookies(baseURL) {
+		req.AddCookie(ck)
+	}
+
+	rec := httptest.NewRecorder()
+	c.h.Engine.ServeHTTP(rec, req)
+
+	res := rec.Result()
+	c.jar.SetCookies(baseURL, res.Cookies())
+	return &Response{Code: rec.Code, Header: rec.Header(), Body: rec.Body.String()}
+}
+
+// Get — GET-запрос
+func (c *Client) Get(path string) *Response {
+	return c.Do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// PostForm — POST формы с действующим CSRF-токеном сессии (как отправка из браузера)
+func (c *Client) PostForm(path string, form url.Values) *Response {
+	values := url.Values{}
+	for k, v := range form {
+		values[k] = v
+	}
+	values.Set("_csrf", c.CSRFToken())
+	return c.PostFormRaw(path, values)
+}
+
+// PostFormRaw — POST формы как есть, без CSRF-токена
+func (c *Client) PostFormRaw(path string, form url.Values) *Response {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
+
+// File — файл для PostMultipart
+type File struct {
+	Name string // Имя файла у клиента
+	Data []byte
+}
+
+// PostMultipart — POST multipart/form-data с файлами (поле → файл) и CSRF-токеном сессии
+func (c *Client) PostMultipart(path string, form url.Values, files map[string]File) *Response {
+	c.h.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("_csrf", c.CSRFToken())
+	for k, vals := range form {
+		for _, v := range vals {
+			_ = w.WriteField(k, v)
+		}
+	}
+	for field, f := range files {
+		part, err := w.CreateFormFile(field, f.Name)
+		if err != nil {
+			c.h.t.Fatal(err)
+		}
+		_, _ = part.Write(f.Data)
+	}
+	if err := w.Close(); err != nil {
+		c.h.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return c.Do(req)
+}
+
+// SendJSON — запрос к API с JSON-телом (nil — без тела) и CSRF-токеном в X-CSRF-Token
+func (c *Client) SendJSON(method, path string, body any) *Response {
+	c.h.t.Helper()
+	req := jsonRequest(c.h.t, method, path, body)
+	req.Header.Set("X-CSRF-Token", c.CSRFToken())
+	return c.Do(req)
+}
+
+// SendIdempotent — SendJSON с заголовком Idempotency-Key (повтор запроса клиентом API)
+func (c *Client) SendIdempotent(method, path, key string, body any) *Response {
+	c.h.t.Helper()
+	req := jsonRequest(c.h.t, method, path, body)
+	req.Header.Set("X-CSRF-Token", c.CSRFToken())
+	req.Header.Set("Idempotency-Key", key)
+	return c.Do(req)
+}
+
+// SendToken — запрос скрипта: Authorization: Bearer и JSON-тело (nil — без тела),
+// без cookie и CSRF-токена
+func (h *Harness) SendToken(method, path, token string, body any) *Response {
+	h.t.Helper()
+	req := jsonRequest(h.t, method, path, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return h.NewClient().Do(req)
+}
+
+// jsonRequest — запрос с JSON-телом (nil — без тела)
+func jsonRequest(t testing.TB, method, path string, body any) *http.Request {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+var csrfFieldRe = regexp.MustCompile(`name="_csrf" value="([^"]+)"`)
+
+// CSRFToken — токен из скрытого поля формы (/form), привязанный к сессии клиента
+func (c *Client) CSRFToken() string {
+	c.h.t.Helper()
+	res := c.Get("/form")
+	m := csrfFieldRe.FindStringSubmatch(res.Body)
+	if m == nil {
+		c.h.t.Fatalf("CSRF-токен не найден на /form (status %d)", res.Code)
+	}
+	return html.UnescapeString(m[1])
+}
+
+// Response — ответ приложения
+type Response struct {
+	Code   int
+	Header http.Header
+	Body   string
+}
+
+// Location — адрес редиректа
+func (r *Response) Location() string {
+	return r.Header.Get("Location")
+}
+
+// Problem — тело ошибки RFC 7807 (core.FailC); ok=false — ответ не ProblemDetail
+func (r *Response) Problem() (p core.ProblemDetail, ok bool) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return p, false
+	}
+	if err := json.Unmarshal([]byte(r.Body), &p); err != nil || p.Code == "" {
+		return p, false
+	}
+	return p, true
+}
+
+// JSON — разбирает JSON-тело в v
+func (r *Response) JSON(v any) error {
+	return json.Unmarshal([]byte(r.Body), v)
+}
@@ -0,0 +1,147 @@
+package apptest
+
+// flows.go — типовые сценарии покупателя, на которых строятся тесты заказов и оплаты
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"myApp/internal/http/handler"
+	"myApp/internal/mail"
+)
+
+// AddToCart — кладёт товар в корзину (POST /cart/add)
+func (c *Client) AddToCart(productID, qty int) {
+	c.h.t.Helper()
+	res := c.PostForm("/cart/add", url.Values{
+		"product_id": {strconv.Itoa(productID)},
+		"quantity":   {strconv.Itoa(qty)},
+	})
+	if res.Code != http.StatusSeeOther {
+		c.h.t.Fatalf("POST /cart/add: status %d, body %s", res.Code, res.Body)
+	}
+}
+
+// FillCheckout — проходит шаги контактов, адреса и доставки с валидными данными
+func (c *Client) FillCheckout(shipping string) {
+	c.h.t.Helper()
+	steps := []struct {
+		path string
+		form url.Values
+	}{
+		{"/checkout/contact", url.Values{"name": {"Иван Петров"}, "email": {"ivan@example.com"}, "phone": {"+358401234567"}}},
+		{"/checkout/address", url.Values{"country": {"Finland"}, "city": {"Hamina"}, "postal_code": {"49400"}, "address": {"Isokatu 1"}}},
+		{"/checkout/shipping", url.Values{"shipping_method": {shipping}}},
+	}
+	for _, st := range steps {
+		if res := c.PostForm(st.path, st.form); res.Code != http.StatusSeeOther {
+			c.h.t.Fatalf("POST %s: status %d, body %s", st.path, res.Code, res.Body)
+		}
+	}
+}
+
+// PlaceOrder — корзина (товар 1 × 2) → оформление → подтверждение; возвращает номер заказа
+func (c *Client) PlaceOrder() string {
+	c.h.t.Helper()
+	c.AddToCart(1, 2)
+	c.FillCheckout("post")
+
+	res := c.PostForm("/checkout/confirm", nil)
+	number, ok := strings.CutPrefix(res.Location(), "/orders/")
+	if res.Code != http.StatusSeeOther || !ok {
+		c.h.t.Fatalf("POST /checkout/confirm: status %d, location %q", res.Code, res.Location())
+	}
+	return number
+}
+
+// StartPayment — POST /orders/:number/pay; возвращает путь страницы оплаты фейкового шлюза
+func (c *Client) StartPayment(number string) string {
+	c.h.t.Helper()
+	res := c.PostForm("/orders/"+number+"/pay", nil)
+	if res.Code != http.StatusSeeOther || !strings.HasPrefix(res.Location(), "/payments/fake/") {
+		c.h.t.Fatalf("POST /orders/%s/pay: status %d, location %q", number, res.Code, res.Location())
+	}
+	return res.Location()
+}
+
+// Register — регистрирует посетителя (POST /register) и оставляет его вошедшим
+func (c *Client) Register(name, email, password string) {
+	c.h.t.Helper()
+	res := c.PostForm("/register", url.Values{
+		"name":     {name},
+		"email":    {email},
+		"password": {password},
+		"confirm":  {password},
+	})
+	if res.Code != http.StatusSeeOther {
+		c.h.t.Fatalf("POST /register: status %d, body %.300s", res.Code, res.Body)
+	}
+}
+
+// RegisterAs — регистрирует посетителя с ролью (storage.RoleStaff, storage.RoleAdmin, ...)
+// и оставляет его вошедшим; пароль — "s3cret-pass"
+func (c *Client) RegisterAs(email, role string) {
+	c.h.t.Helper()
+	c.Register("Сотрудник", email, "s3cret-pass")
+	u, err := c.h.Repos.Users.GetByEmail(context.Background(), email)
+	if err != nil {
+		c.h.t.Fatalf("пользователь %s: %v", email, err)
+	}
+	if err := c.h.Repos.Users.SetRole(context.Background(), u.ID, role); err != nil {
+		c.h.t.Fatalf("роль %s: %v", role, err)
+	}
+}
+
+// Login — вход (POST /login); возвращает ответ для проверки редиректа или ошибки
+func (c *Client) Login(email, password string) *Response {
+	c.h.t.Helper()
+	return c.PostForm("/login", url.Values{"email": {email}, "password": {password}})
+}
+
+// Mails — письма, отправленные приложением, в порядке отправки (после фоновых задач)
+func (h *Harness) Mails() []mail.Message {
+	h.t.Helper()
+	handler.WaitBackground()
+	list, err := mail.ReadDir(h.Config.Mail.Dir)
+	if err != nil {
+		h.t.Fatalf("письма стенда: %v", err)
+	}
+	return list
+}
+
+// MailLink — путь с query из последнего письма на адрес to, ведущий на path
+// ("/account/verify" → "/account/verify?token=..."): по нему "кликает" клиент
+func (h *Harness) MailLink(to, path string) string {
+	h.t.Helper()
+	prefix := h.Config.BaseURL + path + "?"
+	list := h.Mails()
+	for i := len(list) - 1; i >= 0; i-- {
+		if !strings.Contains(list[i].To, to) {
+			continue
+		}
+		for _, field := range strings.Fields(list[i].Text) {
+			if strings.HasPrefix(field, prefix) {
+				return strings.TrimPrefix(field, h.Config.BaseURL)
+			}
+		}
+	}
+	h.t.Fatalf("нет письма на %s со ссылкой %s", to, path)
+	return ""
+}
+
+var createdTokenRe = regexp.MustCompile(`id="created-token"[^>]*value="([^"]+)"`)
+
+// CreateAPIToken — API-токен вошедшего посетителя через /account/tokens (срок 30 дней)
+func (c *Client) CreateAPIToken(name string, scopes ...string) string {
+	c.h.t.Helper()
+	res := c.PostForm("/account/tokens", url.Values{"name": {name}, "scopes": scopes, "days": {"30"}})
+	m := createdTokenRe.FindStringSubmatch(res.Body)
+	if res.Code != http.StatusOK || m == nil {
+		c.h.t.Fatalf("токен не создан: %d %.300s", res.Code, res.Body)
+	}
+	return html.UnescapeString(m[1])
+}
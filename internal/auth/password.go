@@ -0,0 +1,119 @@
+package auth
+
+// password.go — хеширование паролей. Новые хеши — argon2id в формате PHC
+// ($argon2id$v=19$m=...,t=...,p=...$salt$hash). Старые bcrypt-хеши ($2a$/$2b$/$2y$)
+// принимаются при входе и перехешируются в argon2id (upgrade-on-login).
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Params — параметры argon2id
+type Params struct {
+	Memory  uint32 // КиБ
+	Time    uint32 // Число проходов
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultParams — рекомендация OWASP для argon2id (19 МиБ, 2 прохода, 1 поток).
+// Хеши с другими параметрами перехешируются при следующем входе.
+var DefaultParams = Params{Memory: 19 * 1024, Time: 2, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+// ErrInvalidHash — строка не похожа ни на argon2id, ни на bcrypt
+var ErrInvalidHash = errors.New("auth: неизвестный формат хеша пароля")
+
+// b64 — base64 без паддинга, как в эталонной реализации argon2 (PHC)
+var b64 = base64.RawStdEncoding
+
+// HashPassword — argon2id-хеш пароля со случайной солью (DefaultParams)
+func HashPassword(password string) (string, error) {
+	return hashArgon2id(password, DefaultParams)
+}
+
+func hashArgon2id(password string, p Params) (string, error) {
+	salt := make([]byte, p.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// VerifyPassword — сверяет пароль с хешем. rehash=true — пароль верный, но хеш устарел
+// (bcrypt или argon2id с другими параметрами): его нужно заменить на HashPassword(password).
+func VerifyPassword(encoded, password string) (ok, rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		got := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false, nil
+		}
+		cur := DefaultParams
+		return true, p.Memory != cur.Memory || p.Time != cur.Time || p.Threads != cur.Threads ||
+			uint32(len(salt)) != cur.SaltLen || uint32(len(key)) != cur.KeyLen, nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	}
+	return false, false, ErrInvalidHash
+}
+
+// decodeArgon2id — разбор PHC-строки argon2id
+func decodeArgon2id(encoded string) (p Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	// Защита от хеша с чрезмерными параметрами (например, подложенного в БД): не больше 1 ГиБ и 16 проходов
+	if p.Memory == 0 || p.Memory > 1<<20 || p.Time == 0 || p.Time > 16 || p.Threads == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	if salt, err = b64.DecodeString(parts[4]); err != nil || len(salt) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if key, err = b64.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.SaltLen, p.KeyLen = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
+
+// dummyHash — хеш для сравнения, когда пользователь не найден
+var dummyHash, _ = HashPassword("dummy-password-for-timing")
+
+// EqualizeTiming — тратит столько же времени, сколько проверка настоящего пароля.
+// Вызывается при входе с неизвестным email, чтобы по времени ответа нельзя было
+// узнать, зарегистрирован ли адрес.
+func EqualizeTiming(password string) {
+	_, _, _ = VerifyPassword(dummyHash, password)
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("hash = %q", hash)
+	}
+
+	other, _ := HashPassword("correct horse")
+	if other == hash {
+		t.Error("соль не случайна: одинаковые хеши одного пароля")
+	}
+
+	tests := []struct {
+		password   string
+		ok, rehash bool
+	}{
+		{"correct horse", true, false},
+		{"wrong horse", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		ok, rehash, err := VerifyPassword(hash, tt.password)
+		if err != nil || ok != tt.ok || rehash != tt.rehash {
+			t.Errorf("VerifyPassword(%q) = %v, %v, %v; want %v, %v", tt.password, ok, rehash, err, tt.ok, tt.rehash)
+		}
+	}
+}
+
+// TestVerifyPasswordUpgrade — устаревшие хеши верны, но требуют перехеширования
+func TestVerifyPasswordUpgrade(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak, err := hashArgon2id("secret-pass", Params{Memory: 8 * 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, hash := range map[string]string{"bcrypt": string(legacy), "argon2id со старыми параметрами": weak} {
+		if ok, rehash, err := VerifyPassword(hash, "secret-pass"); !ok || !rehash || err != nil {
+			t.Errorf("%s: верный пароль = %v, %v, %v; want true, true", name, ok, rehash, err)
+		}
+		if ok, rehash, err := VerifyPassword(hash, "other-pass"); ok || rehash || err != nil {
+			t.Errorf("%s: неверный пароль = %v, %v, %v; want false, false", name, ok, rehash, err)
+		}
+	}
+}
+
+func TestVerifyPasswordInvalidHash(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plain-text-password",
+		"$argon2id$v=19$m=19456,t=2,p=1$bm90LWJhc2U2NA",
+		"$argon2id$v=18$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=4194304,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$!!!$a2V5",
+	} {
+		if ok, _, err := VerifyPassword(hash, "x"); ok || err == nil {
+			t.Errorf("VerifyPassword(%q) = %v, %v; want ошибку формата", hash, ok, err)
+		}
+	}
+}
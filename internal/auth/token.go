@@ -0,0 +1,41 @@
+package auth
+
+// token.go — одноразовые токены для ссылок в письмах (подтверждение email, сброс пароля)
+// и API-токены (Authorization: Bearer).
+// В письмо уходит сам токен, в БД хранится только его SHA-256: утечка таблицы не даёт
+// рабочих ссылок. Энтропия 256 бит, поэтому медленный хеш (как для паролей) не нужен.
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewToken — случайный токен для ссылки (base64url, 43 символа) и его хеш для хранения
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken — SHA-256 токена в hex (64 символа, колонка token_hash)
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APITokenPrefix — начало API-токена: по нему токен узнаётся в логах и сканерами секретов
+const APITokenPrefix = "myapp_"
+
+// NewAPIToken — API-токен для заголовка Authorization: Bearer (префикс + 256 бит) и его хеш
+func NewAPIToken() (token, hash string, err error) {
+	raw, _, err := NewToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + raw
+	return token, HashToken(token), nil
+}
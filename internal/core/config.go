@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -27,6 +28,82 @@ type Config struct {
 	WriteTimeout      time.Duration // Таймаут записи HTTP-ответа
 	IdleTimeout       time.Duration // Таймаут простоя соединения
 	RequestTimeout    time.Duration // Общий таймаут на обработку запроса в middleware
+
+	Currency string // Валюта цен каталога (ISO 4217, например "EUR")
+	Locale   string // Локаль форматирования сумм ("ru", "fi", "en")
+
+	PaymentProvider      string // Платёжный провайдер ("fake" — локальный шлюз для разработки)
+	PaymentWebhookSecret string // Секрет HMAC-подписи webhook провайдера
+
+	Storage       string // Хранилище данных: "mysql" или "memory" (демо-каталог в памяти, без БД)
+	AutoMigrate   bool   // True — применять миграции при старте сервера
+	MigrationsDir string // Каталог с файлами NNN_name.up.sql / NNN_name.down.sql
+
+	BaseURL string     // Публичный адрес сайта для ссылок в письмах (APP_BASE_URL, без "/" в конце)
+	Mail    MailConfig // Отправка писем
+
+	UploadsDir     string // Каталог загруженных файлов (изображения товаров), раздаётся как /uploads/
+	UploadMaxBytes int    // Предел размера одного загружаемого файла (байт)
+
+	ReservationTTL  time.Duration // Сколько держится резерв товара, пока покупатель оформляет заказ
+	PendingOrderTTL time.Duration // Сколько неоплаченный заказ держит товар; потом заказ отменяется
+	IdempotencyTTL  time.Duration // Сколько хранится ответ на запрос с Idempotency-Key
+
+	CatalogCacheControl string // Cache-Control ответа /catalog/json (данные без сессии — можно public)
+	ProductCacheControl string // Cache-Control страницы товара (в HTML сессия и CSRF-токен — только private)
+
+	DB DBConfig // Подключение к MySQL
+
+	loadErrors []ConfigError // Ошибки чтения ENV (например, недоступный *_FILE)
+}
+
+// Хранилища данных (APP_STORAGE)
+const (
+	StorageMySQL  = "mysql"
+	StorageMemory = "memory"
+)
+
+// Платёжные провайдеры (PAYMENT_PROVIDER)
+const (
+	PaymentFake = "fake" // Локальный шлюз: оплату подтверждает сам покупатель (разработка, тесты)
+)
+
+// Способы отправки писем (MAIL_DRIVER)
+const (
+	MailSMTP = "smtp" // SMTP-сервер (prod)
+	MailDir  = "dir"  // .eml-файлы в MAIL_DIR (разработка и тесты)
+)
+
+// MailConfig — отправка писем: SMTP или .eml-файлы в каталоге
+type MailConfig struct {
+	Driver       string // MAIL_DRIVER: "smtp" или "dir"
+	From         string // Адрес отправителя (MAIL_FROM)
+	Dir          string // Каталог .eml-файлов для MAIL_DRIVER=dir
+	SMTPHost     string // Хост SMTP-сервера
+	SMTPPort     int    // Порт (587 — submission со STARTTLS, 465 — implicit TLS)
+	SMTPUser     string // Логин (пусто — без аутентификации)
+	SMTPPassword string // Пароль (SMTP_PASSWORD или содержимое SMTP_PASSWORD_FILE)
+	SMTPTimeout  time.Duration
+}
+
+// DBConfig — подключение к MySQL и настройки пула.
+// DSN, если задан, используется как есть (кроме обязательных parseTime/multiStatements),
+// иначе строка подключения собирается из отдельных DB_* переменных.
+type DBConfig struct {
+	DSN      string // DB_DSN — полная строка подключения go-sql-driver/mysql
+	User     string // Пользователь БД
+	Password string // Пароль БД (DB_PASSWORD или содержимое DB_PASSWORD_FILE)
+	Host     string // Хост и порт MySQL
+	Name     string // Имя базы данных
+
+	MaxOpenConns    int           // Максимум одновременных подключений
+	MaxIdleConns    int           // Подключения в пуле ожидания
+	ConnMaxLifetime time.Duration // Ротация соединений
+	ConnMaxIdleTime time.Duration // Закрывать соединение, простаивающее дольше (0 — не закрывать)
+
+	ConnectTimeout time.Duration // Таймаут подключения
+	ReadTimeout    time.Duration // Таймаут чтения
+	WriteTimeout   time.Duration // Таймаут записи
 }
 
 // fatalConfigError — централизованно логирует ошибку конфигурации и завершает работу.
@@ -42,8 +119,29 @@ func fatalConfigError(msg string, fields map[string]interface{}) {
 	os.Exit(1)
 }
 
+// ConfigError — нарушение правила конфигурации (Key — переменная окружения)
+type ConfigError struct {
+	Key     string
+	Message string
+	Fields  map[string]interface{}
+}
+
+func (e ConfigError) Error() string { return e.Message }
+
 // Load загружает конфигурацию из ENV с дефолтами и проводит валидацию для Prod.
 func Load() Config {
+	cfg := FromEnv()
+
+	// Первая же ошибка в Prod завершает процесс
+	for _, e := range cfg.Validate() {
+		fatalConfigError(e.Message, e.Fields)
+	}
+
+	return cfg
+}
+
+// FromEnv — читает конфигурацию из ENV без валидации (для `app config check`).
+func FromEnv() Config {
 	cfg := Config{
 		AppName:           getEnv("APP_NAME", "myApp"),
 		Addr:              getEnv("HTTP_ADDR", ":8080"),
@@ -59,43 +157,341 @@ func Load() Config {
 		WriteTimeout:      getEnvDuration("WRITE_TIMEOUT", 30*time.Second),
 		IdleTimeout:       getEnvDuration("IDLE_TIMEOUT", 60*time.Second),
 		RequestTimeout:    getEnvDuration("REQUEST_TIMEOUT", 15*time.Second),
+
+		Currency: strings.ToUpper(getEnv("SHOP_CURRENCY", "EUR")),
+		Locale:   getEnv("SHOP_LOCALE", "ru"),
+
+		PaymentProvider:      strings.ToLower(getEnv("PAYMENT_PROVIDER", PaymentFake)),
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", generateRandomKey()),
+
+		Storage:       strings.ToLower(getEnv("APP_STORAGE", StorageMySQL)),
+		AutoMigrate:   getEnvBool("AUTO_MIGRATE", false),
+		MigrationsDir: getEnv("MIGRATIONS_DIR", "migrations"),
+
+		BaseURL: strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:8080"), "/"),
+		Mail: MailConfig{
+			Driver:      strings.ToLower(getEnv("MAIL_DRIVER", MailDir)),
+			From:        getEnv("MAIL_FROM", "Shop <no-reply@localhost>"),
+			Dir:         getEnv("MAIL_DIR", "var/mail"),
+			SMTPHost:    getEnv("SMTP_HOST", ""),
+			SMTPPort:    getEnvInt("SMTP_PORT", 587),
+			SMTPUser:    getEnv("SMTP_USER", ""),
+			SMTPTimeout: getEnvDuration("SMTP_TIMEOUT", 10*time.Second),
+		},
+
+		UploadsDir:     getEnv("UPLOADS_DIR", "web/uploads"),
+		UploadMaxBytes: getEnvInt("UPLOAD_MAX_BYTES", 10<<20),
+
+		ReservationTTL:  getEnvDuration("RESERVATION_TTL", 15*time.Minute),
+		PendingOrderTTL: getEnvDuration("PENDING_ORDER_TTL", time.Hour),
+		IdempotencyTTL:  getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		CatalogCacheControl: getEnv("CATALOG_CACHE_CONTROL", "public, max-age=60"),
+		ProductCacheControl: getEnv("PRODUCT_CACHE_CONTROL", "private, no-cache"),
+
+		DB: DBConfig{
+			DSN:  getEnv("DB_DSN", ""),
+			User: getEnv("DB_USER", "root"),
+			Host: getEnv("DB_HOST", "localhost:3306"),
+			Name: getEnv("DB_NAME", "shop"),
+
+			MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 0),
+
+			ConnectTimeout: getEnvDuration("DB_CONNECT_TIMEOUT", 5*time.Second),
+			ReadTimeout:    getEnvDuration("DB_READ_TIMEOUT", 5*time.Second),
+			WriteTimeout:   getEnvDuration("DB_WRITE_TIMEOUT", 10*time.Second),
+		},
+	}
+
+	// Пароль БД: DB_PASSWORD_FILE (Docker/Kubernetes secrets) или DB_PASSWORD.
+	// Дефолт "admin" — только для локальной разработки, в Prod пароль обязателен.
+	password, err := getEnvOrFile("DB_PASSWORD", "admin")
+	if err != nil {
+		cfg.loadErrors = append(cfg.loadErrors, ConfigError{
+			Key:     "DB_PASSWORD_FILE",
+			Message: err.Error(),
+			Fields:  map[string]interface{}{"key": "DB_PASSWORD_FILE", "error": err.Error()},
+		})
+	}
+	cfg.DB.Password = password
+
+	smtpPassword, err := getEnvOrFile("SMTP_PASSWORD", "")
+	if err != nil {
+		cfg.loadErrors = append(cfg.loadErrors, ConfigError{
+			Key:     "SMTP_PASSWORD_FILE",
+			Message: err.Error(),
+			Fields:  map[string]interface{}{"key": "SMTP_PASSWORD_FILE", "error": err.Error()},
+		})
+	}
+	cfg.Mail.SMTPPassword = smtpPassword
+
+	return cfg
+}
+
+// Validate — правила конфигурации. Ошибки чтения ENV и настроек пула БД проверяются всегда,
+// остальные — только в продакшене: в dev допустимы случайные ключи и HTTP.
+func (c Config) Validate() []ConfigError {
+	errs := append([]ConfigError(nil), c.loadErrors...)
+
+	// Пул БД: хотя бы одно соединение, простаивающих не больше, чем открытых
+	if c.DB.MaxOpenConns < 1 || c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		errs = append(errs, ConfigError{
+			Key:     "DB_MAX_OPEN_CONNS",
+			Message: "DB_MAX_OPEN_CONNS должен быть ≥ 1, а DB_MAX_IDLE_CONNS — от 0 до DB_MAX_OPEN_CONNS.",
+			Fields:  map[string]interface{}{"max_open": c.DB.MaxOpenConns, "max_idle": c.DB.MaxIdleConns},
+		})
+	}
+
+	if c.Storage != StorageMySQL && c.Storage != StorageMemory {
+		errs = append(errs, ConfigError{
+			Key:     "APP_STORAGE",
+			Message: fmt.Sprintf("Неизвестное хранилище APP_STORAGE %q: допустимо %s или %s.", c.Storage, StorageMySQL, StorageMemory),
+			Fields:  map[string]interface{}{"key": "APP_STORAGE", "value": c.Storage},
+		})
+	}
+
+	if c.PaymentProvider != PaymentFake {
+		errs = append(errs, ConfigError{
+			Key:     "PAYMENT_PROVIDER",
+			Message: fmt.Sprintf("Неизвестный платёжный провайдер PAYMENT_PROVIDER %q: допустимо %s.", c.PaymentProvider, PaymentFake),
+			Fields:  map[string]interface{}{"key": "PAYMENT_PROVIDER", "value": c.PaymentProvider},
+		})
+	}
+
+	switch c.Mail.Driver {
+	case MailDir:
+	case MailSMTP:
+		if c.Mail.SMTPHost == "" {
+			errs = append(errs, ConfigError{
+				Key:     "SMTP_HOST",
+				Message: "MAIL_DRIVER=smtp требует SMTP_HOST.",
+				Fields:  map[string]interface{}{"key": "SMTP_HOST"},
+			})
+		}
+	default:
+		errs = append(errs, ConfigError{
+			Key:     "MAIL_DRIVER",
+			Message: fmt.Sprintf("Неизвестный MAIL_DRIVER %q: допустимо %s или %s.", c.Mail.Driver, MailSMTP, MailDir),
+			Fields:  map[string]interface{}{"key": "MAIL_DRIVER", "value": c.Mail.Driver},
+		})
+	}
+
+	if c.UploadMaxBytes < 1 || c.UploadsDir == "" {
+		errs = append(errs, ConfigError{
+			Key:     "UPLOAD_MAX_BYTES",
+			Message: "UPLOAD_MAX_BYTES должен быть ≥ 1, а UPLOADS_DIR — не пустым.",
+			Fields:  map[string]interface{}{"upload_max_bytes": c.UploadMaxBytes, "uploads_dir": c.UploadsDir},
+		})
+	}
+
+	if c.ReservationTTL < time.Minute {
+		errs = append(errs, ConfigError{
+			Key:     "RESERVATION_TTL",
+			Message: "RESERVATION_TTL должен быть не меньше минуты.",
+			Fields:  map[string]interface{}{"reservation_ttl": c.ReservationTTL.String()},
+		})
+	}
+
+	if c.PendingOrderTTL < time.Minute {
+		errs = append(errs, ConfigError{
+			Key:     "PENDING_ORDER_TTL",
+			Message: "PENDING_ORDER_TTL должен быть не меньше минуты.",
+			Fields:  map[string]interface{}{"pending_order_ttl": c.PendingOrderTTL.String()},
+		})
+	}
+
+	if c.IdempotencyTTL < time.Minute {
+		errs = append(errs, ConfigError{
+			Key:     "IDEMPOTENCY_TTL",
+			Message: "IDEMPOTENCY_TTL должен быть не меньше минуты.",
+			Fields:  map[string]interface{}{"idempotency_ttl": c.IdempotencyTTL.String()},
+		})
+	}
+
+	if !strings.Contains(strings.ToLower(c.ProductCacheControl), "private") && !strings.Contains(strings.ToLower(c.ProductCacheControl), "no-store") {
+		errs = append(errs, ConfigError{
+			Key:     "PRODUCT_CACHE_CONTROL",
+			Message: "Страница товара содержит данные сессии: PRODUCT_CACHE_CONTROL должен быть private или no-store.",
+			Fields:  map[string]interface{}{"product_cache_control": c.ProductCacheControl},
+		})
 	}
 
 	// Валидация для продакшена — ключевой этап безопасности и отказоустойчивости
-	if strings.ToLower(cfg.Env) == "prod" {
+	if strings.ToLower(c.Env) == "prod" {
 
 		// 1. Проверка силы CSRF-ключа (минимум 32 байта)
-		if !isKeyStrong(cfg.CSRFKey, 32) {
-			fatalConfigError(
-				"Недостаточная длина CSRF_KEY в продакшене. Требуется минимум 32 байта.",
-				map[string]interface{}{"key": "CSRF_KEY", "provided_length": len(cfg.CSRFKey)},
-			)
+		if !isKeyStrong(c.CSRFKey, 32) {
+			errs = append(errs, ConfigError{
+				Key:     "CSRF_KEY",
+				Message: "Недостаточная длина CSRF_KEY в продакшене. Требуется минимум 32 байта.",
+				Fields:  map[string]interface{}{"key": "CSRF_KEY", "provided_length": len(c.CSRFKey)},
+			})
 		}
 
 		// 2. Проверка адреса
-		if cfg.Addr == "" {
-			fatalConfigError("Отсутствует HTTP_ADDR в продакшене.", map[string]interface{}{"key": "HTTP_ADDR"})
+		if c.Addr == "" {
+			errs = append(errs, ConfigError{
+				Key:     "HTTP_ADDR",
+				Message: "Отсутствует HTTP_ADDR в продакшене.",
+				Fields:  map[string]interface{}{"key": "HTTP_ADDR"},
+			})
 		}
 
 		// 3. Требование HTTPS-режима (Secure=true)
 		// Гарантируем, что приложение ставит безопасные куки и HSTS (если он включен).
-		if !cfg.Secure {
-			fatalConfigError(
-				"SECURE должен быть true в продакшене. Приложение должно работать в HTTPS-режиме (даже при offload на прокси).",
-				map[string]interface{}{"key": "SECURE", "tip": "Установите SECURE=true"},
-			)
+		if !c.Secure {
+			errs = append(errs, ConfigError{
+				Key:     "SECURE",
+				Message: "SECURE должен быть true в продакшене. Приложение должно работать в HTTPS-режиме (даже при offload на прокси).",
+				Fields:  map[string]interface{}{"key": "SECURE", "tip": "Установите SECURE=true"},
+			})
 		}
 
 		// 4. Проверка файлов TLS (если TLS не offloaded)
-		if !cfg.TLSOffloaded && (cfg.CertFile == "" || cfg.KeyFile == "") {
-			fatalConfigError(
-				"TLS_CERT_FILE / TLS_KEY_FILE отсутствуют, а TLS не offloaded. Требуются файлы сертификата/ключа.",
-				map[string]interface{}{"keys_missing": []string{"TLS_CERT_FILE", "TLS_KEY_FILE"}},
-			)
+		if !c.TLSOffloaded && (c.CertFile == "" || c.KeyFile == "") {
+			errs = append(errs, ConfigError{
+				Key:     "TLS_CERT_FILE",
+				Message: "TLS_CERT_FILE / TLS_KEY_FILE отсутствуют, а TLS не offloaded. Требуются файлы сертификата/ключа.",
+				Fields:  map[string]interface{}{"keys_missing": []string{"TLS_CERT_FILE", "TLS_KEY_FILE"}},
+			})
+		}
+
+		// 5. Секрет подписи webhook: задан явно (случайный дефолт меняется при каждом рестарте)
+		if os.Getenv("PAYMENT_WEBHOOK_SECRET") == "" || !isKeyStrong(c.PaymentWebhookSecret, 32) {
+			errs = append(errs, ConfigError{
+				Key:     "PAYMENT_WEBHOOK_SECRET",
+				Message: "PAYMENT_WEBHOOK_SECRET должен быть задан в продакшене (минимум 32 байта).",
+				Fields:  map[string]interface{}{"key": "PAYMENT_WEBHOOK_SECRET"},
+			})
+		}
+
+		// 6. БД: либо полный DB_DSN, либо явно заданный пароль (дефолт "admin" — только для dev)
+		if c.DB.DSN == "" && os.Getenv("DB_PASSWORD") == "" && os.Getenv("DB_PASSWORD_FILE") == "" {
+			errs = append(errs, ConfigError{
+				Key:     "DB_PASSWORD",
+				Message: "В продакшене задайте DB_DSN или DB_PASSWORD / DB_PASSWORD_FILE.",
+				Fields:  map[string]interface{}{"keys_missing": []string{"DB_DSN", "DB_PASSWORD", "DB_PASSWORD_FILE"}},
+			})
+		}
+
+		// 7. Хранилище в памяти теряет заказы при перезапуске — только для разработки и тестов
+		if c.Storage == StorageMemory {
+			errs = append(errs, ConfigError{
+				Key:     "APP_STORAGE",
+				Message: "APP_STORAGE=memory недопустим в продакшене: данные не сохраняются между перезапусками.",
+				Fields:  map[string]interface{}{"key": "APP_STORAGE"},
+			})
+		}
+
+		// 8. Фейковый шлюз: страница /payments/fake/:id позволяет любому покупателю отметить заказ оплаченным
+		if c.PaymentProvider == PaymentFake {
+			errs = append(errs, ConfigError{
+				Key:     "PAYMENT_PROVIDER",
+				Message: "PAYMENT_PROVIDER=fake недопустим в продакшене: оплату подтверждает сам покупатель.",
+				Fields:  map[string]interface{}{"key": "PAYMENT_PROVIDER"},
+			})
+		}
+
+		// 9. Письма уходят покупателям, а ссылки в них ведут на настоящий HTTPS-адрес сайта
+		if c.Mail.Driver != MailSMTP {
+			errs = append(errs, ConfigError{
+				Key:     "MAIL_DRIVER",
+				Message: "В продакшене нужен MAIL_DRIVER=smtp: письма из MAIL_DIR никто не получит.",
+				Fields:  map[string]interface{}{"key": "MAIL_DRIVER", "value": c.Mail.Driver},
+			})
+		}
+		if !strings.HasPrefix(c.BaseURL, "https://") {
+			errs = append(errs, ConfigError{
+				Key:     "APP_BASE_URL",
+				Message: "APP_BASE_URL должен быть https-адресом сайта в продакшене (ссылки в письмах).",
+				Fields:  map[string]interface{}{"key": "APP_BASE_URL", "value": c.BaseURL},
+			})
 		}
 	}
 
-	return cfg
+	return errs
+}
+
+// Setting — одна настройка для вывода в `app config check`
+type Setting struct {
+	Key     string // Переменная окружения
+	Value   string // Действующее значение (секреты замаскированы)
+	Default bool   // Переменная не задана, используется дефолт
+}
+
+// Settings — действующие настройки в порядке полей Config; секреты не выводятся.
+func (c Config) Settings() []Setting {
+	list := []Setting{
+		{Key: "APP_NAME", Value: c.AppName},
+		{Key: "HTTP_ADDR", Value: c.Addr},
+		{Key: "APP_ENV", Value: c.Env},
+		{Key: "CSRF_KEY", Value: maskSecret(c.CSRFKey)},
+		{Key: "SECURE", Value: fmt.Sprint(c.Secure)},
+		{Key: "TLS_OFFLOADED", Value: fmt.Sprint(c.TLSOffloaded)},
+		{Key: "TLS_CERT_FILE", Value: c.CertFile},
+		{Key: "TLS_KEY_FILE", Value: c.KeyFile},
+		{Key: "SHUTDOWN_TIMEOUT", Value: c.ShutdownTimeout.String()},
+		{Key: "READ_HEADER_TIMEOUT", Value: c.ReadHeaderTimeout.String()},
+		{Key: "READ_TIMEOUT", Value: c.ReadTimeout.String()},
+		{Key: "WRITE_TIMEOUT", Value: c.WriteTimeout.String()},
+		{Key: "IDLE_TIMEOUT", Value: c.IdleTimeout.String()},
+		{Key: "REQUEST_TIMEOUT", Value: c.RequestTimeout.String()},
+		{Key: "SHOP_CURRENCY", Value: c.Currency},
+		{Key: "SHOP_LOCALE", Value: c.Locale},
+		{Key: "PAYMENT_PROVIDER", Value: c.PaymentProvider},
+		{Key: "PAYMENT_WEBHOOK_SECRET", Value: maskSecret(c.PaymentWebhookSecret)},
+		{Key: "APP_STORAGE", Value: c.Storage},
+		{Key: "AUTO_MIGRATE", Value: fmt.Sprint(c.AutoMigrate)},
+		{Key: "MIGRATIONS_DIR", Value: c.MigrationsDir},
+		{Key: "APP_BASE_URL", Value: c.BaseURL},
+		{Key: "MAIL_DRIVER", Value: c.Mail.Driver},
+		{Key: "MAIL_FROM", Value: c.Mail.From},
+		{Key: "MAIL_DIR", Value: c.Mail.Dir},
+		{Key: "SMTP_HOST", Value: c.Mail.SMTPHost},
+		{Key: "SMTP_PORT", Value: fmt.Sprint(c.Mail.SMTPPort)},
+		{Key: "SMTP_USER", Value: c.Mail.SMTPUser},
+		{Key: "SMTP_PASSWORD", Value: maskSecret(c.Mail.SMTPPassword)},
+		{Key: "SMTP_PASSWORD_FILE", Value: os.Getenv("SMTP_PASSWORD_FILE")},
+		{Key: "SMTP_TIMEOUT", Value: c.Mail.SMTPTimeout.String()},
+		{Key: "UPLOADS_DIR", Value: c.UploadsDir},
+		{Key: "UPLOAD_MAX_BYTES", Value: fmt.Sprint(c.UploadMaxBytes)},
+		{Key: "RESERVATION_TTL", Value: c.ReservationTTL.String()},
+		{Key: "PENDING_ORDER_TTL", Value: c.PendingOrderTTL.String()},
+		{Key: "IDEMPOTENCY_TTL", Value: c.IdempotencyTTL.String()},
+		{Key: "CATALOG_CACHE_CONTROL", Value: c.CatalogCacheControl},
+		{Key: "PRODUCT_CACHE_CONTROL", Value: c.ProductCacheControl},
+		{Key: "DB_DSN", Value: maskSecret(c.DB.DSN)},
+		{Key: "DB_USER", Value: c.DB.User},
+		{Key: "DB_PASSWORD", Value: maskSecret(c.DB.Password)},
+		{Key: "DB_PASSWORD_FILE", Value: os.Getenv("DB_PASSWORD_FILE")},
+		{Key: "DB_HOST", Value: c.DB.Host},
+		{Key: "DB_NAME", Value: c.DB.Name},
+		{Key: "DB_MAX_OPEN_CONNS", Value: fmt.Sprint(c.DB.MaxOpenConns)},
+		{Key: "DB_MAX_IDLE_CONNS", Value: fmt.Sprint(c.DB.MaxIdleConns)},
+		{Key: "DB_CONN_MAX_LIFETIME", Value: c.DB.ConnMaxLifetime.String()},
+		{Key: "DB_CONN_MAX_IDLE_TIME", Value: c.DB.ConnMaxIdleTime.String()},
+		{Key: "DB_CONNECT_TIMEOUT", Value: c.DB.ConnectTimeout.String()},
+		{Key: "DB_READ_TIMEOUT", Value: c.DB.ReadTimeout.String()},
+		{Key: "DB_WRITE_TIMEOUT", Value: c.DB.WriteTimeout.String()},
+	}
+	for i := range list {
+		list[i].Default = strings.TrimSpace(os.Getenv(list[i].Key)) == ""
+		if (list[i].Key == "DB_PASSWORD" || list[i].Key == "SMTP_PASSWORD") && os.Getenv(list[i].Key+"_FILE") != "" {
+			list[i].Default = false
+		}
+	}
+	return list
+}
+
+// maskSecret — секрет в выводе: только факт наличия и длина
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return fmt.Sprintf("*** (%d символов)", len(s))
 }
 
 // getEnv — Извлекает строку из ENV, убирает пробелы, или возвращает дефолт.
@@ -116,6 +512,38 @@ func getEnvBool(key string, def bool) bool {
 	return v == "true" || v == "1" || v == "yes" || v == "on"
 }
 
+// getEnvInt — Извлекает int из ENV. При ошибке формата логирует и возвращает дефолт.
+func getEnvInt(key string, def int) int {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		LogError("Неверный формат числа. Используется дефолт.", map[string]interface{}{"key": key, "value": val, "default": def})
+		return def
+	}
+	return n
+}
+
+// getEnvOrFile — значение из файла KEY_FILE (Docker secrets) или из KEY.
+// Одновременно заданные KEY и KEY_FILE — ошибка: непонятно, какой из них действует.
+func getEnvOrFile(key, def string) (string, error) {
+	path := strings.TrimSpace(os.Getenv(key + "_FILE"))
+	if path == "" {
+		return getEnv(key, def), nil
+	}
+	if os.Getenv(key) != "" {
+		return def, fmt.Errorf("заданы одновременно %s и %s_FILE", key, key)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return def, fmt.Errorf("чтение %s_FILE: %w", key, err)
+	}
+	// Файлы секретов обычно заканчиваются переводом строки
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
 // getEnvDuration — Извлекает time.Duration из ENV. Поддерживает форматы Go ("30s") или просто число (интерпретируется как секунды).
 func getEnvDuration(key string, def time.Duration) time.Duration {
 	val := strings.TrimSpace(os.Getenv(key))
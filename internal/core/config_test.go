@@ -0,0 +1,39 @@
+package core_test
+
+import (
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/core"
+)
+
+// hasConfigError — есть ли среди ошибок валидации ошибка по переменной key
+func hasConfigError(errs []core.ConfigError, key string) bool {
+	for _, e := range errs {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// TestValidatePaymentProvider — фейковый шлюз допустим вне продакшена, неизвестный провайдер — нигде
+func TestValidatePaymentProvider(t *testing.T) {
+	tests := []struct {
+		env, provider string
+		invalid       bool
+	}{
+		{"test", core.PaymentFake, false},
+		{"dev", core.PaymentFake, false},
+		{"prod", core.PaymentFake, true},
+		{"test", "stripe", true},
+		{"prod", "stripe", true},
+	}
+	for _, tt := range tests {
+		cfg := apptest.Config()
+		cfg.Env, cfg.PaymentProvider = tt.env, tt.provider
+		if got := hasConfigError(cfg.Validate(), "PAYMENT_PROVIDER"); got != tt.invalid {
+			t.Errorf("APP_ENV=%s PAYMENT_PROVIDER=%s: ошибка %v, want %v", tt.env, tt.provider, got, tt.invalid)
+		}
+	}
+}
@@ -1,11 +1,25 @@
 package core
 
-// context.go
+// context.go — значения запроса, которые кладёт middleware, а читают обработчики и шаблоны.
+// Ключи не экспортируются: положить и достать значение можно только через функции ниже,
+// поэтому опечатка в ключе или неверный тип — ошибка компиляции, а не пустая строка.
 
-// CtxKey — тип ключей для context.Context (чтобы избежать коллизий строк)
-type CtxKey string
+import "context"
+
+// ctxKey — тип ключей для context.Context (чтобы избежать коллизий строк)
+type ctxKey int
 
 const (
-	// CtxNonce — ключ для CSP nonce (кладётся в request.Context в middleware)
-	CtxNonce CtxKey = "nonce"
+	ctxNonce ctxKey = iota // CSP nonce текущего запроса
 )
+
+// WithNonce — контекст запроса с CSP nonce (вызывает middleware до CSP и обработчиков)
+func WithNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, ctxNonce, nonce)
+}
+
+// Nonce — CSP nonce текущего запроса ("" — middleware не подключён)
+func Nonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(ctxNonce).(string)
+	return nonce
+}
@@ -50,7 +50,7 @@ func SecureHeaders() gin.HandlerFunc {
 
 func CSPBasic() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		nonce, _ := c.Request.Context().Value(CtxNonce).(string)
+		nonce := Nonce(c.Request.Context())
 
 		c.Header("Content-Security-Policy",
 			"default-src 'self'; "+
@@ -73,7 +73,7 @@ func CSPBasic() gin.HandlerFunc {
 
 func CSPAllowInlineStyles() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		nonce, _ := c.Request.Context().Value(CtxNonce).(string)
+		nonce := Nonce(c.Request.Context())
 
 		c.Header("Content-Security-Policy",
 			"default-src 'self'; "+
@@ -89,7 +89,7 @@ func CSPAllowInlineStyles() gin.HandlerFunc {
 
 func CSPStrictLocalOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		nonce, _ := c.Request.Context().Value(CtxNonce).(string)
+		nonce := Nonce(c.Request.Context())
 
 		c.Header("Content-Security-Policy",
 			"default-src 'self'; "+
@@ -5,7 +5,6 @@ import (
 	"net/http"
 
 	"myApp/internal/core"
-	"myApp/internal/view"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,7 +17,7 @@ type AboutData struct {
 }
 
 // About — обработчик страницы "О нас" (OWASP A03: Injection)
-func About(tpl *view.Templates) gin.HandlerFunc {
+func About(app *App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Создаем данные для передачи
 		data := AboutData{
@@ -28,7 +27,7 @@ func About(tpl *view.Templates) gin.HandlerFunc {
 		}
 
 		// Передаем структуру data в качестве последнего аргумента
-		if err := tpl.Render(c, "about", "О нас", data); err != nil {
+		if err := app.Templates.Render(c, "about", "О нас", data); err != nil {
 			core.LogError("Ошибка рендеринга шаблона about", map[string]interface{}{
 				"error": err.Error(),
 				"path":  c.Request.URL.Path,
@@ -0,0 +1,259 @@
+package handler
+
+// account.go — подтверждение email и сброс пароля по ссылке из письма.
+// Токен в ссылке одноразовый и ограничен по времени; в БД хранится только его хеш (auth.HashToken).
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"myApp/internal/auth"
+	"myApp/internal/core"
+	"myApp/internal/mail"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Срок действия ссылок из писем
+const (
+	verifyTokenTTL = 48 * time.Hour
+	resetTokenTTL  = time.Hour
+)
+
+// ResetRequestForm — email для письма со ссылкой сброса
+type ResetRequestForm struct {
+	Email string `validate:"required,email,max=255"`
+}
+
+// ResetForm — новый пароль по ссылке из письма
+type ResetForm struct {
+	Password string `validate:"required,min=8,max=128"`
+	Confirm  string `validate:"eqfield=Password"`
+}
+
+// resetRequestFields — сообщения валидации формы восстановления
+var resetRequestFields = map[string]formField{
+	"Email": checkoutFields["Email"],
+}
+
+// resetFields — сообщения валидации нового пароля (как при регистрации)
+var resetFields = map[string]formField{
+	"Password": registerFields["Password"],
+	"Confirm":  registerFields["Confirm"],
+}
+
+// AccountView — данные для reset_request.html, reset_confirm.html и account_message.html
+type AccountView struct {
+	Email   string
+	Token   string // Токен сброса в скрытом поле формы нового пароля
+	Sent    bool   // Письмо со ссылкой отправлено (если адрес зарегистрирован)
+	Message string // Текст account_message.html
+	OK      bool   // account_message.html: успех или ошибка
+	Errors  map[string]string
+}
+
+// VerifyEmail — GET /account/verify?token=...: подтверждает email и гасит токен
+func VerifyEmail(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		userID, err := app.Tokens.Consume(ctx, storage.TokenVerifyEmail, auth.HashToken(c.Query("token")))
+		if err != nil {
+			accountTokenError(c, app, "Подтверждение email", err)
+			return
+		}
+		if err := app.Users.MarkEmailVerified(ctx, userID); err != nil {
+			core.FailC(c, core.Internal("Ошибка подтверждения email", err))
+			return
+		}
+		core.LogInfo("Email подтверждён", map[string]interface{}{"user_id": userID})
+		renderAccount(c, app, "account_message", "Подтверждение email", AccountView{OK: true, Message: "Email подтверждён. Спасибо!"})
+	}
+}
+
+// ResendVerification — POST /account/verify/resend: новое письмо для вошедшего пользователя
+func ResendVerification(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c.Request.Context())
+		if user == nil {
+			c.Redirect(http.StatusSeeOther, "/login")
+			return
+		}
+		if user.VerifiedAt == nil {
+			sendVerification(c.Request.Context(), app, user)
+		}
+		renderAccount(c, app, "account_message", "Подтверждение email", AccountView{
+			OK:      true,
+			Message: fmt.Sprintf("Ссылка для подтверждения отправлена на %s.", user.Email),
+		})
+	}
+}
+
+// ResetRequestPage — GET /account/reset: форма email (?sent=1 — письмо отправлено)
+func ResetRequestPage(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		renderAccount(c, app, "reset_request", "Восстановление пароля", AccountView{Sent: c.Query("sent") == "1"})
+	}
+}
+
+// ResetRequestSubmit — POST /account/reset. Ответ одинаков для зарегистрированного
+// и неизвестного адреса: по форме нельзя узнать, есть ли у магазина такой покупатель.
+// Токен и письмо — в фоне: по времени ответа (SMTP) адрес тоже не угадать.
+func ResetRequestSubmit(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !parseAuthForm(c) {
+			return
+		}
+
+		form := ResetRequestForm{Email: storage.NormalizeEmail(formValue(c, "email"))}
+		if errs := validationErrors(validate.Struct(form), resetRequestFields); len(errs) > 0 {
+			c.Status(http.StatusBadRequest)
+			renderAccount(c, app, "reset_request", "Восстановление пароля", AccountView{Email: form.Email, Errors: errs})
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := app.Users.GetByEmail(ctx, form.Email)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			core.LogInfo("Сброс пароля для неизвестного email", map[string]interface{}{"ip": c.ClientIP()})
+		case err != nil:
+			core.FailC(c, core.Internal("Ошибка восстановления пароля", err))
+			return
+		default:
+			goBackground(ctx, func(ctx context.Context) { sendPasswordReset(ctx, app, user) })
+		}
+		c.Redirect(http.StatusSeeOther, "/account/reset?sent=1")
+	}
+}
+
+// ResetConfirmPage — GET /account/reset/confirm?token=...: форма нового пароля.
+// Токен проверяется, но не гасится — его гасит отправка формы.
+func ResetConfirmPage(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if _, err := app.Tokens.Find(c.Request.Context(), storage.TokenResetPassword, auth.HashToken(token)); err != nil {
+			accountTokenError(c, app, "Восстановление пароля", err)
+			return
+		}
+		renderAccount(c, app, "reset_confirm", "Новый пароль", AccountView{Token: token})
+	}
+}
+
+// ResetConfirmSubmit — POST /account/reset/confirm: гасит токен и меняет пароль.
+// Письмо со ссылкой пришло на этот адрес — email заодно считается подтверждённым.
+// API-токены пользователя отзываются (в той же транзакции, что и смена пароля): их мог выпустить
+// тот, кто знал старый пароль.
+func ResetConfirmSubmit(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !parseAuthForm(c) {
+			return
+		}
+
+		token := c.Request.PostForm.Get("token")
+		form := ResetForm{
+			Password: c.Request.PostForm.Get("password"),
+			Confirm:  c.Request.PostForm.Get("confirm"),
+		}
+		if errs := validationErrors(validate.Struct(form), resetFields); len(errs) > 0 {
+			c.Status(http.StatusBadRequest)
+			renderAccount(c, app, "reset_confirm", "Новый пароль", AccountView{Token: token, Errors: errs})
+			return
+		}
+
+		hash, err := auth.HashPassword(form.Password)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка смены пароля", err))
+			return
+		}
+
+		// Токен, пароль, email и отзыв API-токенов — одна транзакция: при сбое пароль прежний,
+		// ссылка из письма действует и сброс можно повторить
+		userID, revoked, err := app.Tokens.ResetPassword(c.Request.Context(), auth.HashToken(token), hash)
+		if errors.Is(err, storage.ErrTokenInvalid) {
+			accountTokenError(c, app, "Восстановление пароля", err)
+			return
+		}
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка смены пароля", err))
+			return
+		}
+		core.LogInfo("Пароль изменён по ссылке", map[string]interface{}{"user_id": userID, "api_tokens_revoked": revoked})
+		c.Redirect(http.StatusSeeOther, "/login?reset=1")
+	}
+}
+
+// sendVerification — письмо со ссылкой подтверждения email. Ошибка только логируется:
+// регистрация не должна падать из-за почты, письмо можно запросить повторно.
+func sendVerification(ctx context.Context, app *App, user *storage.User) {
+	link, err := accountLink(ctx, app, user, storage.TokenVerifyEmail, verifyTokenTTL, "/account/verify")
+	if err != nil {
+		return
+	}
+	sendAccountMail(ctx, app, user, mail.Message{
+		Subject: "Подтвердите email",
+		Text: fmt.Sprintf("Здравствуйте, %s!\n\nПодтвердите адрес электронной почты по ссылке:\n%s\n\n"+
+			"Ссылка действует %d часов. Если вы не регистрировались в магазине, просто проигнорируйте это письмо.\n",
+			user.Name, link, int(verifyTokenTTL.Hours())),
+	})
+}
+
+// sendPasswordReset — письмо со ссылкой на форму нового пароля
+func sendPasswordReset(ctx context.Context, app *App, user *storage.User) {
+	link, err := accountLink(ctx, app, user, storage.TokenResetPassword, resetTokenTTL, "/account/reset/confirm")
+	if err != nil {
+		return
+	}
+	sendAccountMail(ctx, app, user, mail.Message{
+		Subject: "Восстановление пароля",
+		Text: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %d минут и сработает один раз. Если вы не запрашивали смену пароля, "+
+			"просто проигнорируйте это письмо — пароль останется прежним.\n",
+			user.Name, link, int(resetTokenTTL.Minutes())),
+	})
+}
+
+// accountLink — новый токен и абсолютная ссылка с ним (APP_BASE_URL + path)
+func accountLink(ctx context.Context, app *App, user *storage.User, purpose storage.TokenPurpose, ttl time.Duration, path string) (string, error) {
+	token, hash, err := auth.NewToken()
+	if err == nil {
+		err = app.Tokens.Create(ctx, user.ID, purpose, hash, ttl)
+	}
+	if err != nil {
+		core.LogError("Ошибка создания токена", map[string]interface{}{"user_id": user.ID, "purpose": purpose, "error": err.Error()})
+		return "", err
+	}
+	return app.Config.BaseURL + path + "?token=" + url.QueryEscape(token), nil
+}
+
+func sendAccountMail(ctx context.Context, app *App, user *storage.User, msg mail.Message) {
+	msg.To = user.Email
+	if err := app.Mailer.Send(ctx, msg); err != nil {
+		core.LogError("Ошибка отправки письма", map[string]interface{}{"user_id": user.ID, "subject": msg.Subject, "error": err.Error()})
+	}
+}
+
+// accountTokenError — недействительная ссылка: страница с ошибкой (400), прочее — 500
+func accountTokenError(c *gin.Context, app *App, title string, err error) {
+	if !errors.Is(err, storage.ErrTokenInvalid) {
+		core.FailC(c, core.Internal("Ошибка проверки ссылки", err))
+		return
+	}
+	c.Status(storage.ErrTokenInvalid.Status)
+	renderAccount(c, app, "account_message", title, AccountView{Message: storage.ErrTokenInvalid.Message})
+}
+
+// renderAccount — рендер страниц account.go
+func renderAccount(c *gin.Context, app *App, name, title string, data AccountView) {
+	if data.Errors == nil {
+		data.Errors = map[string]string{}
+	}
+	if err := app.Templates.Render(c, name, title, data); err != nil {
+		core.LogError("Ошибка рендеринга "+name, map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+// admin.go — панель управления /admin. Весь раздел закрыт правом storage.PermAdminAccess
+// (см. registerRoutes), отдельные действия — своими правами.
+import (
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminView — данные для admin_dashboard.html
+type AdminView struct {
+	User *storage.User
+}
+
+// AdminDashboard — GET /admin
+func AdminDashboard(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		renderAdmin(c, app, "admin_dashboard", "Панель управления", AdminView{User: CurrentUser(c.Request.Context())})
+	}
+}
+
+// renderAdmin — рендер страниц панели управления
+func renderAdmin(c *gin.Context, app *App, name, title string, data any) {
+	if err := app.Templates.Render(c, name, title, data); err != nil {
+		core.LogError("Ошибка рендеринга "+name, map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+	}
+}
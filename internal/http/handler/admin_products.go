@@ -0,0 +1,405 @@
+package handler
+
+// admin_products.go — товары в панели управления (/admin/products, право products.write):
+// список с поиском и страницами, создание, редактирование, удаление с подтверждением.
+// Поля формы проходят ту же санитизацию и валидацию, что и FormSubmit; изображение — product_images.go.
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/images"
+	"myApp/internal/money"
+	"myApp/internal/search"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminPageSize — товаров на странице списка
+const adminPageSize = 20
+
+// maxProductPrice — предел DECIMAL(10,2) колонки products.price
+var maxProductPrice = money.MustParse("99999999.99", "")
+
+// ProductForm — поля товара в виде, как их ввёл сотрудник (для повторного показа формы)
+type ProductForm struct {
+	Name        string `validate:"required,min=2,max=255"`
+	Article     string `validate:"required,max=100"`
+	CategoryID  string `validate:"omitempty,number"`
+	Price       string `validate:"required"`
+	Description string `validate:"max=5000"`
+	ImageAlt    string `validate:"max=255"`
+}
+
+// productFields — сообщения валидации товара (ключ — имя поля структуры)
+var productFields = map[string]formField{
+	"Name": {Key: "name", Default: "Некорректное название", Messages: map[string]string{
+		"required": "Укажите название",
+		"min":      "Название должно быть не короче 2 символов",
+		"max":      "Слишком длинное название (макс. 255)",
+	}},
+	"Article": {Key: "article", Default: "Некорректный артикул", Messages: map[string]string{
+		"required": "Укажите артикул",
+		"max":      "Слишком длинный артикул (макс. 100)",
+	}},
+	"CategoryID":  {Key: "category_id", Default: "Выберите категорию из списка"},
+	"Price":       {Key: "price", Default: "Укажите цену"},
+	"Description": {Key: "description", Default: "Слишком длинное описание (макс. 5000)"},
+	"ImageAlt":    {Key: "image_alt", Default: "Слишком длинная подпись (макс. 255)"},
+}
+
+// CategoryOption — категория в выпадающем списке формы (с отступом по глубине дерева)
+type CategoryOption struct {
+	ID    string
+	Label string
+}
+
+// AdminProductsView — данные для admin_products.html
+type AdminProductsView struct {
+	Items      []storage.Product
+	Query      string
+	Pagination Pagination
+	Notice     string
+}
+
+// AdminProductView — данные для admin_product_form.html и admin_product_delete.html
+type AdminProductView struct {
+	Product    *storage.Product // Сохранённый товар (nil — создание)
+	Form       ProductForm
+	Categories []CategoryOption
+	Errors     map[string]string
+	Saved      bool
+}
+
+// AdminProducts — GET /admin/products?q=&page=
+func AdminProducts(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		errs := map[string]string{}
+		page, _ := parsePaging(c, errs)
+		if len(errs) > 0 {
+			page = 1
+		}
+		text := strings.TrimSpace(c.Query("q"))
+
+		res, err := app.Products.List(c.Request.Context(), storage.ProductQuery{
+			Page: page, PageSize: adminPageSize, Sort: "-created_at", Text: text,
+		})
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки товаров", err))
+			return
+		}
+
+		view := AdminProductsView{
+			Items:      res.Items,
+			Query:      text,
+			Pagination: NewPagination(c.Request.URL, res.Page, res.PageSize, res.Total),
+		}
+		if c.Query("deleted") == "1" {
+			view.Notice = "Товар удалён."
+		}
+		renderAdmin(c, app, "admin_products", "Товары", view)
+	}
+}
+
+// AdminProductNew — GET /admin/products/new
+func AdminProductNew(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view, err := newProductView(c.Request.Context(), app, nil)
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		renderAdmin(c, app, "admin_product_form", "Новый товар", view)
+	}
+}
+
+// AdminProductCreate — POST /admin/products
+func AdminProductCreate(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view, p, upload, ok := bindProduct(c, app, nil)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if upload != nil {
+			if err := storeProductImage(ctx, app, p, upload); err != nil {
+				core.FailC(c, core.Internal("Ошибка сохранения изображения", err))
+				return
+			}
+		}
+		if err := app.Products.Create(ctx, p); err != nil {
+			deleteProductImage(ctx, app, p.Image())
+			productSaveError(c, app, "Новый товар", view, err)
+			return
+		}
+		productChanged(c, app, "Товар создан", p)
+		c.Redirect(http.StatusSeeOther, "/admin/products/"+p.ID+"/edit?saved=1")
+	}
+}
+
+// AdminProductEdit — GET /admin/products/:id/edit (?saved=1 — после сохранения)
+func AdminProductEdit(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := adminProduct(c, app)
+		if !ok {
+			return
+		}
+		view, err := newProductView(c.Request.Context(), app, p)
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		view.Saved = c.Query("saved") == "1"
+		renderAdmin(c, app, "admin_product_form", "Товар "+p.Article, view)
+	}
+}
+
+// AdminProductUpdate — POST /admin/products/:id
+func AdminProductUpdate(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cur, ok := adminProduct(c, app)
+		if !ok {
+			return
+		}
+		view, p, upload, ok := bindProduct(c, app, cur)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if upload != nil {
+			if err := storeProductImage(ctx, app, p, upload); err != nil {
+				core.FailC(c, core.Internal("Ошибка сохранения изображения", err))
+				return
+			}
+		}
+		if err := app.Products.Update(ctx, p); err != nil {
+			if upload != nil {
+				deleteProductImage(ctx, app, p.Image())
+			}
+			productSaveError(c, app, "Товар "+cur.Article, view, err)
+			return
+		}
+		// Старые миниатюры больше не нужны: изображение заменено или удалено
+		if old := cur.Image(); old != nil && (p.ImageKey == nil || *p.ImageKey != old.Key) {
+			deleteProductImage(ctx, app, old)
+		}
+		productChanged(c, app, "Товар изменён", p)
+		c.Redirect(http.StatusSeeOther, "/admin/products/"+p.ID+"/edit?saved=1")
+	}
+}
+
+// AdminProductDeleteConfirm — GET /admin/products/:id/delete: страница подтверждения
+func AdminProductDeleteConfirm(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := adminProduct(c, app)
+		if !ok {
+			return
+		}
+		renderAdmin(c, app, "admin_product_delete", "Удаление товара", AdminProductView{Product: p})
+	}
+}
+
+// AdminProductDelete — POST /admin/products/:id/delete
+func AdminProductDelete(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := adminProduct(c, app)
+		if !ok {
+			return
+		}
+		id, _ := strconv.Atoi(p.ID)
+		if err := app.Products.Delete(c.Request.Context(), id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			core.FailC(c, core.Internal("Ошибка удаления товара", err))
+			return
+		}
+		deleteProductImage(c.Request.Context(), app, p.Image())
+		productChanged(c, app, "Товар удалён", p)
+		c.Redirect(http.StatusSeeOther, "/admin/products?deleted=1")
+	}
+}
+
+// adminProduct — товар из :id; false — ответ (404/500) уже отправлен
+func adminProduct(c *gin.Context, app *App) (*storage.Product, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		NotFound(app)(c)
+		return nil, false
+	}
+	p, err := app.Products.GetByID(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		NotFound(app)(c)
+		return nil, false
+	}
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки товара", err))
+		return nil, false
+	}
+	return p, true
+}
+
+// newProductView — форма товара p (nil — пустая форма создания)
+func newProductView(ctx context.Context, app *App, p *storage.Product) (AdminProductView, error) {
+	options, err := categoryOptions(ctx, app)
+	if err != nil {
+		return AdminProductView{}, err
+	}
+	view := AdminProductView{Product: p, Categories: options}
+	if p != nil {
+		view.Form = ProductForm{
+			Name:        p.Name,
+			Article:     p.Article,
+			CategoryID:  deref(p.CategoryID),
+			Price:       p.Price.Decimal(),
+			Description: deref(p.Description),
+			ImageAlt:    deref(p.ImageAlt),
+		}
+	}
+	return view, nil
+}
+
+// bindProduct — разбор, санитизация и валидация формы товара. cur — редактируемый товар
+// (nil — создание). Новое изображение возвращается миниатюрами (nil — не загружено), в BlobStore
+// их кладёт вызывающий. false — ответ уже отправлен (форма с ошибками или ошибка запроса).
+func bindProduct(c *gin.Context, app *App, cur *storage.Product) (AdminProductView, *storage.Product, *images.Set, bool) {
+	title := "Новый товар"
+	if cur != nil {
+		title = "Товар " + cur.Article
+	}
+	if !parseProductForm(c) {
+		return AdminProductView{}, nil, nil, false
+	}
+	view, err := newProductView(c.Request.Context(), app, cur)
+	if err != nil {
+		core.FailC(c, err)
+		return view, nil, nil, false
+	}
+
+	view.Form = ProductForm{
+		Name:        formValue(c, "name"),
+		Article:     formValue(c, "article"),
+		CategoryID:  formValue(c, "category_id"),
+		Price:       formValue(c, "price"),
+		Description: formValue(c, "description"),
+		ImageAlt:    formValue(c, "image_alt"),
+	}
+	view.Errors = validationErrors(validate.Struct(view.Form), productFields)
+
+	price, err := money.Parse(view.Form.Price, money.DefaultCurrency)
+	switch {
+	case view.Errors["price"] != "":
+	case err != nil:
+		view.Errors["price"] = "Цена — число, не более 2 знаков после запятой"
+	case !price.IsPositive():
+		view.Errors["price"] = "Цена должна быть больше нуля"
+	case price.Cmp(maxProductPrice) > 0:
+		view.Errors["price"] = "Слишком большая цена (макс. " + maxProductPrice.Decimal() + ")"
+	}
+	if id := view.Form.CategoryID; id != "" && view.Errors["category_id"] == "" &&
+		!slices.ContainsFunc(view.Categories, func(o CategoryOption) bool { return o.ID == id }) {
+		view.Errors["category_id"] = productFields["CategoryID"].Default
+	}
+
+	data, msg := readProductImage(c, app)
+	if msg != "" {
+		view.Errors["image"] = msg
+	}
+	// Миниатюры — самая дорогая часть: только когда остальная форма в порядке
+	var upload *images.Set
+	if data != nil && len(view.Errors) == 0 {
+		if upload, err = images.Thumbnails(images.NewKey(productImagePrefix), data); err != nil {
+			view.Errors["image"] = imageErrorMessage(err)
+		}
+	}
+
+	if len(view.Errors) > 0 {
+		c.Status(http.StatusBadRequest)
+		renderAdmin(c, app, "admin_product_form", title, view)
+		return view, nil, nil, false
+	}
+
+	p := &storage.Product{
+		Name:        view.Form.Name,
+		Article:     view.Form.Article,
+		CategoryID:  optional(view.Form.CategoryID),
+		Price:       price,
+		Description: optional(view.Form.Description),
+		ImageAlt:    optional(view.Form.ImageAlt),
+	}
+	if cur != nil {
+		p.ID = cur.ID
+		// Изображение остаётся, пока его не заменили или не сняли "Удалить изображение"
+		if c.Request.PostForm.Get("remove_image") != "1" {
+			p.ImageKey, p.ImageWidth, p.ImageHeight = cur.ImageKey, cur.ImageWidth, cur.ImageHeight
+		}
+	}
+	return view, p, upload, true
+}
+
+// productSaveError — занятый артикул показывается у поля (409), остальное — 500
+func productSaveError(c *gin.Context, app *App, title string, view AdminProductView, err error) {
+	if !errors.Is(err, storage.ErrArticleTaken) {
+		core.FailC(c, core.Internal("Ошибка сохранения товара", err))
+		return
+	}
+	view.Errors = storage.ErrArticleTaken.Fields
+	c.Status(storage.ErrArticleTaken.Status)
+	renderAdmin(c, app, "admin_product_form", title, view)
+}
+
+// productChanged — журнал изменений каталога и переиндексация поиска в памяти
+func productChanged(c *gin.Context, app *App, msg string, p *storage.Product) {
+	fields := map[string]interface{}{"product_id": p.ID, "article": p.Article}
+	if u := CurrentUser(c.Request.Context()); u != nil {
+		fields["user_id"] = u.ID
+	}
+	core.LogInfo(msg, fields)
+
+	// MySQL FULLTEXT обновляется сам, а поиск в памяти хранит копию каталога
+	if mem, ok := app.Search.(*search.Memory); ok {
+		products, err := app.Products.ListAll(c.Request.Context())
+		if err != nil {
+			core.LogError("Ошибка переиндексации поиска", map[string]interface{}{"error": err.Error()})
+			return
+		}
+		mem.Replace(products)
+	}
+}
+
+// categoryOptions — категории деревом: "Электроника", "— Телефоны и планшеты", ...
+func categoryOptions(ctx context.Context, app *App) ([]CategoryOption, error) {
+	tree, err := storage.LoadCategoryTree(ctx, app.Categories)
+	if err != nil {
+		return nil, core.Internal("Ошибка загрузки категорий", err)
+	}
+	var out []CategoryOption
+	var walk func(nodes []*storage.Category, depth int)
+	walk = func(nodes []*storage.Category, depth int) {
+		for _, n := range nodes {
+			out = append(out, CategoryOption{ID: n.ID, Label: strings.Repeat("— ", depth) + n.Name})
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(tree, 0)
+	return out, nil
+}
+
+// optional — nil для пустой строки (NULL в БД)
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// deref — значение указателя или ""
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
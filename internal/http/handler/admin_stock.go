@@ -0,0 +1,159 @@
+package handler
+
+// admin_stock.go — остатки товара в панели (/admin/products/:id/stock, право products.write):
+// остаток, резерв и доступно по товару или каждому его варианту, корректировка остатка
+// и последние движения из журнала (продажи и корректировки).
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ограничения формы корректировки
+const (
+	maxStock            = 1_000_000
+	maxStockNote        = 255
+	stockMovementsLimit = 50
+)
+
+// StockRow — единица учёта остатка: товар без вариантов или один вариант
+type StockRow struct {
+	VariantID string // "" — товар без вариантов
+	Label     string // Опции варианта ("" — сам товар)
+	SKU       string
+	Stock     int
+	Available int
+}
+
+// Reserved — отложено под оформление и неоплаченные заказы
+func (r StockRow) Reserved() int {
+	return max(r.Stock-r.Available, 0)
+}
+
+// AdminStockView — данные для admin_product_stock.html
+type AdminStockView struct {
+	Product   *storage.Product
+	Rows      []StockRow
+	Movements []storage.StockMovement
+	Errors    map[string]string
+	Saved     bool
+}
+
+// VariantLabel — подпись позиции движения: опции варианта или "—" у товара без вариантов
+func (v AdminStockView) VariantLabel(variantID *string) string {
+	if variantID == nil {
+		return "—"
+	}
+	for _, r := range v.Rows {
+		if r.VariantID == *variantID {
+			return r.Label
+		}
+	}
+	return "Вариант #" + *variantID // Вариант удалён, запись журнала осталась
+}
+
+// AdminProductStock — GET /admin/products/:id/stock (?saved=1 — после корректировки)
+func AdminProductStock(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := adminProduct(c, app)
+		if !ok {
+			return
+		}
+		view, err := newStockView(c.Request.Context(), app, p)
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		view.Saved = c.Query("saved") == "1"
+		renderAdmin(c, app, "admin_product_stock", "Остатки "+p.Article, view)
+	}
+}
+
+// AdminProductStockUpdate — POST /admin/products/:id/stock: новый остаток товара или варианта
+// (variant_id, stock, note). Остаток меньше резерва не принимается (409).
+func AdminProductStockUpdate(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := adminProduct(c, app)
+		if !ok || !parseAuthForm(c) {
+			return
+		}
+		ctx := c.Request.Context()
+		view, err := newStockView(ctx, app, p)
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		title := "Остатки " + p.Article
+
+		variantID := formValue(c, "variant_id")
+		note := formValue(c, "note")
+		stock, serr := strconv.Atoi(formValue(c, "stock"))
+		switch {
+		case !slices.ContainsFunc(view.Rows, func(r StockRow) bool { return r.VariantID == variantID }):
+			view.Errors["variant_id"] = "Выберите вариант товара"
+		case serr != nil || stock < 0 || stock > maxStock:
+			view.Errors["stock"] = "Остаток — целое число от 0 до " + strconv.Itoa(maxStock)
+		case utf8.RuneCountInString(note) > maxStockNote:
+			view.Errors["note"] = "Слишком длинный комментарий (макс. 255)"
+		}
+		if len(view.Errors) > 0 {
+			c.Status(http.StatusBadRequest)
+			renderAdmin(c, app, "admin_product_stock", title, view)
+			return
+		}
+
+		adj := storage.StockAdjustment{ProductID: p.ID, VariantID: variantID, Stock: stock, Note: note}
+		if u := CurrentUser(ctx); u != nil {
+			adj.UserID = u.ID
+		}
+		err = app.Inventory.Adjust(ctx, adj)
+		var appErr *core.AppError
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// Вариант удалили, пока открыта форма
+			NotFound(app)(c)
+			return
+		case errors.As(err, &appErr) && appErr.Fields != nil:
+			view.Errors = appErr.Fields
+			c.Status(appErr.Status)
+			renderAdmin(c, app, "admin_product_stock", title, view)
+			return
+		case err != nil:
+			core.FailC(c, core.Internal("Ошибка изменения остатка", err))
+			return
+		}
+
+		productChanged(c, app, "Остаток изменён", p)
+		c.Redirect(http.StatusSeeOther, "/admin/products/"+p.ID+"/stock?saved=1")
+	}
+}
+
+// newStockView — остатки товара p по вариантам (или по самому товару) и журнал движений
+func newStockView(ctx context.Context, app *App, p *storage.Product) (AdminStockView, error) {
+	view := AdminStockView{Product: p, Errors: map[string]string{}}
+
+	variants, err := app.Variants.ByProducts(ctx, []string{p.ID})
+	if err != nil {
+		return view, core.Internal("Ошибка загрузки вариантов", err)
+	}
+	for _, v := range variants[p.ID] {
+		view.Rows = append(view.Rows, StockRow{VariantID: v.ID, Label: v.Label(), SKU: v.SKU, Stock: v.Stock, Available: v.Available})
+	}
+	if len(view.Rows) == 0 {
+		view.Rows = []StockRow{{SKU: p.Article, Stock: p.Stock, Available: p.Available}}
+	}
+
+	if view.Movements, err = app.Inventory.Movements(ctx, p.ID, stockMovementsLimit); err != nil {
+		return view, core.Internal("Ошибка загрузки журнала остатков", err)
+	}
+	return view, nil
+}
@@ -0,0 +1,298 @@
+package handler
+
+// api.go — REST API /api/v1: товары, категории, корзина, заказы и учётная запись.
+// Успешный ответ — конверт Envelope ({"data": ..., "pagination": ...} у списков),
+// любая ошибка (в том числе 404 и 405 внутри /api/) — core.FailC (RFC 7807).
+// Сессия общая с сайтом: корзина и вход те же, изменения требуют CSRF-токен в X-CSRF-Token.
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIPrefix — общий префикс JSON API (nginx проксирует location /api/)
+const APIPrefix = "/api/"
+
+// Envelope — успешный ответ /api/v1
+type Envelope struct {
+	Data       any         `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"` // Только у списков
+}
+
+// CartItemInput — тело POST /api/v1/cart/items и PATCH /api/v1/cart/items/:product_id
+type CartItemInput struct {
+	ProductID int  `json:"product_id,omitempty"` // В PATCH — из пути
+	VariantID int  `json:"variant_id,omitempty"` // 0 — товар без вариантов
+	Quantity  *int `json:"quantity,omitempty"`   // POST: по умолчанию 1; PATCH: обязательно (0 — удалить)
+}
+
+// Ошибки API
+var (
+	errAPINotFound         = &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Ресурс не найден"}
+	errAPIMethodNotAllowed = &core.AppError{Code: "method_not_allowed", Status: http.StatusMethodNotAllowed, Message: "Метод не поддерживается"}
+)
+
+// isAPI — запрос к JSON API (/api/...)
+func isAPI(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, APIPrefix)
+}
+
+// APIProducts — GET /api/v1/products: параметры те же, что у /catalog, плюс ?category=slug
+func APIProducts(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := loadCatalog(c, app, strings.TrimSpace(c.Query("category")))
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		items := data.Items
+		if items == nil {
+			items = []storage.Product{}
+		}
+		core.JSON(c, http.StatusOK, Envelope{Data: items, Pagination: &data.Pagination})
+	}
+}
+
+// APIProduct — GET /api/v1/products/:id: товар с вариантами
+func APIProduct(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		product, err := loadProduct(c, app)
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		core.JSON(c, http.StatusOK, Envelope{Data: product})
+	}
+}
+
+// APICategories — GET /api/v1/categories: дерево категорий (children — подкатегории)
+func APICategories(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tree, err := storage.LoadCategoryTree(c.Request.Context(), app.Categories)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
+			return
+		}
+		if tree == nil {
+			tree = []*storage.Category{}
+		}
+		core.JSON(c, http.StatusOK, Envelope{Data: tree})
+	}
+}
+
+// APICart — GET /api/v1/cart: корзина сессии (пустая корзина не создаётся)
+func APICart(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		respondCart(c, app, http.StatusOK)
+	}
+}
+
+// APICartAdd — POST /api/v1/cart/items {product_id, variant_id, quantity}: 201 и корзина
+func APICartAdd(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		in, err := bindCartItem(c)
+		if err == nil {
+			err = addCartItem(c, app, in.ProductID, in.VariantID, *in.Quantity)
+		}
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		respondCart(c, app, http.StatusCreated)
+	}
+}
+
+// APICartUpdate — PATCH /api/v1/cart/items/:product_id {variant_id, quantity}: 0 — удалить
+func APICartUpdate(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		in, err := bindCartItem(c)
+		if err == nil {
+			err = setCartItem(c, app, in.ProductID, in.VariantID, *in.Quantity)
+		}
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		respondCart(c, app, http.StatusOK)
+	}
+}
+
+// APICartRemove — DELETE /api/v1/cart/items/:product_id?variant_id=
+func APICartRemove(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		errs := map[string]string{}
+		productID := pathProductID(c, errs)
+		variantID := 0
+		if raw := strings.TrimSpace(c.Query("variant_id")); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				errs["variant_id"] = "Неверный ID варианта"
+			}
+			variantID = n
+		}
+		if len(errs) > 0 {
+			core.FailC(c, cartInputError(errs))
+			return
+		}
+
+		if err := removeCartItem(c, app, productID, variantID); err != nil {
+			core.FailC(c, err)
+			return
+		}
+		respondCart(c, app, http.StatusOK)
+	}
+}
+
+// APIOrders — GET /api/v1/orders?page=&page_size=: заказы вошедшего пользователя, новые первыми
+func APIOrders(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c.Request.Context())
+		if user == nil {
+			core.FailC(c, errLoginRequired)
+			return
+		}
+		errs := map[string]string{}
+		page, pageSize := parsePaging(c, errs)
+		if len(errs) > 0 {
+			core.FailC(c, &core.AppError{Code: "invalid_query", Status: http.StatusBadRequest, Message: "Некорректные параметры списка", Fields: errs})
+			return
+		}
+
+		res, err := app.Orders.ListByUser(c.Request.Context(), user.ID, page, pageSize)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки заказов", err))
+			return
+		}
+		items := res.Items
+		if items == nil {
+			items = []storage.Order{}
+		}
+		pagination := NewPagination(c.Request.URL, res.Page, res.PageSize, res.Total)
+		core.JSON(c, http.StatusOK, Envelope{Data: items, Pagination: &pagination})
+	}
+}
+
+// APIOrder — GET /api/v1/orders/:number: доступ как у страницы заказа (loadOwnOrder)
+func APIOrder(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := loadOwnOrder(c, app)
+		if !ok {
+			return
+		}
+		core.JSON(c, http.StatusOK, Envelope{Data: order})
+	}
+}
+
+// APIAccount — GET /api/v1/account: вошедший пользователь (аноним — 401)
+func APIAccount(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c.Request.Context())
+		if user == nil {
+			core.FailC(c, errLoginRequired)
+			return
+		}
+		core.JSON(c, http.StatusOK, Envelope{Data: user})
+	}
+}
+
+// MethodNotAllowed — 405 внутри /api/ с заголовком Allow (маршруты routes — для его расчёта).
+// Вне API неизвестное сочетание метода и пути по-прежнему отдаёт HTML-страницу 404.
+func MethodNotAllowed(app *App, routes gin.RoutesInfo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAPI(c) {
+			NotFound(app)(c)
+			return
+		}
+		var allow []string
+		for _, r := range routes {
+			if matchRoute(r.Path, c.Request.URL.Path) && !slices.Contains(allow, r.Method) {
+				allow = append(allow, r.Method)
+			}
+		}
+		slices.Sort(allow)
+		c.Header("Allow", strings.Join(allow, ", "))
+		core.FailC(c, errAPIMethodNotAllowed)
+	}
+}
+
+// matchRoute — подходит ли путь под шаблон маршрута Gin (:param — один сегмент, *rest — остаток)
+func matchRoute(pattern, path string) bool {
+	ps := strings.Split(strings.Trim(pattern, "/"), "/")
+	xs := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range ps {
+		if strings.HasPrefix(p, "*") {
+			return true
+		}
+		if i >= len(xs) || (!strings.HasPrefix(p, ":") && p != xs[i]) {
+			return false
+		}
+	}
+	return len(ps) == len(xs)
+}
+
+// respondCart — корзина сессии в конверте (lines — [] у пустой корзины)
+func respondCart(c *gin.Context, app *App, status int) {
+	cart, err := loadSessionCart(c, app.Carts)
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки корзины", err))
+		return
+	}
+	if cart.Lines == nil {
+		cart.Lines = []storage.CartLine{}
+	}
+	core.JSON(c, status, Envelope{Data: cart})
+}
+
+// bindCartItem — позиция корзины из JSON-тела; product_id в PATCH берётся из пути.
+// Проверки те же, что у формы (parseCartForm).
+func bindCartItem(c *gin.Context) (CartItemInput, error) {
+	var in CartItemInput
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil && !errors.Is(err, io.EOF) {
+		return in, &core.AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: "Некорректный JSON", Err: err}
+	}
+
+	errs := map[string]string{}
+	if c.Param("product_id") != "" {
+		in.ProductID = pathProductID(c, errs)
+		if in.Quantity == nil {
+			errs["quantity"] = "Обязательное поле"
+		}
+	} else if in.ProductID <= 0 {
+		errs["product_id"] = "Неверный ID товара"
+	}
+	if in.VariantID < 0 {
+		errs["variant_id"] = "Неверный ID варианта"
+	}
+	if in.Quantity == nil && errs["quantity"] == "" {
+		one := 1
+		in.Quantity = &one
+	}
+	if q := in.Quantity; q != nil && (*q < 0 || *q > storage.MaxCartQuantity) {
+		errs["quantity"] = "Количество должно быть от 0 до " + strconv.Itoa(storage.MaxCartQuantity)
+	}
+
+	if len(errs) > 0 {
+		return in, cartInputError(errs)
+	}
+	return in, nil
+}
+
+// pathProductID — :product_id из пути; ошибка пишется в errs
+func pathProductID(c *gin.Context, errs map[string]string) int {
+	id, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil || id <= 0 {
+		errs["product_id"] = "Неверный ID товара"
+	}
+	return id
+}
@@ -0,0 +1,153 @@
+package handler
+
+// api_spec.go — описание маршрутов /api/v1 для OpenAPI (internal/openapi): схемы строятся
+// из тех же Go-типов, что отдают обработчики, пути — из зарегистрированных маршрутов.
+// Новый маршрут в группе без строки в APIRoutes не даст собрать приложение.
+import (
+	"net/http"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+	"myApp/internal/openapi"
+	"myApp/internal/search"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion — версия контракта /api/v1 в info.version
+const APIVersion = "1.0.0"
+
+// Параметры query-строки списков
+var (
+	pagingParams = []openapi.Param{
+		{Name: "page", Type: "integer", Description: "Номер страницы (с 1)"},
+		{Name: "page_size", Type: "integer", Description: "Размер страницы (1..100)"},
+	}
+	catalogParams = append([]openapi.Param{
+		{Name: "sort", Type: "string", Description: "name, -name, price, -price, created_at, -created_at"},
+		{Name: "min_price", Type: "string", Description: "Нижняя граница цены (\"10.50\")"},
+		{Name: "max_price", Type: "string", Description: "Верхняя граница цены"},
+		{Name: "category", Type: "string", Description: "Slug категории (с подкатегориями)"},
+	}, pagingParams...)
+)
+
+// NewAPIDocument — документ OpenAPI без путей: ошибки — core.ProblemDetail,
+// у money.Money свой JSON ({"amount": "12.34", "currency": "EUR"})
+func NewAPIDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{Title: "myApp API", Version: APIVersion}, core.ProblemDetail{})
+	doc.DefineType(money.Money{}, "Money", &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"amount":   {Type: "string", Description: "Сумма с двумя знаками после точки"},
+			"currency": {Type: "string", Description: "Код ISO 4217"},
+		},
+		Required:             []string{"amount", "currency"},
+		AdditionalProperties: false,
+	})
+	doc.SetBearerAuth("apiToken", "API-токен из /account/tokens; области — в scopes операции (storage.Scope)")
+	doc.SetIdempotencyKey(IdempotencyHeader, "Ключ повтора (до 255 символов ASCII): повтор запроса получает первый ответ")
+	return doc
+}
+
+// APIRoutes — описание маршрутов /api/v1: ключ — метод и шаблон пути Gin.
+// По Scopes registerRoutes ставит маршруту RequireScope — других мест, где задаются области, нет.
+func APIRoutes() map[string]openapi.Route {
+	list := &Pagination{}
+	cart := []string{string(storage.ScopeCart)}
+	return map[string]openapi.Route{
+		"GET /api/v1/search": {
+			Summary: "Поиск товаров с подсветкой совпадений", Tag: "catalog",
+			Query:    append([]openapi.Param{{Name: "q", Type: "string", Description: "Строка поиска"}}, pagingParams...),
+			Response: Envelope{Data: SearchResults{Items: []search.Hit{}}, Pagination: list},
+			Errors:   []int{http.StatusBadRequest},
+		},
+		"GET /api/v1/products": {
+			Summary: "Каталог товаров", Tag: "catalog",
+			Query:    catalogParams,
+			Response: Envelope{Data: []storage.Product{}, Pagination: list},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		"GET /api/v1/products/:id": {
+			Summary: "Товар с вариантами", Tag: "catalog",
+			Response: Envelope{Data: storage.Product{}},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		"GET /api/v1/categories": {
+			Summary: "Дерево категорий", Tag: "catalog",
+			Response: Envelope{Data: []storage.Category{}},
+		},
+		"GET /api/v1/cart": {
+			Summary: "Корзина сессии (с токеном — корзина его владельца)", Tag: "cart",
+			Response: Envelope{Data: storage.Cart{}},
+			Errors:   []int{http.StatusForbidden},
+			Scopes:   cart,
+		},
+		"POST /api/v1/cart/items": {
+			Summary: "Добавить товар в корзину", Tag: "cart",
+			Body:     CartItemInput{},
+			Status:   http.StatusCreated,
+			Response: Envelope{Data: storage.Cart{}},
+			Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+			Scopes:   cart,
+		},
+		"PATCH /api/v1/cart/items/:product_id": {
+			Summary: "Изменить количество (0 — удалить)", Tag: "cart",
+			Body:     CartItemInput{},
+			Response: Envelope{Data: storage.Cart{}},
+			Errors:   []int{http.StatusBadRequest, http.StatusForbidden},
+			Scopes:   cart,
+		},
+		"DELETE /api/v1/cart/items/:product_id": {
+			Summary: "Удалить позицию", Tag: "cart",
+			Query:    []openapi.Param{{Name: "variant_id", Type: "integer", Description: "Вариант (у товаров с вариантами)"}},
+			Response: Envelope{Data: storage.Cart{}},
+			Errors:   []int{http.StatusBadRequest, http.StatusForbidden},
+			Scopes:   cart,
+		},
+		"GET /api/v1/orders": {
+			Summary: "Заказы вошедшего пользователя, новые первыми", Tag: "orders",
+			Query:    pagingParams,
+			Response: Envelope{Data: []storage.Order{}, Pagination: list},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+			Scopes:   []string{string(storage.ScopeOrdersRead)},
+		},
+		"GET /api/v1/orders/:number": {
+			Summary: "Заказ пользователя или текущей сессии", Tag: "orders",
+			Response: Envelope{Data: storage.Order{}},
+			Errors:   []int{http.StatusForbidden, http.StatusNotFound},
+			Scopes:   []string{string(storage.ScopeOrdersRead)},
+		},
+		"GET /api/v1/account": {
+			Summary: "Вошедший пользователь", Tag: "account",
+			Response: Envelope{Data: storage.User{}},
+			Errors:   []int{http.StatusUnauthorized, http.StatusForbidden},
+			Scopes:   []string{string(storage.ScopeAccountRead)},
+		},
+	}
+}
+
+// APIDocsView — данные для api_docs.html
+type APIDocsView struct {
+	Doc        *openapi.Document
+	Operations []openapi.OperationRef
+}
+
+// OpenAPIJSON — GET /api/openapi.json: документ собирается один раз при старте
+func OpenAPIJSON(doc *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		core.JSON(c, http.StatusOK, doc)
+	}
+}
+
+// APIDocs — GET /api/docs: страница документации по тому же документу (без внешних скриптов)
+func APIDocs(app *App, doc *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data := APIDocsView{Doc: doc, Operations: doc.Operations()}
+		if err := app.Templates.Render(c, "api_docs", "API "+doc.Info.Version, data); err != nil {
+			core.LogError("Ошибка рендеринга api_docs", map[string]interface{}{"error": err.Error()})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+			return
+		}
+	}
+}
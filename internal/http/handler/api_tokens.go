@@ -0,0 +1,280 @@
+package handler
+
+// api_tokens.go — API-токены для скриптов и интеграций: аутентификация по Authorization: Bearer
+// (LoadAPIToken, RequireScope) и страницы управления — свои токены в /account/tokens,
+// токены всех пользователей в /admin/tokens. Токен показывается один раз, в БД — только хеш.
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"myApp/internal/auth"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiTokenCtxKey — ключ токена запроса в context.Context
+type apiTokenCtxKey struct{}
+
+// tokenCartKey — ключ gin.Context с ID корзины пользователя токена (вместо корзины сессии)
+const tokenCartKey = "api_token_cart_id"
+
+// APITokenDays — сроки действия на выбор при создании токена (дни), по умолчанию — 90
+var APITokenDays = []int{7, 30, 90, 365}
+
+const defaultAPITokenDays = 90
+
+// APITokenForm — создание токена
+type APITokenForm struct {
+	Name   string `validate:"required,max=100"`
+	Scopes storage.ScopeList
+	Days   int
+}
+
+// apiTokenFields — сообщения валидации формы токена
+var apiTokenFields = map[string]formField{
+	"Name": {Key: "name", Default: "Некорректное название", Messages: map[string]string{
+		"required": "Назовите токен, например «выгрузка заказов»",
+		"max":      "Слишком длинное название (макс. 100)",
+	}},
+}
+
+// APITokensView — данные для api_tokens.html
+type APITokensView struct {
+	Admin   bool // /admin/tokens: токены всех пользователей, без формы создания
+	Tokens  []storage.APIToken
+	Scopes  []storage.Scope
+	Days    []int
+	Form    APITokenForm
+	Created string // Новый токен — показывается один раз
+	Notice  string
+	Errors  map[string]string
+}
+
+// CurrentAPIToken — токен, которым аутентифицирован запрос (nil — сессия или аноним)
+func CurrentAPIToken(ctx context.Context) *storage.APIToken {
+	t, _ := ctx.Value(apiTokenCtxKey{}).(*storage.APIToken)
+	return t
+}
+
+// LoadAPIToken — middleware: запрос в /api/ с Authorization: Bearer выполняется от имени
+// владельца токена (CurrentUser), сессия такому запросу не нужна. Ставится до CSRF: Authorization
+// браузер сам не подставляет, поэтому запросы с токеном от CSRF освобождены. Права роли
+// токену не передаются — в /api/ доступ ограничивают области (RequireScope).
+// Недействительный токен — 401 с WWW-Authenticate, без отката к сессии.
+func LoadAPIToken(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := bearerToken(c)
+		if !ok || !isAPI(c) {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+
+		token, err := app.APITokens.Authenticate(ctx, auth.HashToken(raw))
+		if err != nil {
+			apiTokenError(c, err)
+			return
+		}
+		user, err := app.Users.GetByID(ctx, token.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = storage.ErrAPITokenInvalid
+			}
+			apiTokenError(c, err)
+			return
+		}
+		cartID, err := app.Carts.IDByUser(ctx, user.ID)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки корзины", err))
+			return
+		}
+
+		ctx = context.WithValue(ctx, userCtxKey{}, user)
+		c.Request = c.Request.WithContext(context.WithValue(ctx, apiTokenCtxKey{}, token))
+		c.Set(tokenCartKey, cartID)
+		c.Next()
+	}
+}
+
+// RequireScope — middleware маршрута /api/v1: у запроса с токеном должна быть область scope
+// (иначе 403 insufficient_scope). Запросы с сессией проходят без проверки.
+func RequireScope(scope storage.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if t := CurrentAPIToken(c.Request.Context()); t != nil && !t.Scopes.Has(scope) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+string(scope)+`"`)
+			core.FailC(c, &core.AppError{Code: "insufficient_scope", Status: http.StatusForbidden, Message: "У токена нет области " + string(scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// AccountTokens — GET /account/tokens: свои токены и форма создания
+func AccountTokens(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view := APITokensView{Form: APITokenForm{Days: defaultAPITokenDays}}
+		if c.Query("revoked") == "1" {
+			view.Notice = "Токен отозван."
+		}
+		renderAPITokens(c, app, CurrentUser(c.Request.Context()).ID, view)
+	}
+}
+
+// AccountTokenCreate — POST /account/tokens: новый токен показывается на странице один раз
+func AccountTokenCreate(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !parseAuthForm(c) {
+			return
+		}
+		user := CurrentUser(c.Request.Context())
+		form, errs := parseAPITokenForm(c)
+		if len(errs) > 0 {
+			c.Status(http.StatusBadRequest) // статус до рендера
+			renderAPITokens(c, app, user.ID, APITokensView{Form: form, Errors: errs})
+			return
+		}
+
+		raw, hash, err := auth.NewAPIToken()
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка создания токена", err))
+			return
+		}
+		token := &storage.APIToken{
+			UserID: user.ID,
+			Name:   form.Name,
+			Hint:   raw[:len(auth.APITokenPrefix)+4],
+			Hash:   hash,
+			Scopes: form.Scopes,
+		}
+		if err := app.APITokens.Create(c.Request.Context(), token, time.Duration(form.Days)*24*time.Hour); err != nil {
+			core.FailC(c, core.Internal("Ошибка создания токена", err))
+			return
+		}
+		core.LogInfo("API-токен создан", map[string]interface{}{"user_id": user.ID, "token_id": token.ID, "scopes": token.Scopes})
+
+		// Страница с токеном не должна оседать в кэше браузера и прокси
+		c.Header("Cache-Control", "no-store")
+		renderAPITokens(c, app, user.ID, APITokensView{Form: APITokenForm{Days: defaultAPITokenDays}, Created: raw})
+	}
+}
+
+// AccountTokenRevoke — POST /account/tokens/:id/revoke: отзыв своего токена
+func AccountTokenRevoke(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revokeAPIToken(c, app, CurrentUser(c.Request.Context()).ID, "/account/tokens")
+	}
+}
+
+// AdminTokens — GET /admin/tokens: токены всех пользователей
+func AdminTokens(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view := APITokensView{Admin: true}
+		if c.Query("revoked") == "1" {
+			view.Notice = "Токен отозван."
+		}
+		renderAPITokens(c, app, "", view)
+	}
+}
+
+// AdminTokenRevoke — POST /admin/tokens/:id/revoke: отзыв любого токена
+func AdminTokenRevoke(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revokeAPIToken(c, app, "", "/admin/tokens")
+	}
+}
+
+// revokeAPIToken — отзыв токена :id (userID != "" — только своего) и возврат к списку
+func revokeAPIToken(c *gin.Context, app *App, userID, back string) {
+	id := c.Param("id")
+	if _, err := strconv.Atoi(id); err != nil {
+		NotFound(app)(c)
+		return
+	}
+	err := app.APITokens.Revoke(c.Request.Context(), id, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		NotFound(app)(c)
+		return
+	}
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка отзыва токена", err))
+		return
+	}
+	core.LogInfo("API-токен отозван", map[string]interface{}{"token_id": id, "by_user_id": CurrentUser(c.Request.Context()).ID})
+	c.Redirect(http.StatusSeeOther, back+"?revoked=1")
+}
+
+// parseAPITokenForm — название, области и срок из формы создания токена
+func parseAPITokenForm(c *gin.Context) (APITokenForm, map[string]string) {
+	form := APITokenForm{Name: formValue(c, "name")}
+	errs := validationErrors(validate.Struct(form), apiTokenFields)
+
+	for _, raw := range c.Request.PostForm["scopes"] {
+		s := storage.Scope(raw)
+		if !slices.Contains(storage.Scopes, s) {
+			errs["scopes"] = "Неизвестная область доступа"
+			continue
+		}
+		if !slices.Contains(form.Scopes, s) {
+			form.Scopes = append(form.Scopes, s)
+		}
+	}
+	if len(form.Scopes) == 0 && errs["scopes"] == "" {
+		errs["scopes"] = "Выберите хотя бы одну область"
+	}
+
+	days, err := strconv.Atoi(strings.TrimSpace(c.Request.PostForm.Get("days")))
+	form.Days = days
+	if err != nil || !slices.Contains(APITokenDays, days) {
+		form.Days = defaultAPITokenDays
+		errs["days"] = "Выберите срок действия"
+	}
+	return form, errs
+}
+
+// renderAPITokens — список токенов (userID = "" — всех пользователей) и форма
+func renderAPITokens(c *gin.Context, app *App, userID string, view APITokensView) {
+	tokens, err := app.APITokens.List(c.Request.Context(), userID)
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки токенов", err))
+		return
+	}
+	view.Tokens, view.Scopes, view.Days = tokens, storage.Scopes, APITokenDays
+	if view.Errors == nil {
+		view.Errors = map[string]string{}
+	}
+	title := "API-токены"
+	if view.Admin {
+		title = "API-токены пользователей"
+	}
+	if err := app.Templates.Render(c, "api_tokens", title, view); err != nil {
+		core.LogError("Ошибка рендеринга api_tokens", map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+	}
+}
+
+// bearerToken — токен из Authorization: Bearer (ok=false — заголовка нет или схема другая)
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// apiTokenError — 401 с WWW-Authenticate (RFC 6750) для недействительного токена, прочее — 500
+func apiTokenError(c *gin.Context, err error) {
+	if !errors.Is(err, storage.ErrAPITokenInvalid) {
+		core.FailC(c, core.Internal("Ошибка проверки токена", err))
+		return
+	}
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	core.FailC(c, storage.ErrAPITokenInvalid)
+}
@@ -0,0 +1,29 @@
+package handler
+
+// app.go — зависимости обработчиков. Собираются один раз в server.New и передаются
+// в конструкторы обработчиков явно: забытая зависимость — ошибка компиляции, а не nil из контекста.
+import (
+	"myApp/internal/core"
+	"myApp/internal/mail"
+	"myApp/internal/payment"
+	"myApp/internal/search"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// App — конфиг, хранилище и сервисы приложения
+type App struct {
+	Config core.Config
+	DB     *sqlx.DB // Пул MySQL (nil при APP_STORAGE=memory) — только для /debug
+
+	// Репозитории: app.Products, app.Carts, app.Orders, app.Users, ... (MySQL или память)
+	storage.Repositories
+
+	Templates *view.Templates
+	Search    search.Engine     // Поиск товаров (/search, /api/v1/search)
+	Gateway   payment.Provider  // Платёжный провайдер (/orders/:number/pay, /payments/webhook)
+	Mailer    mail.Mailer       // Письма покупателям (подтверждение email, сброс пароля)
+	Blobs     storage.BlobStore // Загруженные файлы (изображения товаров)
+}
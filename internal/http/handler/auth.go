@@ -0,0 +1,274 @@
+package handler
+
+// auth.go — учётные записи: /register и /login (GET + POST с CSRF), /logout (POST).
+// Пароли — internal/auth (argon2id, bcrypt перехешируется при входе).
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"myApp/internal/auth"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterForm — поля регистрации. Пароль не санитизируется и не возвращается в шаблон.
+type RegisterForm struct {
+	Name     string `validate:"required,min=2,max=100"`
+	Email    string `validate:"required,email,max=255"`
+	Password string `validate:"required,min=8,max=128"`
+	Confirm  string `validate:"eqfield=Password"`
+}
+
+// LoginForm — поля входа
+type LoginForm struct {
+	Email    string `validate:"required,email,max=255"`
+	Password string `validate:"required,max=128"`
+}
+
+// AuthView — данные для шаблонов register.html и login.html
+type AuthView struct {
+	Name   string
+	Email  string
+	Next   string // Куда вернуться после входа (локальный путь)
+	Notice string // Сообщение над формой (например, после смены пароля)
+	Errors map[string]string
+}
+
+// registerFields — сообщения валидации регистрации (ключ — имя поля структуры)
+var registerFields = map[string]formField{
+	"Name":  checkoutFields["Name"],
+	"Email": checkoutFields["Email"],
+	"Password": {Key: "password", Default: "Некорректный пароль", Messages: map[string]string{
+		"required": "Придумайте пароль",
+		"min":      "Пароль должен быть не короче 8 символов",
+		"max":      "Слишком длинный пароль (макс. 128)",
+	}},
+	"Confirm": {Key: "confirm", Default: "Пароли не совпадают"},
+}
+
+// loginFields — сообщения валидации входа
+var loginFields = map[string]formField{
+	"Email":    checkoutFields["Email"],
+	"Password": {Key: "password", Default: "Введите пароль"},
+}
+
+// errBadCredentials — одинаковый ответ на неизвестный email и неверный пароль
+const errBadCredentials = "Неверный email или пароль"
+
+// RegisterPage — GET /register
+func RegisterPage(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentUser(c.Request.Context()) != nil {
+			c.Redirect(http.StatusSeeOther, "/")
+			return
+		}
+		renderAuth(c, app, "register", "Регистрация", AuthView{Next: safeNext(c.Query("next"))})
+	}
+}
+
+// RegisterSubmit — POST /register: создаёт учётную запись, сразу входит в неё
+// и отправляет письмо для подтверждения email
+func RegisterSubmit(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !parseAuthForm(c) {
+			return
+		}
+
+		form := RegisterForm{
+			Name:     formValue(c, "name"),
+			Email:    storage.NormalizeEmail(formValue(c, "email")),
+			Password: c.Request.PostForm.Get("password"),
+			Confirm:  c.Request.PostForm.Get("confirm"),
+		}
+		view := AuthView{Name: form.Name, Email: form.Email, Next: safeNext(c.Request.PostForm.Get("next"))}
+
+		if errs := validationErrors(validate.Struct(form), registerFields); len(errs) > 0 {
+			view.Errors = errs
+			c.Status(http.StatusBadRequest) // статус до рендера
+			renderAuth(c, app, "register", "Регистрация", view)
+			return
+		}
+
+		hash, err := auth.HashPassword(form.Password)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка регистрации", err))
+			return
+		}
+		user := &storage.User{Email: form.Email, Name: form.Name, PasswordHash: hash}
+		if err := app.Users.Create(c.Request.Context(), user); err != nil {
+			if errors.Is(err, storage.ErrEmailTaken) {
+				view.Errors = storage.ErrEmailTaken.Fields
+				c.Status(storage.ErrEmailTaken.Status)
+				renderAuth(c, app, "register", "Регистрация", view)
+				return
+			}
+			core.FailC(c, core.Internal("Ошибка регистрации", err))
+			return
+		}
+
+		if err := startUserSession(c, app, user); err != nil {
+			core.FailC(c, core.Internal("Ошибка сохранения сессии", err))
+			return
+		}
+		sendVerification(c.Request.Context(), app, user)
+		core.LogInfo("Регистрация пользователя", map[string]interface{}{"user_id": user.ID})
+		c.Redirect(http.StatusSeeOther, view.Next)
+	}
+}
+
+// LoginPage — GET /login
+func LoginPage(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentUser(c.Request.Context()) != nil {
+			c.Redirect(http.StatusSeeOther, safeNext(c.Query("next")))
+			return
+		}
+		view := AuthView{Next: safeNext(c.Query("next"))}
+		if c.Query("reset") == "1" {
+			view.Notice = "Пароль изменён. Войдите с новым паролем."
+		}
+		renderAuth(c, app, "login", "Вход", view)
+	}
+}
+
+// LoginSubmit — POST /login. Неизвестный email и неверный пароль неразличимы ни по ответу,
+// ни по времени (auth.EqualizeTiming). Устаревший хеш пароля заменяется на argon2id.
+func LoginSubmit(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !parseAuthForm(c) {
+			return
+		}
+
+		form := LoginForm{
+			Email:    storage.NormalizeEmail(formValue(c, "email")),
+			Password: c.Request.PostForm.Get("password"),
+		}
+		view := AuthView{Email: form.Email, Next: safeNext(c.Request.PostForm.Get("next"))}
+
+		if errs := validationErrors(validate.Struct(form), loginFields); len(errs) > 0 {
+			view.Errors = errs
+			c.Status(http.StatusBadRequest)
+			renderAuth(c, app, "login", "Вход", view)
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := app.Users.GetByEmail(ctx, form.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			core.FailC(c, core.Internal("Ошибка входа", err))
+			return
+		}
+
+		ok, rehash := false, false
+		if user == nil {
+			auth.EqualizeTiming(form.Password)
+		} else if ok, rehash, err = auth.VerifyPassword(user.PasswordHash, form.Password); err != nil {
+			core.LogError("Повреждённый хеш пароля", map[string]interface{}{"user_id": user.ID, "error": err.Error()})
+		}
+		if !ok {
+			core.LogInfo("Неудачный вход", map[string]interface{}{"ip": c.ClientIP()})
+			view.Errors = map[string]string{"form": errBadCredentials}
+			c.Status(http.StatusUnauthorized)
+			renderAuth(c, app, "login", "Вход", view)
+			return
+		}
+
+		if rehash {
+			if hash, err := auth.HashPassword(form.Password); err != nil {
+				core.LogError("Ошибка перехеширования пароля", map[string]interface{}{"user_id": user.ID, "error": err.Error()})
+			} else if err := app.Users.SetPasswordHash(ctx, user.ID, hash); err != nil {
+				core.LogError("Ошибка сохранения нового хеша пароля", map[string]interface{}{"user_id": user.ID, "error": err.Error()})
+			}
+		}
+		if err := app.Users.TouchLogin(ctx, user.ID); err != nil {
+			core.LogError("Ошибка отметки входа", map[string]interface{}{"user_id": user.ID, "error": err.Error()})
+		}
+
+		if err := startUserSession(c, app, user); err != nil {
+			core.FailC(c, core.Internal("Ошибка сохранения сессии", err))
+			return
+		}
+		core.LogInfo("Вход пользователя", map[string]interface{}{"user_id": user.ID, "rehash": rehash})
+		c.Redirect(http.StatusSeeOther, view.Next)
+	}
+}
+
+// Logout — POST /logout: сессия очищается целиком (корзина пользователя остаётся в БД)
+func Logout(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess := sessions.Default(c)
+		sess.Clear()
+		if err := sess.Save(); err != nil {
+			core.FailC(c, core.Internal("Ошибка сохранения сессии", err))
+			return
+		}
+		c.Redirect(http.StatusSeeOther, "/")
+	}
+}
+
+// sessionCarryOver — ключи, которые переживают вход: корзина, черновик оформления, последний заказ
+var sessionCarryOver = []string{SessionCartKey, SessionCheckoutKey, SessionOrderKey}
+
+// startUserSession — вход пользователя. Защита от фиксации сессии: сессия собирается
+// заново (Clear), переносятся только sessionCarryOver; вместе с остальными значениями
+// сбрасывается соль CSRF — после входа действует новый токен. Анонимная корзина
+// переходит к пользователю (ClaimSessionCart).
+func startUserSession(c *gin.Context, app *App, user *storage.User) error {
+	sess := sessions.Default(c)
+	keep := map[string]any{}
+	for _, k := range sessionCarryOver {
+		if v := sess.Get(k); v != nil {
+			keep[k] = v
+		}
+	}
+
+	sess.Clear()
+	for k, v := range keep {
+		sess.Set(k, v)
+	}
+	sess.Set(SessionUserKey, user.ID)
+	if err := sess.Save(); err != nil {
+		return err
+	}
+	return ClaimSessionCart(c, app.Carts, user.ID)
+}
+
+// parseAuthForm — разбор POST-формы с ограничением размера; false — ответ уже отправлен
+func parseAuthForm(c *gin.Context) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20)
+	if err := c.Request.ParseForm(); err != nil {
+		core.FailC(c, &core.AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: "Некорректный запрос", Err: err})
+		return false
+	}
+	return true
+}
+
+// safeNext — адрес возврата после входа: только локальный путь ("/checkout"), иначе "/".
+// Не даёт превратить /login?next=https://evil.example в открытый редирект.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+	return next
+}
+
+// renderAuth — рендер register.html / login.html
+func renderAuth(c *gin.Context, app *App, name, title string, data AuthView) {
+	if data.Errors == nil {
+		data.Errors = map[string]string{}
+	}
+	if err := app.Templates.Render(c, name, title, data); err != nil {
+		core.LogError("Ошибка рендеринга "+name, map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+	}
+}
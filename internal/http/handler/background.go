@@ -0,0 +1,26 @@
+package handler
+
+// background.go — работа обработчика после ответа клиенту (письма, время которых не должно
+// быть видно по ответу). Остановка сервера и тесты дожидаются её через WaitBackground.
+import (
+	"context"
+	"sync"
+)
+
+// background — незавершённые фоновые задачи процесса
+var background sync.WaitGroup
+
+// goBackground — выполняет fn в отдельной горутине; ctx запроса не отменит её после ответа
+func goBackground(ctx context.Context, fn func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		fn(ctx)
+	}()
+}
+
+// WaitBackground — ждёт завершения фоновых задач (graceful shutdown, проверка писем в тестах)
+func WaitBackground() {
+	background.Wait()
+}
@@ -0,0 +1,320 @@
+package handler
+
+// cart.go — корзина: /cart (GET), /cart/add, /cart/update, /cart/remove (POST + CSRF).
+// Позиция — товар или его вариант: формы передают product_id и (у товаров с вариантами) variant_id.
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Ключи сессии. В cookie лежит только ID корзины — содержимое хранится в MySQL.
+const (
+	SessionCartKey = "cart_id"
+	SessionUserKey = "user_id"
+)
+
+// errNotInStock — товар или выбранный вариант закончился
+var errNotInStock = &core.AppError{Code: "out_of_stock", Status: http.StatusConflict, Message: "Товара нет в наличии"}
+
+// CartView — данные для шаблона cart.html
+type CartView struct {
+	Cart *storage.Cart
+	Max  int // Максимальное количество одного товара (для <input max>)
+}
+
+// Cart — страница корзины. Пустую корзину в БД не создаём — только показываем.
+func Cart(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cart, err := loadSessionCart(c, app.Carts)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки корзины", err))
+			return
+		}
+
+		data := CartView{Cart: cart, Max: storage.MaxCartQuantity}
+		if err := app.Templates.Render(c, "cart", "Корзина", data); err != nil {
+			core.LogError("Ошибка рендеринга cart", map[string]interface{}{"error": err.Error()})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+			return
+		}
+	}
+}
+
+// CartAdd — добавить товар в корзину (POST product_id, variant_id, quantity)
+func CartAdd(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, variantID, qty, err := parseCartForm(c, 1)
+		if err == nil {
+			err = addCartItem(c, app, productID, variantID, qty)
+		}
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+
+		// PRG-паттерн: после POST — редирект на страницу корзины
+		c.Redirect(http.StatusSeeOther, "/cart")
+	}
+}
+
+// CartUpdate — изменить количество (0 — удалить позицию)
+func CartUpdate(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, variantID, qty, err := parseCartForm(c, -1)
+		if err == nil {
+			err = setCartItem(c, app, productID, variantID, qty)
+		}
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		c.Redirect(http.StatusSeeOther, "/cart")
+	}
+}
+
+// CartRemove — удалить позицию из корзины
+func CartRemove(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		productID, variantID, _, err := parseCartForm(c, 0)
+		if err == nil {
+			err = removeCartItem(c, app, productID, variantID)
+		}
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		c.Redirect(http.StatusSeeOther, "/cart")
+	}
+}
+
+// addCartItem — общая часть CartAdd и POST /api/v1/cart/items: проверяет товар, вариант
+// и наличие, при необходимости создаёт корзину сессии
+func addCartItem(c *gin.Context, app *App, productID, variantID, qty int) error {
+	// Проверяем, что товар существует — иначе 404, а не ошибка внешнего ключа
+	product, err := app.Products.GetByID(c.Request.Context(), productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Товар не найден"}
+		}
+		return core.Internal("Ошибка загрузки товара", err)
+	}
+	variant, err := cartVariant(c.Request.Context(), app, product, variantID)
+	if err != nil {
+		return err
+	}
+	available, variantKey := product.Available, ""
+	if variant != nil {
+		available, variantKey = variant.Available, variant.ID
+	}
+	if available <= 0 {
+		return errNotInStock
+	}
+
+	cartID, err := ensureSessionCart(c, app.Carts)
+	if err != nil {
+		return core.Internal("Ошибка корзины", err)
+	}
+
+	if err := app.Carts.AddItem(c.Request.Context(), cartID, product.ID, variantKey, qty); err != nil {
+		return core.Internal("Ошибка корзины", err)
+	}
+	return releaseReservation(c, app, cartID)
+}
+
+// setCartItem — новое количество позиции корзины сессии (0 — удалить)
+func setCartItem(c *gin.Context, app *App, productID, variantID, qty int) error {
+	cartID := sessionCartID(c)
+	if cartID == "" {
+		return nil
+	}
+	if err := app.Carts.SetItemQuantity(c.Request.Context(), cartID, strconv.Itoa(productID), formatVariantID(variantID), qty); err != nil {
+		return core.Internal("Ошибка корзины", err)
+	}
+	return releaseReservation(c, app, cartID)
+}
+
+// removeCartItem — удаляет позицию из корзины сессии
+func removeCartItem(c *gin.Context, app *App, productID, variantID int) error {
+	cartID := sessionCartID(c)
+	if cartID == "" {
+		return nil
+	}
+	if err := app.Carts.RemoveItem(c.Request.Context(), cartID, strconv.Itoa(productID), formatVariantID(variantID)); err != nil {
+		return core.Internal("Ошибка корзины", err)
+	}
+	return releaseReservation(c, app, cartID)
+}
+
+// releaseReservation — корзина изменилась: резерв под оформление снимается и будет
+// заново взят на шаге оформления
+func releaseReservation(c *gin.Context, app *App, cartID string) error {
+	if err := app.Inventory.Release(c.Request.Context(), cartID); err != nil {
+		return core.Internal("Ошибка корзины", err)
+	}
+	return nil
+}
+
+// ClaimSessionCart — вызывается после входа пользователя: анонимная корзина из сессии
+// переходит к пользователю (или сливается с его корзиной), в сессии остаётся ID итоговой корзины.
+func ClaimSessionCart(c *gin.Context, carts storage.CartRepository, userID string) error {
+	cartID, err := carts.Claim(c.Request.Context(), sessionCartID(c), userID)
+	if err != nil {
+		return err
+	}
+
+	sess := sessions.Default(c)
+	if cartID == "" {
+		sess.Delete(SessionCartKey)
+	} else {
+		sess.Set(SessionCartKey, cartID)
+	}
+	return sess.Save()
+}
+
+// parseCartForm — product_id, variant_id (0 — без варианта) и quantity из формы.
+// defQty < 0 — quantity обязателен; иначе используется как значение по умолчанию.
+func parseCartForm(c *gin.Context, defQty int) (productID, variantID, qty int, err error) {
+	// Ограничиваем размер тела запроса (как в FormSubmit)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20)
+	if err := c.Request.ParseForm(); err != nil {
+		return 0, 0, 0, &core.AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: "Некорректный запрос", Err: err}
+	}
+
+	errs := map[string]string{}
+	productID, perr := strconv.Atoi(strings.TrimSpace(c.Request.PostForm.Get("product_id")))
+	if perr != nil || productID <= 0 {
+		errs["product_id"] = "Неверный ID товара"
+	}
+	if raw := strings.TrimSpace(c.Request.PostForm.Get("variant_id")); raw != "" {
+		n, verr := strconv.Atoi(raw)
+		if verr != nil || n <= 0 {
+			errs["variant_id"] = "Неверный ID варианта"
+		}
+		variantID = n
+	}
+
+	qty = defQty
+	if raw := strings.TrimSpace(c.Request.PostForm.Get("quantity")); raw != "" || defQty < 0 {
+		n, qerr := strconv.Atoi(raw)
+		if qerr != nil || n < 0 || n > storage.MaxCartQuantity {
+			errs["quantity"] = "Количество должно быть от 0 до " + strconv.Itoa(storage.MaxCartQuantity)
+		}
+		qty = n
+	}
+
+	if len(errs) > 0 {
+		return 0, 0, 0, cartInputError(errs)
+	}
+	return productID, variantID, qty, nil
+}
+
+// cartInputError — 400 с ошибками по полям позиции корзины (форма и JSON)
+func cartInputError(errs map[string]string) error {
+	return &core.AppError{
+		Code:    "validation",
+		Status:  http.StatusBadRequest,
+		Message: "Некорректные данные корзины",
+		Fields:  errs,
+	}
+}
+
+// sessionCartID — ID корзины из сессии ("" — корзины ещё нет). У запроса с API-токеном
+// сессии нет — это корзина владельца токена (LoadAPIToken).
+func sessionCartID(c *gin.Context) string {
+	if CurrentAPIToken(c.Request.Context()) != nil {
+		return c.GetString(tokenCartKey)
+	}
+	id, _ := sessions.Default(c).Get(SessionCartKey).(string)
+	return id
+}
+
+// sessionUserID — ID пользователя из сессии ("" — аноним)
+func sessionUserID(c *gin.Context) string {
+	switch v := sessions.Default(c).Get(SessionUserKey).(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
+
+// loadSessionCart — корзина из сессии; если её нет или она удалена — пустая корзина (без записи в БД)
+func loadSessionCart(c *gin.Context, carts storage.CartRepository) (*storage.Cart, error) {
+	if cartID := sessionCartID(c); cartID != "" {
+		cart, err := carts.Get(c.Request.Context(), cartID)
+		if err == nil {
+			return cart, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return &storage.Cart{}, nil
+}
+
+// ensureSessionCart — ID корзины из сессии или новая корзина (её ID сохраняется в сессию).
+// Для вошедшего пользователя сначала ищется его собственная корзина.
+func ensureSessionCart(c *gin.Context, carts storage.CartRepository) (string, error) {
+	ctx := c.Request.Context()
+	if CurrentAPIToken(ctx) != nil {
+		return ensureTokenCart(c, carts)
+	}
+	if cartID := sessionCartID(c); cartID != "" {
+		ok, err := carts.Exists(ctx, cartID)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return cartID, nil
+		}
+	}
+
+	userID := sessionUserID(c)
+	cartID := ""
+	if userID != "" {
+		id, err := carts.IDByUser(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		cartID = id
+	}
+	if cartID == "" {
+		id, err := carts.Create(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		cartID = id
+	}
+
+	sess := sessions.Default(c)
+	sess.Set(SessionCartKey, cartID)
+	if err := sess.Save(); err != nil {
+		return "", err
+	}
+	return cartID, nil
+}
+
+// ensureTokenCart — корзина владельца API-токена (новая, если её нет); сессия не меняется
+func ensureTokenCart(c *gin.Context, carts storage.CartRepository) (string, error) {
+	if cartID := c.GetString(tokenCartKey); cartID != "" {
+		return cartID, nil
+	}
+	cartID, err := carts.Create(c.Request.Context(), CurrentUser(c.Request.Context()).ID)
+	if err != nil {
+		return "", err
+	}
+	c.Set(tokenCartKey, cartID)
+	return cartID, nil
+}
@@ -9,28 +9,104 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Catalog — отображает каталог товаров из MySQL
-func Catalog(tpl *view.Templates) gin.HandlerFunc {
+// CatalogView — данные для шаблона catalog.html
+type CatalogView struct {
+	Category    *storage.Category   // Текущая категория (nil — весь каталог)
+	Breadcrumbs []*storage.Category // Путь от корня до текущей категории
+	Items       []storage.Product   // Товары текущей страницы
+	Filter      CatalogFilter       // Применённые фильтры/сортировка (для формы)
+	Pagination  Pagination          // Навигация по страницам (partial "pagination")
+}
+
+// CatalogFilter — значения формы сортировки/фильтра (строки — как их показать в <input>)
+type CatalogFilter struct {
+	Sort     string
+	MinPrice string
+	MaxPrice string
+}
+
+// Catalog — отображает каталог товаров из MySQL (?page=&sort=&min_price=&max_price=)
+func Catalog(app *App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Достаём *sqlx.DB из контекста запроса (мы его туда положили в middleware withNonceAndDB)
-		db := storage.GetDBFromContext(c.Request.Context())
-		if db == nil {
-			core.LogError("DB недоступна в контексте", nil)
-			core.FailC(c, core.Internal("Внутренняя ошибка", nil))
-			return
-		}
+		renderCatalog(c, app, "")
+	}
+}
+
+// CatalogCategory — каталог, ограниченный категорией и всеми её подкатегориями (/catalog/:slug)
+func CatalogCategory(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		renderCatalog(c, app, c.Param("slug"))
+	}
+}
+
+// renderCatalog — общая часть /catalog и /catalog/:slug
+func renderCatalog(c *gin.Context, app *App, slug string) {
+	data, err := loadCatalog(c, app, slug)
+	if err != nil {
+		core.FailC(c, err)
+		return
+	}
+
+	title := "Каталог товаров"
+	if data.Category != nil {
+		title = data.Category.Name
+	}
+	if err := app.Templates.Render(c, "catalog", title, data); err != nil {
+		core.LogError("Ошибка рендеринга catalog", map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+		return
+	}
+}
 
-		items, err := storage.ListAllProducts(c.Request.Context(), db)
+// loadCatalog — разбирает параметры запроса и загружает страницу каталога (HTML и JSON)
+func loadCatalog(c *gin.Context, app *App, slug string) (*CatalogView, error) {
+	q, err := parseProductQuery(c)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &CatalogView{}
+	if slug != "" {
+		// Ищем по дереву, а не отдельным запросом: заодно получаем хлебные крошки и потомков
+		path, err := resolveCategory(c.Request.Context(), app.Categories, slug)
 		if err != nil {
-			core.LogError("Ошибка загрузки каталога", map[string]interface{}{"error": err.Error()})
-			core.FailC(c, core.Internal("Ошибка каталога", err))
-			return
+			return nil, err
 		}
+		data.Breadcrumbs = path
+		data.Category = path[len(path)-1]
+		q.CategoryIDs = data.Category.DescendantIDs()
+	}
+
+	page, err := app.Products.List(c.Request.Context(), q)
+	if err != nil {
+		core.LogError("Ошибка загрузки каталога", map[string]interface{}{"error": err.Error()})
+		return nil, core.Internal("Ошибка каталога", err)
+	}
+
+	// Варианты нужны и карточкам (у товара с вариантами нет кнопки "В корзину"), и JSON
+	if err := attachVariants(c.Request.Context(), app, page.Items); err != nil {
+		return nil, err
+	}
 
-		if err := tpl.Render(c, "catalog", "Каталог товаров", items); err != nil {
-			core.LogError("Ошибка рендеринга catalog", map[string]interface{}{"error": err.Error()})
-			core.FailC(c, core.Internal("Ошибка отображения", err))
-			return
+	data.Items = page.Items
+	data.Filter = CatalogFilter{
+		Sort:     q.Sort,
+		MinPrice: formatPriceFilter(q.MinPrice),
+		MaxPrice: formatPriceFilter(q.MaxPrice),
+	}
+	data.Pagination = NewPagination(c.Request.URL, page.Page, page.PageSize, page.Total)
+	return data, nil
+}
+
+// CategoryMenu — источник меню категорий для layout (view.Templates.SetMenu).
+// Ошибка загрузки не ломает страницу: меню просто не показывается.
+func CategoryMenu(app *App) view.MenuFunc {
+	return func(c *gin.Context) any {
+		tree, err := storage.LoadCategoryTree(c.Request.Context(), app.Categories)
+		if err != nil {
+			core.LogError("Ошибка загрузки меню категорий", map[string]interface{}{"error": err.Error()})
+			return nil
 		}
+		return tree
 	}
 }
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 
 	"myApp/internal/core"
 	"myApp/internal/storage"
@@ -9,24 +10,36 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// CatalogJSON — JSON-эндпоинт каталога (Gin-версия)
-func CatalogJSON() gin.HandlerFunc {
+// CatalogPage — JSON-ответ каталога: товары страницы + пагинация
+type CatalogPage struct {
+	Items      []storage.Product `json:"items"`
+	Pagination Pagination        `json:"pagination"`
+}
+
+// CatalogJSON — JSON-эндпоинт каталога (Gin-версия).
+// Параметры те же, что у /catalog, плюс ?category=slug. Варианты — в items[].variants.
+// ETag — Conditional в маршруте; Last-Modified — время изменения каталога (товары, остатки, резервы).
+func CatalogJSON(app *App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		db := storage.GetDBFromContext(c.Request.Context())
-		if db == nil {
-			core.LogError("DB недоступна в контексте", nil)
-			core.FailC(c, core.Internal("Внутренняя ошибка", nil))
+		// Время — до выборки: изменение между ними даст более старый Last-Modified
+		// (лишний 200), а не более новый (304 с устаревшими данными)
+		modified, err := app.Products.Modified(c.Request.Context())
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки каталога", err))
 			return
 		}
 
-		items, err := storage.ListAllProducts(c.Request.Context(), db)
+		data, err := loadCatalog(c, app, strings.TrimSpace(c.Query("category")))
 		if err != nil {
-			core.LogError("Ошибка загрузки каталога (JSON)", map[string]interface{}{"error": err.Error()})
-			core.FailC(c, core.Internal("Ошибка каталога", err))
+			core.FailC(c, err)
 			return
 		}
 
-		// Можно через ваш helper, если он есть: core.JSON(c, http.StatusOK, items)
-		c.JSON(http.StatusOK, items)
+		items := data.Items
+		if items == nil {
+			items = []storage.Product{} // [] вместо null для клиентов
+		}
+		setLastModified(c, modified)
+		core.JSON(c, http.StatusOK, CatalogPage{Items: items, Pagination: data.Pagination})
 	}
 }
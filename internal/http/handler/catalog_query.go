@@ -0,0 +1,88 @@
+package handler
+
+// catalog_query.go — разбор ?page=&page_size=&sort=&min_price=&max_price=&category= для каталога
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseProductQuery — читает параметры каталога из query-строки.
+// Некорректные значения возвращаются одной ошибкой 400 с перечнем полей (RFC 7807 fields).
+func parseProductQuery(c *gin.Context) (storage.ProductQuery, error) {
+	var q storage.ProductQuery
+	errs := map[string]string{}
+
+	q.Page, q.PageSize = parsePaging(c, errs)
+
+	if v := strings.TrimSpace(c.Query("sort")); v != "" {
+		if !storage.IsValidProductSort(v) {
+			errs["sort"] = "Допустимые значения: name, -name, price, -price, created_at, -created_at"
+		}
+		q.Sort = v
+	}
+
+	q.MinPrice = parsePrice(c.Query("min_price"), "min_price", errs)
+	q.MaxPrice = parsePrice(c.Query("max_price"), "max_price", errs)
+	if q.MinPrice != nil && q.MaxPrice != nil && q.MinPrice.Cmp(*q.MaxPrice) > 0 {
+		errs["max_price"] = "Максимальная цена меньше минимальной"
+	}
+
+	if len(errs) > 0 {
+		return q, &core.AppError{
+			Code:    "invalid_query",
+			Status:  http.StatusBadRequest,
+			Message: "Некорректные параметры каталога",
+			Fields:  errs,
+		}
+	}
+	return q.Normalize(), nil
+}
+
+// parsePrice — неотрицательная цена из query (в валюте магазина); пустое значение — без фильтра
+func parsePrice(raw, field string, errs map[string]string) *money.Money {
+	v := strings.TrimSpace(raw)
+	if v == "" {
+		return nil
+	}
+	m, err := money.Parse(v, money.DefaultCurrency)
+	if err != nil || m.IsNegative() {
+		errs[field] = "Цена должна быть неотрицательным числом (не более 2 знаков после запятой)"
+		return nil
+	}
+	return &m
+}
+
+// formatPriceFilter — цена фильтра для повторного показа в форме ("" — фильтра нет)
+func formatPriceFilter(v *money.Money) string {
+	if v == nil {
+		return ""
+	}
+	return v.Decimal()
+}
+
+// resolveCategory — находит категорию по slug и возвращает путь от корня (для крошек и фильтра потомков)
+func resolveCategory(ctx context.Context, categories storage.CategoryRepository, slug string) ([]*storage.Category, error) {
+	tree, err := storage.LoadCategoryTree(ctx, categories)
+	if err != nil {
+		return nil, core.Internal("Ошибка загрузки категорий", err)
+	}
+
+	path := storage.FindCategoryPath(tree, slug)
+	if path == nil {
+		return nil, &core.AppError{
+			Code:    "not_found",
+			Status:  http.StatusNotFound,
+			Message: "Категория не найдена",
+			Err:     sql.ErrNoRows,
+		}
+	}
+	return path, nil
+}
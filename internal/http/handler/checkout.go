@@ -0,0 +1,380 @@
+package handler
+
+// checkout.go — оформление заказа по шагам: контакты → адрес → доставка → проверка → подтверждение.
+// Черновик хранится в сессии (JSON), заказ создаётся в БД только на шаге подтверждения.
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+	"myApp/internal/storage"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// SessionCheckoutKey — ключ сессии с черновиком заказа
+const SessionCheckoutKey = "checkout"
+
+// Шаги оформления (совпадают с путями /checkout/<шаг>)
+const (
+	StepContact  = "contact"
+	StepAddress  = "address"
+	StepShipping = "shipping"
+	StepReview   = "review"
+)
+
+// CheckoutStep — пункт индикатора шагов
+type CheckoutStep struct {
+	Name   string
+	Title  string
+	Active bool
+	Done   bool
+}
+
+var checkoutSteps = []CheckoutStep{
+	{Name: StepContact, Title: "Контакты"},
+	{Name: StepAddress, Title: "Адрес"},
+	{Name: StepShipping, Title: "Доставка"},
+	{Name: StepReview, Title: "Проверка"},
+}
+
+// CheckoutContact — шаг 1: контактные данные
+type CheckoutContact struct {
+	Name  string `json:"name" validate:"required,min=2,max=100"`
+	Email string `json:"email" validate:"required,email,max=255"`
+	Phone string `json:"phone" validate:"required,min=5,max=30"`
+}
+
+// CheckoutAddress — шаг 2: адрес доставки
+type CheckoutAddress struct {
+	Country    string `json:"country" validate:"required,max=60"`
+	City       string `json:"city" validate:"required,max=100"`
+	PostalCode string `json:"postal_code" validate:"required,printascii,max=20"`
+	Address    string `json:"address" validate:"required,max=255"`
+}
+
+// CheckoutDraft — черновик заказа в сессии
+type CheckoutDraft struct {
+	Contact  CheckoutContact `json:"contact"`
+	Address  CheckoutAddress `json:"address"`
+	Shipping string          `json:"shipping"`
+}
+
+// CheckoutView — данные для шаблона checkout.html
+type CheckoutView struct {
+	Step     string
+	Steps    []CheckoutStep
+	Draft    CheckoutDraft
+	Errors   map[string]string
+	Methods  []storage.ShippingMethod
+	Cart     *storage.Cart
+	Method   storage.ShippingMethod // Выбранный способ (шаг review)
+	Total    money.Money            // Корзина + доставка (шаг review)
+	Complete bool
+}
+
+// formField — имя поля формы и сообщения об ошибках по тегам валидатора
+type formField struct {
+	Key      string
+	Messages map[string]string
+	Default  string
+}
+
+// checkoutFields — поля шагов оформления (ключ — имя поля структуры)
+var checkoutFields = map[string]formField{
+	"Name": {Key: "name", Default: "Некорректное имя", Messages: map[string]string{
+		"required": "Укажите имя",
+		"min":      "Имя должно быть не короче 2 символов",
+		"max":      "Слишком длинное имя (макс. 100)",
+	}},
+	"Email": {Key: "email", Default: "Некорректный email", Messages: map[string]string{
+		"required": "Укажите email",
+		"email":    "Введите корректный email",
+	}},
+	"Phone": {Key: "phone", Default: "Некорректный телефон", Messages: map[string]string{
+		"required": "Укажите телефон",
+	}},
+	"Country":    {Key: "country", Default: "Укажите страну"},
+	"City":       {Key: "city", Default: "Укажите город"},
+	"PostalCode": {Key: "postal_code", Default: "Некорректный почтовый индекс"},
+	"Address":    {Key: "address", Default: "Укажите адрес (улица, дом, квартира)"},
+}
+
+// Checkout — /checkout: ведёт на первый незаполненный шаг
+func Checkout(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Redirect(http.StatusSeeOther, "/checkout/"+firstIncompleteStep(loadCheckoutDraft(c)))
+	}
+}
+
+// CheckoutPage — GET /checkout/<step>
+func CheckoutPage(app *App, step string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cart, ok := checkoutCart(c, app)
+		if !ok {
+			return
+		}
+
+		draft := loadCheckoutDraft(c)
+		// Нельзя перескочить через незаполненные шаги
+		if first := firstIncompleteStep(draft); stepIndex(step) > stepIndex(first) {
+			c.Redirect(http.StatusSeeOther, "/checkout/"+first)
+			return
+		}
+		renderCheckout(c, app, step, draft, cart, nil)
+	}
+}
+
+// CheckoutSubmit — POST /checkout/<step>: валидирует шаг, сохраняет черновик, ведёт на следующий шаг
+func CheckoutSubmit(app *App, step string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cart, ok := checkoutCart(c, app)
+		if !ok {
+			return
+		}
+
+		// Ограничиваем размер тела запроса (как в FormSubmit)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20)
+		if err := c.Request.ParseForm(); err != nil {
+			core.FailC(c, &core.AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: "Некорректный запрос", Err: err})
+			return
+		}
+
+		draft := loadCheckoutDraft(c)
+		var errs map[string]string
+		switch step {
+		case StepContact:
+			draft.Contact = CheckoutContact{
+				Name:  formValue(c, "name"),
+				Email: formValue(c, "email"),
+				Phone: formValue(c, "phone"),
+			}
+			errs = validationErrors(validate.Struct(draft.Contact), checkoutFields)
+		case StepAddress:
+			draft.Address = CheckoutAddress{
+				Country:    formValue(c, "country"),
+				City:       formValue(c, "city"),
+				PostalCode: strings.ToUpper(strings.ReplaceAll(formValue(c, "postal_code"), " ", "")),
+				Address:    formValue(c, "address"),
+			}
+			errs = validationErrors(validate.Struct(draft.Address), checkoutFields)
+		case StepShipping:
+			draft.Shipping = formValue(c, "shipping_method")
+			if _, ok := storage.FindShippingMethod(draft.Shipping); !ok {
+				errs = map[string]string{"shipping_method": "Выберите способ доставки"}
+			}
+		default:
+			core.FailC(c, &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Шаг оформления не найден"})
+			return
+		}
+
+		if len(errs) > 0 {
+			c.Status(http.StatusBadRequest) // статус до рендера
+			renderCheckout(c, app, step, draft, cart, errs)
+			return
+		}
+
+		if err := saveCheckoutDraft(c, draft); err != nil {
+			core.FailC(c, core.Internal("Ошибка сохранения сессии", err))
+			return
+		}
+
+		// PRG-паттерн: следующий шаг (или первый незаполненный)
+		next := checkoutSteps[stepIndex(step)+1].Name
+		if first := firstIncompleteStep(draft); stepIndex(first) < stepIndex(next) {
+			next = first
+		}
+		c.Redirect(http.StatusSeeOther, "/checkout/"+next)
+	}
+}
+
+// CheckoutConfirm — POST /checkout/confirm: создаёт заказ из корзины и ведёт на страницу заказа
+func CheckoutConfirm(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft := loadCheckoutDraft(c)
+		if step := firstIncompleteStep(draft); step != StepReview {
+			c.Redirect(http.StatusSeeOther, "/checkout/"+step)
+			return
+		}
+
+		cartID := sessionCartID(c)
+		if cartID == "" {
+			c.Redirect(http.StatusSeeOther, "/cart")
+			return
+		}
+
+		order, err := app.Orders.CreateFromCart(c.Request.Context(), cartID, storage.OrderDraft{
+			UserID:         sessionUserID(c),
+			Email:          draft.Contact.Email,
+			Name:           draft.Contact.Name,
+			Phone:          draft.Contact.Phone,
+			Country:        draft.Address.Country,
+			City:           draft.Address.City,
+			PostalCode:     draft.Address.PostalCode,
+			Address:        draft.Address.Address,
+			ShippingMethod: draft.Shipping,
+		}, app.Config.PendingOrderTTL)
+		// Пустая корзина или товар закончился — корзина покажет, каких позиций не хватает
+		if errors.Is(err, storage.ErrEmptyCart) || errors.Is(err, storage.ErrOutOfStock) {
+			c.Redirect(http.StatusSeeOther, "/cart")
+			return
+		}
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+
+		// Черновик больше не нужен; номер заказа запоминаем — по нему гость видит свой заказ
+		sess := sessions.Default(c)
+		sess.Delete(SessionCheckoutKey)
+		sess.Set(SessionOrderKey, order.Number)
+		if err := sess.Save(); err != nil {
+			core.LogError("Ошибка сохранения сессии после заказа", map[string]interface{}{"error": err.Error()})
+		}
+
+		c.Redirect(http.StatusSeeOther, "/orders/"+order.Number)
+	}
+}
+
+// checkoutCart — корзина из сессии с продлённым резервом товара на RESERVATION_TTL.
+// Пустая корзина или товара не хватает — редирект на /cart (ok=false).
+func checkoutCart(c *gin.Context, app *App) (*storage.Cart, bool) {
+	cart, err := loadSessionCart(c, app.Carts)
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки корзины", err))
+		return nil, false
+	}
+	if len(cart.Lines) == 0 {
+		c.Redirect(http.StatusSeeOther, "/cart")
+		return nil, false
+	}
+
+	err = app.Inventory.Reserve(c.Request.Context(), cart.ID, app.Config.ReservationTTL)
+	if errors.Is(err, storage.ErrOutOfStock) {
+		c.Redirect(http.StatusSeeOther, "/cart")
+		return nil, false
+	}
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка резерва товара", err))
+		return nil, false
+	}
+	return cart, true
+}
+
+// renderCheckout — рендер шага оформления
+func renderCheckout(c *gin.Context, app *App, step string, draft CheckoutDraft, cart *storage.Cart, errs map[string]string) {
+	if errs == nil {
+		errs = map[string]string{}
+	}
+
+	current := stepIndex(step)
+	steps := make([]CheckoutStep, len(checkoutSteps))
+	for i, s := range checkoutSteps {
+		s.Active = i == current
+		s.Done = i < current
+		steps[i] = s
+	}
+
+	data := CheckoutView{
+		Step:    step,
+		Steps:   steps,
+		Draft:   draft,
+		Errors:  errs,
+		Methods: storage.ShippingMethods,
+		Cart:    cart,
+	}
+	if m, ok := storage.FindShippingMethod(draft.Shipping); ok {
+		data.Method = m
+		data.Total = cart.Total.Add(m.Price)
+		data.Complete = firstIncompleteStep(draft) == StepReview
+	}
+
+	if err := app.Templates.Render(c, "checkout", "Оформление заказа", data); err != nil {
+		core.LogError("Ошибка рендеринга checkout", map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+	}
+}
+
+// firstIncompleteStep — первый шаг, данные которого не заполнены или невалидны (review — всё готово)
+func firstIncompleteStep(d CheckoutDraft) string {
+	switch {
+	case validate.Struct(d.Contact) != nil:
+		return StepContact
+	case validate.Struct(d.Address) != nil:
+		return StepAddress
+	}
+	if _, ok := storage.FindShippingMethod(d.Shipping); !ok {
+		return StepShipping
+	}
+	return StepReview
+}
+
+// stepIndex — порядковый номер шага (неизвестный шаг — первый)
+func stepIndex(step string) int {
+	for i, s := range checkoutSteps {
+		if s.Name == step {
+			return i
+		}
+	}
+	return 0
+}
+
+// loadCheckoutDraft — черновик из сессии (битый или отсутствующий — пустой)
+func loadCheckoutDraft(c *gin.Context) CheckoutDraft {
+	var d CheckoutDraft
+	if raw, ok := sessions.Default(c).Get(SessionCheckoutKey).(string); ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &d); err != nil {
+			return CheckoutDraft{}
+		}
+	}
+	return d
+}
+
+// saveCheckoutDraft — черновик в сессию (JSON-строкой: cookie-store кодирует только простые типы)
+func saveCheckoutDraft(c *gin.Context, d CheckoutDraft) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	sess := sessions.Default(c)
+	sess.Set(SessionCheckoutKey, string(raw))
+	return sess.Save()
+}
+
+// formValue — поле формы без пробелов по краям и с санитизацией (как в FormSubmit)
+func formValue(c *gin.Context, key string) string {
+	return sanitizer.Sanitize(strings.TrimSpace(c.Request.PostForm.Get(key)))
+}
+
+// validationErrors — ошибки validator.Struct в виде "поле формы → сообщение"
+func validationErrors(err error, fields map[string]formField) map[string]string {
+	errs := map[string]string{}
+	if err == nil {
+		return errs
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		errs["form"] = "Ошибка валидации"
+		core.LogError("Неожиданная ошибка валидации", map[string]interface{}{"error": err.Error()})
+		return errs
+	}
+	for _, e := range verrs {
+		f, ok := fields[e.Field()]
+		if !ok {
+			errs["form"] = "Ошибка валидации"
+			continue
+		}
+		msg, ok := f.Messages[e.Tag()]
+		if !ok {
+			msg = f.Default
+		}
+		errs[f.Key] = msg
+	}
+	return errs
+}
@@ -0,0 +1,119 @@
+package handler
+
+// conditional.go — условные GET (RFC 9110, раздел 13): ETag по содержимому ответа,
+// Last-Modified от обработчика, 304 на If-None-Match / If-Modified-Since и Cache-Control маршрута.
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Conditional — middleware GET-маршрута: ответ 200 копится до конца обработчика, ETag — SHA-256
+// тела без CSP nonce (он свой у каждого запроса). Совпал If-None-Match или, если его нет,
+// ресурс не менялся с If-Modified-Since (Last-Modified ставит обработчик, setLastModified) —
+// 304 без тела. cacheControl — политика маршрута (CATALOG_CACHE_CONTROL, PRODUCT_CACHE_CONTROL).
+// Запрос к БД и рендер всё равно выполняются: экономится передача тела.
+func Conditional(cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() != http.StatusOK {
+			w.flush()
+			return
+		}
+		etag := contentETag(w.buf.Bytes(), core.Nonce(c.Request.Context()))
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", cacheControl)
+		if !notModified(c.Request, etag, c.Writer.Header().Get("Last-Modified")) {
+			w.flush()
+			return
+		}
+
+		// Кэш клиента дополняет сохранённый ответ заголовками 304: CSP с новым nonce
+		// заблокировала бы скрипты сохранённой страницы, поэтому её не отправляем
+		h := c.Writer.Header()
+		for _, k := range []string{"Content-Security-Policy", "Content-Type", "Content-Length"} {
+			h.Del(k)
+		}
+		c.Writer.WriteHeader(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+	}
+}
+
+// setLastModified — Last-Modified ответа (нулевое время — без заголовка). Только для ответов
+// без данных сессии: время изменения данных не отражает вход, корзину и т.п.
+func setLastModified(c *gin.Context, t time.Time) {
+	if !t.IsZero() {
+		c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// contentETag — сильный ETag тела; nonce из тела исключается (в атрибутах html/template
+// экранирует "+" base64 как &#43;)
+func contentETag(body []byte, nonce string) string {
+	if nonce != "" {
+		body = bytes.ReplaceAll(body, []byte(nonce), nil)
+		body = bytes.ReplaceAll(body, []byte(strings.ReplaceAll(nonce, "+", "&#43;")), nil)
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified — у клиента актуальная копия: If-None-Match сравнивается слабо (nginx с gzip
+// отдаёт W/"..."), If-Modified-Since учитывается только без If-None-Match
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Values("If-None-Match"); len(inm) > 0 {
+		for _, tag := range strings.Split(strings.Join(inm, ","), ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified == "" {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && !modified.After(since)
+}
+
+// bufferedWriter — копит тело ответа, чтобы посчитать ETag до отправки клиенту
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// flush — отправляет накопленное тело (и статус, если он ещё не ушёл)
+func (w *bufferedWriter) flush() {
+	if w.buf.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
@@ -0,0 +1,66 @@
+package handler
+
+// current_user.go — текущий пользователь запроса: LoadUser кладёт его в контекст,
+// обработчики читают через CurrentUser, шаблоны — через PageData.User (см. UserMenu).
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// userCtxKey — ключ пользователя в context.Context (не экспортируется, как ключи core)
+type userCtxKey struct{}
+
+// CurrentUser — вошедший пользователь (nil — аноним или LoadUser не подключён)
+func CurrentUser(ctx context.Context) *storage.User {
+	u, _ := ctx.Value(userCtxKey{}).(*storage.User)
+	return u
+}
+
+// LoadUser — middleware: пользователь из user_id сессии вместе с правами его роли
+// (User.Can, RequirePermission). Удалённый пользователь
+// разлогинивается: ключ убирается из сессии, запрос идёт дальше анонимно.
+// Запрос с API-токеном сессию не читает: пользователя уже задал LoadAPIToken.
+func LoadUser(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := sessionUserID(c)
+		if id == "" || CurrentAPIToken(c.Request.Context()) != nil {
+			c.Next()
+			return
+		}
+
+		u, err := app.Users.GetByID(c.Request.Context(), id)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			sess := sessions.Default(c)
+			sess.Delete(SessionUserKey)
+			if err := sess.Save(); err != nil {
+				core.LogError("Ошибка сохранения сессии", map[string]interface{}{"error": err.Error()})
+			}
+		case err != nil:
+			core.FailC(c, core.Internal("Ошибка загрузки пользователя", err))
+			return
+		default:
+			if u.Permissions, err = app.Roles.Permissions(c.Request.Context(), u.Role); err != nil {
+				core.FailC(c, core.Internal("Ошибка загрузки прав пользователя", err))
+				return
+			}
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), userCtxKey{}, u))
+		}
+		c.Next()
+	}
+}
+
+// UserMenu — источник PageData.User для layout (view.Templates.SetUser)
+func UserMenu(c *gin.Context) any {
+	if u := CurrentUser(c.Request.Context()); u != nil {
+		return u
+	}
+	return nil
+}
@@ -12,7 +12,6 @@ import (
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
 )
 
 // Переменные, которые могут быть установлены через ldflags при сборке
@@ -22,12 +21,8 @@ var (
 	appStartTime = time.Now()
 )
 
-// Константы для ключей Gin Context (должны совпадать с main.go)
-const (
-	ContextNonceKey = "csp_nonce"
-	ContextDBKey    = "db_connection"
-	SchemaVersion   = "1.0"
-)
+// SchemaVersion — версия формата ответа /debug
+const SchemaVersion = "1.0"
 
 // tryExtractSessionValues пытается "best-effort" получить полное содержимое сессии.
 // Возвращает map[string]interface{} или nil, если извлечение не удалось.
@@ -103,7 +98,14 @@ func mapFromReflectMap(m reflect.Value) map[string]interface{} {
 }
 
 // Debug — handler, возвращающий расширенную отладочную информацию в JSON.
-func Debug(c *gin.Context) {
+func Debug(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		debugInfo(c, app)
+	}
+}
+
+// debugInfo — тело /debug
+func debugInfo(c *gin.Context, app *App) {
 	startTime := time.Now()
 
 	var memStats runtime.MemStats
@@ -144,7 +146,7 @@ func Debug(c *gin.Context) {
 			"latency_ms": 0,
 		},
 		"context": map[string]interface{}{
-			"nonce":        c.GetString(ContextNonceKey),
+			"nonce":        core.Nonce(c.Request.Context()),
 			"db_connected": false,
 		},
 		"health": map[string]interface{}{
@@ -166,7 +168,7 @@ func Debug(c *gin.Context) {
 	sessionInfo := map[string]interface{}{
 		"exists":             false,
 		"session_cookie_raw": rawSessionCookie,
-		"authenticated":      false,
+		"authenticated":      CurrentUser(c.Request.Context()) != nil,
 		"values":             nil,
 	}
 
@@ -174,18 +176,12 @@ func Debug(c *gin.Context) {
 		sessionInfo["exists"] = true
 		if vals := tryExtractSessionValues(sess); vals != nil {
 			sessionInfo["values"] = vals
-			if _, ok := vals["user_id"]; ok {
-				sessionInfo["authenticated"] = true
-			}
 		} else {
 			known := []string{"user_id", "user", "email", "authenticated", "is_admin", "csrf"}
 			fallback := map[string]interface{}{}
 			for _, k := range known {
 				if v := sess.Get(k); v != nil {
 					fallback[k] = v
-					if k == "user_id" {
-						sessionInfo["authenticated"] = true
-					}
 				}
 			}
 			sessionInfo["values"] = fallback
@@ -194,26 +190,24 @@ func Debug(c *gin.Context) {
 
 	info["session"] = sessionInfo
 
-	if dbIface, ok := c.Get(ContextDBKey); ok {
-		if db, ok := dbIface.(*sqlx.DB); ok && db != nil {
-			startPing := time.Now()
-			err := db.Ping()
-			health := info["health"].(map[string]interface{})
-			if err != nil {
-				health["db_ping"] = fmt.Sprintf("error: %v", err)
-			} else {
-				stats := db.Stats()
-				health["db_pool_open"] = stats.OpenConnections
-				health["db_pool_in_use"] = stats.InUse
-				health["db_idle"] = stats.Idle
-				health["db_ping"] = fmt.Sprintf("ok (latency: %dms)", time.Since(startPing).Milliseconds())
-			}
-			info["context"].(map[string]interface{})["db_connected"] = true
+	if db := app.DB; db != nil {
+		startPing := time.Now()
+		err := db.PingContext(c.Request.Context())
+		health := info["health"].(map[string]interface{})
+		if err != nil {
+			health["db_ping"] = fmt.Sprintf("error: %v", err)
 		} else {
-			info["health"].(map[string]interface{})["db_ping"] = "error: DB interface conversion failed"
+			stats := db.Stats()
+			health["db_pool_open"] = stats.OpenConnections
+			health["db_pool_in_use"] = stats.InUse
+			health["db_idle"] = stats.Idle
+			health["db_ping"] = fmt.Sprintf("ok (latency: %dms)", time.Since(startPing).Milliseconds())
 		}
+		info["context"].(map[string]interface{})["db_connected"] = true
+	} else if app.Config.Storage == core.StorageMemory {
+		info["health"].(map[string]interface{})["db_ping"] = "n/a: хранилище в памяти (APP_STORAGE=memory)"
 	} else {
-		info["health"].(map[string]interface{})["db_ping"] = "error: no DB found in Gin context (key: db_connection)"
+		info["health"].(map[string]interface{})["db_ping"] = "error: DB не подключена"
 	}
 
 	info["processing"].(map[string]interface{})["latency_ms"] = time.Since(startTime).Milliseconds()
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"myApp/internal/core"
-	"myApp/internal/view"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -35,7 +34,7 @@ var (
 )
 
 // FormIndex — GET-страница формы (OWASP A03: Injection)
-func FormIndex(tpl *view.Templates) gin.HandlerFunc {
+func FormIndex(app *App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ok := c.Query("ok") == "1"
 
@@ -45,7 +44,7 @@ func FormIndex(tpl *view.Templates) gin.HandlerFunc {
 			OK:     ok,
 		}
 
-		if err := tpl.Render(c, "form", "Форма", data); err != nil {
+		if err := app.Templates.Render(c, "form", "Форма", data); err != nil {
 			core.LogError("Ошибка рендеринга шаблона form", map[string]interface{}{
 				"error": err.Error(),
 				"path":  c.Request.URL.Path,
@@ -57,7 +56,7 @@ func FormIndex(tpl *view.Templates) gin.HandlerFunc {
 }
 
 // FormSubmit — POST-обработчик отправки формы (OWASP A03, A05)
-func FormSubmit(tpl *view.Templates) gin.HandlerFunc {
+func FormSubmit(app *App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method != http.MethodPost {
 			c.String(http.StatusMethodNotAllowed, "Метод не разрешён")
@@ -139,7 +138,7 @@ func FormSubmit(tpl *view.Templates) gin.HandlerFunc {
 				OK:     false,
 			}
 			c.Status(http.StatusBadRequest) // статус до рендера
-			if err := tpl.Render(c, "form", "Форма", data); err != nil {
+			if err := app.Templates.Render(c, "form", "Форма", data); err != nil {
 				core.LogError("Ошибка рендеринга шаблона form", map[string]interface{}{
 					"error": err.Error(),
 					"path":  c.Request.URL.Path,
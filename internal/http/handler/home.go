@@ -4,13 +4,12 @@ import (
 	"net/http"
 
 	"myApp/internal/core"
-	"myApp/internal/view"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Home — обработчик главной страницы (OWASP A03: Injection)
-func Home(tpl *view.Templates) gin.HandlerFunc {
+func Home(app *App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Пример данных для шаблона (можешь убрать/заменить)
 		data := map[string]any{
@@ -19,7 +18,7 @@ func Home(tpl *view.Templates) gin.HandlerFunc {
 		}
 
 		// Рендерим шаблон "home"
-		if err := tpl.Render(c, "home", "Главная", data); err != nil {
+		if err := app.Templates.Render(c, "home", "Главная", data); err != nil {
 			core.LogError("Ошибка рендеринга шаблона home", map[string]interface{}{
 				"error": err.Error(),
 				"path":  c.Request.URL.Path,
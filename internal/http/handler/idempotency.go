@@ -0,0 +1,202 @@
+package handler
+
+// idempotency.go — Idempotency-Key для POST/PUT/PATCH в /api/v1: клиент, повторяющий запрос
+// после обрыва связи, получает сохранённый первый ответ, а не второй заказ или позицию корзины.
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"myApp/internal/auth"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyHeader — заголовок ключа повторов (IETF draft-ietf-httpapi-idempotency-key-header)
+const IdempotencyHeader = "Idempotency-Key"
+
+// SessionClientKey — ключ сессии со случайным ID анонимного клиента (область его ключей)
+const SessionClientKey = "client_id"
+
+// maxIdempotencyKey — предел длины ключа (колонка idem_key)
+const maxIdempotencyKey = 255
+
+// Ошибки ключа Idempotency-Key
+var (
+	errIdempotencyKey        = &core.AppError{Code: "invalid_idempotency_key", Status: http.StatusBadRequest, Message: "Idempotency-Key: от 1 до 255 печатных символов ASCII"}
+	errIdempotencyMismatch   = &core.AppError{Code: "idempotency_key_reused", Status: http.StatusConflict, Message: "Idempotency-Key уже использован для другого запроса"}
+	errIdempotencyInProgress = &core.AppError{Code: "idempotency_key_in_progress", Status: http.StatusConflict, Message: "Запрос с этим Idempotency-Key ещё выполняется"}
+)
+
+// Idempotency — middleware группы /api/v1: POST/PUT/PATCH с заголовком Idempotency-Key
+// выполняется один раз за IDEMPOTENCY_TTL. Повтор с тем же методом, путём и телом получает
+// сохранённый ответ (код, заголовки обработчика, тело) с Idempotent-Replayed: true; тот же ключ
+// с другим запросом — 409 idempotency_key_reused. Ключи разных токенов, пользователей и
+// сессий не пересекаются. Ответ 5xx не сохраняется — повтор выполнит запрос заново.
+func Idempotency(app *App) gin.HandlerFunc {
+	// Незавершённый дольше этого ключ брошен: запрос дольше REQUEST_TIMEOUT уже прерван
+	stale := app.Config.RequestTimeout + time.Minute
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" || !slices.Contains([]string{http.MethodPost, http.MethodPut, http.MethodPatch}, c.Request.Method) {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey(key) {
+			core.FailC(c, errIdempotencyKey)
+			return
+		}
+
+		scope, err := idempotencyScope(c)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка сохранения сессии", err))
+			return
+		}
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			core.FailC(c, &core.AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: "Тело запроса не прочитано", Err: err})
+			return
+		}
+
+		ctx := c.Request.Context()
+		rec := &storage.IdempotencyRecord{Scope: scope, Key: key, Fingerprint: fingerprint}
+		prev, err := app.Idempotency.Begin(ctx, rec, app.Config.IdempotencyTTL, stale)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка проверки Idempotency-Key", err))
+			return
+		}
+		switch {
+		case prev == nil:
+		case prev.Fingerprint != fingerprint:
+			core.FailC(c, errIdempotencyMismatch)
+			return
+		case !prev.Done():
+			c.Header("Retry-After", "1")
+			core.FailC(c, errIdempotencyInProgress)
+			return
+		default:
+			core.LogInfo("Повтор запроса по Idempotency-Key", map[string]interface{}{"scope": scope, "path": c.FullPath(), "status": prev.Status})
+			replayResponse(c, prev)
+			return
+		}
+
+		before := c.Writer.Header().Clone()
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		// Ответ уже отправлен: отмену запроса клиентом сохранение не прерывает
+		ctx = context.WithoutCancel(ctx)
+		if w.Status() >= http.StatusInternalServerError {
+			_ = app.Idempotency.Release(ctx, scope, key)
+			return
+		}
+		rec.Status, rec.Header, rec.Body = w.Status(), handlerHeaders(before, w.Header()), w.body.Bytes()
+		_ = app.Idempotency.Complete(ctx, rec)
+	}
+}
+
+// validIdempotencyKey — ключ из 1..255 печатных символов ASCII
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKey {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// idempotencyScope — чей ключ: API-токен, пользователь или анонимная сессия
+// (ей при первом ключе выдаётся случайный SessionClientKey)
+func idempotencyScope(c *gin.Context) (string, error) {
+	ctx := c.Request.Context()
+	if t := CurrentAPIToken(ctx); t != nil {
+		return "token:" + t.ID, nil
+	}
+	if u := CurrentUser(ctx); u != nil {
+		return "user:" + u.ID, nil
+	}
+
+	sess := sessions.Default(c)
+	id, _ := sess.Get(SessionClientKey).(string)
+	if id == "" {
+		raw, _, err := auth.NewToken()
+		if err != nil {
+			return "", err
+		}
+		id = raw
+		sess.Set(SessionClientKey, id)
+		if err := sess.Save(); err != nil {
+			return "", err
+		}
+	}
+	return "session:" + id, nil
+}
+
+// requestFingerprint — SHA-256 метода, пути с query и тела (тело возвращается в запрос)
+func requestFingerprint(c *gin.Context) (string, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replayResponse — сохранённый ответ вместо выполнения обработчика
+func replayResponse(c *gin.Context, rec *storage.IdempotencyRecord) {
+	for k, v := range rec.Header {
+		c.Writer.Header()[k] = slices.Clone(v)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Writer.WriteHeader(rec.Status)
+	if len(rec.Body) > 0 {
+		_, _ = c.Writer.Write(rec.Body)
+	}
+	c.Abort()
+}
+
+// handlerHeaders — заголовки, которые выставил или изменил обработчик (общие заголовки
+// безопасности и cookie сессии не сохраняются: повтор получит свои)
+func handlerHeaders(before, after http.Header) http.Header {
+	h := http.Header{}
+	for k, v := range after {
+		if k != "Set-Cookie" && !slices.Equal(before[k], v) {
+			h[k] = slices.Clone(v)
+		}
+	}
+	return h
+}
+
+// recordingWriter — пропускает ответ клиенту и копит его тело для сохранения
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
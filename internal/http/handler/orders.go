@@ -0,0 +1,97 @@
+package handler
+
+// orders.go — страница заказа /orders/:number
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionOrderKey — номер последнего оформленного в этой сессии заказа
+const SessionOrderKey = "order_number"
+
+// OrderView — данные для шаблона order.html
+type OrderView struct {
+	Order   *storage.Order
+	Method  storage.ShippingMethod
+	Placed  bool   // Заказ только что оформлен (показываем "Спасибо")
+	Payment string // Результат возврата со страницы оплаты: success, declined
+}
+
+// OrderShow — страница заказа. ?payment=success|declined — возврат со страницы оплаты.
+func OrderShow(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := loadOwnOrder(c, app)
+		if !ok {
+			return
+		}
+
+		placed, _ := sessions.Default(c).Get(SessionOrderKey).(string)
+		method, _ := storage.FindShippingMethod(order.ShippingMethod)
+		data := OrderView{
+			Order:   order,
+			Method:  method,
+			Placed:  placed == order.Number && order.Status == storage.OrderPending,
+			Payment: c.Query("payment"),
+		}
+		if err := app.Templates.Render(c, "order", "Заказ "+order.Number, data); err != nil {
+			core.LogError("Ошибка рендеринга order", map[string]interface{}{"error": err.Error()})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+			return
+		}
+	}
+}
+
+// OrderCancel — POST: покупатель отменяет неоплаченный заказ, резерв товара снимается.
+// Оплата, пришедшая позже, не переведёт заказ — событие уйдёт в конфликт на ручной возврат.
+func OrderCancel(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := loadOwnOrder(c, app)
+		if !ok {
+			return
+		}
+		if order.Status != storage.OrderPending {
+			core.FailC(c, &core.AppError{Code: "order_not_cancellable", Status: http.StatusConflict, Message: "Заказ уже оплачен или отменён"})
+			return
+		}
+
+		// Недопустимый переход (заказ оплачен параллельно) — 409 из конечного автомата
+		if err := app.Orders.UpdateStatus(c.Request.Context(), order.ID, storage.OrderCancelled, "Отменён покупателем"); err != nil {
+			core.FailC(c, err)
+			return
+		}
+		c.Redirect(http.StatusSeeOther, "/orders/"+order.Number)
+	}
+}
+
+// loadOwnOrder — заказ по :number, если его видит текущий посетитель: пользователь заказа
+// или сессия, в которой он оформлен. Чужой или несуществующий номер — одинаковый 404
+// (номер не раскрывает наличие заказа). ok=false — ответ уже отправлен.
+func loadOwnOrder(c *gin.Context, app *App) (*storage.Order, bool) {
+	notFound := &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Заказ не найден"}
+
+	order, err := app.Orders.GetByNumber(c.Request.Context(), c.Param("number"))
+	if errors.Is(err, sql.ErrNoRows) {
+		core.FailC(c, notFound)
+		return nil, false
+	}
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки заказа", err))
+		return nil, false
+	}
+
+	placed, _ := sessions.Default(c).Get(SessionOrderKey).(string)
+	userID := sessionUserID(c)
+	owner := placed == order.Number || (userID != "" && order.UserID != nil && *order.UserID == userID)
+	if !owner {
+		core.FailC(c, notFound)
+		return nil, false
+	}
+	return order, true
+}
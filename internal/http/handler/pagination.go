@@ -0,0 +1,72 @@
+package handler
+
+// pagination.go — общая постраничная навигация для HTML (partial "pagination") и JSON
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination — метаданные страницы списка + готовые ссылки вперёд/назад
+type Pagination struct {
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	PrevURL    string `json:"prev,omitempty"`
+	NextURL    string `json:"next,omitempty"`
+}
+
+// NewPagination — считает число страниц и строит ссылки на соседние страницы.
+// Остальные параметры запроса (sort, фильтры) сохраняются, меняется только page.
+func NewPagination(u *url.URL, page, pageSize, total int) Pagination {
+	p := Pagination{Page: page, PageSize: pageSize, Total: total}
+	if pageSize > 0 {
+		p.TotalPages = (total + pageSize - 1) / pageSize
+	}
+	if page > 1 {
+		p.PrevURL = pageURL(u, page-1)
+	}
+	if page < p.TotalPages {
+		p.NextURL = pageURL(u, page+1)
+	}
+	return p
+}
+
+// pageURL — тот же путь и query, но с другим номером страницы
+func pageURL(u *url.URL, page int) string {
+	q := u.Query()
+	if page <= 1 {
+		q.Del("page")
+	} else {
+		q.Set("page", strconv.Itoa(page))
+	}
+
+	out := url.URL{Path: u.Path, RawQuery: q.Encode()}
+	return out.String()
+}
+
+// parsePaging — ?page=&page_size= из query. Ошибки пишутся в errs по имени параметра,
+// нули означают "по умолчанию" (их подставит Normalize запроса).
+func parsePaging(c *gin.Context, errs map[string]string) (page, pageSize int) {
+	if v := strings.TrimSpace(c.Query("page")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			errs["page"] = "Номер страницы должен быть положительным числом"
+		}
+		page = n
+	}
+
+	if v := strings.TrimSpace(c.Query("page_size")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > storage.MaxPageSize {
+			errs["page_size"] = "Размер страницы должен быть от 1 до " + strconv.Itoa(storage.MaxPageSize)
+		}
+		pageSize = n
+	}
+	return page, pageSize
+}
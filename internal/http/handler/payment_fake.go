@@ -0,0 +1,83 @@
+package handler
+
+// payment_fake.go — "страница банка" фейкового провайдера: ввод тестовой карты и шаг 3-D Secure.
+// Регистрируется только при PAYMENT_PROVIDER=fake.
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/payment"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FakePaymentView — данные для шаблона payment_fake.html
+type FakePaymentView struct {
+	Intent *payment.Intent
+	Cards  []payment.TestCard
+	Is3DS  bool
+}
+
+// FakePayment — GET /payments/fake/:id и /payments/fake/:id/3ds
+func FakePayment(app *App, fake *payment.Fake) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		intent, err := fake.Intent(c.Param("id"))
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+
+		data := FakePaymentView{
+			Intent: intent,
+			Cards:  payment.TestCards,
+			Is3DS:  intent.Status == payment.IntentRequiresAction,
+		}
+		if err := app.Templates.Render(c, "payment_fake", "Оплата заказа "+intent.OrderNumber, data); err != nil {
+			core.LogError("Ошибка рендеринга payment_fake", map[string]interface{}{"error": err.Error()})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+			return
+		}
+	}
+}
+
+// FakePaymentSubmit — POST /payments/fake/:id: "ввод карты"
+func FakePaymentSubmit(app *App, fake *payment.Fake) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20)
+		intent, err := fake.Authorize(c.Request.Context(), c.Param("id"), strings.TrimSpace(c.PostForm("card")))
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		redirectAfterPayment(c, intent)
+	}
+}
+
+// FakePayment3DSSubmit — POST /payments/fake/:id/3ds: подтверждение или отказ покупателя
+func FakePayment3DSSubmit(app *App, fake *payment.Fake) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 1<<20)
+		intent, err := fake.Confirm3DS(c.Request.Context(), c.Param("id"), c.PostForm("result") == "approve")
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		redirectAfterPayment(c, intent)
+	}
+}
+
+// redirectAfterPayment — 3-D Secure или возврат в магазин с результатом (?payment=)
+func redirectAfterPayment(c *gin.Context, intent *payment.Intent) {
+	if intent.Status == payment.IntentRequiresAction {
+		c.Redirect(http.StatusSeeOther, intent.RedirectURL)
+		return
+	}
+
+	result := "success"
+	if intent.Status == payment.IntentDeclined {
+		result = "declined"
+	}
+	c.Redirect(http.StatusSeeOther, intent.ReturnURL+"?payment="+url.QueryEscape(result))
+}
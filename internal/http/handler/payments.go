@@ -0,0 +1,76 @@
+package handler
+
+// payments.go — оплата заказа (/orders/:number/pay) и webhook провайдера (/payments/webhook)
+import (
+	"io"
+	"net/http"
+
+	"myApp/internal/core"
+	"myApp/internal/payment"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBody — предел тела webhook (события небольшие)
+const maxWebhookBody = 64 << 10
+
+// OrderPay — POST: создаёт платёж у провайдера и отправляет покупателя на страницу оплаты
+func OrderPay(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := loadOwnOrder(c, app)
+		if !ok {
+			return
+		}
+		if order.Status != storage.OrderPending {
+			core.FailC(c, &core.AppError{Code: "order_not_payable", Status: http.StatusConflict, Message: "Заказ уже оплачен или отменён"})
+			return
+		}
+
+		intent, err := app.Gateway.CreateIntent(c.Request.Context(), payment.IntentRequest{
+			OrderNumber: order.Number,
+			Amount:      order.Total,
+			ReturnURL:   "/orders/" + order.Number,
+		})
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+
+		if err := app.Payments.Create(c.Request.Context(), storage.Payment{
+			OrderID:  order.ID,
+			Provider: app.Gateway.Name(),
+			IntentID: intent.ID,
+			Status:   string(intent.Status),
+			Amount:   intent.Amount,
+		}); err != nil {
+			core.FailC(c, core.Internal("Ошибка создания платежа", err))
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, intent.RedirectURL)
+	}
+}
+
+// PaymentWebhook — приём событий провайдера. Подпись — в заголовке payment.SignatureHeader.
+// Повторная доставка того же события отвечает 200 и ничего не меняет. Событие, которое не
+// переводит заказ (оплата отменённого), тоже отвечает 200 с conflict: true — нужен ручной возврат.
+// Маршрут исключён из CSRF-проверки: запрос приходит от провайдера, а не из браузера.
+func PaymentWebhook(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBody))
+		if err != nil {
+			core.FailC(c, &core.AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: "Некорректный запрос", Err: err})
+			return
+		}
+
+		res, err := payment.HandleWebhook(c.Request.Context(), app.Payments, app.Gateway, payload, c.GetHeader(payment.SignatureHeader))
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+
+		// Конфликт с заказом тоже подтверждается: повтор доставки его не исправит
+		core.JSON(c, http.StatusOK, gin.H{"received": true, "duplicate": !res.Applied, "conflict": res.Conflict != ""})
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+// product_images.go — изображение товара в форме /admin/products: файл из поля "image"
+// (multipart), проверка типа по содержимому и размера, миниатюры JPEG/WebP (internal/images)
+// в app.Blobs. Старые миниатюры удаляются после того, как товар сохранён без них.
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"myApp/internal/core"
+	"myApp/internal/images"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productFormMemory — сколько multipart-формы держать в памяти (остальное — во временных файлах)
+const productFormMemory = 1 << 20
+
+// productImagePrefix — каталог изображений товаров в BlobStore
+const productImagePrefix = "products"
+
+// parseProductForm — форма товара: multipart (с файлом) или обычная; false — ответ уже отправлен
+func parseProductForm(c *gin.Context) bool {
+	err := c.Request.ParseMultipartForm(productFormMemory)
+	if errors.Is(err, http.ErrNotMultipart) {
+		return parseAuthForm(c)
+	}
+	if err != nil {
+		core.FailC(c, &core.AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: "Некорректный запрос", Err: err})
+		return false
+	}
+	return true
+}
+
+// readProductImage — содержимое файла из поля "image": nil — файл не выбран.
+// Второе значение — сообщение для поля формы (слишком большой файл, не изображение).
+func readProductImage(c *gin.Context, app *App) ([]byte, string) {
+	file, header, err := c.Request.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+		return nil, ""
+	}
+	if err != nil {
+		return nil, "Не удалось получить файл"
+	}
+	defer func() { _ = file.Close() }()
+
+	limit := int64(app.Config.UploadMaxBytes)
+	tooLarge := fmt.Sprintf("Файл больше %.3g МБ", float64(limit)/(1<<20))
+	if header.Size > limit {
+		return nil, tooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, "Не удалось получить файл"
+	}
+	if int64(len(data)) > limit {
+		return nil, tooLarge
+	}
+	if len(data) == 0 {
+		return nil, ""
+	}
+	// Тип — по содержимому: имя файла и Content-Type части задаёт клиент
+	if _, err := images.Sniff(data); err != nil {
+		return nil, imageErrorMessage(err)
+	}
+	return data, ""
+}
+
+// imageErrorMessage — ошибка internal/images для поля формы
+func imageErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, images.ErrUnsupported):
+		return "Загрузите изображение JPEG, PNG, GIF или WebP"
+	case errors.Is(err, images.ErrTooLarge):
+		return fmt.Sprintf("Слишком большое изображение (макс. %d Мп)", images.MaxPixels/1_000_000)
+	default:
+		return "Не удалось прочитать изображение"
+	}
+}
+
+// storeProductImage — миниатюры в BlobStore и их ключ в p; при ошибке записанное удаляется
+func storeProductImage(ctx context.Context, app *App, p *storage.Product, set *images.Set) error {
+	for _, r := range set.Renditions {
+		if err := app.Blobs.Put(ctx, r.Name, bytes.NewReader(r.Data)); err != nil {
+			deleteProductImage(ctx, app, &storage.ProductImage{Key: set.Key, Width: set.Width})
+			return err
+		}
+	}
+	p.ImageKey, p.ImageWidth, p.ImageHeight = &set.Key, &set.Width, &set.Height
+	return nil
+}
+
+// deleteProductImage — удаляет миниатюры изображения (nil — ничего); ошибки — только в журнал:
+// товар уже сохранён, а лишний файл на диске не виден покупателю
+func deleteProductImage(ctx context.Context, app *App, img *storage.ProductImage) {
+	if img == nil {
+		return
+	}
+	for _, name := range images.Names(img.Key, img.Width) {
+		if err := app.Blobs.Delete(ctx, name); err != nil {
+			core.LogError("Ошибка удаления изображения", map[string]interface{}{"key": name, "error": err.Error()})
+		}
+	}
+}
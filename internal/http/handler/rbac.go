@@ -0,0 +1,86 @@
+package handler
+
+// rbac.go — проверка прав доступа. Права роли загружает LoadUser, поэтому middleware
+// ставится после него: r.Group("/admin", RequirePermission(app, storage.PermAdminAccess)).
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errLoginRequired — JSON-ответ анониму на закрытом маршруте
+var errLoginRequired = &core.AppError{Code: "unauthorized", Status: http.StatusUnauthorized, Message: "Требуется вход"}
+
+// RequirePermission — middleware: пропускает пользователя с правом perm.
+// Аноним: HTML — редирект на /login?next=..., JSON — 401. Нет права: HTML — страница 403,
+// JSON — core.Forbidden (RFC 7807).
+func RequirePermission(app *App, perm storage.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c.Request.Context())
+		switch {
+		case user.Can(perm):
+			c.Next()
+		case user == nil:
+			loginRequired(c)
+		default:
+			core.LogInfo("Доступ запрещён", map[string]interface{}{
+				"user_id":    user.ID,
+				"role":       user.Role,
+				"permission": perm,
+				"path":       c.FullPath(),
+			})
+			if wantsJSON(c) {
+				core.FailC(c, core.Forbidden("Недостаточно прав"))
+				return
+			}
+			Forbidden(app)(c)
+			c.Abort()
+		}
+	}
+}
+
+// RequireLogin — middleware: пропускает только вошедшего пользователя (аноним — как в RequirePermission)
+func RequireLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentUser(c.Request.Context()) == nil {
+			loginRequired(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// loginRequired — ответ анониму: HTML — редирект на /login?next=..., JSON — 401
+func loginRequired(c *gin.Context) {
+	if wantsJSON(c) {
+		core.FailC(c, errLoginRequired)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+	c.Abort()
+}
+
+// Forbidden — страница 403 (нет прав на раздел)
+func Forbidden(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusForbidden)
+		if err := app.Templates.Render(c, "forbidden", "Доступ запрещён", nil); err != nil {
+			core.LogError("Ошибка рендеринга шаблона forbidden", map[string]interface{}{"error": err.Error()})
+			c.String(http.StatusForbidden, "Доступ запрещён")
+		}
+	}
+}
+
+// wantsJSON — клиент ждёт JSON: маршруты /api/ и запросы с Accept: application/json без text/html
+func wantsJSON(c *gin.Context) bool {
+	if isAPI(c) {
+		return true
+	}
+	accept := c.GetHeader("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
@@ -0,0 +1,104 @@
+package handler
+
+// search.go — /search (HTML) и /api/v1/search (JSON)
+import (
+	"net/http"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchView — данные для шаблона search.html
+type SearchView struct {
+	Query      string       // Строка запроса (как ввёл пользователь)
+	Hits       []search.Hit // Найденные товары с подсветкой
+	Pagination Pagination
+}
+
+// SearchResults — data ответа /api/v1/search (пагинация — в конверте)
+type SearchResults struct {
+	Query string       `json:"query"`
+	Terms []string     `json:"terms"`
+	Items []search.Hit `json:"items"`
+}
+
+// Search — страница поиска. Пустой запрос — просто форма, без ошибки.
+func Search(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data := SearchView{Query: strings.TrimSpace(c.Query("q"))}
+
+		if data.Query != "" {
+			q, err := parseSearchQuery(c)
+			if err != nil {
+				core.FailC(c, err)
+				return
+			}
+			res, err := app.Search.Search(c.Request.Context(), q)
+			if err != nil {
+				core.FailC(c, core.Internal("Ошибка поиска", err))
+				return
+			}
+			data.Hits = res.Hits
+			data.Pagination = NewPagination(c.Request.URL, res.Page, res.PageSize, res.Total)
+		}
+
+		if err := app.Templates.Render(c, "search", "Поиск товаров", data); err != nil {
+			core.LogError("Ошибка рендеринга search", map[string]interface{}{"error": err.Error()})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+			return
+		}
+	}
+}
+
+// SearchJSON — JSON-поиск (/api/v1/search?q=&page=&page_size=)
+func SearchJSON(app *App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q, err := parseSearchQuery(c)
+		if err != nil {
+			core.FailC(c, err)
+			return
+		}
+		if q.Text == "" {
+			core.FailC(c, &core.AppError{
+				Code:    "invalid_query",
+				Status:  http.StatusBadRequest,
+				Message: "Укажите строку поиска",
+				Fields:  map[string]string{"q": "Обязательный параметр"},
+			})
+			return
+		}
+
+		res, err := app.Search.Search(c.Request.Context(), q)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка поиска", err))
+			return
+		}
+
+		pagination := NewPagination(c.Request.URL, res.Page, res.PageSize, res.Total)
+		core.JSON(c, http.StatusOK, Envelope{
+			Data:       SearchResults{Query: q.Text, Terms: res.Terms, Items: res.Hits},
+			Pagination: &pagination,
+		})
+	}
+}
+
+// parseSearchQuery — ?q=&page=&page_size= (проверки как у каталога)
+func parseSearchQuery(c *gin.Context) (search.Query, error) {
+	q := search.Query{Text: c.Query("q")}
+	errs := map[string]string{}
+
+	q.Page, q.PageSize = parsePaging(c, errs)
+
+	if len(errs) > 0 {
+		return q, &core.AppError{
+			Code:    "invalid_query",
+			Status:  http.StatusBadRequest,
+			Message: "Некорректные параметры поиска",
+			Fields:  errs,
+		}
+	}
+	return q.Normalize(), nil
+}
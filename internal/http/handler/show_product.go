@@ -9,65 +9,24 @@ import (
 
 	"myApp/internal/core"
 	"myApp/internal/storage"
-	"myApp/internal/view"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Product — детальная страница товара
-func Product(tpl *view.Templates) gin.HandlerFunc {
+// Product — детальная страница товара (ETag — Conditional в маршруте). Last-Modified нет:
+// страница зависит от сессии, время изменения каталога её не отражает.
+func Product(app *App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1) Достаём DB из контекста (кладётся в middleware withNonceAndDB)
-		db := storage.GetDBFromContext(c.Request.Context())
-		if db == nil {
-			core.LogError("DB недоступна в контексте", nil)
-			core.FailC(c, core.Internal("Внутренняя ошибка", nil))
-			return
-		}
-
-		// 2) Берём :id из маршрута (/product/:id) и валидируем
-		idStr := c.Param("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil || id <= 0 {
-			core.LogError("Неверный ID товара", map[string]interface{}{
-				"id":    idStr,
-				"error": err,
-			})
-			// 400 Bad Request в формате RFC7807
-			core.FailC(c, &core.AppError{
-				Code:    "bad_request",
-				Status:  http.StatusBadRequest,
-				Message: "Неверный ID товара",
-				Err:     err,
-			})
-			return
-		}
-
-		// 3) Достаём товар из БД
-		product, err := storage.GetProductByID(c.Request.Context(), db, id)
+		product, err := loadProduct(c, app)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				core.LogError("Товар не найден", map[string]interface{}{"id": id})
-				// 404 Not Found в формате RFC7807
-				core.FailC(c, &core.AppError{
-					Code:    "not_found",
-					Status:  http.StatusNotFound,
-					Message: "Товар не найден",
-				})
-				return
-			}
-			core.LogError("Ошибка загрузки товара", map[string]interface{}{
-				"id":    id,
-				"error": err.Error(),
-			})
-			core.FailC(c, core.Internal("Ошибка загрузки товара", err))
+			core.FailC(c, err)
 			return
 		}
 
-		// 4) Рендерим шаблон "product" (заголовок — имя товара)
-		if err := tpl.Render(c, "product", product.Name, product); err != nil {
+		// Рендерим шаблон "product" (заголовок — имя товара)
+		if err := app.Templates.Render(c, "product", product.Name, product); err != nil {
 			core.LogError("Ошибка рендеринга product", map[string]interface{}{
-				"id":    id,
+				"id":    product.ID,
 				"error": err.Error(),
 			})
 			core.FailC(c, core.Internal("Ошибка отображения", err))
@@ -75,3 +34,49 @@ func Product(tpl *view.Templates) gin.HandlerFunc {
 		}
 	}
 }
+
+// loadProduct — товар по :id вместе с вариантами (страница товара и /api/v1/products/:id)
+func loadProduct(c *gin.Context, app *App) (*storage.Product, error) {
+	// 1) Берём :id из маршрута (/product/:id) и валидируем
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		core.LogError("Неверный ID товара", map[string]interface{}{
+			"id":    idStr,
+			"error": err,
+		})
+		// 400 Bad Request в формате RFC7807
+		return nil, &core.AppError{
+			Code:    "bad_request",
+			Status:  http.StatusBadRequest,
+			Message: "Неверный ID товара",
+			Err:     err,
+		}
+	}
+
+	// 2) Достаём товар из БД
+	product, err := app.Products.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			core.LogError("Товар не найден", map[string]interface{}{"id": id})
+			// 404 Not Found в формате RFC7807
+			return nil, &core.AppError{
+				Code:    "not_found",
+				Status:  http.StatusNotFound,
+				Message: "Товар не найден",
+			}
+		}
+		core.LogError("Ошибка загрузки товара", map[string]interface{}{
+			"id":    id,
+			"error": err.Error(),
+		})
+		return nil, core.Internal("Ошибка загрузки товара", err)
+	}
+
+	// 3) Варианты (размер, цвет) — для выбора перед добавлением в корзину
+	items := []storage.Product{*product}
+	if err := attachVariants(c.Request.Context(), app, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
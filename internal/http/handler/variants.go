@@ -0,0 +1,72 @@
+package handler
+
+// variants.go — варианты товаров (размер, цвет) в обработчиках: загрузка для страниц
+// и JSON каталога, проверка выбранного варианта при добавлении в корзину.
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+)
+
+// attachVariants — заполняет Variants у товаров одним запросом на всю страницу
+func attachVariants(ctx context.Context, app *App, items []storage.Product) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]string, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+	}
+	variants, err := app.Variants.ByProducts(ctx, ids)
+	if err != nil {
+		core.LogError("Ошибка загрузки вариантов", map[string]interface{}{"error": err.Error()})
+		return core.Internal("Ошибка загрузки вариантов", err)
+	}
+	for i := range items {
+		items[i].Variants = variants[items[i].ID]
+	}
+	return nil
+}
+
+// cartVariant — выбранный вариант для строки корзины (nil — товар без вариантов).
+// У товара с вариантами вариант обязателен и должен принадлежать этому товару.
+func cartVariant(ctx context.Context, app *App, p *storage.Product, variantID int) (*storage.Variant, error) {
+	if variantID == 0 {
+		variants, err := app.Variants.ByProducts(ctx, []string{p.ID})
+		if err != nil {
+			return nil, core.Internal("Ошибка загрузки вариантов", err)
+		}
+		if len(variants[p.ID]) > 0 {
+			return nil, variantError("Выберите вариант")
+		}
+		return nil, nil
+	}
+
+	v, err := app.Variants.GetByID(ctx, variantID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && v.ProductID != p.ID) {
+		return nil, variantError("Вариант не найден")
+	}
+	if err != nil {
+		return nil, core.Internal("Ошибка загрузки варианта", err)
+	}
+	return v, nil
+}
+
+// variantError — ошибка валидации поля variant_id
+func variantError(msg string) error {
+	return &core.AppError{Code: "validation", Status: http.StatusBadRequest, Message: "Некорректные данные корзины",
+		Fields: map[string]string{"variant_id": msg}}
+}
+
+// formatVariantID — variant_id из формы для репозитория корзины (0 — "")
+func formatVariantID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,168 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"myApp/internal/apptest"
+	"myApp/internal/auth"
+	"myApp/internal/storage"
+)
+
+// TestVerifyEmail — письмо при регистрации, переход по ссылке, повторный переход отклоняется
+func TestVerifyEmail(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	if !strings.Contains(h.Get("/").Body, "Подтвердить email") {
+		t.Error("в меню нет \"Подтвердить email\" до подтверждения")
+	}
+
+	mails := h.Mails()
+	if len(mails) != 1 || mails[0].Subject != "Подтвердите email" || !strings.Contains(mails[0].Text, "Анна") {
+		t.Fatalf("письма после регистрации: %+v", mails)
+	}
+	link := h.MailLink("anna@example.com", "/account/verify")
+
+	// Ссылку открывают в другом браузере: вход не нужен
+	if res := h.NewClient().Get(link); res.Code != http.StatusOK || !strings.Contains(res.Body, "Email подтверждён") {
+		t.Fatalf("переход по ссылке: status %d", res.Code)
+	}
+	u, err := h.Repos.Users.GetByEmail(context.Background(), "anna@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.VerifiedAt == nil {
+		t.Error("email_verified_at не отмечен")
+	}
+	if strings.Contains(h.Get("/").Body, "Подтвердить email") {
+		t.Error("\"Подтвердить email\" в меню после подтверждения")
+	}
+
+	if res := h.Get(link); res.Code != http.StatusBadRequest || !strings.Contains(res.Body, "Ссылка недействительна") {
+		t.Errorf("повторный переход: status %d, want 400", res.Code)
+	}
+}
+
+// TestResendVerification — новое письмо гасит ссылку из предыдущего
+func TestResendVerification(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	first := h.MailLink("anna@example.com", "/account/verify")
+
+	if res := h.PostForm("/account/verify/resend", nil); res.Code != http.StatusOK {
+		t.Fatalf("resend: status %d", res.Code)
+	}
+	second := h.MailLink("anna@example.com", "/account/verify")
+	if first == second {
+		t.Fatal("повторное письмо с той же ссылкой")
+	}
+
+	if res := h.Get(first); res.Code != http.StatusBadRequest {
+		t.Errorf("старая ссылка: status %d, want 400", res.Code)
+	}
+	if res := h.Get(second); res.Code != http.StatusOK {
+		t.Errorf("новая ссылка: status %d, want 200", res.Code)
+	}
+}
+
+// TestPasswordReset — сброс пароля по ссылке: новый пароль работает, старый, ссылка
+// и API-токены — нет
+func TestPasswordReset(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "old-pass-123")
+	apiToken := h.CreateAPIToken("скрипт", "account:read")
+	h.PostForm("/logout", nil)
+
+	res := h.PostForm("/account/reset", url.Values{"email": {" ANNA@example.com "}})
+	if res.Code != http.StatusSeeOther || res.Location() != "/account/reset?sent=1" {
+		t.Fatalf("запрос сброса: status %d, Location %q", res.Code, res.Location())
+	}
+	link := h.MailLink("anna@example.com", "/account/reset/confirm")
+	token, err := url.ParseRequestURI(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := h.Get(link); res.Code != http.StatusOK || !strings.Contains(res.Body, `name="token"`) {
+		t.Fatalf("форма нового пароля: status %d", res.Code)
+	}
+
+	form := url.Values{"token": {token.Query().Get("token")}, "password": {"new-pass-123"}, "confirm": {"other-pass"}}
+	if res := h.PostForm("/account/reset/confirm", form); res.Code != http.StatusBadRequest || !strings.Contains(res.Body, "Пароли не совпадают") {
+		t.Fatalf("несовпадающие пароли: status %d", res.Code)
+	}
+
+	// Ошибка валидации не гасит ссылку
+	form.Set("confirm", "new-pass-123")
+	res = h.PostForm("/account/reset/confirm", form)
+	if res.Code != http.StatusSeeOther || res.Location() != "/login?reset=1" {
+		t.Fatalf("смена пароля: status %d, Location %q", res.Code, res.Location())
+	}
+	if !strings.Contains(h.Get(res.Location()).Body, "Пароль изменён") {
+		t.Error("на странице входа нет сообщения о смене пароля")
+	}
+
+	if res := h.SendToken(http.MethodGet, "/api/v1/account", apiToken, nil); res.Code != http.StatusUnauthorized {
+		t.Errorf("API-токен после смены пароля: status %d, want 401", res.Code)
+	}
+	if res := h.Login("anna@example.com", "old-pass-123"); res.Code != http.StatusUnauthorized {
+		t.Errorf("старый пароль: status %d, want 401", res.Code)
+	}
+	if res := h.Login("anna@example.com", "new-pass-123"); res.Code != http.StatusSeeOther {
+		t.Errorf("новый пароль: status %d, want 303", res.Code)
+	}
+
+	// Ссылка одноразовая; письмо пришло на адрес — email подтверждён
+	if res := h.PostForm("/account/reset/confirm", form); res.Code != http.StatusBadRequest {
+		t.Errorf("повторное использование ссылки: status %d, want 400", res.Code)
+	}
+	u, _ := h.Repos.Users.GetByEmail(context.Background(), "anna@example.com")
+	if u == nil || u.VerifiedAt == nil {
+		t.Error("email не подтверждён сбросом пароля")
+	}
+}
+
+// TestPasswordResetUnknownEmail — ответ для неизвестного адреса тот же, письма нет
+func TestPasswordResetUnknownEmail(t *testing.T) {
+	h := apptest.New(t)
+
+	res := h.PostForm("/account/reset", url.Values{"email": {"nobody@example.com"}})
+	if res.Code != http.StatusSeeOther || res.Location() != "/account/reset?sent=1" {
+		t.Errorf("status %d, Location %q", res.Code, res.Location())
+	}
+	if n := len(h.Mails()); n != 0 {
+		t.Errorf("отправлено писем: %d, want 0", n)
+	}
+
+	if res := h.PostForm("/account/reset", url.Values{"email": {"nobody"}}); res.Code != http.StatusBadRequest {
+		t.Errorf("некорректный email: status %d, want 400", res.Code)
+	}
+}
+
+// TestResetTokenExpired — истёкшая ссылка и токен другого назначения не принимаются
+func TestResetTokenExpired(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "old-pass-123")
+	ctx := context.Background()
+	u, err := h.Repos.Users.GetByEmail(ctx, "anna@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, hash, _ := auth.NewToken()
+	if err := h.Repos.Tokens.Create(ctx, u.ID, storage.TokenResetPassword, hash, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if res := h.Get("/account/reset/confirm?token=" + url.QueryEscape(expired)); res.Code != http.StatusBadRequest {
+		t.Errorf("истёкшая ссылка: status %d, want 400", res.Code)
+	}
+
+	verify := h.MailLink("anna@example.com", "/account/verify")
+	if res := h.Get(strings.Replace(verify, "/account/verify", "/account/reset/confirm", 1)); res.Code != http.StatusBadRequest {
+		t.Errorf("токен подтверждения email в сбросе пароля: status %d, want 400", res.Code)
+	}
+}
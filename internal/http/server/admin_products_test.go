@@ -0,0 +1,170 @@
+package server_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/storage"
+)
+
+// validProduct — форма нового товара без ошибок
+func validProduct() url.Values {
+	return url.Values{
+		"name":        {"Наушники QWE Air"},
+		"article":     {"ART-100"},
+		"category_id": {"5"},
+		"price":       {"49,90"},
+		"description": {"Беспроводные наушники с шумоподавлением."},
+		"image_alt":   {"Наушники в кейсе"},
+	}
+}
+
+// TestAdminProductCRUD — создание, правка, поиск и удаление товара сотрудником
+func TestAdminProductCRUD(t *testing.T) {
+	h := apptest.New(t)
+	h.RegisterAs("staff@example.com", storage.RoleStaff)
+	ctx := context.Background()
+
+	if res := h.Get("/admin/products/new"); res.Code != http.StatusOK || !strings.Contains(res.Body, "— Аудио") {
+		t.Fatalf("форма нового товара: status %d", res.Code)
+	}
+
+	res := h.PostForm("/admin/products", validProduct())
+	if res.Code != http.StatusSeeOther || !strings.HasSuffix(res.Location(), "/edit?saved=1") {
+		t.Fatalf("создание: status %d, Location %q, body %.300s", res.Code, res.Location(), res.Body)
+	}
+	page, err := h.Repos.Products.List(ctx, storage.ProductQuery{Text: "ART-100"})
+	if err != nil || page.Total != 1 {
+		t.Fatalf("товар не создан: %v, %+v", err, page)
+	}
+	p := page.Items[0]
+	if p.Price.Decimal() != "49.90" || p.CategoryID == nil || *p.CategoryID != "5" || p.Description == nil {
+		t.Errorf("поля товара: %+v", p)
+	}
+	if !strings.Contains(h.Get(res.Location()).Body, "Товар сохранён") {
+		t.Error("нет сообщения о сохранении")
+	}
+
+	// Новый товар сразу виден в каталоге и поиске
+	if !strings.Contains(h.Get("/product/"+p.ID).Body, "Наушники QWE Air") {
+		t.Error("товар не открывается на витрине")
+	}
+	if !strings.Contains(h.Get("/search?q=QWE").Body, "/product/"+p.ID) {
+		t.Error("новый товар не находится поиском")
+	}
+
+	form := validProduct()
+	form.Set("name", "Наушники QWE Air 2")
+	form.Set("category_id", "")
+	form.Set("description", "")
+	if res := h.PostForm("/admin/products/"+p.ID, form); res.Code != http.StatusSeeOther {
+		t.Fatalf("правка: status %d, body %.300s", res.Code, res.Body)
+	}
+	got, _ := h.Repos.Products.GetByID(ctx, atoi(t, p.ID))
+	if got.Name != "Наушники QWE Air 2" || got.CategoryID != nil || got.Description != nil || !got.CreatedAt.Equal(p.CreatedAt) {
+		t.Errorf("после правки: %+v", got)
+	}
+
+	list := h.Get("/admin/products?q=qwe")
+	if !strings.Contains(list.Body, "Наушники QWE Air 2") || strings.Contains(list.Body, "Смартфон XYZ Pro") {
+		t.Error("поиск в списке товаров")
+	}
+
+	if res := h.Get("/admin/products/" + p.ID + "/delete"); res.Code != http.StatusOK || !strings.Contains(res.Body, "Удалить товар?") {
+		t.Fatalf("подтверждение удаления: status %d", res.Code)
+	}
+	res = h.PostForm("/admin/products/"+p.ID+"/delete", nil)
+	if res.Code != http.StatusSeeOther || res.Location() != "/admin/products?deleted=1" {
+		t.Fatalf("удаление: status %d, Location %q", res.Code, res.Location())
+	}
+	if _, err := h.Repos.Products.GetByID(ctx, atoi(t, p.ID)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("товар не удалён: %v", err)
+	}
+	if res := h.Get("/admin/products/" + p.ID + "/edit"); res.Code != http.StatusNotFound {
+		t.Errorf("правка удалённого: status %d, want 404", res.Code)
+	}
+}
+
+// TestAdminProductValidation — ошибки у полей формы, санитизация и занятый артикул
+func TestAdminProductValidation(t *testing.T) {
+	with := func(k, v string) url.Values {
+		f := validProduct()
+		f.Set(k, v)
+		return f
+	}
+	tests := []struct {
+		name    string
+		form    url.Values
+		status  int
+		message string
+	}{
+		{"нет названия", with("name", ""), http.StatusBadRequest, "Укажите название"},
+		{"название из разметки", with("name", "<script>x</script>"), http.StatusBadRequest, "Укажите название"},
+		{"нет артикула", with("article", " "), http.StatusBadRequest, "Укажите артикул"},
+		{"цена текстом", with("price", "дёшево"), http.StatusBadRequest, "Цена — число, не более 2 знаков после запятой"},
+		{"нулевая цена", with("price", "0"), http.StatusBadRequest, "Цена должна быть больше нуля"},
+		{"огромная цена", with("price", "100000000"), http.StatusBadRequest, "Слишком большая цена"},
+		{"нет категории", with("category_id", "999"), http.StatusBadRequest, "Выберите категорию из списка"},
+		{"длинное описание", with("description", strings.Repeat("x", 5001)), http.StatusBadRequest, "Слишком длинное описание"},
+		{"занятый артикул", with("article", "art-001"), http.StatusConflict, "Товар с таким артикулом уже есть"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apptest.New(t)
+			h.RegisterAs("staff@example.com", storage.RoleStaff)
+
+			res := h.PostForm("/admin/products", tt.form)
+			if res.Code != tt.status || !strings.Contains(html.UnescapeString(res.Body), tt.message) {
+				t.Errorf("status %d, want %d с %q", res.Code, tt.status, tt.message)
+			}
+			if !strings.Contains(res.Body, "is-invalid") {
+				t.Error("ошибка не показана у поля")
+			}
+		})
+	}
+
+	t.Run("свой артикул при правке", func(t *testing.T) {
+		h := apptest.New(t)
+		h.RegisterAs("staff@example.com", storage.RoleStaff)
+		form := with("article", "ART-001")
+		if res := h.PostForm("/admin/products/1", form); res.Code != http.StatusSeeOther {
+			t.Errorf("status %d, want 303", res.Code)
+		}
+		if res := h.PostForm("/admin/products/2", form); res.Code != http.StatusConflict {
+			t.Errorf("чужой артикул: status %d, want 409", res.Code)
+		}
+	})
+}
+
+// TestAdminProductsPermission — покупатель не видит раздел, изменения без права не проходят
+func TestAdminProductsPermission(t *testing.T) {
+	h := apptest.New(t)
+	h.RegisterAs("user@example.com", storage.RoleCustomer)
+
+	if res := h.Get("/admin/products"); res.Code != http.StatusForbidden {
+		t.Errorf("список: status %d, want 403", res.Code)
+	}
+	if res := h.PostForm("/admin/products/1/delete", nil); res.Code != http.StatusForbidden {
+		t.Errorf("удаление: status %d, want 403", res.Code)
+	}
+	if _, err := h.Repos.Products.GetByID(context.Background(), 1); err != nil {
+		t.Errorf("товар удалён без прав: %v", err)
+	}
+}
+
+func atoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,235 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+)
+
+// apiPage — конверт списка /api/v1
+type apiPage[T any] struct {
+	Data       []T `json:"data"`
+	Pagination struct {
+		Page       int    `json:"page"`
+		PageSize   int    `json:"page_size"`
+		Total      int    `json:"total"`
+		TotalPages int    `json:"total_pages"`
+		NextURL    string `json:"next"`
+	} `json:"pagination"`
+}
+
+// apiItem — конверт одного объекта /api/v1
+type apiItem[T any] struct {
+	Data T `json:"data"`
+}
+
+// apiCart — корзина в ответах /api/v1/cart
+type apiCart struct {
+	Count int `json:"count"`
+	Lines []struct {
+		ProductID string  `json:"product_id"`
+		VariantID *string `json:"variant_id"`
+		Quantity  int     `json:"quantity"`
+	} `json:"lines"`
+}
+
+// TestAPICatalog — товары, категории и ошибки внутри /api/ в формате RFC 7807
+func TestAPICatalog(t *testing.T) {
+	h := apptest.New(t)
+
+	var list apiPage[struct {
+		ID string `json:"id"`
+	}]
+	if err := h.Get("/api/v1/products?page_size=2&sort=price").JSON(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data) != 2 || list.Pagination.Total != 5 || list.Pagination.TotalPages != 3 ||
+		list.Pagination.NextURL != "/api/v1/products?page=2&page_size=2&sort=price" {
+		t.Errorf("список товаров: %+v", list)
+	}
+
+	var product apiItem[struct {
+		Article  string        `json:"article"`
+		Variants []variantJSON `json:"variants"`
+	}]
+	if err := h.Get("/api/v1/products/5").JSON(&product); err != nil {
+		t.Fatal(err)
+	}
+	if product.Data.Article != "ART-005" || len(product.Data.Variants) != 3 {
+		t.Errorf("товар 5: %+v", product.Data)
+	}
+
+	var categories apiItem[[]struct {
+		Slug     string `json:"slug"`
+		Children []struct {
+			Slug string `json:"slug"`
+		} `json:"children"`
+	}]
+	if err := h.Get("/api/v1/categories").JSON(&categories); err != nil {
+		t.Fatal(err)
+	}
+	if len(categories.Data) != 1 || categories.Data[0].Slug != "electronics" || len(categories.Data[0].Children) != 3 {
+		t.Errorf("дерево категорий: %+v", categories.Data)
+	}
+
+	// Поиск — в том же конверте: запрос, слова и совпадения в data
+	var found struct {
+		Data struct {
+			Query string   `json:"query"`
+			Terms []string `json:"terms"`
+			Items []struct {
+				Product struct {
+					ID string `json:"id"`
+				} `json:"product"`
+			} `json:"items"`
+		} `json:"data"`
+		Pagination struct {
+			Total int `json:"total"`
+		} `json:"pagination"`
+	}
+	if err := h.Get("/api/v1/search?q=ART-002").JSON(&found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Data.Query != "ART-002" || len(found.Data.Terms) == 0 || len(found.Data.Items) != 1 ||
+		found.Data.Items[0].Product.ID != "2" || found.Pagination.Total != 1 {
+		t.Errorf("поиск: %+v", found)
+	}
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{"GET", "/api/v1/search", http.StatusBadRequest, "invalid_query"},
+		{"GET", "/api/v1/products?sort=nope", http.StatusBadRequest, "invalid_query"},
+		{"GET", "/api/v1/products/abc", http.StatusBadRequest, "bad_request"},
+		{"GET", "/api/v1/no-such-resource", http.StatusNotFound, "not_found"},
+		{"GET", "/api/v2/products", http.StatusNotFound, "not_found"},
+		{"HEAD", "/api/v1/products", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+	for _, tt := range tests {
+		res := h.Do(httptest.NewRequest(tt.method, tt.path, nil))
+		if p, ok := res.Problem(); res.Code != tt.status || !ok || p.Code != tt.code {
+			t.Errorf("%s %s: status %d, problem %+v; want %d %q", tt.method, tt.path, res.Code, p, tt.status, tt.code)
+		}
+	}
+
+	// 405 — с перечнем допустимых методов
+	res := h.SendJSON(http.MethodPut, "/api/v1/cart/items/1", nil)
+	if res.Code != http.StatusMethodNotAllowed || res.Header.Get("Allow") != "DELETE, PATCH" {
+		t.Errorf("PUT /api/v1/cart/items/1: status %d, Allow %q", res.Code, res.Header.Get("Allow"))
+	}
+	// Вне API неизвестный метод — по-прежнему HTML-страница 404
+	if res := h.SendJSON(http.MethodPut, "/cart", nil); res.Code != http.StatusNotFound ||
+		!strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("PUT /cart: status %d, Content-Type %q", res.Code, res.Header.Get("Content-Type"))
+	}
+}
+
+// TestAPICart — корзина через API: токен из X-CSRF-Token, добавление, изменение и удаление позиций
+func TestAPICart(t *testing.T) {
+	h := apptest.New(t)
+
+	res := h.Get("/api/v1/cart")
+	var cart apiItem[apiCart]
+	if err := res.JSON(&cart); err != nil || cart.Data.Lines == nil || cart.Data.Count != 0 {
+		t.Fatalf("пустая корзина: %v, %s", err, res.Body)
+	}
+
+	// Токен из заголовка GET-ответа принимается для изменений
+	token := res.Header.Get("X-CSRF-Token")
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cart/items", strings.NewReader(`{"product_id": 1, "quantity": 2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Token", token)
+	res = h.Do(req)
+	if err := res.JSON(&cart); res.Code != http.StatusCreated || err != nil || cart.Data.Count != 2 {
+		t.Fatalf("добавление: status %d, body %.300s", res.Code, res.Body)
+	}
+
+	res = h.SendJSON(http.MethodPost, "/api/v1/cart/items", map[string]any{"product_id": 5, "variant_id": 2})
+	if err := res.JSON(&cart); res.Code != http.StatusCreated || err != nil || len(cart.Data.Lines) != 2 {
+		t.Fatalf("добавление варианта: status %d, body %.300s", res.Code, res.Body)
+	}
+
+	res = h.SendJSON(http.MethodPatch, "/api/v1/cart/items/1", map[string]any{"quantity": 5})
+	if err := res.JSON(&cart); res.Code != http.StatusOK || err != nil || cart.Data.Count != 6 {
+		t.Errorf("изменение количества: status %d, body %.300s", res.Code, res.Body)
+	}
+
+	res = h.SendJSON(http.MethodDelete, "/api/v1/cart/items/5?variant_id=2", nil)
+	if err := res.JSON(&cart); res.Code != http.StatusOK || err != nil || len(cart.Data.Lines) != 1 || cart.Data.Lines[0].ProductID != "1" {
+		t.Errorf("удаление варианта: status %d, body %.300s", res.Code, res.Body)
+	}
+
+	// Ошибки — RFC 7807 с полями, как у формы корзины
+	for _, tt := range []struct {
+		method, path string
+		body         any
+		status       int
+		field        string
+	}{
+		{http.MethodPost, "/api/v1/cart/items", map[string]any{"quantity": 1}, http.StatusBadRequest, "product_id"},
+		{http.MethodPost, "/api/v1/cart/items", map[string]any{"product_id": 5}, http.StatusBadRequest, "variant_id"},
+		{http.MethodPatch, "/api/v1/cart/items/1", map[string]any{}, http.StatusBadRequest, "quantity"},
+		{http.MethodPatch, "/api/v1/cart/items/1", map[string]any{"quantity": 1000}, http.StatusBadRequest, "quantity"},
+		{http.MethodPost, "/api/v1/cart/items", map[string]any{"product_id": 5, "variant_id": 3}, http.StatusConflict, ""},
+	} {
+		res := h.SendJSON(tt.method, tt.path, tt.body)
+		p, ok := res.Problem()
+		if res.Code != tt.status || !ok || (tt.field != "" && p.Fields[tt.field] == "") {
+			t.Errorf("%s %s %v: status %d, problem %+v; want %d (%s)", tt.method, tt.path, tt.body, res.Code, p, tt.status, tt.field)
+		}
+	}
+	if res := h.SendJSON(http.MethodPost, "/api/v1/cart/items", "{"); res.Code != http.StatusBadRequest {
+		t.Errorf("некорректный JSON: status %d", res.Code)
+	}
+}
+
+// TestAPIOrdersAccount — учётная запись и заказы вошедшего пользователя; чужой заказ — 404
+func TestAPIOrdersAccount(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Покупатель", "buyer@example.com", "s3cret-pass")
+
+	var account apiItem[struct {
+		Email string `json:"email"`
+	}]
+	if err := h.Get("/api/v1/account").JSON(&account); err != nil || account.Data.Email != "buyer@example.com" {
+		t.Fatalf("учётная запись: %v, %+v", err, account)
+	}
+
+	first := h.PlaceOrder()
+	second := h.PlaceOrder()
+
+	var orders apiPage[struct {
+		Number string `json:"number"`
+		Items  []struct {
+			Article string `json:"article"`
+		} `json:"items"`
+	}]
+	if err := h.Get("/api/v1/orders?page_size=1").JSON(&orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Data) != 1 || orders.Data[0].Number != second || len(orders.Data[0].Items) != 1 ||
+		orders.Pagination.Total != 2 || orders.Pagination.NextURL != "/api/v1/orders?page=2&page_size=1" {
+		t.Errorf("заказы: %+v", orders)
+	}
+
+	var order apiItem[struct {
+		Number string `json:"number"`
+		Status string `json:"status"`
+	}]
+	if err := h.Get("/api/v1/orders/" + first).JSON(&order); err != nil || order.Data.Number != first || order.Data.Status != "pending" {
+		t.Errorf("заказ %s: %v, %+v", first, err, order)
+	}
+
+	other := h.NewClient()
+	if p, ok := other.Get("/api/v1/orders/" + first).Problem(); !ok || p.Status != http.StatusNotFound {
+		t.Errorf("чужой заказ: %+v", p)
+	}
+	if res := other.Get("/api/v1/orders?page=0"); res.Code != http.StatusUnauthorized {
+		t.Errorf("заказы анонима: status %d, want 401", res.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/storage"
+)
+
+var revokeFormRe = regexp.MustCompile(`action="/account/tokens/(\d+)/revoke"`)
+
+// TestAPITokens — скрипт с токеном работает без cookie и CSRF, в пределах областей
+func TestAPITokens(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+
+	res := h.PostForm("/account/tokens", url.Values{"name": {"без областей"}, "days": {"30"}})
+	if res.Code != http.StatusBadRequest || !strings.Contains(res.Body, "Выберите хотя бы одну область") {
+		t.Errorf("токен без областей: %d", res.Code)
+	}
+	token := h.CreateAPIToken("выгрузка", string(storage.ScopeCart), string(storage.ScopeOrdersRead))
+	if !strings.HasPrefix(token, "myapp_") {
+		t.Fatalf("токен: %q", token)
+	}
+
+	res = h.SendToken(http.MethodPost, "/api/v1/cart/items", token, map[string]any{"product_id": 1, "quantity": 2})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("POST с токеном без CSRF: %d %s", res.Code, res.Body)
+	}
+	if res.Header.Get("Set-Cookie") != "" || res.Header.Get("X-CSRF-Token") != "" {
+		t.Errorf("запрос с токеном получил сессию: %v", res.Header)
+	}
+	var cart apiItem[apiCart]
+	if err := h.SendToken(http.MethodGet, "/api/v1/cart", token, nil).JSON(&cart); err != nil || cart.Data.Count != 2 {
+		t.Errorf("корзина владельца токена: %+v %v", cart, err)
+	}
+
+	if res := h.SendToken(http.MethodGet, "/api/v1/orders", token, nil); res.Code != http.StatusOK {
+		t.Errorf("orders:read: %d", res.Code)
+	}
+	res = h.SendToken(http.MethodGet, "/api/v1/account", token, nil)
+	if p, _ := res.Problem(); res.Code != http.StatusForbidden || p.Code != "insufficient_scope" ||
+		!strings.Contains(res.Header.Get("WWW-Authenticate"), `scope="account:read"`) {
+		t.Errorf("нет области account:read: %d %+v", res.Code, p)
+	}
+	res = h.SendToken(http.MethodGet, "/api/v1/cart", token+"x", nil)
+	if p, _ := res.Problem(); res.Code != http.StatusUnauthorized || p.Code != "invalid_token" {
+		t.Errorf("неверный токен: %d %+v", res.Code, p)
+	}
+
+	page := h.Get("/account/tokens")
+	if !strings.Contains(page.Body, token[:10]+"…") || strings.Contains(page.Body, token) || strings.Contains(page.Body, "никогда") {
+		t.Errorf("список токенов: подсказка, без самого токена, с временем использования")
+	}
+	m := revokeFormRe.FindStringSubmatch(page.Body)
+	if m == nil {
+		t.Fatal("нет кнопки отзыва")
+	}
+
+	other := h.NewClient()
+	other.Register("Борис", "boris@example.com", "s3cret-pass")
+	if res := other.PostForm("/account/tokens/"+m[1]+"/revoke", url.Values{}); res.Code != http.StatusNotFound {
+		t.Errorf("отзыв чужого токена: %d", res.Code)
+	}
+	if res := h.PostForm("/account/tokens/"+m[1]+"/revoke", url.Values{}); res.Location() != "/account/tokens?revoked=1" {
+		t.Errorf("отзыв: %d %s", res.Code, res.Location())
+	}
+	if res := h.SendToken(http.MethodGet, "/api/v1/cart", token, nil); res.Code != http.StatusUnauthorized {
+		t.Errorf("отозванный токен: %d", res.Code)
+	}
+}
+
+// TestAdminTokens — токены всех пользователей видит только право tokens.manage
+func TestAdminTokens(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	h.CreateAPIToken("выгрузка", string(storage.ScopeOrdersRead))
+
+	staff := h.NewClient()
+	staff.RegisterAs("staff@example.com", storage.RoleStaff)
+	if res := staff.Get("/admin/tokens"); res.Code != http.StatusForbidden {
+		t.Errorf("staff: %d", res.Code)
+	}
+
+	admin := h.NewClient()
+	admin.RegisterAs("admin@example.com", storage.RoleAdmin)
+	page := admin.Get("/admin/tokens")
+	if page.Code != http.StatusOK || !strings.Contains(page.Body, "anna@example.com") {
+		t.Fatalf("список токенов: %d", page.Code)
+	}
+	m := regexp.MustCompile(`action="/admin/tokens/(\d+)/revoke"`).FindStringSubmatch(page.Body)
+	if m == nil {
+		t.Fatal("нет кнопки отзыва")
+	}
+	if res := admin.PostForm("/admin/tokens/"+m[1]+"/revoke", url.Values{}); res.Location() != "/admin/tokens?revoked=1" {
+		t.Errorf("отзыв: %d", res.Code)
+	}
+	if !strings.Contains(admin.Get("/admin/tokens").Body, "отозван") {
+		t.Error("токен не отмечен отозванным")
+	}
+}
@@ -0,0 +1,205 @@
+package server_test
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestRegisterLoginLogout — регистрация входит в аккаунт, выход и повторный вход
+func TestRegisterLoginLogout(t *testing.T) {
+	h := apptest.New(t)
+
+	h.Register("Анна", "Anna@Example.com", "s3cret-pass")
+	if body := h.Get("/").Body; !strings.Contains(body, "Анна") || !strings.Contains(body, "Выйти") {
+		t.Error("после регистрации в меню нет имени пользователя")
+	}
+	if res := h.Get("/login"); res.Code != http.StatusSeeOther {
+		t.Errorf("GET /login вошедшим: status %d, want 303", res.Code)
+	}
+
+	if res := h.PostForm("/logout", nil); res.Code != http.StatusSeeOther || res.Location() != "/" {
+		t.Fatalf("logout: status %d, Location %q", res.Code, res.Location())
+	}
+	if body := h.Get("/").Body; strings.Contains(body, "Выйти") || !strings.Contains(body, "Войти") {
+		t.Error("после выхода пользователь всё ещё в меню")
+	}
+
+	// Email без учёта регистра и пробелов
+	if res := h.Login("  anna@example.COM ", "s3cret-pass"); res.Code != http.StatusSeeOther || res.Location() != "/" {
+		t.Fatalf("login: status %d, Location %q", res.Code, res.Location())
+	}
+	if !strings.Contains(h.Get("/").Body, "Анна") {
+		t.Error("после входа в меню нет имени пользователя")
+	}
+}
+
+// TestLoginFailure — неизвестный email и неверный пароль неразличимы
+func TestLoginFailure(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	h.PostForm("/logout", nil)
+
+	for _, email := range []string{"anna@example.com", "nobody@example.com"} {
+		res := h.Login(email, "wrong-pass")
+		if res.Code != http.StatusUnauthorized || !strings.Contains(res.Body, "Неверный email или пароль") {
+			t.Errorf("%s: status %d, want 401 с общим сообщением", email, res.Code)
+		}
+		if strings.Contains(res.Body, "wrong-pass") {
+			t.Errorf("%s: пароль вернулся в разметку", email)
+		}
+	}
+}
+
+// TestRegisterValidation — сообщения валидации регистрации и занятый email
+func TestRegisterValidation(t *testing.T) {
+	valid := url.Values{"name": {"Анна"}, "email": {"anna@example.com"}, "password": {"s3cret-pass"}, "confirm": {"s3cret-pass"}}
+	with := func(k, v string) url.Values {
+		f := url.Values{}
+		for key, vals := range valid {
+			f[key] = vals
+		}
+		f.Set(k, v)
+		return f
+	}
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		message string
+	}{
+		{"короткий пароль", with("password", "short"), "Пароль должен быть не короче 8 символов"},
+		{"пароли не совпадают", with("confirm", "other-pass"), "Пароли не совпадают"},
+		{"неверный email", with("email", "anna"), "Введите корректный email"},
+		{"нет имени", with("name", ""), "Укажите имя"},
+		{"длинный пароль", with("password", strings.Repeat("x", 129)), "Слишком длинный пароль (макс. 128)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apptest.New(t)
+			res := h.PostForm("/register", tt.form)
+			if res.Code != http.StatusBadRequest || !strings.Contains(html.UnescapeString(res.Body), tt.message) {
+				t.Errorf("status %d, want 400 с %q", res.Code, tt.message)
+			}
+		})
+	}
+
+	t.Run("занятый email", func(t *testing.T) {
+		h := apptest.New(t)
+		h.Register("Анна", "anna@example.com", "s3cret-pass")
+
+		res := h.NewClient().PostForm("/register", with("email", "ANNA@example.com"))
+		if res.Code != http.StatusConflict || !strings.Contains(res.Body, "Этот email уже зарегистрирован") {
+			t.Errorf("status %d, want 409", res.Code)
+		}
+	})
+}
+
+// TestLoginUpgradesHash — bcrypt-хеш заменяется на argon2id при входе
+func TestLoginUpgradesHash(t *testing.T) {
+	h := apptest.New(t)
+	ctx := context.Background()
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("legacy-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &storage.User{Email: "old@example.com", Name: "Старый", PasswordHash: string(legacy)}
+	if err := h.Repos.Users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := h.Login("old@example.com", "legacy-pass"); res.Code != http.StatusSeeOther {
+		t.Fatalf("login: status %d", res.Code)
+	}
+	got, err := h.Repos.Users.GetByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got.PasswordHash, "$argon2id$") {
+		t.Errorf("хеш не обновлён: %.10s…", got.PasswordHash)
+	}
+	if got.LastLoginAt == nil {
+		t.Error("last_login_at не отмечен")
+	}
+
+	// Со старым паролем по новому хешу вход по-прежнему работает
+	h.PostForm("/logout", nil)
+	if res := h.Login("old@example.com", "legacy-pass"); res.Code != http.StatusSeeOther {
+		t.Errorf("повторный вход: status %d", res.Code)
+	}
+}
+
+// TestLoginRegeneratesSession — вход пересобирает сессию: CSRF-токен до входа больше не действует
+func TestLoginRegeneratesSession(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	h.PostForm("/logout", nil)
+
+	before := h.CSRFToken()
+	h.Login("anna@example.com", "s3cret-pass")
+
+	if after := h.CSRFToken(); after == before {
+		t.Error("CSRF-токен не сменился после входа")
+	}
+	res := h.PostFormRaw("/cart/add", url.Values{"product_id": {"1"}, "_csrf": {before}})
+	if res.Code != http.StatusForbidden {
+		t.Errorf("старый CSRF-токен: status %d, want 403", res.Code)
+	}
+}
+
+// TestLoginClaimsCart — анонимная корзина переходит к пользователю и доступна с другого устройства
+func TestLoginClaimsCart(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	h.PostForm("/logout", nil)
+
+	h.AddToCart(3, 1)
+	h.Login("anna@example.com", "s3cret-pass")
+	if !strings.Contains(h.Get("/cart").Body, "Планшет DEF Mini") {
+		t.Error("корзина потеряна при входе")
+	}
+
+	h.PostForm("/logout", nil)
+	if strings.Contains(h.Get("/cart").Body, "Планшет DEF Mini") {
+		t.Error("корзина пользователя видна после выхода")
+	}
+
+	other := h.NewClient()
+	other.Login("anna@example.com", "s3cret-pass")
+	if !strings.Contains(other.Get("/cart").Body, "Планшет DEF Mini") {
+		t.Error("корзина пользователя не видна при входе с другого устройства")
+	}
+}
+
+// TestLoginNext — возврат после входа только на локальный путь
+func TestLoginNext(t *testing.T) {
+	tests := []struct {
+		next, want string
+	}{
+		{"/checkout", "/checkout"},
+		{"/catalog?page=2", "/catalog?page=2"},
+		{"//evil.example/", "/"},
+		{"/\\evil.example", "/"},
+		{"https://evil.example/", "/"},
+		{"", "/"},
+	}
+
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	for _, tt := range tests {
+		h.PostForm("/logout", nil)
+		res := h.PostForm("/login", url.Values{"email": {"anna@example.com"}, "password": {"s3cret-pass"}, "next": {tt.next}})
+		if res.Location() != tt.want {
+			t.Errorf("next=%q: Location %q, want %q", tt.next, res.Location(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"myApp/internal/apptest"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+)
+
+// getWith — GET с заголовками условного запроса
+func getWith(c *apptest.Client, path string, header map[string]string) *apptest.Response {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return c.Do(req)
+}
+
+// TestConditionalCatalogJSON — ETag каталога, 304 и новый ETag после изменения остатка и резерва
+func TestConditionalCatalogJSON(t *testing.T) {
+	h := apptest.New(t)
+	res := h.Get("/catalog/json")
+	etag := res.Header.Get("ETag")
+	if res.Code != http.StatusOK || etag == "" || res.Header.Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("каталог: %d %v", res.Code, res.Header)
+	}
+	if again := h.Get("/catalog/json"); again.Header.Get("ETag") != etag {
+		t.Errorf("ETag того же содержимого изменился: %s → %s", etag, again.Header.Get("ETag"))
+	}
+
+	for name, header := range map[string]string{
+		"If-None-Match":      etag,
+		"слабый ETag (gzip)": `"other", W/` + etag,
+	} {
+		res := getWith(h.Client, "/catalog/json", map[string]string{"If-None-Match": header})
+		if res.Code != http.StatusNotModified || res.Body != "" || res.Header.Get("ETag") != etag {
+			t.Errorf("%s: %d %q", name, res.Code, res.Body)
+		}
+	}
+
+	// Last-Modified — с секунды, следующей за изменением каталога; без If-None-Match действует If-Modified-Since
+	modified := lastModified(t, h.Client)
+	if res := getWith(h.Client, "/catalog/json", map[string]string{"If-Modified-Since": modified}); res.Code != http.StatusNotModified {
+		t.Errorf("If-Modified-Since = Last-Modified: %d", res.Code)
+	}
+	header := map[string]string{"If-Modified-Since": modified, "If-None-Match": `"other"`}
+	if res := getWith(h.Client, "/catalog/json", header); res.Code != http.StatusOK {
+		t.Errorf("If-None-Match не совпал, If-Modified-Since не учитывается: %d", res.Code)
+	}
+
+	// Резерв заказа меняет наличие, не трогая товар
+	buyer := h.NewClient()
+	buyer.AddToCart(1, 1)
+	buyer.FillCheckout("pickup")
+	buyer.PlaceOrder()
+	res = getWith(h.Client, "/catalog/json", map[string]string{"If-None-Match": etag})
+	if res.Code != http.StatusOK || res.Header.Get("ETag") == etag {
+		t.Errorf("после резерва: %d %s", res.Code, res.Header.Get("ETag"))
+	}
+	if res := getWith(h.Client, "/catalog/json", map[string]string{"If-Modified-Since": modified}); res.Code != http.StatusOK {
+		t.Errorf("после резерва If-Modified-Since: %d", res.Code)
+	}
+	etag = res.Header.Get("ETag")
+
+	ctx := context.Background()
+	p, err := h.Repos.Products.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Repos.Inventory.Adjust(ctx, storage.StockAdjustment{ProductID: p.ID, Stock: p.Stock + 5}); err != nil {
+		t.Fatal(err)
+	}
+	res = getWith(h.Client, "/catalog/json", map[string]string{"If-None-Match": etag})
+	if res.Code != http.StatusOK || res.Header.Get("ETag") == etag {
+		t.Errorf("после изменения остатка: %d %s", res.Code, res.Header.Get("ETag"))
+	}
+}
+
+// lastModified — Last-Modified /catalog/json: заголовка нет, пока идёт секунда последнего изменения
+func lastModified(t *testing.T, c *apptest.Client) string {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if lm := c.Get("/catalog/json").Header.Get("Last-Modified"); lm != "" {
+			return lm
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("/catalog/json без Last-Modified")
+	return ""
+}
+
+// TestConditionalReservationExpiry — истечение резерва меняет наличие без записи в БД: Last-Modified
+// сдвигается на время истечения, If-Modified-Since прежней копии — 200
+func TestConditionalReservationExpiry(t *testing.T) {
+	h := apptest.New(t, func(c *core.Config) { c.ReservationTTL = 1500 * time.Millisecond })
+	buyer := h.NewClient()
+	buyer.AddToCart(1, 1)
+	buyer.FillCheckout("pickup")
+
+	reserved := lastModified(t, h.Client)
+	if res := getWith(h.Client, "/catalog/json", map[string]string{"If-Modified-Since": reserved}); res.Code != http.StatusNotModified {
+		t.Fatalf("резерв действует: %d", res.Code)
+	}
+
+	deadline := time.Now().Add(4 * time.Second)
+	for time.Now().Before(deadline) {
+		res := getWith(h.Client, "/catalog/json", map[string]string{"If-Modified-Since": reserved})
+		if res.Code == http.StatusOK && res.Header.Get("Last-Modified") != "" {
+			if res.Header.Get("Last-Modified") == reserved {
+				t.Fatalf("200 с прежним Last-Modified %s", reserved)
+			}
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Error("истечение резерва не сдвинуло Last-Modified")
+}
+
+// TestConditionalProduct — страница товара: ETag не зависит от nonce, 304 без CSP, кэш только private
+func TestConditionalProduct(t *testing.T) {
+	h := apptest.New(t)
+	res := h.Get("/product/1")
+	etag := res.Header.Get("ETag")
+	if res.Code != http.StatusOK || etag == "" || res.Header.Get("Cache-Control") != "private, no-cache" || res.Header.Get("Last-Modified") != "" {
+		t.Fatalf("страница товара: %d %v", res.Code, res.Header)
+	}
+	// nonce со знаком "+" попадает в атрибуты как &#43; — ETag от этого тоже не зависит
+	for i := 0; i < 20; i++ {
+		if again := h.Get("/product/1"); again.Header.Get("ETag") != etag {
+			t.Fatal("ETag зависит от nonce запроса")
+		}
+	}
+
+	res = getWith(h.Client, "/product/1", map[string]string{"If-None-Match": etag})
+	if res.Code != http.StatusNotModified || res.Header.Get("Content-Security-Policy") != "" {
+		t.Errorf("304: %d, CSP %q", res.Code, res.Header.Get("Content-Security-Policy"))
+	}
+
+	// В странице CSRF-токен сессии: другой посетитель получает свою версию
+	if other := getWith(h.NewClient(), "/product/1", map[string]string{"If-None-Match": etag}); other.Code != http.StatusOK {
+		t.Errorf("чужая сессия: %d", other.Code)
+	}
+	if res := h.Get("/product/999999"); res.Code != http.StatusNotFound || res.Header.Get("ETag") != "" {
+		t.Errorf("нет товара: %d %s", res.Code, res.Header.Get("ETag"))
+	}
+}
@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+)
+
+// TestFormSubmit — сообщения валидации /form и PRG-редирект при успехе
+func TestFormSubmit(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     url.Values
+		messages []string
+	}{
+		{
+			name:     "пустая форма",
+			form:     url.Values{},
+			messages: []string{"Укажите имя", "Укажите email", "Напишите сообщение"},
+		},
+		{
+			name:     "короткое имя и неверный email",
+			form:     url.Values{"name": {"A"}, "email": {"not-an-email"}, "message": {"Привет"}},
+			messages: []string{"Имя должно быть не короче 2 символов", "Введите корректный email"},
+		},
+		{
+			name:     "слишком длинные поля",
+			form:     url.Values{"name": {strings.Repeat("я", 101)}, "email": {"a@b.fi"}, "message": {strings.Repeat("x", 2001)}},
+			messages: []string{"Слишком длинное имя (макс. 100)", "Слишком длинное сообщение (макс. 2000)"},
+		},
+		{
+			name:     "разметка вырезается до валидации",
+			form:     url.Values{"name": {"<script>x</script>"}, "email": {"a@b.fi"}, "message": {"<script>alert(1)</script>"}},
+			messages: []string{"Укажите имя", "Напишите сообщение"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apptest.New(t)
+
+			res := h.PostForm("/form", tt.form)
+			if res.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", res.Code)
+			}
+			body := html.UnescapeString(res.Body)
+			for _, msg := range tt.messages {
+				if !strings.Contains(body, msg) {
+					t.Errorf("нет сообщения %q", msg)
+				}
+			}
+			if strings.Contains(res.Body, "<script>alert(1)") {
+				t.Error("ввод пользователя попал в страницу без экранирования")
+			}
+		})
+	}
+}
+
+func TestFormSubmitOK(t *testing.T) {
+	h := apptest.New(t)
+
+	res := h.PostForm("/form", url.Values{"name": {"Иван"}, "email": {"ivan@example.com"}, "message": {"Здравствуйте!"}})
+	if res.Code != http.StatusSeeOther || res.Location() != "/form?ok=1" {
+		t.Fatalf("status %d, Location %q; want 303 /form?ok=1", res.Code, res.Location())
+	}
+	if res := h.Get(res.Location()); res.Code != http.StatusOK {
+		t.Errorf("GET /form?ok=1: status %d", res.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+)
+
+// TestIdempotencyKey — повтор POST с тем же ключом получает первый ответ и не меняет корзину
+func TestIdempotencyKey(t *testing.T) {
+	h := apptest.New(t)
+	item := map[string]any{"product_id": 1, "quantity": 2}
+
+	first := h.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "retry-1", item)
+	if first.Code != http.StatusCreated || first.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("первый запрос: %d %s", first.Code, first.Body)
+	}
+	retry := h.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "retry-1", item)
+	if retry.Code != http.StatusCreated || retry.Header.Get("Idempotent-Replayed") != "true" ||
+		retry.Body != first.Body || !strings.HasPrefix(retry.Header.Get("Content-Type"), "application/json") {
+		t.Errorf("повтор: %d %v %s", retry.Code, retry.Header, retry.Body)
+	}
+	var cart apiItem[apiCart]
+	if err := h.SendJSON(http.MethodGet, "/api/v1/cart", nil).JSON(&cart); err != nil || cart.Data.Count != 2 {
+		t.Errorf("повтор выполнен второй раз: %+v %v", cart, err)
+	}
+
+	res := h.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "retry-1", map[string]any{"product_id": 1, "quantity": 3})
+	if p, _ := res.Problem(); res.Code != http.StatusConflict || p.Code != "idempotency_key_reused" {
+		t.Errorf("ключ с другим телом: %d %+v", res.Code, p)
+	}
+	res = h.SendIdempotent(http.MethodPatch, "/api/v1/cart/items/1", "retry-1", item)
+	if p, _ := res.Problem(); res.Code != http.StatusConflict || p.Code != "idempotency_key_reused" {
+		t.Errorf("ключ с другим адресом: %d %+v", res.Code, p)
+	}
+
+	// Ошибка клиента тоже сохраняется: повтор не выполняет запрос заново
+	missing := map[string]any{"product_id": 999999, "quantity": 1}
+	if res := h.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "retry-404", missing); res.Code != http.StatusNotFound {
+		t.Errorf("нет товара: %d", res.Code)
+	}
+	if res := h.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "retry-404", missing); res.Code != http.StatusNotFound || res.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("повтор ошибки: %d %v", res.Code, res.Header)
+	}
+
+	if res := h.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "ключ", item); res.Code != http.StatusBadRequest {
+		t.Errorf("ключ не ASCII: %d", res.Code)
+	}
+}
+
+// TestIdempotencyKeyScope — одинаковые ключи разных клиентов не пересекаются
+func TestIdempotencyKeyScope(t *testing.T) {
+	h := apptest.New(t)
+	item := map[string]any{"product_id": 1, "quantity": 1}
+	h.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "same", item)
+
+	other := h.NewClient()
+	res := other.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "same", item)
+	if res.Code != http.StatusCreated || res.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("ключ другой сессии: %d %v", res.Code, res.Header)
+	}
+
+	other.Register("Борис", "boris@example.com", "s3cret-pass")
+	res = other.SendIdempotent(http.MethodPost, "/api/v1/cart/items", "same", item)
+	if res.Code != http.StatusCreated || res.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("ключ после входа: %d %v", res.Code, res.Header)
+	}
+
+	spec := h.Get("/api/openapi.json").Body
+	if !strings.Contains(spec, `"name":"Idempotency-Key","in":"header"`) {
+		t.Error("заголовок Idempotency-Key не описан в спецификации")
+	}
+}
@@ -0,0 +1,325 @@
+package server_test
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"myApp/internal/apptest"
+	"myApp/internal/core"
+	"myApp/internal/http/server"
+	"myApp/internal/payment"
+	"myApp/internal/storage"
+)
+
+// available — сколько товара доступно к покупке (остаток − резервы)
+func available(t *testing.T, h *apptest.Harness, id int) int {
+	t.Helper()
+	p, err := h.Repos.Products.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Available
+}
+
+// TestStockReservation — резерв на время оформления не даёт перекупить товар и истекает по RESERVATION_TTL
+func TestStockReservation(t *testing.T) {
+	h := apptest.New(t, func(c *core.Config) { c.ReservationTTL = 300 * time.Millisecond })
+	ctx := context.Background()
+	if err := h.Repos.Inventory.Adjust(ctx, storage.StockAdjustment{ProductID: "4", Stock: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Первый покупатель начал оформление — оба наушника отложены за ним
+	h.AddToCart(4, 2)
+	h.FillCheckout("pickup")
+	if got := available(t, h, 4); got != 0 {
+		t.Fatalf("доступно после резерва %d, want 0", got)
+	}
+
+	other := h.NewClient()
+	res := other.PostForm("/cart/add", url.Values{"product_id": {"4"}})
+	if p, ok := res.Problem(); res.Code != http.StatusConflict || !ok || p.Code != "out_of_stock" {
+		t.Fatalf("добавление зарезервированного товара: status %d, problem %+v; want 409 out_of_stock", res.Code, p)
+	}
+	if body := html.UnescapeString(other.Get("/catalog/audio").Body); !strings.Contains(body, "Нет в наличии") {
+		t.Error("каталог: нет отметки \"Нет в наличии\"")
+	}
+	var page struct {
+		Items []struct {
+			ID        string `json:"id"`
+			Available int    `json:"available"`
+		} `json:"items"`
+	}
+	if err := other.Get("/catalog/json?page_size=100").JSON(&page); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range page.Items {
+		if p.ID == "4" && p.Available != 0 {
+			t.Errorf("/catalog/json: available товара 4 = %d, want 0", p.Available)
+		}
+	}
+
+	// Резерв истёк — второй покупатель успевает купить один из двух
+	time.Sleep(400 * time.Millisecond)
+	other.AddToCart(4, 1)
+	other.FillCheckout("pickup")
+	if res := other.PostForm("/checkout/confirm", nil); !strings.HasPrefix(res.Location(), "/orders/") {
+		t.Fatalf("заказ второго покупателя: status %d, Location %q", res.Code, res.Location())
+	}
+
+	// Первому двух уже не хватает: заказ не создаётся, корзина объясняет почему
+	res = h.PostForm("/checkout/confirm", nil)
+	if res.Code != http.StatusSeeOther || res.Location() != "/cart" {
+		t.Fatalf("подтверждение без остатка: status %d, Location %q; want 303 /cart", res.Code, res.Location())
+	}
+	if cart := html.UnescapeString(h.Get("/cart").Body); !strings.Contains(cart, "В наличии только 1 шт.") {
+		t.Error("корзина: нет предупреждения об остатке")
+	}
+	if res := h.Get("/checkout/contact"); res.Location() != "/cart" {
+		t.Errorf("оформление без остатка: Location %q, want /cart", res.Location())
+	}
+}
+
+// TestStockSale — заказ держит резерв; оплата списывает остаток с записью в журнал, возврат денег
+// возвращает товар на склад, отмена — освобождает резерв
+func TestStockSale(t *testing.T) {
+	h := apptest.New(t)
+	ctx := context.Background()
+
+	number := h.PlaceOrder() // Товар 1 × 2 из 25
+	if got := available(t, h, 1); got != 23 {
+		t.Fatalf("доступно после заказа %d, want 23", got)
+	}
+
+	payURL := h.StartPayment(number)
+	res := h.PostForm(payURL, url.Values{"card": {payment.CardSuccess}})
+	if !strings.HasSuffix(res.Location(), "?payment=success") {
+		t.Fatalf("оплата: status %d, Location %q", res.Code, res.Location())
+	}
+	p, _ := h.Repos.Products.GetByID(ctx, 1)
+	if p.Stock != 23 || p.Available != 23 {
+		t.Errorf("после оплаты stock %d, available %d; want 23, 23", p.Stock, p.Available)
+	}
+	order, _ := h.Repos.Orders.GetByNumber(ctx, number)
+	moves, err := h.Repos.Inventory.Movements(ctx, "1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].Reason != storage.MovementSale || moves[0].Delta != -2 || moves[0].StockAfter != 23 ||
+		moves[0].OrderID == nil || *moves[0].OrderID != order.ID {
+		t.Errorf("журнал после оплаты: %+v", moves)
+	}
+
+	// Возврат денег до отгрузки возвращает товар на склад встречным движением refund
+	ev := payment.Event{ID: "evt_apptest_refund", Type: payment.EventRefunded, IntentID: strings.TrimPrefix(payURL, "/payments/fake/"),
+		OrderNumber: number, Status: payment.IntentRefunded, Created: time.Now().Unix()}
+	if _, conflict := deliverWebhook(t, h, ev); conflict {
+		t.Fatal("возврат отмечен конфликтом")
+	}
+	if p, _ := h.Repos.Products.GetByID(ctx, 1); p.Stock != 25 || p.Available != 25 {
+		t.Errorf("после возврата stock %d, available %d; want 25, 25", p.Stock, p.Available)
+	}
+	moves, _ = h.Repos.Inventory.Movements(ctx, "1", 10)
+	if len(moves) != 2 || moves[0].Reason != storage.MovementRefund || moves[0].Delta != 2 || moves[0].StockAfter != 25 ||
+		moves[0].OrderID == nil || *moves[0].OrderID != order.ID {
+		t.Errorf("журнал после возврата: %+v", moves)
+	}
+
+	// Отменённый заказ освобождает резерв, остаток не меняется
+	other := h.NewClient()
+	cancelled, _ := h.Repos.Orders.GetByNumber(ctx, other.PlaceOrder())
+	if got := available(t, h, 1); got != 23 {
+		t.Fatalf("доступно со вторым заказом %d, want 23", got)
+	}
+	if err := h.Repos.Orders.UpdateStatus(ctx, cancelled.ID, storage.OrderCancelled, "Отменён покупателем"); err != nil {
+		t.Fatal(err)
+	}
+	if got := available(t, h, 1); got != 25 {
+		t.Errorf("доступно после отмены %d, want 25", got)
+	}
+}
+
+// placeOrderOf — клиент оформляет заказ товара id × qty с самовывозом; возвращает номер
+func placeOrderOf(t *testing.T, c *apptest.Client, id, qty int) string {
+	t.Helper()
+	c.AddToCart(id, qty)
+	c.FillCheckout("pickup")
+	res := c.PostForm("/checkout/confirm", nil)
+	number, ok := strings.CutPrefix(res.Location(), "/orders/")
+	if res.Code != http.StatusSeeOther || !ok {
+		t.Fatalf("заказ: status %d, Location %q", res.Code, res.Location())
+	}
+	return number
+}
+
+// TestPendingOrderExpiry — неоплаченный заказ держит товар PENDING_ORDER_TTL, затем задача сервера
+// отменяет его и товар возвращается в продажу; оплатить отменённый заказ уже нельзя
+func TestPendingOrderExpiry(t *testing.T) {
+	h := apptest.New(t, func(c *core.Config) { c.PendingOrderTTL = 300 * time.Millisecond })
+	ctx := context.Background()
+	if err := h.Repos.Inventory.Adjust(ctx, storage.StockAdjustment{ProductID: "4", Stock: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	number := placeOrderOf(t, h.Client, 4, 2)
+	if got := available(t, h, 4); got != 0 {
+		t.Fatalf("доступно с неоплаченным заказом %d, want 0", got)
+	}
+	if n, err := h.Repos.Orders.CancelExpired(ctx); err != nil || n != 0 {
+		t.Fatalf("CancelExpired до срока: %d, %v; want 0", n, err)
+	}
+
+	jobCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go server.RunOrderExpiry(jobCtx, h.Repos.Orders, 50*time.Millisecond)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for available(t, h, 4) != 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := available(t, h, 4); got != 2 {
+		t.Fatalf("доступно после срока заказа %d, want 2", got)
+	}
+	order, _ := h.Repos.Orders.GetByNumber(ctx, number)
+	if order.Status != storage.OrderCancelled {
+		t.Errorf("статус просроченного заказа %q, want %q", order.Status, storage.OrderCancelled)
+	}
+	if p, _ := h.Repos.Products.GetByID(ctx, 4); p.Stock != 2 {
+		t.Errorf("остаток после отмены %d, want 2", p.Stock)
+	}
+	res := h.PostForm("/orders/"+number+"/pay", nil)
+	if p, ok := res.Problem(); res.Code != http.StatusConflict || !ok || p.Code != "order_not_payable" {
+		t.Errorf("оплата отменённого заказа: status %d, problem %+v", res.Code, p)
+	}
+}
+
+// TestExpiredOrderLatePayment — резерв заказа истёк, но задача его ещё не отменила: оплата проходит,
+// только если товар не ушёл в чужой резерв
+func TestExpiredOrderLatePayment(t *testing.T) {
+	h := apptest.New(t, func(c *core.Config) { c.PendingOrderTTL = 200 * time.Millisecond })
+	ctx := context.Background()
+	if err := h.Repos.Inventory.Adjust(ctx, storage.StockAdjustment{ProductID: "4", Stock: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	late := placeOrderOf(t, h.Client, 4, 2)
+	time.Sleep(300 * time.Millisecond)
+	other := h.NewClient()
+	placeOrderOf(t, other, 4, 2) // Резерв первого заказа истёк — товар достался второму
+
+	h.PostForm(h.StartPayment(late), url.Values{"card": {payment.CardSuccess}})
+	order, _ := h.Repos.Orders.GetByNumber(ctx, late)
+	if order.Status != storage.OrderPending {
+		t.Errorf("заказ без остатка: статус %q, want %q (событие — конфликт)", order.Status, storage.OrderPending)
+	}
+	if p, _ := h.Repos.Products.GetByID(ctx, 4); p.Stock != 3 || p.Available != 1 {
+		t.Errorf("после отклонённого списания stock %d, available %d; want 3, 1", p.Stock, p.Available)
+	}
+
+	// Остаток есть — просроченный заказ всё ещё можно оплатить
+	if err := h.Repos.Inventory.Adjust(ctx, storage.StockAdjustment{ProductID: "4", Stock: 4}); err != nil {
+		t.Fatal(err)
+	}
+	third := h.NewClient()
+	number := placeOrderOf(t, third, 4, 1)
+	time.Sleep(300 * time.Millisecond)
+	third.PostForm(third.StartPayment(number), url.Values{"card": {payment.CardSuccess}})
+	if order, _ := h.Repos.Orders.GetByNumber(ctx, number); order.Status != storage.OrderPaid {
+		t.Errorf("просроченный заказ при наличии товара: статус %q, want %q", order.Status, storage.OrderPaid)
+	}
+}
+
+// TestOrderCancel — покупатель отменяет неоплаченный заказ: резерв снимается, чужой заказ не отменить
+func TestOrderCancel(t *testing.T) {
+	h := apptest.New(t)
+	ctx := context.Background()
+
+	number := h.PlaceOrder() // Товар 1 × 2 из 25
+	if got := available(t, h, 1); got != 23 {
+		t.Fatalf("доступно после заказа %d, want 23", got)
+	}
+	if !strings.Contains(h.Get("/orders/"+number).Body, `action="/orders/`+number+`/cancel"`) {
+		t.Error("страница заказа: нет кнопки отмены")
+	}
+
+	if res := h.NewClient().PostForm("/orders/"+number+"/cancel", nil); res.Code != http.StatusNotFound {
+		t.Errorf("отмена чужого заказа: status %d, want 404", res.Code)
+	}
+
+	res := h.PostForm("/orders/"+number+"/cancel", nil)
+	if res.Code != http.StatusSeeOther || res.Location() != "/orders/"+number {
+		t.Fatalf("отмена: status %d, Location %q", res.Code, res.Location())
+	}
+	if order, _ := h.Repos.Orders.GetByNumber(ctx, number); order.Status != storage.OrderCancelled {
+		t.Errorf("статус после отмены %q, want %q", order.Status, storage.OrderCancelled)
+	}
+	if got := available(t, h, 1); got != 25 {
+		t.Errorf("доступно после отмены %d, want 25", got)
+	}
+
+	res = h.PostForm("/orders/"+number+"/cancel", nil)
+	if p, ok := res.Problem(); res.Code != http.StatusConflict || !ok || p.Code != "order_not_cancellable" {
+		t.Errorf("повторная отмена: status %d, problem %+v", res.Code, p)
+	}
+}
+
+// TestAdminStock — корректировка остатка варианта, журнал и запрет опускать остаток ниже резерва
+func TestAdminStock(t *testing.T) {
+	h := apptest.New(t)
+	h.RegisterAs("staff@example.com", storage.RoleStaff)
+	ctx := context.Background()
+
+	page := html.UnescapeString(h.Get("/admin/products/5/stock").Body)
+	for _, want := range []string{"ART-005-BLK-RED", "ART-005-WHT-BRN", "Цвет: Белый, Переключатели: Brown"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("/admin/products/5/stock: нет %q", want)
+		}
+	}
+	if !strings.Contains(html.UnescapeString(h.Get("/product/5").Body), "Переключатели: Brown — нет в наличии") {
+		t.Error("/product/5: закончившийся вариант не отмечен")
+	}
+
+	res := h.PostForm("/admin/products/5/stock", url.Values{"variant_id": {"3"}, "stock": {"4"}, "note": {"Приёмка"}})
+	if res.Code != http.StatusSeeOther || res.Location() != "/admin/products/5/stock?saved=1" {
+		t.Fatalf("корректировка: status %d, Location %q", res.Code, res.Location())
+	}
+	if v, _ := h.Repos.Variants.GetByID(ctx, 3); v.Stock != 4 || v.Available != 4 {
+		t.Errorf("вариант 3 после корректировки: %+v", v)
+	}
+	moves, _ := h.Repos.Inventory.Movements(ctx, "5", 10)
+	if len(moves) != 1 || moves[0].Reason != storage.MovementAdjust || moves[0].Delta != 4 ||
+		moves[0].Note == nil || *moves[0].Note != "Приёмка" || moves[0].UserID == nil {
+		t.Errorf("журнал после корректировки: %+v", moves)
+	}
+	if body := h.Get("/admin/products/5/stock").Body; !strings.Contains(body, "Приёмка") {
+		t.Error("движение не показано в панели")
+	}
+
+	// Ошибки формы: у товара с вариантами нужен вариант; остаток — неотрицательное число
+	for _, form := range []url.Values{
+		{"stock": {"3"}},
+		{"variant_id": {"3"}, "stock": {"-1"}},
+		{"variant_id": {"99"}, "stock": {"1"}},
+	} {
+		if res := h.PostForm("/admin/products/5/stock", form); res.Code != http.StatusBadRequest {
+			t.Errorf("%v: status %d, want 400", form, res.Code)
+		}
+	}
+
+	// Покупатель держит 5 единиц — остаток меньше 5 не принимается
+	buyer := h.NewClient()
+	buyer.AddToCart(1, 5)
+	buyer.FillCheckout("pickup")
+	res = h.PostForm("/admin/products/1/stock", url.Values{"stock": {"3"}})
+	if res.Code != http.StatusConflict || !strings.Contains(html.UnescapeString(res.Body), "Не меньше зарезервированного: 5") {
+		t.Errorf("остаток ниже резерва: status %d, want 409", res.Code)
+	}
+	if p, _ := h.Repos.Products.GetByID(ctx, 1); p.Stock != 25 {
+		t.Errorf("остаток изменён несмотря на резерв: %d", p.Stock)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+// jobs.go — периодические задачи сервера (запускает cmd/app рядом с HTTP-сервером)
+import (
+	"context"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+)
+
+// OrderExpiryInterval — как часто ищутся неоплаченные заказы с истёкшим резервом
+const OrderExpiryInterval = time.Minute
+
+// RunOrderExpiry — до отмены ctx раз в every отменяет неоплаченные заказы, чей резерв истёк
+// (PENDING_ORDER_TTL): товар возвращается в продажу. Ошибка не останавливает задачу —
+// следующий проход повторит отмену. Несколько экземпляров приложения не мешают друг другу:
+// каждый заказ перепроверяется под блокировкой.
+func RunOrderExpiry(ctx context.Context, orders storage.OrderRepository, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := orders.CancelExpired(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				core.LogError("Ошибка отмены просроченных заказов", map[string]interface{}{"error": err.Error()})
+			}
+			continue
+		}
+		if n > 0 {
+			core.LogInfo("Отменены неоплаченные заказы с истёкшим резервом", map[string]interface{}{"count": n})
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+// middleware.go — middleware приложения: таймаут запроса, CSP nonce, CSRF, статика
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/http/handler"
+
+	"github.com/gin-gonic/gin"
+	csrf "github.com/utrack/gin-csrf"
+)
+
+// RequestTimeout — безопасный таймаут для всего запроса.
+// ⭐ Улучшение: Использование Context.Done() для проверки таймаута Gin-стиле.
+func RequestTimeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		// Создаем контекст таймаута на основе контекста запроса
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		// Заменяем контекст запроса на новый с таймаутом
+		c.Request = c.Request.WithContext(ctx)
+
+		// Выполняем цепочку middleware/обработчиков
+		c.Next()
+
+		// Если контекст протух И ответ еще не был отправлен:
+		if err := ctx.Err(); err != nil && errors.Is(err, context.DeadlineExceeded) {
+			core.LogError("Запрос завершился по таймауту", map[string]interface{}{
+				"timeout": d.String(),
+				"path":    c.FullPath(),
+				"error":   err.Error(),
+			})
+			// Аборт и ответ с 408 (если ответ ещё не был отправлен)
+			if !c.Writer.Written() {
+				c.AbortWithStatusJSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			}
+		}
+	}
+}
+
+// withNonce — генерирует CSP nonce и кладёт его в контекст запроса (core.WithNonce).
+func withNonce() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		nonce, err := generateNonce()
+		if err != nil {
+			core.LogError("Ошибка генерации CSP nonce", map[string]interface{}{"error": err})
+			core.FailC(c, core.Internal("nonce generation failed", err))
+			return
+		}
+
+		c.Request = c.Request.WithContext(core.WithNonce(c.Request.Context(), nonce))
+		c.Next()
+	}
+}
+
+// CSPBasic — простая (но безопасная) CSP-политика с nonce из контекста запроса.
+// Если nonce отсутствует, ставит политику без nonce.
+func CSPBasic() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		nonce := core.Nonce(c.Request.Context())
+
+		// Построим базовую политику. Подстраивайте по нуждам приложения.
+		// Включаем nonce для inline-скриптов, если он есть.
+		policyBuilder := []string{
+			"default-src 'self'",
+			"object-src 'none'",
+			"base-uri 'self'",
+			"frame-ancestors 'none'",
+		}
+
+		if nonce != "" {
+			// script-src с nonce
+			policyBuilder = append(policyBuilder, fmt.Sprintf("script-src 'self' 'nonce-%s'", nonce))
+			// style-src — лучше не полагаться на nonce для стилей, если используете inline styles, можно добавить 'unsafe-inline' или использовать nonce так же.
+			policyBuilder = append(policyBuilder, "style-src 'self' 'unsafe-inline'")
+		} else {
+			// Если нет nonce — запретим inline-скрипты, но разрешаем self
+			policyBuilder = append(policyBuilder, "script-src 'self'")
+			policyBuilder = append(policyBuilder, "style-src 'self' 'unsafe-inline'")
+		}
+
+		// Собираем политику и ставим заголовок
+		policy := strings.Join(policyBuilder, "; ")
+		c.Header("Content-Security-Policy", policy)
+
+		c.Next()
+	}
+}
+
+// csrfError — единообразный ответ на невалидный CSRF-токен (HTTP 403 Forbidden).
+func csrfError(c *gin.Context) {
+	core.FailC(c, core.Forbidden("CSRF token is invalid or missing."))
+}
+
+// apiCSRFToken — CSRF-токен сессии в заголовке X-CSRF-Token ответов на GET в /api/v1:
+// клиент API возвращает его в том же заголовке при POST/PATCH/DELETE (utrack/gin-csrf читает X-CSRF-TOKEN).
+// Запросам с API-токеном CSRF-токен не нужен.
+func apiCSRFToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet && handler.CurrentAPIToken(c.Request.Context()) == nil {
+			c.Header("X-CSRF-Token", csrf.GetToken(c))
+		}
+		c.Next()
+	}
+}
+
+// serveStatic — раздача файлов из web/assets. Отключает кэш в режиме dev.
+func serveStatic(r *gin.Engine, env string) {
+	if _, err := os.Stat("web/assets"); os.IsNotExist(err) {
+		core.LogError("Папка web/assets отсутствует, статика не будет доступна", nil)
+		return
+	}
+
+	if strings.ToLower(env) == "dev" {
+		r.Use(func(c *gin.Context) {
+			if strings.HasPrefix(c.Request.URL.Path, "/assets/") {
+				c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+				c.Header("Pragma", "no-cache")
+				c.Header("Expires", "0")
+			}
+			c.Next()
+		})
+	}
+
+	r.Static("/assets", "web/assets")
+}
+
+// uploadsPath — адрес каталога загруженных файлов (LocalBlobStore)
+const uploadsPath = "/uploads"
+
+// serveUploads — загруженные файлы. Имена не повторяются (images.NewKey) — кэш навсегда.
+func serveUploads(r *gin.Engine, dir string) {
+	r.Group(uploadsPath, func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Next()
+	}).Static("/", dir)
+}
+
+// LimitBody — предел размера тела запроса: Content-Length больше n — сразу 413,
+// тело без длины (chunked) обрывается на n байтах.
+func LimitBody(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > n {
+			core.FailC(c, &core.AppError{Code: "payload_too_large", Status: http.StatusRequestEntityTooLarge, Message: "Слишком большой запрос"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+		c.Next()
+	}
+}
+
+// skipPaths — пропускает middleware mw для перечисленных путей
+func skipPaths(mw gin.HandlerFunc, paths ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, p := range paths {
+			if c.Request.URL.Path == p {
+				c.Next()
+				return
+			}
+		}
+		mw(c)
+	}
+}
+
+// skipTokenAuth — mw не выполняется для запросов с API-токеном (handler.LoadAPIToken):
+// заголовок Authorization браузер сам не подставляет, поэтому CSRF таким запросам не грозит
+func skipTokenAuth(mw gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handler.CurrentAPIToken(c.Request.Context()) != nil {
+			c.Next()
+			return
+		}
+		mw(c)
+	}
+}
+
+// generateNonce — Создаёт 16 байт криптографически стойкой случайности и кодирует в Base64.
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
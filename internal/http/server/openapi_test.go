@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/http/handler"
+	"myApp/internal/openapi"
+	"myApp/internal/storage"
+)
+
+// TestOpenAPIDocument — спецификация отдаётся целиком и описывает все маршруты /api/v1
+func TestOpenAPIDocument(t *testing.T) {
+	h := apptest.New(t)
+
+	res := h.Get("/api/openapi.json")
+	var doc openapi.Document
+	if err := res.JSON(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != openapi.Version || doc.Info.Version == "" {
+		t.Errorf("заголовок документа: %q %+v", doc.OpenAPI, doc.Info)
+	}
+	for _, path := range []string{"/api/v1/products", "/api/v1/products/{id}", "/api/v1/cart/items/{product_id}", "/api/v1/orders/{number}"} {
+		if doc.Paths[path] == nil {
+			t.Errorf("нет пути %s", path)
+		}
+	}
+	if item := doc.Paths["/api/v1/cart/items"]; item == nil || (*item)["post"] == nil || (*item)["post"].Responses["201"] == nil {
+		t.Errorf("POST /api/v1/cart/items без ответа 201")
+	}
+	for _, name := range []string{"ProblemDetail", "Product", "Cart", "Order", "Money"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("нет схемы %s", name)
+		}
+	}
+
+	page := h.Get("/api/docs")
+	if page.Code != http.StatusOK || !strings.Contains(page.Body, "/api/v1/cart/items/{product_id}") ||
+		!strings.Contains(page.Body, `id="schema-Product"`) {
+		t.Errorf("страница документации: %d", page.Code)
+	}
+}
+
+// TestOpenAPIValidator — в test-окружении запросы вне спецификации отклоняются до обработчика
+func TestOpenAPIValidator(t *testing.T) {
+	h := apptest.New(t)
+
+	cases := []struct {
+		name  string
+		res   *apptest.Response
+		field string
+	}{
+		{"неизвестный параметр", h.Get("/api/v1/products?colour=red"), "query.colour"},
+		{"неизвестное поле тела", h.SendJSON(http.MethodPost, "/api/v1/cart/items", map[string]any{"product_id": 1, "qty": 2}), "body.qty"},
+		{"строка вместо числа", h.SendJSON(http.MethodPost, "/api/v1/cart/items", map[string]any{"product_id": "1"}), "body.product_id"},
+		{"тело у GET", h.SendJSON(http.MethodGet, "/api/v1/cart", map[string]any{"x": 1}), "body"},
+	}
+	for _, tc := range cases {
+		p, ok := tc.res.Problem()
+		if tc.res.Code != http.StatusBadRequest || !ok || p.Code != "invalid_request" || p.Fields[tc.field] == "" {
+			t.Errorf("%s: %d %+v", tc.name, tc.res.Code, p)
+		}
+	}
+
+	if res := h.SendJSON(http.MethodPost, "/api/v1/cart/items", map[string]any{"product_id": 1, "quantity": 2}); res.Code != http.StatusCreated {
+		t.Errorf("корректный запрос: %d %s", res.Code, res.Body)
+	}
+}
+
+// TestAPIRouteScopes — области из handler.APIRoutes действительно проверяются: токен без области
+// маршрута получает 403 insufficient_scope, маршрут без областей пускает любой токен
+func TestAPIRouteScopes(t *testing.T) {
+	h := apptest.New(t)
+	h.Register("Анна", "anna@example.com", "s3cret-pass")
+	number := h.PlaceOrder()
+
+	// Токен без области scope — с остальными областями
+	tokens := map[storage.Scope]string{}
+	for _, scope := range storage.Scopes {
+		var others []string
+		for _, s := range storage.Scopes {
+			if s != scope {
+				others = append(others, string(s))
+			}
+		}
+		tokens[scope] = h.CreateAPIToken("без "+string(scope), others...)
+	}
+	paths := strings.NewReplacer(":id", "1", ":product_id", "1", ":number", number)
+	queries := map[string]string{"GET /api/v1/search": "?q=ART"}
+	bodies := map[string]any{
+		"POST /api/v1/cart/items":              map[string]any{"product_id": 1, "quantity": 1},
+		"PATCH /api/v1/cart/items/:product_id": map[string]any{"quantity": 1},
+	}
+
+	for key, route := range handler.APIRoutes() {
+		method, path, _ := strings.Cut(key, " ")
+		for _, scope := range storage.Scopes {
+			res := h.SendToken(method, paths.Replace(path)+queries[key], tokens[scope], bodies[key])
+			p, _ := res.Problem()
+			denied := res.Code == http.StatusForbidden && p.Code == "insufficient_scope"
+			if want := slices.Contains(route.Scopes, string(scope)); denied != want {
+				t.Errorf("%s без %s: status %d %+v, want отказ %v", key, scope, res.Code, p, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/storage"
+)
+
+// pngFile — PNG w×h для загрузки в форму товара
+func pngFile(t *testing.T, w, h int) apptest.File {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 120, A: 255})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return apptest.File{Name: "photo.png", Data: b.Bytes()}
+}
+
+// uploaded — файлы миниатюр изображения товара в UPLOADS_DIR
+func uploaded(t *testing.T, h *apptest.Harness, key string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(h.Config.UploadsDir, filepath.FromSlash(key)) + "-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// TestAdminProductImage — загрузка, показ через srcset, замена, удаление изображения и товара
+func TestAdminProductImage(t *testing.T) {
+	h := apptest.New(t)
+	h.RegisterAs("staff@example.com", storage.RoleStaff)
+	ctx := context.Background()
+
+	res := h.PostMultipart("/admin/products", validProduct(), map[string]apptest.File{"image": pngFile(t, 2000, 1000)})
+	if res.Code != http.StatusSeeOther {
+		t.Fatalf("создание с изображением: status %d, body %.300s", res.Code, res.Body)
+	}
+	page, _ := h.Repos.Products.List(ctx, storage.ProductQuery{Text: "ART-100"})
+	p := page.Items[0]
+	img := p.Image()
+	if img == nil || img.Width != 1280 || img.Height != 640 {
+		t.Fatalf("изображение товара: %+v", img)
+	}
+	if files := uploaded(t, h, img.Key); len(files) != 6 {
+		t.Fatalf("миниатюр на диске %d, want 6: %v", len(files), files)
+	}
+
+	// Файлы раздаются с долгим кэшем
+	thumb := h.Get("/uploads/" + img.Key + "-320.webp")
+	if thumb.Code != http.StatusOK || thumb.Header.Get("Content-Type") != "image/webp" ||
+		!strings.Contains(thumb.Header.Get("Cache-Control"), "immutable") {
+		t.Errorf("миниатюра: status %d, headers %v", thumb.Code, thumb.Header)
+	}
+
+	// Витрина: <picture> с WebP и JPEG, alt — подпись из формы
+	for _, path := range []string{"/product/" + p.ID, "/catalog/audio", "/search?q=QWE"} {
+		body := html.UnescapeString(h.Get(path).Body)
+		webp := `srcset="/uploads/` + img.Key + `-320.webp 320w, /uploads/` + img.Key + `-640.webp 640w, /uploads/` + img.Key + `-1280.webp 1280w"`
+		if !strings.Contains(body, webp) || !strings.Contains(body, `src="/uploads/`+img.Key+`-640.jpg"`) ||
+			!strings.Contains(body, `alt="Наушники в кейсе"`) {
+			t.Errorf("%s: нет srcset или alt изображения", path)
+		}
+	}
+
+	// Правка без файла изображение не трогает, новый файл заменяет старый
+	if res := h.PostForm("/admin/products/"+p.ID, validProduct()); res.Code != http.StatusSeeOther {
+		t.Fatalf("правка: status %d", res.Code)
+	}
+	if got, _ := h.Repos.Products.GetByID(ctx, atoi(t, p.ID)); got.Image() == nil || got.Image().Key != img.Key {
+		t.Fatal("изображение потеряно при правке без файла")
+	}
+	res = h.PostMultipart("/admin/products/"+p.ID, validProduct(), map[string]apptest.File{"image": pngFile(t, 200, 100)})
+	if res.Code != http.StatusSeeOther {
+		t.Fatalf("замена изображения: status %d, body %.300s", res.Code, res.Body)
+	}
+	got, _ := h.Repos.Products.GetByID(ctx, atoi(t, p.ID))
+	small := got.Image()
+	if small == nil || small.Key == img.Key || small.Width != 200 {
+		t.Fatalf("после замены: %+v", small)
+	}
+	if files := uploaded(t, h, img.Key); len(files) != 0 {
+		t.Errorf("старые миниатюры не удалены: %v", files)
+	}
+	if !strings.Contains(h.Get("/product/"+p.ID).Body, `-200.webp 200w"`) {
+		t.Error("маленькое изображение не растягивается до стандартных ширин")
+	}
+
+	// "Удалить изображение" — снова заглушка
+	form := validProduct()
+	form.Set("remove_image", "1")
+	h.PostForm("/admin/products/"+p.ID, form)
+	if got, _ := h.Repos.Products.GetByID(ctx, atoi(t, p.ID)); got.Image() != nil {
+		t.Error("изображение не удалено")
+	}
+	if files := uploaded(t, h, small.Key); len(files) != 0 {
+		t.Errorf("миниатюры удалённого изображения остались: %v", files)
+	}
+	if body := h.Get("/product/" + p.ID).Body; strings.Contains(body, "<picture>") || !strings.Contains(body, "<svg") {
+		t.Error("без изображения нет заглушки")
+	}
+
+	// Удаление товара удаляет и его изображение
+	h.PostMultipart("/admin/products/"+p.ID, validProduct(), map[string]apptest.File{"image": pngFile(t, 400, 400)})
+	got, _ = h.Repos.Products.GetByID(ctx, atoi(t, p.ID))
+	h.PostForm("/admin/products/"+p.ID+"/delete", nil)
+	if files := uploaded(t, h, got.Image().Key); len(files) != 0 {
+		t.Errorf("миниатюры удалённого товара остались: %v", files)
+	}
+}
+
+// TestAdminProductImageRejected — тип по содержимому, предел размера файла и тела запроса
+func TestAdminProductImageRejected(t *testing.T) {
+	h := apptest.New(t)
+	h.RegisterAs("staff@example.com", storage.RoleStaff)
+
+	big := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, h.Config.UploadMaxBytes)...)
+	tests := []struct {
+		name    string
+		file    apptest.File
+		message string
+	}{
+		{"не изображение", apptest.File{Name: "photo.jpg", Data: []byte("<?php system($_GET['c']); ?>")}, "Загрузите изображение JPEG, PNG, GIF или WebP"},
+		{"svg", apptest.File{Name: "logo.png", Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)}, "Загрузите изображение JPEG, PNG, GIF или WebP"},
+		{"повреждённый png", apptest.File{Name: "photo.png", Data: pngFile(t, 50, 50).Data[:60]}, "Не удалось прочитать изображение"},
+		{"больше предела", apptest.File{Name: "photo.png", Data: big}, "Файл больше 2 МБ"},
+	}
+	for _, tt := range tests {
+		res := h.PostMultipart("/admin/products", validProduct(), map[string]apptest.File{"image": tt.file})
+		if res.Code != http.StatusBadRequest || !strings.Contains(html.UnescapeString(res.Body), tt.message) {
+			t.Errorf("%s: status %d, want 400 с %q", tt.name, res.Code, tt.message)
+		}
+	}
+	if page, _ := h.Repos.Products.List(context.Background(), storage.ProductQuery{Text: "ART-100"}); page.Total != 0 {
+		t.Error("товар создан с отклонённым файлом")
+	}
+	if entries, _ := os.ReadDir(h.Config.UploadsDir); len(entries) != 0 {
+		t.Errorf("в UPLOADS_DIR остались файлы: %v", entries)
+	}
+
+	// Тело больше UPLOAD_MAX_BYTES + 1 МБ отклоняется до разбора формы
+	req := httptest.NewRequest(http.MethodPost, "/admin/products", bytes.NewReader(make([]byte, h.Config.UploadMaxBytes+2<<20)))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	if res := h.Do(req); res.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("большое тело: status %d, want 413", res.Code)
+	}
+}
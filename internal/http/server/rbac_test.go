@@ -0,0 +1,92 @@
+package server_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/storage"
+)
+
+// TestAdminAccess — /admin и /debug по правам роли
+func TestAdminAccess(t *testing.T) {
+	tests := []struct {
+		role         string
+		admin, debug int
+	}{
+		{storage.RoleCustomer, http.StatusForbidden, http.StatusForbidden},
+		{storage.RoleStaff, http.StatusOK, http.StatusForbidden},
+		{storage.RoleAdmin, http.StatusOK, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			h := apptest.New(t)
+			h.RegisterAs("user@example.com", tt.role)
+
+			if res := h.Get("/admin"); res.Code != tt.admin {
+				t.Errorf("/admin: status %d, want %d", res.Code, tt.admin)
+			} else if res.Code == http.StatusForbidden && !strings.Contains(res.Body, "Доступ запрещён") {
+				t.Error("/admin: нет страницы 403")
+			}
+			if res := h.Get("/debug"); res.Code != tt.debug {
+				t.Errorf("/debug: status %d, want %d", res.Code, tt.debug)
+			}
+
+			menu := strings.Contains(h.Get("/").Body, `href="/admin"`)
+			if want := tt.admin == http.StatusOK; menu != want {
+				t.Errorf("ссылка на /admin в меню: %v, want %v", menu, want)
+			}
+		})
+	}
+}
+
+// TestRequirePermissionJSON — JSON-клиент получает RFC 7807 вместо страницы и редиректа
+func TestRequirePermissionJSON(t *testing.T) {
+	h := apptest.New(t)
+	get := func(c *apptest.Client) *apptest.Response {
+		req := httptest.NewRequest(http.MethodGet, "/debug", nil)
+		req.Header.Set("Accept", "application/json")
+		return c.Do(req)
+	}
+
+	res := get(h.NewClient())
+	if p, ok := res.Problem(); !ok || res.Code != http.StatusUnauthorized || p.Code != "unauthorized" {
+		t.Errorf("аноним: status %d, problem %+v", res.Code, p)
+	}
+
+	h.RegisterAs("staff@example.com", storage.RoleStaff)
+	res = get(h.Client)
+	if p, ok := res.Problem(); !ok || res.Code != http.StatusForbidden || p.Code != "forbidden" {
+		t.Errorf("staff: status %d, problem %+v", res.Code, p)
+	}
+}
+
+// TestRoleChangeApplies — права читаются на каждый запрос: смена роли действует без перевхода
+func TestRoleChangeApplies(t *testing.T) {
+	h := apptest.New(t)
+	h.RegisterAs("user@example.com", storage.RoleStaff)
+	if res := h.Get("/admin"); res.Code != http.StatusOK {
+		t.Fatalf("staff: status %d", res.Code)
+	}
+
+	ctx := context.Background()
+	u, _ := h.Repos.Users.GetByEmail(ctx, "user@example.com")
+	if err := h.Repos.Users.SetRole(ctx, u.ID, storage.RoleCustomer); err != nil {
+		t.Fatal(err)
+	}
+	if res := h.Get("/admin"); res.Code != http.StatusForbidden {
+		t.Errorf("после понижения: status %d, want 403", res.Code)
+	}
+
+	if err := h.Repos.Users.SetRole(ctx, u.ID, "root"); !errors.Is(err, storage.ErrUnknownRole) {
+		t.Errorf("неизвестная роль: %v, want ErrUnknownRole", err)
+	}
+	if err := h.Repos.Users.SetRole(ctx, "999999", storage.RoleStaff); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("нет пользователя: %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+// routes.go — таблица маршрутов приложения
+import (
+	"net/http"
+	"strings"
+
+	"myApp/internal/http/handler"
+	"myApp/internal/openapi"
+	"myApp/internal/payment"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// paymentWebhookPath — адрес приёма событий платёжного провайдера
+const paymentWebhookPath = "/payments/webhook"
+
+// registerRoutes — Регистрация всех маршрутов приложения.
+// Ошибка — маршрут /api/v1 без описания в handler.APIRoutes (или наоборот).
+func registerRoutes(r *gin.Engine, app *handler.App) error {
+	r.GET("/", handler.Home(app))
+	r.GET("/catalog", handler.Catalog(app))
+	r.GET("/product/:id", handler.Conditional(app.Config.ProductCacheControl), handler.Product(app))
+	r.GET("/form", handler.FormIndex(app))
+	r.POST("/form", handler.FormSubmit(app))
+	r.GET("/about", handler.About(app))
+	r.GET("/debug", handler.RequirePermission(app, storage.PermDebugView), handler.Debug(app))
+	r.GET("/catalog/json", handler.Conditional(app.Config.CatalogCacheControl), handler.CatalogJSON(app))
+	r.GET("/catalog/:slug", handler.CatalogCategory(app))
+	r.GET("/register", handler.RegisterPage(app))
+	r.POST("/register", handler.RegisterSubmit(app))
+	r.GET("/login", handler.LoginPage(app))
+	r.POST("/login", handler.LoginSubmit(app))
+	r.POST("/logout", handler.Logout(app))
+	r.GET("/account/verify", handler.VerifyEmail(app))
+	r.POST("/account/verify/resend", handler.ResendVerification(app))
+	r.GET("/account/reset", handler.ResetRequestPage(app))
+	r.POST("/account/reset", handler.ResetRequestSubmit(app))
+	r.GET("/account/reset/confirm", handler.ResetConfirmPage(app))
+	r.POST("/account/reset/confirm", handler.ResetConfirmSubmit(app))
+	r.GET("/cart", handler.Cart(app))
+	r.POST("/cart/add", handler.CartAdd(app))
+	r.POST("/cart/update", handler.CartUpdate(app))
+	r.POST("/cart/remove", handler.CartRemove(app))
+	r.GET("/checkout", handler.Checkout(app))
+	for _, step := range []string{handler.StepContact, handler.StepAddress, handler.StepShipping} {
+		r.GET("/checkout/"+step, handler.CheckoutPage(app, step))
+		r.POST("/checkout/"+step, handler.CheckoutSubmit(app, step))
+	}
+	r.GET("/checkout/"+handler.StepReview, handler.CheckoutPage(app, handler.StepReview))
+	r.POST("/checkout/confirm", handler.CheckoutConfirm(app))
+	r.GET("/orders/:number", handler.OrderShow(app))
+	r.POST("/orders/:number/pay", handler.OrderPay(app))
+	r.POST("/orders/:number/cancel", handler.OrderCancel(app))
+	r.POST(paymentWebhookPath, handler.PaymentWebhook(app))
+	if fake, ok := app.Gateway.(*payment.Fake); ok {
+		r.GET("/payments/fake/:id", handler.FakePayment(app, fake))
+		r.POST("/payments/fake/:id", handler.FakePaymentSubmit(app, fake))
+		r.GET("/payments/fake/:id/3ds", handler.FakePayment(app, fake))
+		r.POST("/payments/fake/:id/3ds", handler.FakePayment3DSSubmit(app, fake))
+	}
+	r.GET("/search", handler.Search(app))
+
+	// API-токены пользователя (токен показывается один раз при создании)
+	tokens := r.Group("/account/tokens", handler.RequireLogin())
+	tokens.GET("", handler.AccountTokens(app))
+	tokens.POST("", handler.AccountTokenCreate(app))
+	tokens.POST("/:id/revoke", handler.AccountTokenRevoke(app))
+
+	// JSON API: конверт {"data", "pagination"}, ошибки — RFC 7807 (включая 404 и 405).
+	// В dev и test запросы и ответы сверяются со спецификацией (openapi.Validator).
+	// С API-токеном (handler.LoadAPIToken) корзина, заказы и учётная запись требуют его областей.
+	// Idempotency-Key стоит после валидатора: запрос вне спецификации ключ не занимает.
+	spec := handler.NewAPIDocument()
+	env := strings.ToLower(app.Config.Env)
+	api := r.Group("/api/v1", apiCSRFToken(), openapi.Validator(spec, env == "dev" || env == "test"), handler.Idempotency(app))
+	// Области токена берутся из описания маршрута: RequireScope и security спецификации не расходятся
+	apiRoutes := handler.APIRoutes()
+	apiRoute := func(method, path string, h gin.HandlerFunc) {
+		route := apiRoutes[method+" "+api.BasePath()+path]
+		handlers := make([]gin.HandlerFunc, 0, len(route.Scopes)+1)
+		for _, scope := range route.Scopes {
+			handlers = append(handlers, handler.RequireScope(storage.Scope(scope)))
+		}
+		api.Handle(method, path, append(handlers, h)...)
+	}
+	apiRoute(http.MethodGet, "/search", handler.SearchJSON(app))
+	apiRoute(http.MethodGet, "/products", handler.APIProducts(app))
+	apiRoute(http.MethodGet, "/products/:id", handler.APIProduct(app))
+	apiRoute(http.MethodGet, "/categories", handler.APICategories(app))
+	apiRoute(http.MethodGet, "/cart", handler.APICart(app))
+	apiRoute(http.MethodPost, "/cart/items", handler.APICartAdd(app))
+	apiRoute(http.MethodPatch, "/cart/items/:product_id", handler.APICartUpdate(app))
+	apiRoute(http.MethodDelete, "/cart/items/:product_id", handler.APICartRemove(app))
+	apiRoute(http.MethodGet, "/orders", handler.APIOrders(app))
+	apiRoute(http.MethodGet, "/orders/:number", handler.APIOrder(app))
+	apiRoute(http.MethodGet, "/account", handler.APIAccount(app))
+	if err := spec.AddRoutes(r.Routes(), "/api/v1/", apiRoutes); err != nil {
+		return err
+	}
+	r.GET("/api/openapi.json", handler.OpenAPIJSON(spec))
+	r.GET("/api/docs", handler.APIDocs(app, spec))
+
+	// Панель управления: только пользователи с правом admin.access (handler.LoadUser загружает права)
+	admin := r.Group("/admin", handler.RequirePermission(app, storage.PermAdminAccess))
+	admin.GET("", handler.AdminDashboard(app))
+
+	products := admin.Group("/products", handler.RequirePermission(app, storage.PermProductsWrite))
+	products.GET("", handler.AdminProducts(app))
+	products.POST("", handler.AdminProductCreate(app))
+	products.GET("/new", handler.AdminProductNew(app))
+	products.GET("/:id/edit", handler.AdminProductEdit(app))
+	products.POST("/:id", handler.AdminProductUpdate(app))
+	products.GET("/:id/stock", handler.AdminProductStock(app))
+	products.POST("/:id/stock", handler.AdminProductStockUpdate(app))
+	products.GET("/:id/delete", handler.AdminProductDeleteConfirm(app))
+	products.POST("/:id/delete", handler.AdminProductDelete(app))
+
+	apiTokens := admin.Group("/tokens", handler.RequirePermission(app, storage.PermTokensManage))
+	apiTokens.GET("", handler.AdminTokens(app))
+	apiTokens.POST("/:id/revoke", handler.AdminTokenRevoke(app))
+
+	// Обработчики 404 и 405 (405 — только внутри /api/, см. handler.MethodNotAllowed)
+	r.NoRoute(handler.NotFound(app))
+	r.HandleMethodNotAllowed = true
+	r.NoMethod(handler.MethodNotAllowed(app, r.Routes()))
+	return nil
+}
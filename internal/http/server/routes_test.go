@@ -0,0 +1,204 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/storage"
+)
+
+// routeCase — ожидаемый ответ маршрута для нового посетителя (пустая сессия и корзина)
+type routeCase struct {
+	method  string
+	route   string     // Шаблон маршрута, как в registerRoutes
+	path    string     // Конкретный URL запроса
+	form    url.Values // Тело POST (CSRF-токен подставляется)
+	status  int
+	problem string // Ожидаемый code RFC 7807 ("" — ответ не ошибка)
+	target  string // Ожидаемый Location для редиректа
+}
+
+var routeCases = []routeCase{
+	{method: "GET", route: "/", path: "/", status: 200},
+	{method: "GET", route: "/about", path: "/about", status: 200},
+	{method: "GET", route: "/catalog", path: "/catalog", status: 200},
+	{method: "GET", route: "/catalog/json", path: "/catalog/json", status: 200},
+	{method: "GET", route: "/catalog/:slug", path: "/catalog/phones", status: 200},
+	{method: "GET", route: "/product/:id", path: "/product/1", status: 200},
+	{method: "GET", route: "/form", path: "/form", status: 200},
+	{method: "POST", route: "/form", path: "/form", form: url.Values{}, status: 400},
+	{method: "GET", route: "/debug", path: "/debug", status: 303, target: "/login?next=%2Fdebug"},
+	{method: "GET", route: "/search", path: "/search?q=смартфон", status: 200},
+	{method: "GET", route: "/api/v1/search", path: "/api/v1/search?q=ART-002", status: 200},
+	{method: "GET", route: "/api/v1/products", path: "/api/v1/products", status: 200},
+	{method: "GET", route: "/api/v1/products/:id", path: "/api/v1/products/999", status: 404, problem: "not_found"},
+	{method: "GET", route: "/api/v1/categories", path: "/api/v1/categories", status: 200},
+	{method: "GET", route: "/api/v1/cart", path: "/api/v1/cart", status: 200},
+	{method: "POST", route: "/api/v1/cart/items", path: "/api/v1/cart/items", status: 403, problem: "forbidden"},
+	{method: "PATCH", route: "/api/v1/cart/items/:product_id", path: "/api/v1/cart/items/1", status: 403, problem: "forbidden"},
+	{method: "DELETE", route: "/api/v1/cart/items/:product_id", path: "/api/v1/cart/items/1", status: 403, problem: "forbidden"},
+	{method: "GET", route: "/api/v1/orders", path: "/api/v1/orders", status: 401, problem: "unauthorized"},
+	{method: "GET", route: "/api/v1/orders/:number", path: "/api/v1/orders/NOSUCHORDER1", status: 404, problem: "not_found"},
+	{method: "GET", route: "/api/v1/account", path: "/api/v1/account", status: 401, problem: "unauthorized"},
+	{method: "GET", route: "/api/openapi.json", path: "/api/openapi.json", status: 200},
+	{method: "GET", route: "/api/docs", path: "/api/docs", status: 200},
+	{method: "GET", route: "/register", path: "/register", status: 200},
+	{method: "POST", route: "/register", path: "/register", form: url.Values{}, status: 400},
+	{method: "GET", route: "/login", path: "/login", status: 200},
+	{method: "POST", route: "/login", path: "/login", form: url.Values{"email": {"nobody@example.com"}, "password": {"password1"}}, status: 401},
+	{method: "POST", route: "/logout", path: "/logout", form: url.Values{}, status: 303, target: "/"},
+	{method: "GET", route: "/admin", path: "/admin", status: 303, target: "/login?next=%2Fadmin"},
+	{method: "GET", route: "/admin/products", path: "/admin/products", status: 303, target: "/login?next=%2Fadmin%2Fproducts"},
+	{method: "POST", route: "/admin/products", path: "/admin/products", form: url.Values{}, status: 303, target: "/login?next=%2Fadmin%2Fproducts"},
+	{method: "GET", route: "/admin/products/new", path: "/admin/products/new", status: 303, target: "/login?next=%2Fadmin%2Fproducts%2Fnew"},
+	{method: "GET", route: "/admin/products/:id/edit", path: "/admin/products/1/edit", status: 303, target: "/login?next=%2Fadmin%2Fproducts%2F1%2Fedit"},
+	{method: "POST", route: "/admin/products/:id", path: "/admin/products/1", form: url.Values{}, status: 303, target: "/login?next=%2Fadmin%2Fproducts%2F1"},
+	{method: "GET", route: "/admin/products/:id/stock", path: "/admin/products/1/stock", status: 303, target: "/login?next=%2Fadmin%2Fproducts%2F1%2Fstock"},
+	{method: "POST", route: "/admin/products/:id/stock", path: "/admin/products/1/stock", form: url.Values{}, status: 303, target: "/login?next=%2Fadmin%2Fproducts%2F1%2Fstock"},
+	{method: "GET", route: "/admin/products/:id/delete", path: "/admin/products/1/delete", status: 303, target: "/login?next=%2Fadmin%2Fproducts%2F1%2Fdelete"},
+	{method: "POST", route: "/admin/products/:id/delete", path: "/admin/products/1/delete", form: url.Values{}, status: 303, target: "/login?next=%2Fadmin%2Fproducts%2F1%2Fdelete"},
+	{method: "GET", route: "/admin/tokens", path: "/admin/tokens", status: 303, target: "/login?next=%2Fadmin%2Ftokens"},
+	{method: "POST", route: "/admin/tokens/:id/revoke", path: "/admin/tokens/1/revoke", form: url.Values{}, status: 303, target: "/login?next=%2Fadmin%2Ftokens%2F1%2Frevoke"},
+	{method: "GET", route: "/account/tokens", path: "/account/tokens", status: 303, target: "/login?next=%2Faccount%2Ftokens"},
+	{method: "POST", route: "/account/tokens", path: "/account/tokens", form: url.Values{}, status: 303, target: "/login?next=%2Faccount%2Ftokens"},
+	{method: "POST", route: "/account/tokens/:id/revoke", path: "/account/tokens/1/revoke", form: url.Values{}, status: 303, target: "/login?next=%2Faccount%2Ftokens%2F1%2Frevoke"},
+	{method: "GET", route: "/account/verify", path: "/account/verify?token=nope", status: 400},
+	{method: "POST", route: "/account/verify/resend", path: "/account/verify/resend", form: url.Values{}, status: 303, target: "/login"},
+	{method: "GET", route: "/account/reset", path: "/account/reset", status: 200},
+	{method: "POST", route: "/account/reset", path: "/account/reset", form: url.Values{"email": {"nobody@example.com"}}, status: 303, target: "/account/reset?sent=1"},
+	{method: "GET", route: "/account/reset/confirm", path: "/account/reset/confirm?token=nope", status: 400},
+	{method: "POST", route: "/account/reset/confirm", path: "/account/reset/confirm", form: url.Values{"token": {"nope"}, "password": {"new-pass-123"}, "confirm": {"new-pass-123"}}, status: 400},
+	{method: "GET", route: "/cart", path: "/cart", status: 200},
+	{method: "POST", route: "/cart/add", path: "/cart/add", form: url.Values{"product_id": {"1"}}, status: 303, target: "/cart"},
+	{method: "POST", route: "/cart/update", path: "/cart/update", form: url.Values{"product_id": {"1"}, "quantity": {"3"}}, status: 303, target: "/cart"},
+	{method: "POST", route: "/cart/remove", path: "/cart/remove", form: url.Values{"product_id": {"1"}}, status: 303, target: "/cart"},
+	{method: "GET", route: "/checkout", path: "/checkout", status: 303, target: "/checkout/contact"},
+	{method: "GET", route: "/checkout/contact", path: "/checkout/contact", status: 303, target: "/cart"},
+	{method: "POST", route: "/checkout/contact", path: "/checkout/contact", form: url.Values{}, status: 303, target: "/cart"},
+	{method: "GET", route: "/checkout/address", path: "/checkout/address", status: 303, target: "/cart"},
+	{method: "POST", route: "/checkout/address", path: "/checkout/address", form: url.Values{}, status: 303, target: "/cart"},
+	{method: "GET", route: "/checkout/shipping", path: "/checkout/shipping", status: 303, target: "/cart"},
+	{method: "POST", route: "/checkout/shipping", path: "/checkout/shipping", form: url.Values{}, status: 303, target: "/cart"},
+	{method: "GET", route: "/checkout/review", path: "/checkout/review", status: 303, target: "/cart"},
+	{method: "POST", route: "/checkout/confirm", path: "/checkout/confirm", form: url.Values{}, status: 303, target: "/checkout/contact"},
+	{method: "GET", route: "/orders/:number", path: "/orders/NOSUCHORDER1", status: 404, problem: "not_found"},
+	{method: "POST", route: "/orders/:number/pay", path: "/orders/NOSUCHORDER1/pay", form: url.Values{}, status: 404, problem: "not_found"},
+	{method: "POST", route: "/orders/:number/cancel", path: "/orders/NOSUCHORDER1/cancel", form: url.Values{}, status: 404, problem: "not_found"},
+	{method: "POST", route: "/payments/webhook", path: "/payments/webhook", status: 400, problem: "invalid_signature"},
+	{method: "GET", route: "/payments/fake/:id", path: "/payments/fake/pi_missing", status: 404, problem: "payment_not_found"},
+	{method: "POST", route: "/payments/fake/:id", path: "/payments/fake/pi_missing", form: url.Values{"card": {"4242424242424242"}}, status: 404, problem: "payment_not_found"},
+	{method: "GET", route: "/payments/fake/:id/3ds", path: "/payments/fake/pi_missing/3ds", status: 404, problem: "payment_not_found"},
+	{method: "POST", route: "/payments/fake/:id/3ds", path: "/payments/fake/pi_missing/3ds", form: url.Values{"result": {"approve"}}, status: 404, problem: "payment_not_found"},
+	{method: "GET", route: "/assets/*filepath", path: "/assets/css/style.css", status: 200},
+	{method: "HEAD", route: "/assets/*filepath", path: "/assets/css/style.css", status: 200},
+	{method: "GET", route: "/uploads/*filepath", path: "/uploads/products/missing-320.jpg", status: 404},
+	{method: "HEAD", route: "/uploads/*filepath", path: "/uploads/products/missing-320.jpg", status: 404},
+}
+
+// TestRoutes — каждый маршрут отвечает ожидаемым статусом новому посетителю
+func TestRoutes(t *testing.T) {
+	for _, tc := range routeCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			h := apptest.New(t)
+
+			var res *apptest.Response
+			switch {
+			case tc.form != nil:
+				res = h.PostForm(tc.path, tc.form)
+			default:
+				res = h.Do(httptest.NewRequest(tc.method, tc.path, nil))
+			}
+
+			if res.Code != tc.status {
+				t.Fatalf("status = %d, want %d; body: %.300s", res.Code, tc.status, res.Body)
+			}
+			if tc.target != "" && res.Location() != tc.target {
+				t.Errorf("Location = %q, want %q", res.Location(), tc.target)
+			}
+			if tc.problem != "" {
+				p, ok := res.Problem()
+				if !ok {
+					t.Fatalf("ответ не RFC 7807: %.300s", res.Body)
+				}
+				if p.Code != tc.problem || p.Status != tc.status || p.Type != "/errors/"+tc.problem {
+					t.Errorf("problem = %+v, want code %q status %d", p, tc.problem, tc.status)
+				}
+			}
+		})
+	}
+}
+
+// TestRoutesCovered — новый маршрут в registerRoutes без строки в routeCases валит тест
+func TestRoutesCovered(t *testing.T) {
+	h := apptest.New(t)
+
+	covered := map[string]bool{}
+	for _, tc := range routeCases {
+		covered[tc.method+" "+tc.route] = true
+	}
+	for _, rt := range h.Engine.Routes() {
+		if !covered[rt.Method+" "+rt.Path] {
+			t.Errorf("маршрут %s %s не покрыт routeCases", rt.Method, rt.Path)
+		}
+	}
+}
+
+// TestCatalogReservedSlugs — статический /catalog/<slug> перекрывает категорию: slug должен быть
+// в storage.ReservedCategorySlugs, и такой категории нет в фикстурах
+func TestCatalogReservedSlugs(t *testing.T) {
+	h := apptest.New(t)
+
+	for _, rt := range h.Engine.Routes() {
+		slug, ok := strings.CutPrefix(rt.Path, "/catalog/")
+		if !ok || strings.ContainsAny(slug, ":*/") {
+			continue
+		}
+		if !slices.Contains(storage.ReservedCategorySlugs, slug) {
+			t.Errorf("%s %s: slug %q не зарезервирован", rt.Method, rt.Path, slug)
+		}
+	}
+	for _, c := range storage.DemoFixtures().Categories {
+		if slices.Contains(storage.ReservedCategorySlugs, c.Slug) {
+			t.Errorf("категория %s с зарезервированным slug %q", c.ID, c.Slug)
+		}
+	}
+}
+
+// TestNotFound — неизвестный адрес: HTML-страница 404, а не JSON
+func TestNotFound(t *testing.T) {
+	h := apptest.New(t)
+
+	res := h.Get("/no/such/page")
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", res.Code)
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+}
+
+// TestProductErrors — ошибки карточки товара в формате RFC 7807
+func TestProductErrors(t *testing.T) {
+	h := apptest.New(t)
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/product/999", http.StatusNotFound, "not_found"},
+		{"/catalog/no-such-category", http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		res := h.Get(tt.path)
+		p, ok := res.Problem()
+		if res.Code != tt.status || !ok || p.Code != tt.code {
+			t.Errorf("GET %s: status %d, problem %+v; want %d %q", tt.path, res.Code, p, tt.status, tt.code)
+		}
+	}
+}
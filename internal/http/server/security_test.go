@@ -0,0 +1,108 @@
+package server_test
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+)
+
+// TestSecurityHeaders — заголовки безопасности на HTML, JSON и ошибках
+func TestSecurityHeaders(t *testing.T) {
+	h := apptest.New(t)
+
+	want := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"Referrer-Policy":        "strict-origin-when-cross-origin",
+		"Permissions-Policy":     "camera=(), microphone=(), geolocation=(), payment=()",
+	}
+	for _, path := range []string{"/", "/catalog/json", "/product/999", "/no/such/page"} {
+		res := h.Get(path)
+		for k, v := range want {
+			if got := res.Header.Get(k); got != v {
+				t.Errorf("GET %s: %s = %q, want %q", path, k, got, v)
+			}
+		}
+		if res.Header.Get("X-Request-ID") == "" {
+			t.Errorf("GET %s: нет X-Request-ID", path)
+		}
+	}
+}
+
+var cspNonceRe = regexp.MustCompile(`script-src 'self' 'nonce-([^']+)'`)
+
+// TestCSPNonce — nonce в CSP свой на каждый запрос и совпадает с nonce в разметке
+func TestCSPNonce(t *testing.T) {
+	h := apptest.New(t)
+
+	nonces := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		res := h.Get("/product/1")
+		csp := res.Header.Get("Content-Security-Policy")
+		for _, d := range []string{"default-src 'self'", "object-src 'none'", "frame-ancestors 'none'", "base-uri 'self'"} {
+			if !strings.Contains(csp, d) {
+				t.Errorf("CSP %q: нет директивы %q", csp, d)
+			}
+		}
+
+		m := cspNonceRe.FindStringSubmatch(csp)
+		if m == nil {
+			t.Fatalf("CSP без nonce: %q", csp)
+		}
+		if !strings.Contains(html.UnescapeString(res.Body), `nonce="`+m[1]+`"`) {
+			t.Errorf("nonce %q из CSP не найден в разметке", m[1])
+		}
+		nonces[m[1]] = true
+	}
+	if len(nonces) != 2 {
+		t.Error("nonce повторяется между запросами")
+	}
+}
+
+// TestCSRF — POST без токена или с чужим токеном отклоняется (403, RFC 7807)
+func TestCSRF(t *testing.T) {
+	h := apptest.New(t)
+	form := url.Values{"product_id": {"1"}}
+
+	check := func(name string, res *apptest.Response) {
+		t.Helper()
+		p, ok := res.Problem()
+		if res.Code != http.StatusForbidden || !ok || p.Code != "forbidden" {
+			t.Errorf("%s: status %d, problem %+v; want 403 forbidden", name, res.Code, p)
+		}
+	}
+
+	check("без токена", h.PostFormRaw("/cart/add", form))
+
+	other := h.NewClient()
+	withForeign := url.Values{"product_id": {"1"}, "_csrf": {other.CSRFToken()}}
+	check("токен другой сессии", h.PostFormRaw("/cart/add", withForeign))
+
+	if res := h.PostForm("/cart/add", form); res.Code != http.StatusSeeOther {
+		t.Errorf("с токеном: status %d, want 303", res.Code)
+	}
+}
+
+// TestSessionCookie — cookie сессии недоступна скриптам и не уходит на сторонние сайты
+func TestSessionCookie(t *testing.T) {
+	h := apptest.New(t)
+
+	res := h.Get("/form")
+	var found bool
+	for _, line := range res.Header.Values("Set-Cookie") {
+		if !strings.HasPrefix(line, "mysession=") {
+			continue
+		}
+		found = true
+		if !strings.Contains(line, "HttpOnly") || !strings.Contains(line, "SameSite=Lax") {
+			t.Errorf("Set-Cookie %q: нужны HttpOnly и SameSite=Lax", line)
+		}
+	}
+	if !found {
+		t.Fatal("сессионная cookie не выставлена")
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+// server.go — сборка Gin-движка: хранилище, middleware, маршруты.
+// Сервер (cmd/app) и тесты (internal/apptest) получают одно и то же приложение.
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/http/handler"
+	"myApp/internal/mail"
+	"myApp/internal/payment"
+	"myApp/internal/search"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	csrf "github.com/utrack/gin-csrf"
+)
+
+// Backend — хранилище приложения: репозитории и поиск поверх MySQL или памяти (APP_STORAGE)
+type Backend struct {
+	DB     *sqlx.DB // nil для хранилища в памяти
+	Repos  storage.Repositories
+	Search search.Engine
+}
+
+// MySQLBackend — репозитории и FULLTEXT-поиск поверх пула MySQL
+func MySQLBackend(db *sqlx.DB) Backend {
+	return Backend{DB: db, Repos: storage.NewMySQLRepositories(db), Search: search.NewMySQL(db)}
+}
+
+// MemoryBackend — демо-каталог в памяти: без БД и миграций
+func MemoryBackend() Backend {
+	repos := storage.NewMemory(storage.DemoFixtures()).Repositories()
+	// Индекс поиска — из репозитория, а не из фикстур: у товаров должно быть посчитано "в наличии"
+	products, _ := repos.Products.ListAll(context.Background())
+	return Backend{Repos: repos, Search: search.NewMemory(products)}
+}
+
+// New — Главный конструктор Gin, собирает всю цепочку middleware и роуты.
+// Используется сервером (cmd/app) и тестами (internal/apptest).
+func New(cfg core.Config, st Backend, csrfKey []byte) (*gin.Engine, error) {
+	tpl, err := view.New()
+	if err != nil {
+		return nil, err
+	}
+
+	// Платёжный провайдер
+	provider, err := initPayments(cfg, st.Repos.Payments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Почта: SMTP или .eml-файлы в каталоге (MAIL_DRIVER)
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	// Загруженные файлы (изображения товаров): каталог UPLOADS_DIR, раздаётся как /uploads/
+	blobs := storage.NewLocalBlobStore(cfg.UploadsDir, uploadsPath)
+
+	// Зависимости обработчиков: передаются в конструкторы явно, а не через контекст запроса
+	app := &handler.App{
+		Config:       cfg,
+		DB:           st.DB,
+		Repositories: st.Repos,
+		Templates:    tpl,
+		Search:       st.Search,
+		Gateway:      provider,
+		Mailer:       mailer,
+		Blobs:        blobs,
+	}
+	// Меню категорий в блоке "nav" layout.html
+	tpl.SetMenu(handler.CategoryMenu(app))
+	// Имя пользователя и "Выйти" в блоке "nav" (handler.LoadUser)
+	tpl.SetUser(handler.UserMenu)
+	// srcset изображений товаров
+	tpl.SetBlobURL(blobs.URL)
+
+	r := gin.New()
+
+	if strings.ToLower(cfg.Env) == "prod" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	r.Use(gin.Logger(), gin.Recovery())
+
+	_ = r.SetTrustedProxies([]string{"127.0.0.1", "::1"})
+
+	// Корреляция запросов (RequestID)
+	r.Use(requestid.New())
+
+	// Таймаут запроса (отсекаем "висящие" клиенты)
+	r.Use(RequestTimeout(cfg.RequestTimeout))
+
+	// CSP nonce в контексте запроса (core.Nonce).
+	// Это должно идти до middleware, которое его использует (CSP, шаблоны).
+	r.Use(withNonce())
+
+	// Security заголовки (X-Frame-Options, X-Content-Type-Options и пр.)
+	r.Use(core.SecureHeaders())
+
+	// CSP (Content-Security-Policy) — nonce берём из контекста запроса (core.Nonce)
+	r.Use(CSPBasic())
+
+	// Безопасные cookie-сессии
+	store := cookie.NewStore(csrfKey)
+	store.Options(sessions.Options{
+		Path:     "/",
+		MaxAge:   86400 * 7,
+		HttpOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	r.Use(sessions.Sessions("mysession", store))
+
+	// Предел размера тела — до CSRF: csrf.Middleware разбирает форму (и multipart с файлами)
+	// раньше обработчика. Запас 1 МБ сверх UPLOAD_MAX_BYTES — на остальные поля формы.
+	r.Use(LimitBody(int64(cfg.UploadMaxBytes) + 1<<20))
+
+	// API-токен (Authorization: Bearer) в /api/ — до CSRF: такие запросы от него освобождены
+	r.Use(handler.LoadAPIToken(app))
+
+	// CSRF защита форм. Webhook платёжного провайдера приходит не из браузера —
+	// его подлинность проверяется HMAC-подписью, а не CSRF-токеном.
+	r.Use(skipPaths(skipTokenAuth(csrf.Middleware(csrf.Options{
+		Secret:    base64.StdEncoding.EncodeToString(csrfKey),
+		ErrorFunc: csrfError,
+	})), paymentWebhookPath))
+
+	// Текущий пользователь из сессии (handler.CurrentUser, PageData.User)
+	r.Use(handler.LoadUser(app))
+
+	// Статика
+	serveStatic(r, cfg.Env)
+	serveUploads(r, blobs.Dir())
+
+	// Роуты
+	if err := registerRoutes(r, app); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// initPayments — провайдер по PAYMENT_PROVIDER.
+// Фейковый провайдер доставляет события в тот же обработчик, что и /payments/webhook, — без сети;
+// в продакшене его и неизвестные имена отвергает Config.Validate.
+func initPayments(cfg core.Config, payments storage.PaymentRepository) (payment.Provider, error) {
+	switch cfg.PaymentProvider {
+	case core.PaymentFake:
+		fake := payment.NewFake([]byte(cfg.PaymentWebhookSecret))
+		fake.Deliver = func(ctx context.Context, payload []byte, signature string) error {
+			_, err := payment.HandleWebhook(ctx, payments, fake, payload, signature)
+			return err
+		}
+		return fake, nil
+	default:
+		return nil, fmt.Errorf("неизвестный платёжный провайдер %q", cfg.PaymentProvider)
+	}
+}
@@ -0,0 +1,360 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"myApp/internal/apptest"
+	"myApp/internal/payment"
+	"myApp/internal/storage"
+)
+
+// TestCartValidation — некорректные данные корзины: 400 validation с ошибками по полям
+func TestCartValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		form   url.Values
+		fields []string
+	}{
+		{"нет товара", "/cart/add", url.Values{}, []string{"product_id"}},
+		{"ID не число", "/cart/add", url.Values{"product_id": {"abc"}}, []string{"product_id"}},
+		{"количество больше лимита", "/cart/add", url.Values{"product_id": {"1"}, "quantity": {"100"}}, []string{"quantity"}},
+		{"отрицательное количество", "/cart/update", url.Values{"product_id": {"1"}, "quantity": {"-1"}}, []string{"quantity"}},
+		{"обновление без количества", "/cart/update", url.Values{"product_id": {"0"}}, []string{"product_id", "quantity"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apptest.New(t)
+
+			res := h.PostForm(tt.path, tt.form)
+			p, ok := res.Problem()
+			if res.Code != http.StatusBadRequest || !ok || p.Code != "validation" {
+				t.Fatalf("status %d, problem %+v; want 400 validation", res.Code, p)
+			}
+			if len(p.Fields) != len(tt.fields) {
+				t.Errorf("fields = %v, want %v", p.Fields, tt.fields)
+			}
+			for _, f := range tt.fields {
+				if p.Fields[f] == "" {
+					t.Errorf("нет ошибки для поля %q: %v", f, p.Fields)
+				}
+			}
+		})
+	}
+}
+
+// TestCart — добавление, изменение количества и удаление товара; корзины посетителей не пересекаются
+func TestCart(t *testing.T) {
+	h := apptest.New(t)
+
+	if res := h.PostForm("/cart/add", url.Values{"product_id": {"999"}}); res.Code != http.StatusNotFound {
+		t.Errorf("несуществующий товар: status %d, want 404", res.Code)
+	}
+
+	h.AddToCart(1, 2)
+	h.AddToCart(4, 1)
+	body := html.UnescapeString(h.Get("/cart").Body)
+	for _, want := range []string{"Смартфон XYZ Pro", "Наушники GHI Wireless"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("в корзине нет %q", want)
+		}
+	}
+
+	if res := h.PostForm("/cart/update", url.Values{"product_id": {"1"}, "quantity": {"0"}}); res.Code != http.StatusSeeOther {
+		t.Fatalf("update: status %d", res.Code)
+	}
+	if res := h.PostForm("/cart/remove", url.Values{"product_id": {"4"}}); res.Code != http.StatusSeeOther {
+		t.Fatalf("remove: status %d", res.Code)
+	}
+	body = html.UnescapeString(h.Get("/cart").Body)
+	if strings.Contains(body, "Смартфон XYZ Pro") || strings.Contains(body, "Наушники GHI Wireless") {
+		t.Error("товары остались в корзине после удаления")
+	}
+
+	other := h.NewClient()
+	h.AddToCart(2, 1)
+	if strings.Contains(html.UnescapeString(other.Get("/cart").Body), "Ноутбук ABC Ultra") {
+		t.Error("корзина видна другому посетителю")
+	}
+}
+
+// TestCheckoutValidation — ошибки шагов оформления показываются на той же странице (400)
+func TestCheckoutValidation(t *testing.T) {
+	h := apptest.New(t)
+	h.AddToCart(1, 1)
+
+	tests := []struct {
+		path     string
+		form     url.Values
+		messages []string
+	}{
+		{"/checkout/contact", url.Values{"name": {"И"}, "email": {"bad"}}, []string{"Имя должно быть не короче 2 символов", "Введите корректный email", "Укажите телефон"}},
+		{"/checkout/contact", url.Values{}, []string{"Укажите имя", "Укажите email"}},
+	}
+	for _, tt := range tests {
+		res := h.PostForm(tt.path, tt.form)
+		if res.Code != http.StatusBadRequest {
+			t.Fatalf("POST %s: status %d, want 400", tt.path, res.Code)
+		}
+		body := html.UnescapeString(res.Body)
+		for _, msg := range tt.messages {
+			if !strings.Contains(body, msg) {
+				t.Errorf("POST %s: нет сообщения %q", tt.path, msg)
+			}
+		}
+	}
+
+	// Шаги нельзя пропустить: без контактов адрес и подтверждение ведут назад
+	if res := h.Get("/checkout/address"); res.Location() != "/checkout/contact" {
+		t.Errorf("GET /checkout/address: Location %q, want /checkout/contact", res.Location())
+	}
+	if res := h.PostForm("/checkout/shipping", url.Values{"shipping_method": {"teleport"}}); res.Code != http.StatusBadRequest ||
+		!strings.Contains(html.UnescapeString(res.Body), "Выберите способ доставки") {
+		t.Errorf("неизвестная доставка: status %d", res.Code)
+	}
+}
+
+// TestOrderAccess — заказ видит только тот, кто его оформил
+func TestOrderAccess(t *testing.T) {
+	h := apptest.New(t)
+	number := h.PlaceOrder()
+
+	res := h.Get("/orders/" + number)
+	if res.Code != http.StatusOK || !strings.Contains(res.Body, number) {
+		t.Fatalf("владелец: status %d", res.Code)
+	}
+	if res := h.Get("/cart"); strings.Contains(html.UnescapeString(res.Body), "Смартфон XYZ Pro") {
+		t.Error("корзина не очищена после оформления")
+	}
+
+	other := h.NewClient()
+	for _, res := range []*apptest.Response{
+		other.Get("/orders/" + number),
+		other.PostForm("/orders/"+number+"/pay", nil),
+	} {
+		if p, ok := res.Problem(); res.Code != http.StatusNotFound || !ok || p.Code != "not_found" {
+			t.Errorf("чужой заказ: status %d, problem %+v; want 404 not_found", res.Code, p)
+		}
+	}
+}
+
+// TestPayment — оплата тестовыми картами фейкового шлюза
+func TestPayment(t *testing.T) {
+	tests := []struct {
+		name   string
+		card   string
+		tds    string // Ответ на 3-D Secure ("" — шага нет)
+		result string
+		status storage.OrderStatus
+	}{
+		{"успех", payment.CardSuccess, "", "success", storage.OrderPaid},
+		{"отказ банка", payment.CardDecline, "", "declined", storage.OrderPending},
+		{"3-D Secure подтверждён", payment.Card3DS, "approve", "success", storage.OrderPaid},
+		{"3-D Secure отклонён", payment.Card3DS, "reject", "declined", storage.OrderPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apptest.New(t)
+			number := h.PlaceOrder()
+			page := h.StartPayment(number)
+
+			if res := h.Get(page); res.Code != http.StatusOK {
+				t.Fatalf("GET %s: status %d", page, res.Code)
+			}
+			res := h.PostForm(page, url.Values{"card": {tt.card}})
+			if tt.tds != "" {
+				if res.Location() != page+"/3ds" {
+					t.Fatalf("ожидался шаг 3-D Secure, Location %q", res.Location())
+				}
+				res = h.PostForm(page+"/3ds", url.Values{"result": {tt.tds}})
+			}
+
+			want := "/orders/" + number + "?payment=" + tt.result
+			if res.Code != http.StatusSeeOther || res.Location() != want {
+				t.Fatalf("status %d, Location %q; want 303 %q", res.Code, res.Location(), want)
+			}
+			if res := h.Get(want); res.Code != http.StatusOK {
+				t.Errorf("GET %s: status %d", want, res.Code)
+			}
+
+			order, err := h.Repos.Orders.GetByNumber(context.Background(), number)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order.Status != tt.status {
+				t.Errorf("статус заказа %q, want %q", order.Status, tt.status)
+			}
+		})
+	}
+}
+
+// TestWebhook — подпись проверяется, повторная доставка события не применяется дважды
+func TestWebhook(t *testing.T) {
+	h := apptest.New(t)
+	number := h.PlaceOrder()
+	intentID := strings.TrimPrefix(h.StartPayment(number), "/payments/fake/")
+
+	payload, err := json.Marshal(payment.Event{
+		ID:          "evt_apptest_1",
+		Type:        payment.EventSucceeded,
+		IntentID:    intentID,
+		OrderNumber: number,
+		Status:      payment.IntentSucceeded,
+		Created:     time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(signature string) *apptest.Response {
+		req := httptest.NewRequest(http.MethodPost, "/payments/webhook", strings.NewReader(string(payload)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(payment.SignatureHeader, signature)
+		// Провайдер ходит без cookie сессии и CSRF-токена
+		return h.NewClient().Do(req)
+	}
+
+	if res := post(payment.Sign([]byte("wrong-secret"), payload, time.Now())); res.Code != http.StatusBadRequest {
+		t.Errorf("чужая подпись: status %d, want 400", res.Code)
+	}
+
+	for i, duplicate := range []bool{false, true} {
+		res := post(payment.Sign([]byte(apptest.WebhookSecret), payload, time.Now()))
+		var got struct {
+			Received  bool `json:"received"`
+			Duplicate bool `json:"duplicate"`
+		}
+		if res.Code != http.StatusOK || res.JSON(&got) != nil || !got.Received || got.Duplicate != duplicate {
+			t.Errorf("доставка %d: status %d, body %s; want duplicate=%v", i+1, res.Code, res.Body, duplicate)
+		}
+	}
+
+	order, err := h.Repos.Orders.GetByNumber(context.Background(), number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != storage.OrderPaid {
+		t.Errorf("статус заказа %q, want %q", order.Status, storage.OrderPaid)
+	}
+}
+
+// TestWebhookOrderConflict — оплата отменённого заказа: событие подтверждается (200, conflict),
+// заказ остаётся отменённым, повторная доставка — дубликат
+func TestWebhookOrderConflict(t *testing.T) {
+	h := apptest.New(t)
+	number := h.PlaceOrder()
+	intentID := strings.TrimPrefix(h.StartPayment(number), "/payments/fake/")
+
+	ctx := context.Background()
+	order, err := h.Repos.Orders.GetByNumber(ctx, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Repos.Orders.UpdateStatus(ctx, order.ID, storage.OrderCancelled, "тест"); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(payment.Event{
+		ID:          "evt_apptest_late",
+		Type:        payment.EventSucceeded,
+		IntentID:    intentID,
+		OrderNumber: number,
+		Status:      payment.IntentSucceeded,
+		Created:     time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, duplicate := range []bool{false, true} {
+		req := httptest.NewRequest(http.MethodPost, "/payments/webhook", strings.NewReader(string(payload)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(payment.SignatureHeader, payment.Sign([]byte(apptest.WebhookSecret), payload, time.Now()))
+		res := h.NewClient().Do(req)
+
+		var got struct {
+			Duplicate bool `json:"duplicate"`
+			Conflict  bool `json:"conflict"`
+		}
+		if res.Code != http.StatusOK || res.JSON(&got) != nil || got.Duplicate != duplicate || got.Conflict == duplicate {
+			t.Errorf("доставка %d: status %d, body %s", i+1, res.Code, res.Body)
+		}
+	}
+
+	if order, _ = h.Repos.Orders.GetByNumber(ctx, number); order.Status != storage.OrderCancelled {
+		t.Errorf("статус заказа %q, want %q", order.Status, storage.OrderCancelled)
+	}
+	payments, err := h.Repos.Payments.ListByOrder(ctx, order.ID)
+	if err != nil || len(payments) == 0 || payments[0].Status != string(payment.IntentSucceeded) {
+		t.Errorf("платёж: %+v %v — статус провайдера должен сохраниться для возврата", payments, err)
+	}
+}
+
+// deliverWebhook — подписанное событие провайдера на /payments/webhook; ответ — флаги received/duplicate/conflict
+func deliverWebhook(t *testing.T, h *apptest.Harness, ev payment.Event) (duplicate, conflict bool) {
+	t.Helper()
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhook", strings.NewReader(string(payload)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payment.SignatureHeader, payment.Sign([]byte(apptest.WebhookSecret), payload, time.Now()))
+	res := h.NewClient().Do(req)
+
+	var got struct {
+		Duplicate bool `json:"duplicate"`
+		Conflict  bool `json:"conflict"`
+	}
+	if res.Code != http.StatusOK || res.JSON(&got) != nil {
+		t.Fatalf("webhook %s: status %d, body %s", ev.ID, res.Code, res.Body)
+	}
+	return got.Duplicate, got.Conflict
+}
+
+// TestWebhookSecondIntent — покупатель начал две оплаты одного заказа и прошли обе: заказ оплачен
+// первой, успех второй — конфликт (двойное списание на возврат), её возврат заказ не трогает
+func TestWebhookSecondIntent(t *testing.T) {
+	h := apptest.New(t)
+	ctx := context.Background()
+	number := h.PlaceOrder() // Товар 1 × 2 из 25
+	first := h.StartPayment(number)
+	second := strings.TrimPrefix(h.StartPayment(number), "/payments/fake/")
+
+	if res := h.PostForm(first, url.Values{"card": {payment.CardSuccess}}); !strings.HasSuffix(res.Location(), "?payment=success") {
+		t.Fatalf("первая оплата: status %d, Location %q", res.Code, res.Location())
+	}
+
+	ev := payment.Event{ID: "evt_apptest_second", Type: payment.EventSucceeded, IntentID: second,
+		OrderNumber: number, Status: payment.IntentSucceeded, Created: time.Now().Unix()}
+	if _, conflict := deliverWebhook(t, h, ev); !conflict {
+		t.Error("успех второго платежа не отмечен конфликтом")
+	}
+
+	order, _ := h.Repos.Orders.GetByNumber(ctx, number)
+	if order.Status != storage.OrderPaid || order.PaidIntentID == nil || *order.PaidIntentID != strings.TrimPrefix(first, "/payments/fake/") {
+		t.Errorf("заказ: статус %q, платёж %v; want paid первым платежом", order.Status, order.PaidIntentID)
+	}
+	if moves, _ := h.Repos.Inventory.Movements(ctx, "1", 10); len(moves) != 1 {
+		t.Errorf("списаний остатка %d, want 1", len(moves))
+	}
+
+	// Возврат лишнего платежа — заказ остаётся оплаченным
+	ev = payment.Event{ID: "evt_apptest_second_refund", Type: payment.EventRefunded, IntentID: second,
+		OrderNumber: number, Status: payment.IntentRefunded, Created: time.Now().Unix()}
+	if _, conflict := deliverWebhook(t, h, ev); conflict {
+		t.Error("возврат второго платежа отмечен конфликтом")
+	}
+	if order, _ = h.Repos.Orders.GetByNumber(ctx, number); order.Status != storage.OrderPaid {
+		t.Errorf("статус после возврата второго платежа %q, want %q", order.Status, storage.OrderPaid)
+	}
+}
@@ -0,0 +1,149 @@
+package server_test
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"myApp/internal/apptest"
+	"myApp/internal/money"
+)
+
+// TestProductVariants — выбор варианта, строки корзины по вариантам и снимок варианта в заказе
+func TestProductVariants(t *testing.T) {
+	h := apptest.New(t)
+
+	// Страница товара: выбор варианта; в каталоге вместо "В корзину" — ссылка на товар
+	page := html.UnescapeString(h.Get("/product/5").Body)
+	for _, want := range []string{`name="variant_id"`, "Цвет: Чёрный, Переключатели: Red", "Цвет: Белый, Переключатели: Brown"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("/product/5: нет %q", want)
+		}
+	}
+	if !strings.Contains(h.Get("/catalog/peripherals").Body, "Выбрать вариант") {
+		t.Error("/catalog/peripherals: нет ссылки на выбор варианта")
+	}
+
+	// Вариант обязателен и должен принадлежать товару
+	for _, form := range []url.Values{
+		{"product_id": {"5"}},
+		{"product_id": {"5"}, "variant_id": {"99"}},
+		{"product_id": {"1"}, "variant_id": {"1"}},
+	} {
+		res := h.PostForm("/cart/add", form)
+		if p, ok := res.Problem(); res.Code != http.StatusBadRequest || !ok || p.Fields["variant_id"] == "" {
+			t.Errorf("%v: status %d, problem %+v; want 400 с ошибкой variant_id", form, res.Code, p)
+		}
+	}
+
+	add := func(variant, qty string) {
+		t.Helper()
+		res := h.PostForm("/cart/add", url.Values{"product_id": {"5"}, "variant_id": {variant}, "quantity": {qty}})
+		if res.Code != http.StatusSeeOther {
+			t.Fatalf("вариант %s: status %d, body %.300s", variant, res.Code, res.Body)
+		}
+	}
+	add("1", "2")
+	add("2", "1")
+	add("1", "1")
+
+	cart := html.UnescapeString(h.Get("/cart").Body)
+	for _, want := range []string{"ART-005-BLK-RED", "ART-005-BLK-BRN", `value="3"`, `id="qty-5-1"`, `name="variant_id" value="2"`} {
+		if !strings.Contains(cart, want) {
+			t.Errorf("корзина: нет %q", want)
+		}
+	}
+
+	// Позиции вариантов меняются и удаляются независимо
+	h.PostForm("/cart/remove", url.Values{"product_id": {"5"}, "variant_id": {"2"}})
+	if cart := h.Get("/cart").Body; strings.Contains(cart, "ART-005-BLK-BRN") || !strings.Contains(cart, "ART-005-BLK-RED") {
+		t.Error("удаление варианта задело другую позицию")
+	}
+
+	// Заказ хранит вариант, его опции и SKU; цена — по варианту
+	add("2", "1")
+	h.FillCheckout("pickup")
+	res := h.PostForm("/checkout/confirm", nil)
+	number, ok := strings.CutPrefix(res.Location(), "/orders/")
+	if !ok {
+		t.Fatalf("confirm: status %d, Location %q", res.Code, res.Location())
+	}
+	order, err := h.Repos.Orders.GetByNumber(context.Background(), number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Items) != 2 {
+		t.Fatalf("позиций в заказе %d, want 2", len(order.Items))
+	}
+	red, brown := order.Items[0], order.Items[1]
+	if red.VariantID == nil || *red.VariantID != "1" || red.Article != "ART-005-BLK-RED" || red.Quantity != 3 ||
+		!red.UnitPrice.Equal(money.MustParse("129.00", "")) {
+		t.Errorf("позиция варианта 1: %+v", red)
+	}
+	if brown.Variant == nil || *brown.Variant != "Цвет: Чёрный, Переключатели: Brown" || brown.Article != "ART-005-BLK-BRN" {
+		t.Errorf("позиция варианта 2: %+v", brown)
+	}
+	if !order.Total.Equal(money.MustParse("516.00", "")) {
+		t.Errorf("итого %s, want 516.00", order.Total)
+	}
+	if body := html.UnescapeString(h.Get("/orders/" + number).Body); !strings.Contains(body, "Цвет: Чёрный, Переключатели: Red") {
+		t.Error("на странице заказа нет опций варианта")
+	}
+}
+
+// TestCatalogJSONVariants — варианты вложены в товар; у товара без вариантов поля нет
+func TestCatalogJSONVariants(t *testing.T) {
+	h := apptest.New(t)
+
+	var page struct {
+		Items []struct {
+			ID       string        `json:"id"`
+			Variants []variantJSON `json:"variants"`
+		} `json:"items"`
+	}
+	res := h.Get("/catalog/json?page_size=100")
+	if err := res.JSON(&page); err != nil {
+		t.Fatalf("status %d: %v", res.Code, err)
+	}
+
+	for _, p := range page.Items {
+		switch p.ID {
+		case "5":
+			if len(p.Variants) != 3 {
+				t.Fatalf("вариантов у товара 5: %d, want 3", len(p.Variants))
+			}
+			v := p.Variants[2]
+			if v.SKU != "ART-005-WHT-BRN" || !v.Price.Equal(money.MustParse("139.00", "")) || v.Available != 0 || len(v.Options) != 2 ||
+				v.Options[0].Type != "color" || v.Options[0].Value != "Белый" {
+				t.Errorf("вариант 3: %+v", v)
+			}
+		case "1":
+			if p.Variants != nil {
+				t.Errorf("у товара без вариантов variants = %+v", p.Variants)
+			}
+		}
+	}
+	if strings.Count(res.Body, `"variants"`) != 1 {
+		t.Errorf("поле variants должно быть только у товара 5: %s", res.Body)
+	}
+	// Складской остаток не публикуется — ни у товара, ни у варианта
+	if strings.Contains(res.Body, `"stock"`) {
+		t.Errorf("stock в JSON каталога: %.300s", res.Body)
+	}
+}
+
+// variantJSON — вариант в /catalog/json
+type variantJSON struct {
+	ID        string      `json:"id"`
+	SKU       string      `json:"sku"`
+	Price     money.Money `json:"price"`
+	Available int         `json:"available"`
+	Options   []struct {
+		Type  string `json:"type"`
+		Title string `json:"title"`
+		Value string `json:"value"`
+	} `json:"options"`
+}
@@ -0,0 +1,185 @@
+package images
+
+// images.go — изображения товаров: проверка загруженного файла (тип — по содержимому,
+// размер растра ограничен) и миниатюры нескольких ширин в JPEG и WebP для <img srcset>.
+// Только чистый Go: масштабирование — golang.org/x/image/draw, WebP — nativewebp (без cgo).
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Декодеры регистрируются в image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strconv"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Widths — ширины миниатюр (px). Исходник уже самой большой ширины не растягивается:
+// его ширина становится последней миниатюрой (см. WidthsFor).
+var Widths = []int{320, 640, 1280}
+
+// Форматы миниатюр — расширения файлов
+const (
+	JPEG = "jpg"
+	WebP = "webp" // Без потерь: чистый Go умеет только VP8L
+)
+
+// MaxPixels — предел ширина×высота исходника: маленький файл может раскрыться в гигантский растр
+const MaxPixels = 40_000_000
+
+// jpegQuality — качество JPEG-миниатюр
+const jpegQuality = 82
+
+// formats — разрешённые типы (по http.DetectContentType) → имя формата в image.Decode
+var formats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif", // Анимация — только первый кадр
+	"image/webp": "webp",
+}
+
+var (
+	// ErrUnsupported — файл не JPEG, PNG, GIF или WebP (по содержимому, а не по имени)
+	ErrUnsupported = errors.New("images: неподдерживаемый формат")
+	// ErrTooLarge — растр больше MaxPixels
+	ErrTooLarge = errors.New("images: слишком большое изображение")
+	// ErrCorrupt — заголовок или данные изображения повреждены
+	ErrCorrupt = errors.New("images: не удалось прочитать изображение")
+)
+
+// Rendition — одна миниатюра
+type Rendition struct {
+	Name   string // Ключ в BlobStore: <key>-<ширина>.<jpg|webp>
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Set — миниатюры одного изображения
+type Set struct {
+	Key        string // Префикс имён (Product.ImageKey)
+	Width      int    // Размер самой большой миниатюры
+	Height     int
+	Renditions []Rendition
+}
+
+// Sniff — MIME-тип по первым байтам файла; не изображение из списка — ErrUnsupported
+func Sniff(data []byte) (string, error) {
+	ct := http.DetectContentType(data)
+	if _, ok := formats[ct]; !ok {
+		return ct, fmt.Errorf("%w: %s", ErrUnsupported, ct)
+	}
+	return ct, nil
+}
+
+// Thumbnails — проверяет исходник и строит миниатюры всех ширин (WidthsFor) в JPEG и WebP.
+// Размер растра проверяется по заголовку до декодирования.
+func Thumbnails(key string, data []byte) (*Set, error) {
+	ct, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	// Декодер выбирается по содержимому ещё раз — формат должен совпасть с Sniff
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != formats[ct] || cfg.Width < 1 || cfg.Height < 1 {
+		return nil, ErrCorrupt
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: %d×%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	b := src.Bounds()
+	widths := WidthsFor(min(b.Dx(), Widths[len(Widths)-1]))
+	set := &Set{Key: key}
+
+	// От большей миниатюры к меньшей: каждая уменьшается из предыдущей — быстрее, чем из исходника
+	prev := src
+	for i := len(widths) - 1; i >= 0; i-- {
+		w := widths[i]
+		h := max(1, (b.Dy()*w+b.Dx()/2)/b.Dx())
+		img := resize(prev, w, h)
+		prev = img
+		if i == len(widths)-1 {
+			set.Width, set.Height = w, h
+		}
+
+		var jpg, wp bytes.Buffer
+		if err := jpeg.Encode(&jpg, flatten(img), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		if err := nativewebp.Encode(&wp, img, nil); err != nil {
+			return nil, err
+		}
+		set.Renditions = append(set.Renditions,
+			Rendition{Name: Name(key, w, JPEG), Width: w, Height: h, Data: jpg.Bytes()},
+			Rendition{Name: Name(key, w, WebP), Width: w, Height: h, Data: wp.Bytes()},
+		)
+	}
+	return set, nil
+}
+
+// WidthsFor — ширины миниатюр изображения, самая большая из которых — maxWidth:
+// WidthsFor(1280) = [320 640 1280], WidthsFor(500) = [320 500]
+func WidthsFor(maxWidth int) []int {
+	var out []int
+	for _, w := range Widths {
+		if w < maxWidth {
+			out = append(out, w)
+		}
+	}
+	return append(out, maxWidth)
+}
+
+// Name — ключ миниатюры ширины width в формате format (JPEG или WebP)
+func Name(key string, width int, format string) string {
+	return key + "-" + strconv.Itoa(width) + "." + format
+}
+
+// Names — ключи всех миниатюр изображения (для удаления)
+func Names(key string, maxWidth int) []string {
+	var out []string
+	for _, w := range WidthsFor(maxWidth) {
+		out = append(out, Name(key, w, JPEG), Name(key, w, WebP))
+	}
+	return out
+}
+
+// NewKey — новый случайный ключ изображения: "products/9f86d081884c7d659a2feaa0".
+// Имя никогда не повторяется, поэтому файлы можно кэшировать навсегда.
+func NewKey(prefix string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return prefix + "/" + hex.EncodeToString(b)
+}
+
+// resize — изображение w×h (Catmull-Rom; тот же размер — просто копия в RGBA)
+func resize(src image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if src.Bounds().Dx() == w && src.Bounds().Dy() == h {
+		draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+		return dst
+	}
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// flatten — прозрачность на белом фоне: JPEG без альфа-канала сделал бы её чёрной
+func flatten(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Over)
+	return dst
+}
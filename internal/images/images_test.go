@@ -0,0 +1,110 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testPNG — PNG w×h с полупрозрачным левым краем
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := uint8(255)
+			if x < w/4 {
+				a = 0
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: a})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// TestThumbnails — ширины без увеличения, пропорции, оба формата декодируются
+func TestThumbnails(t *testing.T) {
+	tests := []struct {
+		w, h       int
+		widths     []int
+		maxW, maxH int
+	}{
+		{2000, 1000, []int{320, 640, 1280}, 1280, 640},
+		{500, 700, []int{320, 500}, 500, 700},
+		{100, 50, []int{100}, 100, 50},
+	}
+	for _, tt := range tests {
+		set, err := Thumbnails("products/abc", testPNG(t, tt.w, tt.h))
+		if err != nil {
+			t.Fatalf("%d×%d: %v", tt.w, tt.h, err)
+		}
+		if set.Width != tt.maxW || set.Height != tt.maxH {
+			t.Errorf("%d×%d: самая большая миниатюра %d×%d, want %d×%d", tt.w, tt.h, set.Width, set.Height, tt.maxW, tt.maxH)
+		}
+		if len(set.Renditions) != 2*len(tt.widths) {
+			t.Fatalf("%d×%d: %d миниатюр, want %d", tt.w, tt.h, len(set.Renditions), 2*len(tt.widths))
+		}
+
+		for _, r := range set.Renditions {
+			var cfg image.Config
+			var err error
+			if r.Name == Name("products/abc", r.Width, WebP) {
+				cfg, err = webp.DecodeConfig(bytes.NewReader(r.Data))
+			} else {
+				cfg, err = jpeg.DecodeConfig(bytes.NewReader(r.Data))
+			}
+			if err != nil || cfg.Width != r.Width || cfg.Height != r.Height {
+				t.Errorf("%s: %v, %d×%d", r.Name, err, cfg.Width, cfg.Height)
+			}
+		}
+	}
+
+	// Прозрачность в JPEG — белый фон, а не чёрный
+	set, _ := Thumbnails("products/abc", testPNG(t, 400, 400))
+	img, err := jpeg.Decode(bytes.NewReader(set.Renditions[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := img.At(2, 2).RGBA(); r>>8 < 240 || g>>8 < 240 || b>>8 < 240 {
+		t.Errorf("прозрачный пиксель в JPEG: %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestThumbnailsRejects — не изображение, повреждённый файл и "бомба" по размеру растра
+func TestThumbnailsRejects(t *testing.T) {
+	valid := testPNG(t, 10, 10)
+
+	// Заголовок IHDR объявляет 20000×20000 (контрольная сумма пересчитана)
+	bomb := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint32(bomb[16:], 20000)
+	binary.BigEndian.PutUint32(bomb[20:], 20000)
+	binary.BigEndian.PutUint32(bomb[29:], crc32.ChecksumIEEE(bomb[12:29]))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"текст", []byte("<?php echo 'hi'; ?>"), ErrUnsupported},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ErrUnsupported},
+		{"обрезанный png", valid[:40], ErrCorrupt},
+		{"20000×20000", bomb, ErrTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := Thumbnails("products/abc", tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package mail
+
+// dir.go — "отправка" в каталог: каждое письмо — .eml-файл (открывается почтовым клиентом).
+// Для разработки и тестов: ссылки подтверждения и сброса пароля берутся из файла.
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+)
+
+// Dir — Mailer, сохраняющий письма в каталог
+type Dir struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+// NewDir — письма в каталог dir (создаётся при первой отправке) от имени from
+func NewDir(dir, from string) *Dir {
+	return &Dir{dir: dir, from: from, now: time.Now}
+}
+
+// Send — записывает письмо в <dir>/<время>-<случайное>.eml
+func (d *Dir) Send(_ context.Context, msg Message) error {
+	now := d.now()
+	raw, _, to, err := build(d.from, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.dir, 0o750); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	path := filepath.Join(d.dir, name)
+	// 0600: в письмах одноразовые ссылки входа в аккаунт
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		core.LogError("Ошибка записи письма", map[string]interface{}{"path": path, "error": err.Error()})
+		return err
+	}
+
+	core.LogInfo("Письмо сохранено", map[string]interface{}{"to": to, "subject": msg.Subject, "path": path})
+	return nil
+}
+
+// ReadDir — письма из каталога Dir в порядке отправки (тела и темы раскодированы)
+func ReadDir(dir string) ([]Message, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths) // Имя начинается со времени отправки
+
+	list := make([]Message, 0, len(paths))
+	for _, path := range paths {
+		msg, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		list = append(list, msg)
+	}
+	return list, nil
+}
+
+func readFile(path string) (Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Message{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	m, err := mail.ReadMessage(f)
+	if err != nil {
+		return Message{}, err
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		return Message{}, err
+	}
+
+	var body io.Reader = m.Body
+	if strings.EqualFold(m.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(m.Body)
+	}
+	text, err := io.ReadAll(body)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      m.Header.Get("To"),
+		Subject: subject,
+		Text:    strings.ReplaceAll(string(text), "\r\n", "\n"),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package mail
+
+// mail.go — письма покупателям. Обработчики знают только интерфейс Mailer:
+// в проде за ним SMTP (smtp.go), в разработке и тестах — .eml-файлы в каталоге (dir.go).
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+)
+
+// Message — текстовое письмо одному получателю
+type Message struct {
+	To      string // Адрес получателя ("anna@example.com" или "Анна <anna@example.com>")
+	Subject string
+	Text    string // Тело (text/plain, UTF-8)
+}
+
+// Mailer — отправка писем
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New — Mailer по MAIL_DRIVER
+func New(cfg core.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case core.MailSMTP:
+		return NewSMTP(cfg), nil
+	case core.MailDir:
+		return NewDir(cfg.Dir, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("неизвестный MAIL_DRIVER %q", cfg.Driver)
+	}
+}
+
+// ErrInvalidAddress — адрес, который нельзя подставить в заголовок письма
+var ErrInvalidAddress = errors.New("mail: некорректный адрес")
+
+// build — письмо в формате RFC 5322: заголовки в UTF-8 (RFC 2047), тело quoted-printable.
+// Возвращает также "голые" адреса отправителя и получателя для SMTP-конверта.
+func build(from string, msg Message, now time.Time) (raw []byte, fromAddr, toAddr string, err error) {
+	f, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%w: MAIL_FROM %q", ErrInvalidAddress, from)
+	}
+	t, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%w: %q", ErrInvalidAddress, msg.To)
+	}
+	// Перевод строки в теме — попытка внедрить заголовки
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, "", "", errors.New("mail: перевод строки в теме письма")
+	}
+
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", f.String())
+	header("To", t.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID(f.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	text := strings.ReplaceAll(strings.ReplaceAll(msg.Text, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return nil, "", "", err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, "", "", err
+	}
+	return b.Bytes(), f.Address, t.Address, nil
+}
+
+// messageID — уникальный Message-ID в домене отправителя
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
@@ -0,0 +1,145 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"myApp/internal/core"
+)
+
+var testMsg = Message{
+	To:      "Анна <anna@example.com>",
+	Subject: "Подтвердите email",
+	Text:    "Здравствуйте, Анна!\n\nСсылка: https://shop.example/account/verify?token=abc_DEF-123=\n",
+}
+
+// TestDirRoundTrip — письмо в .eml и обратно: кириллица в теме и теле, длинные строки
+func TestDirRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDir(dir, "Shop <no-reply@shop.example>")
+
+	long := testMsg
+	long.Text += strings.Repeat("длинная строка ", 20) + "\n"
+	if err := d.Send(context.Background(), testMsg); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Send(context.Background(), long); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("писем %d, want 2", len(list))
+	}
+	got := list[0]
+	if got.Subject != testMsg.Subject || got.Text != testMsg.Text || !strings.Contains(got.To, "anna@example.com") {
+		t.Errorf("got %+v, want %+v", got, testMsg)
+	}
+	if list[1].Text != long.Text {
+		t.Errorf("длинное письмо искажено: %q", list[1].Text)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("права файла %o, want 600", perm)
+	}
+}
+
+// TestBuildRejectsInjection — перевод строки в адресе или теме не попадает в заголовки
+func TestBuildRejectsInjection(t *testing.T) {
+	for _, msg := range []Message{
+		{To: "anna@example.com\r\nBcc: victim@example.com", Subject: "x"},
+		{To: "anna@example.com", Subject: "x\r\nBcc: victim@example.com"},
+		{To: "not an address", Subject: "x"},
+	} {
+		if _, _, _, err := build("no-reply@shop.example", msg, time.Now()); err == nil {
+			t.Errorf("build(%q, %q) без ошибки", msg.To, msg.Subject)
+		}
+	}
+}
+
+// TestSMTPSend — диалог с SMTP-сервером без TLS (локальный сервер-заглушка)
+func TestSMTPSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	type envelope struct {
+		from, to, data string
+	}
+	got := make(chan envelope, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		tp := textproto.NewConn(conn)
+		var env envelope
+
+		_ = tp.PrintfLine("220 test ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				_ = tp.PrintfLine("250 test")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				env.from = line[len("MAIL FROM:"):]
+				_ = tp.PrintfLine("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				env.to = line[len("RCPT TO:"):]
+				_ = tp.PrintfLine("250 OK")
+			case cmd == "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				b, _ := tp.ReadDotBytes()
+				env.data = string(b)
+				_ = tp.PrintfLine("250 queued")
+			case cmd == "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				got <- env
+				return
+			default:
+				_ = tp.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	s := NewSMTP(core.MailConfig{From: "Shop <no-reply@shop.example>", SMTPHost: host, SMTPPort: p, SMTPTimeout: 5 * time.Second})
+	if err := s.Send(context.Background(), testMsg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case env := <-got:
+		if env.from != "<no-reply@shop.example>" || env.to != "<anna@example.com>" {
+			t.Errorf("конверт %q → %q", env.from, env.to)
+		}
+		if !strings.Contains(env.data, "Subject: =?utf-8?q?") || !strings.Contains(env.data, "quoted-printable") {
+			t.Errorf("письмо без кодирования заголовков/тела:\n%s", env.data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("сервер не получил QUIT")
+	}
+}
@@ -0,0 +1,110 @@
+package mail
+
+// smtp.go — отправка через SMTP-сервер: порт 465 — implicit TLS, иначе STARTTLS, если сервер
+// его поддерживает. Без TLS smtp.PlainAuth не отправит пароль никуда, кроме localhost.
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"myApp/internal/core"
+)
+
+// SMTP — Mailer поверх SMTP-сервера
+type SMTP struct {
+	cfg core.MailConfig
+	now func() time.Time
+}
+
+// NewSMTP — Mailer с настройками MAIL_FROM и SMTP_*
+func NewSMTP(cfg core.MailConfig) *SMTP {
+	return &SMTP{cfg: cfg, now: time.Now}
+}
+
+// Send — одно письмо в отдельном SMTP-соединении (писем мало: регистрация, сброс пароля)
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	raw, from, to, err := build(s.cfg.From, msg, s.now())
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.SMTPTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.SMTPTimeout)
+		defer cancel()
+	}
+
+	if err := s.send(ctx, from, to, raw); err != nil {
+		core.LogError("Ошибка отправки письма", map[string]interface{}{
+			"host":  s.cfg.SMTPHost,
+			"to":    to,
+			"error": err.Error(),
+		})
+		return err
+	}
+	core.LogInfo("Письмо отправлено", map[string]interface{}{"to": to, "subject": msg.Subject})
+	return nil
+}
+
+func (s *SMTP) send(ctx context.Context, from, to string, raw []byte) error {
+	addr := net.JoinHostPort(s.cfg.SMTPHost, strconv.Itoa(s.cfg.SMTPPort))
+	tlsCfg := &tls.Config{ServerName: s.cfg.SMTPHost, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.SMTPPort == 465 {
+		conn, err = (&tls.Dialer{Config: tlsCfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp: подключение к %s: %w", addr, err)
+	}
+	// Дедлайн контекста действует на весь диалог, а не только на подключение
+	if d, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(d)
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.SMTPHost)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp: приветствие: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if _, isTLS := conn.(*tls.Conn); !isTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsCfg); err != nil {
+				return fmt.Errorf("smtp: STARTTLS: %w", err)
+			}
+		}
+	}
+	if s.cfg.SMTPUser != "" {
+		// PlainAuth сам откажет без TLS на чужом хосте
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPassword, s.cfg.SMTPHost)); err != nil {
+			return fmt.Errorf("smtp: аутентификация: %w", err)
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return fmt.Errorf("smtp: MAIL FROM: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("smtp: RCPT TO: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: DATA: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("smtp: запись письма: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: завершение DATA: %w", err)
+	}
+	return c.Quit()
+}
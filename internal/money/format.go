@@ -0,0 +1,99 @@
+package money
+
+// format.go — валюты и форматирование сумм для людей с учётом локали
+import "strings"
+
+// DefaultLocale — локаль форматирования по умолчанию (SHOP_LOCALE)
+var DefaultLocale = "ru"
+
+// currency — знаков после запятой и символ валюты
+type currency struct {
+	digits int
+	symbol string
+}
+
+// currencies — поддерживаемые валюты ISO 4217
+var currencies = map[string]currency{
+	"EUR": {digits: 2, symbol: "€"},
+	"USD": {digits: 2, symbol: "$"},
+	"GBP": {digits: 2, symbol: "£"},
+	"SEK": {digits: 2, symbol: "kr"},
+	"NOK": {digits: 2, symbol: "kr"},
+	"RUB": {digits: 2, symbol: "₽"},
+	"JPY": {digits: 0, symbol: "¥"},
+}
+
+// currencyOf — параметры валюты (неизвестная — 2 знака, символ = код)
+func currencyOf(code string) currency {
+	if c, ok := currencies[code]; ok {
+		return c
+	}
+	return currency{digits: 2, symbol: code}
+}
+
+// IsKnownCurrency — поддерживается ли валюта
+func IsKnownCurrency(code string) bool {
+	_, ok := currencies[strings.ToUpper(code)]
+	return ok
+}
+
+// locale — разделители и положение символа валюты
+type locale struct {
+	group   string // Разделитель тысяч
+	decimal string // Десятичный разделитель
+	prefix  bool   // Символ перед суммой ($1,234.50), иначе после (1 234,50 €)
+}
+
+// locales — поддерживаемые локали (язык без региона)
+var locales = map[string]locale{
+	"ru": {group: " ", decimal: ","},
+	"fi": {group: " ", decimal: ","},
+	"sv": {group: " ", decimal: ","},
+	"de": {group: ".", decimal: ","},
+	"en": {group: ",", decimal: ".", prefix: true},
+}
+
+// Format — сумма для показа: "1 234,50 €" (ru, fi), "€1,234.50" (en).
+// Неизвестная локаль — DefaultLocale; "fi-FI" и "fi_FI" сводятся к "fi".
+func (m Money) Format(loc string) string {
+	l, ok := locales[baseLanguage(loc)]
+	if !ok {
+		l = locales[baseLanguage(DefaultLocale)]
+	}
+	c := currencyOf(m.Currency())
+
+	dec := m.Decimal()
+	sign := ""
+	if strings.HasPrefix(dec, "-") {
+		sign = "-"
+		dec = dec[1:]
+	}
+	intPart, frac, _ := strings.Cut(dec, ".")
+
+	// Группы по три цифры справа налево
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteRune(r)
+	}
+	num := b.String()
+	if frac != "" {
+		num += l.decimal + frac
+	}
+
+	if l.prefix {
+		return sign + c.symbol + num
+	}
+	return sign + num + " " + c.symbol
+}
+
+// baseLanguage — "fi-FI" → "fi"
+func baseLanguage(loc string) string {
+	loc = strings.ToLower(strings.TrimSpace(loc))
+	if i := strings.IndexAny(loc, "-_"); i > 0 {
+		loc = loc[:i]
+	}
+	return loc
+}
@@ -0,0 +1,258 @@
+package money
+
+// money.go — денежная сумма: целое число минимальных единиц (центов) + код валюты ISO 4217.
+// Никаких float64: суммы строк и итоги корзины/заказа складываются без дрейфа округления.
+// В MySQL пишется и читается как DECIMAL ("12.34"), в JSON — {"amount":"12.34","currency":"EUR"}.
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency — валюта сумм без явной валюты (цены каталога, нулевое значение Money).
+// Задаётся при старте из конфигурации (SHOP_CURRENCY).
+var DefaultCurrency = "EUR"
+
+// maxDigits — предел значащих цифр (с запасом укладывается в int64)
+const maxDigits = 15
+
+// Money — сумма в минимальных единицах валюты. Нулевое значение — 0 в DefaultCurrency.
+type Money struct {
+	minor    int64
+	currency string
+}
+
+// Ошибки разбора суммы
+var (
+	ErrInvalid   = errors.New("money: некорректная сумма")
+	ErrPrecision = errors.New("money: слишком много знаков после запятой")
+	ErrCurrency  = errors.New("money: неизвестная валюта")
+)
+
+// New — сумма из минимальных единиц (для EUR — центов)
+func New(minor int64, currency string) Money {
+	return Money{minor: minor, currency: strings.ToUpper(currency)}
+}
+
+// Parse — сумма из десятичной строки: "12", "12.3", "12,30", "-0.99".
+// Больше знаков после запятой, чем у валюты, — ошибка (а не тихое округление).
+func Parse(s, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	c, ok := currencies[currency]
+	if !ok {
+		return Money{}, ErrCurrency
+	}
+
+	s = strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, frac, _ := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	if intPart == "" && frac == "" {
+		return Money{}, ErrInvalid
+	}
+	// Сначала символы: "1.2.3" — некорректная сумма, а не лишние знаки
+	for _, r := range intPart + frac {
+		if r < '0' || r > '9' {
+			return Money{}, ErrInvalid
+		}
+	}
+	if len(frac) > c.digits {
+		// Хвостовые нули не меняют сумму: "12.300" в EUR допустимо (так MySQL отдаёт DECIMAL(10,3))
+		trimmed := strings.TrimRight(frac[c.digits:], "0")
+		if trimmed != "" {
+			return Money{}, ErrPrecision
+		}
+		frac = frac[:c.digits]
+	}
+	frac += strings.Repeat("0", c.digits-len(frac))
+
+	digits := intPart + frac
+	if digits == "" || len(strings.TrimLeft(digits, "0")) > maxDigits {
+		return Money{}, ErrInvalid
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalid
+	}
+	if neg {
+		n = -n
+	}
+	return Money{minor: n, currency: currency}, nil
+}
+
+// MustParse — Parse для констант в коде (паникует на ошибке)
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(fmt.Sprintf("money.MustParse(%q, %q): %v", s, currency, err))
+	}
+	return m
+}
+
+// Minor — сумма в минимальных единицах
+func (m Money) Minor() int64 { return m.minor }
+
+// Currency — код валюты (для нулевого значения — DefaultCurrency)
+func (m Money) Currency() string {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+// WithCurrency — та же сумма в минимальных единицах, но в другой валюте
+// (например, цены из БД, у которых валюта хранится в отдельной колонке)
+func (m Money) WithCurrency(currency string) Money {
+	if currency == "" {
+		return m
+	}
+	return Money{minor: m.minor, currency: strings.ToUpper(currency)}
+}
+
+// IsZero — сумма равна нулю
+func (m Money) IsZero() bool { return m.minor == 0 }
+
+// IsPositive — сумма больше нуля
+func (m Money) IsPositive() bool { return m.minor > 0 }
+
+// IsNegative — сумма меньше нуля
+func (m Money) IsNegative() bool { return m.minor < 0 }
+
+// Add — сумма двух сумм одной валюты (разные валюты — ошибка программиста, паника)
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{minor: m.minor + o.minor, currency: m.Currency()}
+}
+
+// Sub — разность двух сумм одной валюты
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{minor: m.minor - o.minor, currency: m.Currency()}
+}
+
+// Mul — умножение на количество
+func (m Money) Mul(n int64) Money {
+	return Money{minor: m.minor * n, currency: m.Currency()}
+}
+
+// Cmp — -1, 0, 1 (m < o, m == o, m > o)
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.minor < o.minor:
+		return -1
+	case m.minor > o.minor:
+		return 1
+	}
+	return 0
+}
+
+// Equal — равенство суммы и валюты
+func (m Money) Equal(o Money) bool {
+	return m.minor == o.minor && m.Currency() == o.Currency()
+}
+
+// Decimal — сумма десятичной строкой без символа валюты: "1234.50"
+func (m Money) Decimal() string {
+	digits := currencyOf(m.Currency()).digits
+	n := m.minor
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+	if digits == 0 {
+		return sign + strconv.FormatInt(n, 10)
+	}
+	p := int64(math.Pow10(digits))
+	return fmt.Sprintf("%s%d.%0*d", sign, n/p, digits, n%p)
+}
+
+// String — "1234.50 EUR" (для логов; для людей — Format)
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency()
+}
+
+// mustMatch — паника при смешивании валют
+func (m Money) mustMatch(o Money) {
+	if m.Currency() != o.Currency() {
+		panic("money: разные валюты " + m.Currency() + " и " + o.Currency())
+	}
+}
+
+// Value — запись в DECIMAL-колонку (driver.Valuer)
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// Scan — чтение из DECIMAL-колонки (sql.Scanner). Валюта — DefaultCurrency,
+// если у строки есть своя колонка валюты, её подставляют через WithCurrency.
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		*m = Money{minor: v * int64(math.Pow10(currencyOf(DefaultCurrency).digits)), currency: DefaultCurrency}
+		return nil
+	case float64:
+		// float приходит только от нестандартных драйверов; округляем до минимальных единиц
+		p := math.Pow10(currencyOf(DefaultCurrency).digits)
+		*m = Money{minor: int64(math.Round(v * p)), currency: DefaultCurrency}
+		return nil
+	default:
+		return fmt.Errorf("money: неподдерживаемый тип %T", src)
+	}
+
+	parsed, err := Parse(s, DefaultCurrency)
+	if err != nil {
+		return fmt.Errorf("money: scan %q: %w", s, err)
+	}
+	*m = parsed
+	return nil
+}
+
+// moneyJSON — JSON-представление: сумма строкой, чтобы клиенты не теряли точность на float
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON — {"amount":"12.34","currency":"EUR"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency()})
+}
+
+// UnmarshalJSON — обратное MarshalJSON
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	parsed, err := Parse(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
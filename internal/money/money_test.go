@@ -0,0 +1,194 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestParse — десятичная строка → минимальные единицы; лишние знаки не округляются, а отклоняются
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, currency string
+		minor        int64
+		err          error
+	}{
+		{"12", "EUR", 1200, nil},
+		{"12.3", "eur", 1230, nil},
+		{"12,30", "EUR", 1230, nil},
+		{" +0.99 ", "EUR", 99, nil},
+		{".5", "EUR", 50, nil},
+		{"12.300", "EUR", 1230, nil}, // Хвостовые нули DECIMAL(10,3)
+		{"1500", "JPY", 1500, nil},
+		{"-0.99", "EUR", -99, nil},
+		{"-1234.56", "USD", -123456, nil},
+		{"12.345", "EUR", 0, ErrPrecision},
+		{"0.001", "EUR", 0, ErrPrecision},
+		{"1500.5", "JPY", 0, ErrPrecision},
+		{"", "EUR", 0, ErrInvalid},
+		{"-", "EUR", 0, ErrInvalid},
+		{"1e3", "EUR", 0, ErrInvalid},
+		{"1.2.3", "EUR", 0, ErrInvalid},
+		{"--1", "EUR", 0, ErrInvalid},
+		{"1234567890123456", "EUR", 0, ErrInvalid}, // Больше maxDigits цифр
+		{"10", "XXX", 0, ErrCurrency},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.in, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q, %q): ошибка %v, ожидалась %v", tt.in, tt.currency, err, tt.err)
+			continue
+		}
+		if err == nil && m.Minor() != tt.minor {
+			t.Errorf("Parse(%q, %q) = %d, ожидалось %d", tt.in, tt.currency, m.Minor(), tt.minor)
+		}
+	}
+
+	if m := MustParse("5", ""); m.Currency() != DefaultCurrency {
+		t.Errorf("валюта по умолчанию: %s", m.Currency())
+	}
+}
+
+// TestNegative — знак в арифметике, сравнении и строковом виде
+func TestNegative(t *testing.T) {
+	price := MustParse("10.00", "EUR")
+	discount := MustParse("12.50", "EUR")
+
+	diff := price.Sub(discount)
+	if !diff.IsNegative() || diff.IsPositive() || diff.Minor() != -250 {
+		t.Fatalf("10.00 − 12.50 = %s", diff)
+	}
+	if got := diff.Decimal(); got != "-2.50" {
+		t.Errorf("Decimal: %s", got)
+	}
+	if got := MustParse("-0.05", "EUR").Decimal(); got != "-0.05" {
+		t.Errorf("Decimal меньше единицы: %s", got)
+	}
+	if diff.Cmp(New(0, "EUR")) != -1 || !diff.Add(discount).Equal(price) {
+		t.Errorf("Cmp/Add с отрицательной суммой: %s", diff)
+	}
+	if got := price.Mul(-3).Minor(); got != -3000 {
+		t.Errorf("Mul(-3) = %d", got)
+	}
+}
+
+// TestCurrencyMismatch — смешивание валют в арифметике — паника, Equal — просто false
+func TestCurrencyMismatch(t *testing.T) {
+	eur, usd := MustParse("1", "EUR"), MustParse("1", "USD")
+	for name, op := range map[string]func(){
+		"Add": func() { eur.Add(usd) },
+		"Sub": func() { eur.Sub(usd) },
+		"Cmp": func() { eur.Cmp(usd) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s EUR и USD без паники", name)
+				}
+			}()
+			op()
+		}()
+	}
+	if eur.Equal(usd) {
+		t.Error("1 EUR == 1 USD")
+	}
+
+	// Нулевое значение — DefaultCurrency: складывается с суммой в ней
+	var zero Money
+	if got := zero.Add(eur); !got.Equal(eur) {
+		t.Errorf("0 + 1 EUR = %s", got)
+	}
+}
+
+// TestSQL — Value пишет DECIMAL-строку, Scan читает её и прочие типы драйвера
+func TestSQL(t *testing.T) {
+	for _, in := range []string{"0.00", "12.30", "-7.05", "1234567.89"} {
+		v, err := MustParse(in, "EUR").Value()
+		if err != nil || v != in {
+			t.Errorf("Value(%s) = %v, %v", in, v, err)
+			continue
+		}
+		var m Money
+		if err := m.Scan([]byte(v.(string))); err != nil || m.Decimal() != in {
+			t.Errorf("Scan(%s) = %s, %v", in, m.Decimal(), err)
+		}
+	}
+
+	tests := []struct {
+		src   any
+		minor int64
+	}{
+		{"19.990", 1999},
+		{int64(5), 500},
+		{19.999, 2000}, // float округляется до центов
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		m := MustParse("1", "EUR")
+		if err := m.Scan(tt.src); err != nil || m.Minor() != tt.minor || m.Currency() != DefaultCurrency {
+			t.Errorf("Scan(%#v) = %s, %v; ожидалось %d", tt.src, m, err, tt.minor)
+		}
+	}
+
+	var m Money
+	if err := m.Scan("12.345"); !errors.Is(err, ErrPrecision) {
+		t.Errorf("Scan с лишними знаками: %v", err)
+	}
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan(bool) без ошибки")
+	}
+	if got := MustParse("3.5", "").WithCurrency("usd"); got.Currency() != "USD" || got.Minor() != 350 {
+		t.Errorf("WithCurrency: %s", got)
+	}
+}
+
+// TestJSON — сумма строкой (без потери точности) и валюта; обратный разбор
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(map[string]Money{"total": MustParse("1234.50", "usd")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != `{"total":{"amount":"1234.50","currency":"USD"}}` {
+		t.Errorf("Marshal: %s", got)
+	}
+
+	var back map[string]Money
+	if err := json.Unmarshal(b, &back); err != nil || !back["total"].Equal(MustParse("1234.50", "USD")) {
+		t.Errorf("Unmarshal: %v, %v", back, err)
+	}
+
+	for _, in := range []string{
+		`{"amount":"1.234","currency":"EUR"}`,
+		`{"amount":"abc","currency":"EUR"}`,
+		`{"amount":"1","currency":"XXX"}`,
+		`{"amount":1.5,"currency":"EUR"}`,
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Errorf("Unmarshal(%s) без ошибки: %s", in, m)
+		}
+	}
+}
+
+// TestFormat — разделители и символ валюты по локали (пробелы неразрывные: сумма не переносится)
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount, currency, locale, want string
+	}{
+		{"1234.50", "EUR", "ru", "1\u00a0234,50\u00a0€"},
+		{"1234.50", "EUR", "fi-FI", "1\u00a0234,50\u00a0€"},
+		{"1234.50", "EUR", "sv_SE", "1\u00a0234,50\u00a0€"},
+		{"1234567.00", "EUR", "de", "1.234.567,00\u00a0€"},
+		{"1234.50", "USD", "en", "$1,234.50"},
+		{"-1234.50", "USD", "en-US", "-$1,234.50"},
+		{"-0.99", "EUR", "ru", "-0,99\u00a0€"},
+		{"999.00", "SEK", "sv", "999,00\u00a0kr"},
+		{"150000", "JPY", "en", "¥150,000"},
+		{"5.00", "EUR", "xx", "5,00\u00a0€"}, // Неизвестная локаль — DefaultLocale
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.amount, tt.currency).Format(tt.locale); got != tt.want {
+			t.Errorf("Format(%s %s, %s) = %q, ожидалось %q", tt.amount, tt.currency, tt.locale, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package openapi
+
+// openapi.go — документ OpenAPI 3.1 для JSON API. Не пишется руками: пути берутся
+// из зарегистрированных маршрутов Gin (AddRoutes), схемы — из Go-типов (schema.go).
+// Описание каждого маршрута (Route) лежит рядом с обработчиками; маршрут без описания
+// или описание без маршрута — ошибка сборки приложения.
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version — версия спецификации OpenAPI
+const Version = "3.1.0"
+
+// problemSchema — имя схемы ошибки RFC 7807 в components (задаётся через New)
+const problemSchema = "ProblemDetail"
+
+// Document — корень документа OpenAPI
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	gen         *generator
+	bearer      string     // Имя схемы Bearer-токена в components ("" — не задана, см. SetBearerAuth)
+	idempotency *Parameter // Заголовок ключа повторов у POST/PUT/PATCH (см. SetIdempotencyKey)
+}
+
+// Info — название и версия API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem — операции одного пути по HTTP-методу ("get", "post", ...)
+type PathItem map[string]*Operation
+
+// Operation — операция (метод + путь)
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"` // Варианты: {"схема": [области]}; {} — без токена
+}
+
+// Parameter — параметр пути или query-строки
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query, header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody — тело запроса (только application/json)
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response — ответ с кодом статуса
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType — схема тела для типа содержимого
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components — именованные схемы (#/components/schemas/...) и схемы аутентификации
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme — схема аутентификации (у нас только HTTP Bearer)
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+// Route — описание маршрута для генератора. Типы тела и ответа задаются значениями:
+// поля-интерфейсы (Envelope.Data) описываются по динамическому типу значения.
+type Route struct {
+	Summary  string
+	Tag      string
+	Query    []Param
+	Body     any // Значение типа тела запроса (nil — без тела)
+	Status   int // Код успешного ответа (0 — 200)
+	Response any // Значение типа успешного ответа
+	Errors   []int
+	Scopes   []string // Области Bearer-токена, которые требует операция
+}
+
+// Param — параметр query-строки
+type Param struct {
+	Name        string
+	Type        string // integer, string
+	Description string
+}
+
+// New — пустой документ. problem — значение типа ошибки API (core.ProblemDetail):
+// им описываются все ответы с ошибкой.
+func New(info Info, problem any) *Document {
+	d := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      map[string]*PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+	d.gen = newGenerator(d.Components.Schemas)
+	d.gen.named(reflect.TypeOf(problem), problemSchema)
+	return d
+}
+
+// DefineType — своя схема для типа с собственным JSON (MarshalJSON), например money.Money
+func (d *Document) DefineType(v any, name string, s *Schema) {
+	d.gen.define(reflect.TypeOf(v), name, s)
+}
+
+// SetBearerAuth — операции принимают Authorization: Bearer (схема name в components).
+// Токен необязателен: у каждой операции есть вариант без него (сессия или аноним).
+// Вызывать до AddRoutes.
+func (d *Document) SetBearerAuth(name, description string) {
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = map[string]*SecurityScheme{}
+	}
+	d.Components.SecuritySchemes[name] = &SecurityScheme{Type: "http", Scheme: "bearer", Description: description}
+	d.bearer = name
+}
+
+// SetIdempotencyKey — необязательный заголовок name у операций POST, PUT и PATCH
+// (вызывать до AddRoutes); повтор с другим запросом описывается ответом 409
+func (d *Document) SetIdempotencyKey(name, description string) {
+	d.idempotency = &Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+// AddRoutes — добавляет в документ маршруты с префиксом prefix. Ключ routes —
+// "METHOD /path" в синтаксисе Gin (":id"), как в gin.RouteInfo.
+func (d *Document) AddRoutes(registered gin.RoutesInfo, prefix string, routes map[string]Route) error {
+	seen := map[string]bool{}
+	for _, ri := range registered {
+		if !strings.HasPrefix(ri.Path, prefix) {
+			continue
+		}
+		key := ri.Method + " " + ri.Path
+		r, ok := routes[key]
+		if !ok {
+			return fmt.Errorf("openapi: маршрут %s не описан", key)
+		}
+		seen[key] = true
+
+		path := ToPath(ri.Path)
+		item := d.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			d.Paths[path] = item
+		}
+		(*item)[strings.ToLower(ri.Method)] = d.operation(ri.Method, ri.Path, r)
+	}
+	for key := range routes {
+		if !seen[key] {
+			return fmt.Errorf("openapi: описан незарегистрированный маршрут %s", key)
+		}
+	}
+	return nil
+}
+
+// Find — операция по методу и шаблону пути Gin (c.FullPath()); nil — не описана
+func (d *Document) Find(method, ginPath string) *Operation {
+	item := d.Paths[ToPath(ginPath)]
+	if item == nil {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
+
+// Operations — операции в порядке пути и метода (для страницы документации)
+func (d *Document) Operations() []OperationRef {
+	var out []OperationRef
+	for path, item := range d.Paths {
+		for method, op := range *item {
+			out = append(out, OperationRef{Method: strings.ToUpper(method), Path: path, Operation: op})
+		}
+	}
+	order := map[string]int{"GET": 0, "POST": 1, "PUT": 2, "PATCH": 3, "DELETE": 4}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return order[out[i].Method] < order[out[j].Method]
+	})
+	return out
+}
+
+// OperationRef — операция вместе с методом и путём
+type OperationRef struct {
+	Method string
+	Path   string
+	*Operation
+}
+
+// ToPath — шаблон пути Gin в шаблон OpenAPI: /products/:id → /products/{id}
+func ToPath(ginPath string) string {
+	parts := strings.Split(ginPath, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// operation — операция по описанию маршрута
+func (d *Document) operation(method, ginPath string, r Route) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, ginPath),
+		Summary:     r.Summary,
+		Responses:   map[string]*Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+
+	for _, p := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			op.Parameters = append(op.Parameters, Parameter{Name: p[1:], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	for _, q := range r.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: q.Name, In: "query", Description: q.Description, Schema: &Schema{Type: q.Type}})
+	}
+	idempotent := d.idempotency != nil && (method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch)
+	if idempotent {
+		op.Parameters = append(op.Parameters, *d.idempotency)
+	}
+
+	if d.bearer != "" {
+		scopes := r.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		op.Security = []map[string][]string{{d.bearer: scopes}, {}}
+	}
+
+	if r.Body != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(d.gen.value(reflect.ValueOf(r.Body)))}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = &Response{
+		Description: http.StatusText(status),
+		Content:     jsonContent(d.gen.value(reflect.ValueOf(r.Response))),
+	}
+	problem := jsonContent(Ref(problemSchema))
+	errs := r.Errors
+	if idempotent && !slices.Contains(errs, http.StatusConflict) {
+		errs = append(slices.Clone(errs), http.StatusConflict)
+	}
+	for _, code := range errs {
+		op.Responses[strconv.Itoa(code)] = &Response{Description: http.StatusText(code), Content: problem}
+	}
+	op.Responses["default"] = &Response{Description: "Ошибка (RFC 7807)", Content: problem}
+	return op
+}
+
+// operationID — "GET /api/v1/products/:id" → "getApiV1ProductsId"
+func operationID(method, ginPath string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, p := range strings.FieldsFunc(ginPath, func(r rune) bool { return r == '/' || r == ':' || r == '*' || r == '_' }) {
+		b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+	}
+	return b.String()
+}
+
+// jsonContent — content с одной схемой application/json
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
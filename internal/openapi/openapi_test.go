@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testProblem struct {
+	Code   string            `json:"code"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+type testItem struct {
+	ID      int        `json:"id"`
+	Name    string     `json:"name"`
+	Note    *string    `json:"note"`
+	Tags    []string   `json:"tags,omitempty"`
+	Created time.Time  `json:"created_at"`
+	Parent  *testItem  `json:"parent,omitempty"`
+	Deleted *time.Time `json:"deleted_at,omitempty"`
+}
+
+type testEnvelope struct {
+	Data any `json:"data"`
+}
+
+// TestSchemaFromTypes — схемы по тегам json: обязательные поля, null, ссылки, конверт по значению
+func TestSchemaFromTypes(t *testing.T) {
+	d := New(Info{Title: "test", Version: "1"}, testProblem{})
+	s := d.gen.value(reflect.ValueOf(testEnvelope{Data: []testItem{}}))
+
+	if got := s.Properties["data"].String(); got != "array<testItem>" {
+		t.Errorf("data: %s", got)
+	}
+	item := d.Components.Schemas["testItem"]
+	if item == nil {
+		t.Fatal("нет схемы testItem")
+	}
+	if got := strings.Join(item.Required, ","); got != "id,name,note,created_at" {
+		t.Errorf("required: %s", got)
+	}
+	checks := map[string]string{
+		"note":       "string | null",
+		"created_at": "string (date-time)",
+		"parent":     "testItem",
+		"tags":       "array<string>",
+	}
+	for name, want := range checks {
+		if got := item.Properties[name].String(); got != want {
+			t.Errorf("%s: %s, ожидалось %s", name, got, want)
+		}
+	}
+
+	valid := `{"id": 1, "name": "a", "note": null, "created_at": "2026-01-02T03:04:05Z", "parent": {"id": 2, "name": "b", "note": "x", "created_at": "2026-01-02T03:04:05Z"}}`
+	invalid := `{"id": 1.5, "name": "a", "created_at": "вчера", "extra": true}`
+	for body, want := range map[string][]string{valid: nil, invalid: {"item.id", "item.note", "item.created_at", "item.extra"}} {
+		v, err := decode([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := d.Validate(Ref("testItem"), v, "item")
+		if len(errs) != len(want) {
+			t.Errorf("%s: %v", body, errs)
+		}
+		for _, k := range want {
+			if errs[k] == "" {
+				t.Errorf("%s: нет ошибки %s (%v)", body, k, errs)
+			}
+		}
+	}
+}
+
+// TestValidatorDrift — ответ, расходящийся со спецификацией, заменяется на 500 spec_violation
+func TestValidatorDrift(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	d := New(Info{Title: "test", Version: "1"}, testProblem{})
+	r := gin.New()
+	api := r.Group("/api", Validator(d, true))
+	api.GET("/items/:id", func(c *gin.Context) {
+		if c.Param("id") == "bad" {
+			c.JSON(http.StatusOK, gin.H{"data": gin.H{"id": "1"}})
+			return
+		}
+		c.JSON(http.StatusOK, testEnvelope{Data: testItem{ID: 1, Name: "a"}})
+	})
+	api.POST("/items", func(c *gin.Context) { c.Status(http.StatusAccepted) })
+
+	routes := map[string]Route{
+		"GET /api/items/:id": {Summary: "Товар", Response: testEnvelope{Data: testItem{}}, Errors: []int{http.StatusNotFound}},
+	}
+	if err := d.AddRoutes(r.Routes(), "/api/", routes); err == nil || !strings.Contains(err.Error(), "POST /api/items") {
+		t.Fatalf("маршрут без описания: %v", err)
+	}
+	routes["POST /api/items"] = Route{Summary: "Создать", Body: testItem{}, Status: http.StatusCreated, Response: testEnvelope{Data: testItem{}}}
+	routes["DELETE /api/items/:id"] = Route{Summary: "Удалить"}
+	if err := d.AddRoutes(r.Routes(), "/api/", routes); err == nil || !strings.Contains(err.Error(), "DELETE /api/items/:id") {
+		t.Fatalf("описание без маршрута: %v", err)
+	}
+	delete(routes, "DELETE /api/items/:id")
+	if err := d.AddRoutes(r.Routes(), "/api/", routes); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, body string) (int, testProblem) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var p testProblem
+		_ = json.Unmarshal(w.Body.Bytes(), &p)
+		return w.Code, p
+	}
+
+	if code, _ := do(http.MethodGet, "/api/items/1", ""); code != http.StatusOK {
+		t.Errorf("ответ по спецификации: %d", code)
+	}
+	if code, p := do(http.MethodGet, "/api/items/bad", ""); code != http.StatusInternalServerError || p.Code != "spec_violation" || p.Fields["response.data.id"] == "" {
+		t.Errorf("ответ вне спецификации: %d %+v", code, p)
+	}
+	item := `{"id": 1, "name": "a", "note": null, "created_at": "2026-01-02T03:04:05Z"}`
+	if code, p := do(http.MethodPost, "/api/items", item); code != http.StatusInternalServerError || p.Fields["status"] == "" {
+		t.Errorf("неописанный код ответа: %d %+v", code, p)
+	}
+	if code, p := do(http.MethodPost, "/api/items", `{"id": 1}`); code != http.StatusBadRequest || p.Code != "invalid_request" {
+		t.Errorf("тело вне спецификации: %d %+v", code, p)
+	}
+}
@@ -0,0 +1,245 @@
+package openapi
+
+// schema.go — JSON Schema (диалект OpenAPI 3.1) из Go-типов по тегам json: именованные
+// структуры попадают в components, поля без omitempty обязательны, указатель без omitempty
+// может быть null, неизвестные поля в объектах структур запрещены.
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema — JSON Schema
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"` // string или []string (с "null")
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // false или *Schema
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// refPrefix — префикс ссылок на именованные схемы
+const refPrefix = "#/components/schemas/"
+
+// Ref — ссылка на именованную схему
+func Ref(name string) *Schema {
+	return &Schema{Ref: refPrefix + name}
+}
+
+// RefName — имя схемы из ссылки ("" — не ссылка)
+func (s *Schema) RefName() string {
+	return strings.TrimPrefix(s.Ref, refPrefix)
+}
+
+// Types — допустимые типы схемы ("null" — допускается null); пусто — любое значение
+func (s *Schema) Types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+// IsRequired — обязательно ли свойство name объекта
+func (s *Schema) IsRequired(name string) bool {
+	return slices.Contains(s.Required, name)
+}
+
+// String — краткая запись типа для страницы документации: "Product", "array<Variant>", "string | null"
+func (s *Schema) String() string {
+	switch {
+	case s.Ref != "":
+		return s.RefName()
+	case len(s.AnyOf) > 0:
+		parts := make([]string, len(s.AnyOf))
+		for i, a := range s.AnyOf {
+			parts[i] = a.String()
+		}
+		return strings.Join(parts, " | ")
+	}
+	types := s.Types()
+	if len(types) == 0 {
+		return "any"
+	}
+	parts := make([]string, len(types))
+	for i, t := range types {
+		switch {
+		case t == "array" && s.Items != nil:
+			t = "array<" + s.Items.String() + ">"
+		case t == "object" && s.Properties == nil:
+			if add, ok := s.AdditionalProperties.(*Schema); ok {
+				t = "map<" + add.String() + ">"
+			}
+		case s.Format != "":
+			t += " (" + s.Format + ")"
+		}
+		parts[i] = t
+	}
+	return strings.Join(parts, " | ")
+}
+
+// generator — схемы Go-типов; именованные структуры складываются в schemas
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator(schemas map[string]*Schema) *generator {
+	return &generator{schemas: schemas, names: map[reflect.Type]string{}}
+}
+
+// define — готовая схема для типа (у него свой MarshalJSON)
+func (g *generator) define(t reflect.Type, name string, s *Schema) {
+	g.schemas[name] = s
+	g.names[t] = name
+}
+
+// named — структура t под заданным именем
+func (g *generator) named(t reflect.Type, name string) *Schema {
+	g.names[t] = name
+	g.schemas[name] = nil // Занимаем имя до полей: тип может ссылаться сам на себя
+	g.schemas[name] = g.object(t, func(f reflect.StructField) *Schema { return g.typ(f.Type) })
+	return Ref(name)
+}
+
+// typ — схема типа
+func (g *generator) typ(t reflect.Type) *Schema {
+	if name, ok := g.names[t]; ok {
+		return Ref(name)
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typ(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.typ(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.typ(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, func(f reflect.StructField) *Schema { return g.typ(f.Type) })
+		}
+		return g.named(t, g.freeName(t))
+	}
+	return &Schema{} // interface{} — любое значение
+}
+
+// value — схема по значению: поля-интерфейсы описываются динамическим типом.
+// Так один Envelope даёт разные схемы ответа ({"data": [Product]}, {"data": Cart}).
+func (g *generator) value(v reflect.Value) *Schema {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return &Schema{}
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return g.typ(v.Type())
+		}
+		return g.value(v.Elem())
+	case reflect.Struct:
+		if !hasInterface(v.Type(), map[reflect.Type]bool{}) {
+			return g.typ(v.Type())
+		}
+		return g.object(v.Type(), func(f reflect.StructField) *Schema {
+			fv, err := v.FieldByIndexErr(f.Index)
+			if err != nil { // Поле встроенной структуры по nil-указателю
+				return g.typ(f.Type)
+			}
+			return g.value(fv)
+		})
+	}
+	return g.typ(v.Type())
+}
+
+// object — схема объекта по полям структуры; field — схема значения поля
+func (g *generator) object(t reflect.Type, field func(reflect.StructField) *Schema) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := strings.Contains(opts, "omitempty")
+
+		fs := field(f)
+		if f.Type.Kind() == reflect.Pointer && !omitempty {
+			fs = nullable(fs)
+		}
+		s.Properties[name] = fs
+		if !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// freeName — имя схемы типа: имя Go-типа, при совпадении у разных пакетов — с именем пакета
+func (g *generator) freeName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
+
+// nullable — та же схема, но допускающая null
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	if t, ok := s.Type.(string); ok {
+		cp := *s
+		cp.Type = []string{t, "null"}
+		return &cp
+	}
+	return s
+}
+
+// hasInterface — есть ли в структуре (на любом уровне) поле-интерфейс
+func hasInterface(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Interface:
+		return true
+	case t.Kind() != reflect.Struct || seen[t]:
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() && hasInterface(t.Field(i).Type, seen) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package openapi
+
+// validate.go — проверка запросов и ответов по документу (в dev и test, см. Validator).
+// Поддерживается то подмножество JSON Schema, которое даёт генератор: type, format date-time,
+// properties/required/additionalProperties, items, anyOf и $ref на components.
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ошибки расхождения со спецификацией
+var (
+	errRequest = &core.AppError{Code: "invalid_request", Status: http.StatusBadRequest, Message: "Запрос не соответствует спецификации API"}
+	errDrift   = &core.AppError{Code: "spec_violation", Status: http.StatusInternalServerError, Message: "Ответ не соответствует спецификации API"}
+)
+
+// Validator — middleware: запрос к описанной операции проверяется до обработчика
+// (неизвестные query-параметры, тело по схеме — иначе 400 invalid_request), ответ — после
+// (иначе вместо него 500 spec_violation). enabled=false — middleware ничего не делает.
+func Validator(d *Document, enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+		op := d.Find(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		if errs := d.checkRequest(c.Request, op); len(errs) > 0 {
+			core.FailC(c, withFields(errRequest, errs))
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if errs := d.checkResponse(op, w.Status(), w.Header().Get("Content-Type"), w.buf.Bytes()); len(errs) > 0 {
+			core.LogError("Ответ расходится со спецификацией OpenAPI", map[string]interface{}{
+				"path":   c.FullPath(),
+				"status": w.Status(),
+				"errors": errs,
+			})
+			c.Header("Content-Type", "")
+			core.FailC(c, withFields(errDrift, errs))
+			return
+		}
+		w.flush()
+	}
+}
+
+// Validate — ошибки значения v (разобранного с json.Decoder.UseNumber) по схеме s:
+// путь в значении → сообщение. Пусто — значение соответствует схеме.
+func (d *Document) Validate(s *Schema, v any, path string) map[string]string {
+	errs := map[string]string{}
+	d.validate(s, v, path, errs)
+	return errs
+}
+
+// checkRequest — query-параметры и тело запроса
+func (d *Document) checkRequest(r *http.Request, op *Operation) map[string]string {
+	errs := map[string]string{}
+	declared := map[string]bool{}
+	for _, p := range op.Parameters {
+		if p.In == "query" {
+			declared[p.Name] = true
+		}
+	}
+	for name := range r.URL.Query() {
+		if !declared[name] {
+			errs["query."+name] = "Неизвестный параметр"
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errs["body"] = "Тело запроса не прочитано"
+		return errs
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	switch {
+	case op.RequestBody == nil && len(bytes.TrimSpace(body)) > 0:
+		errs["body"] = "Операция не принимает тело"
+	case op.RequestBody != nil:
+		v, err := decode(body)
+		if err != nil {
+			errs["body"] = "Некорректный JSON"
+			break
+		}
+		d.validate(op.RequestBody.Content["application/json"].Schema, v, "body", errs)
+	}
+	return errs
+}
+
+// checkResponse — тело ответа по схеме его кода (или default)
+func (d *Document) checkResponse(op *Operation, status int, contentType string, body []byte) map[string]string {
+	if len(body) == 0 && (status == http.StatusNoContent || status == http.StatusNotModified) {
+		return nil
+	}
+	resp := op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	errs := map[string]string{}
+	if status < 400 && op.Responses[strconv.Itoa(status)] == nil {
+		errs["status"] = "Код " + strconv.Itoa(status) + " не описан"
+		return errs
+	}
+	media, ok := resp.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	if !strings.HasPrefix(contentType, "application/json") {
+		errs["content-type"] = "Ожидался application/json, а не " + strconv.Quote(contentType)
+		return errs
+	}
+	v, err := decode(body)
+	if err != nil {
+		errs["response"] = "Некорректный JSON"
+		return errs
+	}
+	d.validate(media.Schema, v, "response", errs)
+	return errs
+}
+
+// validate — рекурсивная проверка значения v по схеме s
+func (d *Document) validate(s *Schema, v any, path string, errs map[string]string) {
+	if s == nil {
+		return
+	}
+	if name := s.RefName(); s.Ref != "" {
+		d.validate(d.Components.Schemas[name], v, path, errs)
+		return
+	}
+	if len(s.AnyOf) > 0 {
+		for _, alt := range s.AnyOf {
+			if len(d.Validate(alt, v, path)) == 0 {
+				return
+			}
+		}
+		errs[path] = "Значение не подходит ни под один из вариантов: " + s.String()
+		return
+	}
+
+	types := s.Types()
+	if len(types) == 0 {
+		return
+	}
+	actual := jsonType(v)
+	if !typeAllowed(types, actual) {
+		errs[path] = "Ожидался тип " + s.String() + ", получен " + actual
+		return
+	}
+
+	switch val := v.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, val); err != nil {
+				errs[path] = "Ожидалась дата RFC 3339"
+			}
+		}
+	case []any:
+		for i, item := range val {
+			d.validate(s.Items, item, path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				errs[path+"."+name] = "Обязательное поле"
+			}
+		}
+		for name, item := range val {
+			if ps, ok := s.Properties[name]; ok {
+				d.validate(ps, item, path+"."+name, errs)
+				continue
+			}
+			switch add := s.AdditionalProperties.(type) {
+			case bool:
+				if !add {
+					errs[path+"."+name] = "Неизвестное поле"
+				}
+			case *Schema:
+				d.validate(add, item, path+"."+name, errs)
+			}
+		}
+	}
+}
+
+// jsonType — тип JSON-значения в терминах JSON Schema
+func jsonType(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+// typeAllowed — подходит ли фактический тип под один из допустимых (integer — частный случай number)
+func typeAllowed(types []string, actual string) bool {
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// decode — JSON с числами как json.Number (integer отличается от number)
+func decode(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("лишние данные после JSON")
+	}
+	return v, nil
+}
+
+// withFields — копия ошибки с полями
+func withFields(e *core.AppError, fields map[string]string) *core.AppError {
+	cp := *e
+	cp.Fields = fields
+	return &cp
+}
+
+// bufferedWriter — копит тело ответа, чтобы проверить его до отправки клиенту
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// flush — отправляет накопленное тело (и статус, если он ещё не ушёл)
+func (w *bufferedWriter) flush() {
+	if w.buf.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
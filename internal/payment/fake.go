@@ -0,0 +1,271 @@
+package payment
+
+// fake.go — локальный провайдер для разработки: без сети и внешних аккаунтов.
+// Исход оплаты задаётся тестовой картой (успех, отказ, 3-D Secure), события
+// подписываются тем же HMAC, что и у настоящего провайдера, и доставляются через Deliver.
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+)
+
+// Тестовые карты фейкового провайдера
+const (
+	CardSuccess = "4242424242424242" // Оплата проходит сразу
+	CardDecline = "4000000000000002" // Отказ банка
+	Card3DS     = "4000000000003220" // Требует подтверждения 3-D Secure
+)
+
+// TestCard — подсказка на странице оплаты
+type TestCard struct {
+	Number string
+	Title  string
+}
+
+// TestCards — тестовые карты в порядке показа
+var TestCards = []TestCard{
+	{Number: CardSuccess, Title: "Успешная оплата"},
+	{Number: CardDecline, Title: "Отказ банка"},
+	{Number: Card3DS, Title: "Подтверждение 3-D Secure"},
+}
+
+// WebhookFunc — доставка подписанного события (payload + значение SignatureHeader)
+type WebhookFunc func(ctx context.Context, payload []byte, signature string) error
+
+// Fake — провайдер, полностью работающий в памяти процесса
+type Fake struct {
+	secret []byte
+	now    func() time.Time
+
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+
+	// Deliver — куда отправлять события (обычно — тот же обработчик, что и /payments/webhook).
+	// nil — события не доставляются.
+	Deliver WebhookFunc
+}
+
+type fakeIntent struct {
+	Intent
+	manualCapture bool
+}
+
+// NewFake — фейковый провайдер с секретом подписи webhook
+func NewFake(secret []byte) *Fake {
+	return &Fake{secret: secret, now: time.Now, intents: map[string]*fakeIntent{}}
+}
+
+// Name — код провайдера
+func (f *Fake) Name() string { return "fake" }
+
+// CreateIntent — платёж в статусе requires_payment; оплата — на странице /payments/fake/<id>
+func (f *Fake) CreateIntent(_ context.Context, req IntentRequest) (*Intent, error) {
+	if !req.Amount.IsPositive() {
+		return nil, &core.AppError{Code: "invalid_amount", Status: http.StatusBadRequest, Message: "Сумма платежа должна быть больше нуля"}
+	}
+	id, err := randomID("pi_fake_")
+	if err != nil {
+		return nil, err
+	}
+
+	in := &fakeIntent{
+		Intent: Intent{
+			ID:          id,
+			OrderNumber: req.OrderNumber,
+			Amount:      req.Amount,
+			Status:      IntentRequiresPayment,
+			RedirectURL: "/payments/fake/" + id,
+			ReturnURL:   req.ReturnURL,
+		},
+		manualCapture: req.ManualCapture,
+	}
+
+	f.mu.Lock()
+	f.intents[id] = in
+	f.mu.Unlock()
+
+	out := in.Intent
+	return &out, nil
+}
+
+// Intent — текущее состояние платежа
+func (f *Fake) Intent(id string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	in, ok := f.intents[id]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	out := in.Intent
+	return &out, nil
+}
+
+// Authorize — "ввод карты" на странице оплаты. Исход определяется тестовой картой.
+func (f *Fake) Authorize(ctx context.Context, id, card string) (*Intent, error) {
+	card = strings.NewReplacer(" ", "", "-", "").Replace(card)
+
+	f.mu.Lock()
+	in, ok := f.intents[id]
+	if !ok {
+		f.mu.Unlock()
+		return nil, ErrIntentNotFound
+	}
+	if in.Status != IntentRequiresPayment {
+		f.mu.Unlock()
+		return nil, ErrInvalidState
+	}
+
+	var ev EventType
+	switch card {
+	case CardSuccess:
+		ev = f.completeLocked(in)
+	case CardDecline:
+		in.Status = IntentDeclined
+		ev = EventFailed
+	case Card3DS:
+		in.Status = IntentRequiresAction
+		in.RedirectURL = "/payments/fake/" + id + "/3ds"
+	default:
+		f.mu.Unlock()
+		return nil, &core.AppError{Code: "validation", Status: http.StatusBadRequest, Message: "Используйте одну из тестовых карт",
+			Fields: map[string]string{"card": "Неизвестная тестовая карта"}}
+	}
+	out := in.Intent
+	f.mu.Unlock()
+
+	return &out, f.emit(ctx, ev, out)
+}
+
+// Confirm3DS — результат проверки 3-D Secure (approve=false — покупатель не подтвердил)
+func (f *Fake) Confirm3DS(ctx context.Context, id string, approve bool) (*Intent, error) {
+	f.mu.Lock()
+	in, ok := f.intents[id]
+	if !ok {
+		f.mu.Unlock()
+		return nil, ErrIntentNotFound
+	}
+	if in.Status != IntentRequiresAction {
+		f.mu.Unlock()
+		return nil, ErrInvalidState
+	}
+
+	var ev EventType
+	if approve {
+		ev = f.completeLocked(in)
+	} else {
+		in.Status = IntentDeclined
+		ev = EventFailed
+	}
+	out := in.Intent
+	f.mu.Unlock()
+
+	return &out, f.emit(ctx, ev, out)
+}
+
+// Capture — списание авторизованной суммы
+func (f *Fake) Capture(ctx context.Context, id string) (*Intent, error) {
+	f.mu.Lock()
+	in, ok := f.intents[id]
+	if !ok {
+		f.mu.Unlock()
+		return nil, ErrIntentNotFound
+	}
+	if in.Status != IntentRequiresCapture {
+		f.mu.Unlock()
+		return nil, ErrInvalidState
+	}
+	in.Status = IntentSucceeded
+	out := in.Intent
+	f.mu.Unlock()
+
+	return &out, f.emit(ctx, EventSucceeded, out)
+}
+
+// Refund — полный возврат (частичные возвраты фейк не моделирует)
+func (f *Fake) Refund(ctx context.Context, id string, amount money.Money) (*Intent, error) {
+	f.mu.Lock()
+	in, ok := f.intents[id]
+	if !ok {
+		f.mu.Unlock()
+		return nil, ErrIntentNotFound
+	}
+	if in.Status != IntentSucceeded {
+		f.mu.Unlock()
+		return nil, ErrInvalidState
+	}
+	if !amount.Equal(in.Amount) {
+		f.mu.Unlock()
+		return nil, &core.AppError{Code: "invalid_amount", Status: http.StatusBadRequest, Message: "Фейковый провайдер поддерживает только полный возврат"}
+	}
+	in.Status = IntentRefunded
+	out := in.Intent
+	f.mu.Unlock()
+
+	return &out, f.emit(ctx, EventRefunded, out)
+}
+
+// VerifyWebhook — проверка подписи и разбор события
+func (f *Fake) VerifyWebhook(payload []byte, signature string) (*Event, error) {
+	if err := VerifySignature(f.secret, payload, signature, f.now()); err != nil {
+		return nil, err
+	}
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil || ev.ID == "" || ev.IntentID == "" {
+		return nil, ErrBadPayload
+	}
+	return &ev, nil
+}
+
+// completeLocked — успешная авторизация: списание сразу или ожидание Capture
+func (f *Fake) completeLocked(in *fakeIntent) EventType {
+	in.RedirectURL = ""
+	if in.manualCapture {
+		in.Status = IntentRequiresCapture
+		return EventAuthorized
+	}
+	in.Status = IntentSucceeded
+	return EventSucceeded
+}
+
+// emit — подписывает событие и отдаёт его в Deliver ("" — событий нет)
+func (f *Fake) emit(ctx context.Context, typ EventType, in Intent) error {
+	if typ == "" || f.Deliver == nil {
+		return nil
+	}
+	id, err := randomID("evt_fake_")
+	if err != nil {
+		return err
+	}
+
+	now := f.now()
+	payload, err := json.Marshal(Event{
+		ID:          id,
+		Type:        typ,
+		IntentID:    in.ID,
+		OrderNumber: in.OrderNumber,
+		Amount:      in.Amount,
+		Status:      in.Status,
+		Created:     now.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return f.Deliver(ctx, payload, Sign(f.secret, payload, now))
+}
+
+// randomID — префикс + 96 случайных бит (hex)
+func randomID(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
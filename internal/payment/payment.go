@@ -0,0 +1,91 @@
+package payment
+
+// payment.go — подключаемые платёжные провайдеры.
+// Провайдер создаёт платёж (intent), списывает и возвращает деньги, а о результате
+// сообщает подписанными webhook-событиями — по ним и меняется статус заказа.
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+)
+
+// Provider — интерфейс платёжного провайдера (PaymentProvider)
+type Provider interface {
+	// Name — код провайдера (хранится в payments.provider)
+	Name() string
+	// CreateIntent — создаёт платёж; покупателя нужно отправить на Intent.RedirectURL
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Capture — списывает ранее авторизованную сумму (IntentRequest.ManualCapture)
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Refund — возвращает деньги по успешному платежу
+	Refund(ctx context.Context, intentID string, amount money.Money) (*Intent, error)
+	// VerifyWebhook — проверяет подпись тела webhook и разбирает событие
+	VerifyWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// IntentRequest — параметры нового платежа
+type IntentRequest struct {
+	OrderNumber   string
+	Amount        money.Money // Сумма и валюта платежа
+	ReturnURL     string      // Куда вернуть покупателя после оплаты
+	ManualCapture bool        // true — только авторизация, списание через Capture
+}
+
+// IntentStatus — статус платежа на стороне провайдера
+type IntentStatus string
+
+const (
+	IntentRequiresPayment IntentStatus = "requires_payment" // Ждёт данных карты
+	IntentRequiresAction  IntentStatus = "requires_action"  // Нужно подтверждение 3-D Secure
+	IntentRequiresCapture IntentStatus = "requires_capture" // Авторизован, ждёт списания
+	IntentSucceeded       IntentStatus = "succeeded"        // Деньги списаны
+	IntentDeclined        IntentStatus = "declined"         // Отказ банка
+	IntentRefunded        IntentStatus = "refunded"         // Деньги возвращены
+)
+
+// Intent — платёж у провайдера
+type Intent struct {
+	ID          string       `json:"id"`
+	OrderNumber string       `json:"order_number"`
+	Amount      money.Money  `json:"amount"`
+	Status      IntentStatus `json:"status"`
+	RedirectURL string       `json:"redirect_url,omitempty"` // Страница оплаты / 3-D Secure
+	ReturnURL   string       `json:"return_url,omitempty"`
+}
+
+// EventType — тип webhook-события
+type EventType string
+
+const (
+	EventAuthorized EventType = "payment.authorized" // Авторизован (ручное списание)
+	EventSucceeded  EventType = "payment.succeeded"  // Деньги списаны
+	EventFailed     EventType = "payment.failed"     // Отказ
+	EventRefunded   EventType = "payment.refunded"   // Возврат
+)
+
+// Event — webhook-событие провайдера; ID уникален и служит ключом идемпотентности
+type Event struct {
+	ID          string       `json:"id"`
+	Type        EventType    `json:"type"`
+	IntentID    string       `json:"intent_id"`
+	OrderNumber string       `json:"order_number"`
+	Amount      money.Money  `json:"amount"`
+	Status      IntentStatus `json:"status"`
+	Created     int64        `json:"created"` // Unix-время
+}
+
+// CreatedAt — время события
+func (e Event) CreatedAt() time.Time {
+	return time.Unix(e.Created, 0)
+}
+
+// Ошибки провайдера (уходят клиенту через core.FailC как RFC 7807)
+var (
+	ErrIntentNotFound = &core.AppError{Code: "payment_not_found", Status: http.StatusNotFound, Message: "Платёж не найден"}
+	ErrInvalidState   = &core.AppError{Code: "payment_invalid_state", Status: http.StatusConflict, Message: "Операция недоступна в текущем статусе платежа"}
+	ErrBadSignature   = &core.AppError{Code: "invalid_signature", Status: http.StatusBadRequest, Message: "Неверная подпись webhook"}
+	ErrBadPayload     = &core.AppError{Code: "invalid_payload", Status: http.StatusBadRequest, Message: "Некорректное тело webhook"}
+)
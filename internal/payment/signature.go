@@ -0,0 +1,70 @@
+package payment
+
+// signature.go — HMAC-SHA256 подпись webhook: "t=<unix>,v1=<hex>" от строки "<t>.<тело>".
+// Метка времени в подписи защищает от повторной отправки старых запросов.
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader — заголовок с подписью webhook
+const SignatureHeader = "X-Payment-Signature"
+
+// SignatureTolerance — допустимое расхождение часов между провайдером и магазином
+const SignatureTolerance = 5 * time.Minute
+
+// Sign — значение заголовка SignatureHeader для тела payload
+func Sign(secret, payload []byte, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(computeMAC(secret, ts, payload))
+}
+
+// VerifySignature — nil, если подпись верна и не старше SignatureTolerance
+func VerifySignature(secret, payload []byte, header string, now time.Time) error {
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			if b, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, b)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrBadSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > SignatureTolerance || d < -SignatureTolerance {
+		return ErrBadSignature
+	}
+
+	// Несколько v1 допустимы — так провайдер ротирует секреты
+	expected := computeMAC(secret, ts, payload)
+	for _, s := range sigs {
+		if hmac.Equal(s, expected) {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+// computeMAC — HMAC-SHA256 от "<ts>.<payload>"
+func computeMAC(secret []byte, ts string, payload []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(ts))
+	m.Write([]byte("."))
+	m.Write(payload)
+	return m.Sum(nil)
+}
@@ -0,0 +1,48 @@
+package payment
+
+// webhook.go — обработка событий провайдера: проверка подписи → идемпотентная запись → статус заказа
+import (
+	"context"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+)
+
+// eventOrderStatus — в какой статус переводит заказ событие (нет в map — статус не меняется)
+var eventOrderStatus = map[EventType]storage.OrderStatus{
+	EventSucceeded: storage.OrderPaid,
+	EventRefunded:  storage.OrderRefunded,
+}
+
+// HandleWebhook — проверяет и применяет событие. Applied=false — событие уже было обработано
+// (повторная доставка), это не ошибка. Недопустимый переход заказа — тоже не ошибка: событие
+// записано с Conflict, провайдер получает подтверждение, платёж ждёт ручного возврата.
+func HandleWebhook(ctx context.Context, payments storage.PaymentRepository, p Provider, payload []byte, signature string) (storage.PaymentEventResult, error) {
+	ev, err := p.VerifyWebhook(payload, signature)
+	if err != nil {
+		return storage.PaymentEventResult{}, err
+	}
+
+	res, err := payments.ApplyEvent(ctx, storage.PaymentEvent{
+		Provider:      p.Name(),
+		EventID:       ev.ID,
+		IntentID:      ev.IntentID,
+		Type:          string(ev.Type),
+		PaymentStatus: string(ev.Status),
+		OrderStatus:   eventOrderStatus[ev.Type],
+	})
+	if err != nil {
+		return res, err
+	}
+
+	core.LogInfo("Платёжное событие", map[string]interface{}{
+		"provider":  p.Name(),
+		"event_id":  ev.ID,
+		"type":      ev.Type,
+		"intent_id": ev.IntentID,
+		"order":     ev.OrderNumber,
+		"duplicate": !res.Applied,
+		"conflict":  res.Conflict,
+	})
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package search
+
+// highlight.go — подсветка совпадений и короткие фрагменты описания
+import (
+	"html/template"
+	"strings"
+)
+
+// snippetLength — длина фрагмента описания в выдаче (в символах)
+const snippetLength = 160
+
+// Highlight — экранирует текст и оборачивает совпадения со словами запроса в <mark>.
+// Сравнение без учёта регистра; экранирование делается ДО вставки тегов (OWASP A03: Injection).
+func Highlight(text string, terms []string) template.HTML {
+	if text == "" || len(terms) == 0 {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	// ToLower может изменить длину строки в рунах (редкие символы) — тогда просто без подсветки
+	if len(lower) != len(runes) {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+
+	marked := make([]bool, len(runes))
+	for _, t := range terms {
+		tr := []rune(t)
+		if len(tr) == 0 {
+			continue
+		}
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if string(lower[i:i+len(tr)]) == t {
+				for j := i; j < i+len(tr); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	open := false
+	for i, r := range runes {
+		if marked[i] && !open {
+			b.WriteString("<mark>")
+			open = true
+		}
+		if !marked[i] && open {
+			b.WriteString("</mark>")
+			open = false
+		}
+		b.WriteString(template.HTMLEscapeString(string(r)))
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return template.HTML(b.String())
+}
+
+// Snippet — фрагмент текста длиной ~size символов вокруг первого совпадения.
+// Если совпадений нет — начало текста.
+func Snippet(text string, terms []string, size int) string {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text
+	}
+
+	lower := strings.ToLower(text)
+	start := 0
+	for _, t := range terms {
+		if idx := strings.Index(lower, t); idx >= 0 {
+			// Переводим байтовую позицию в руны и отступаем назад на четверть фрагмента
+			start = len([]rune(lower[:idx])) - size/4
+			break
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + size
+	if end > len(runes) {
+		end = len(runes)
+		start = end - size
+	}
+
+	out := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(runes) {
+		out += "…"
+	}
+	return out
+}
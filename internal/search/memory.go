@@ -0,0 +1,116 @@
+package search
+
+// memory.go — in-process движок поиска: для тестов и запуска без MySQL.
+// Семантика как у MySQL-движка: все слова обязательны (префикс слова), точный артикул — всегда найден.
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"myApp/internal/storage"
+)
+
+// Memory — поиск по срезу товаров в памяти (потокобезопасный)
+type Memory struct {
+	mu       sync.RWMutex
+	products []storage.Product
+}
+
+// NewMemory — движок по заданному набору товаров
+func NewMemory(products []storage.Product) *Memory {
+	m := &Memory{}
+	m.Replace(products)
+	return m
+}
+
+// Replace — заменяет индексируемый набор товаров (например, после изменения каталога)
+func (m *Memory) Replace(products []storage.Product) {
+	cp := append([]storage.Product(nil), products...)
+	m.mu.Lock()
+	m.products = cp
+	m.mu.Unlock()
+}
+
+// Search — линейный проход с подсчётом релевантности
+func (m *Memory) Search(_ context.Context, q Query) (*Result, error) {
+	q = q.Normalize()
+	terms := Terms(q.Text)
+	res := &Result{Page: q.Page, PageSize: q.PageSize, Terms: terms, Hits: []Hit{}}
+	if len(terms) == 0 {
+		return res, nil
+	}
+
+	m.mu.RLock()
+	var matched []Hit
+	for _, p := range m.products {
+		if score, ok := memoryScore(p, q.Text, terms); ok {
+			matched = append(matched, newHit(p, score, terms))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Score != matched[j].Score {
+			return matched[i].Score > matched[j].Score
+		}
+		return matched[i].Product.Name < matched[j].Product.Name
+	})
+
+	res.Total = len(matched)
+	start := (q.Page - 1) * q.PageSize
+	if start < len(matched) {
+		end := start + q.PageSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+		res.Hits = matched[start:end]
+	}
+	return res, nil
+}
+
+// memoryScore — релевантность товара: сумма совпадений слов (название весит больше описания)
+func memoryScore(p storage.Product, text string, terms []string) (float64, bool) {
+	if strings.EqualFold(strings.TrimSpace(text), p.Article) {
+		return 100, true
+	}
+
+	fields := []struct {
+		words  []string
+		weight float64
+	}{
+		{words(p.Name), 3},
+		{words(p.Article), 2},
+	}
+	if p.Description != nil {
+		fields = append(fields, struct {
+			words  []string
+			weight float64
+		}{words(*p.Description), 1})
+	}
+
+	var score float64
+	for _, t := range terms {
+		found := false
+		for _, f := range fields {
+			for _, w := range f.words {
+				if strings.HasPrefix(w, t) {
+					score += f.weight
+					found = true
+				}
+			}
+		}
+		if !found {
+			return 0, false // все слова обязательны
+		}
+	}
+	return score, true
+}
+
+// words — слова текста в нижнем регистре (разделители — как в Terms)
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
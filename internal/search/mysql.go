@@ -0,0 +1,127 @@
+package search
+
+// mysql.go — поиск через FULLTEXT-индекс ft_products_search (migrations/004_products_search.sql)
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// minFulltextToken — innodb_ft_min_token_size по умолчанию: более короткие слова индекс не хранит
+const minFulltextToken = 3
+
+// MySQL — движок поиска на MATCH ... AGAINST (BOOLEAN MODE)
+type MySQL struct {
+	db *sqlx.DB
+}
+
+// NewMySQL — движок поверх пула подключений
+func NewMySQL(db *sqlx.DB) *MySQL {
+	return &MySQL{db: db}
+}
+
+// scoredProduct — строка выборки: товар + релевантность
+type scoredProduct struct {
+	storage.Product
+	Score float64 `db:"score"`
+}
+
+// Search — все слова обязательны (+слово*), точное совпадение артикула поднимается наверх
+func (m *MySQL) Search(ctx context.Context, q Query) (*Result, error) {
+	q = q.Normalize()
+	terms := Terms(q.Text)
+	res := &Result{Page: q.Page, PageSize: q.PageSize, Terms: terms, Hits: []Hit{}}
+	if len(terms) == 0 {
+		return res, nil
+	}
+
+	scoreSQL, whereSQL, args := buildMySQLQuery(q.Text, terms)
+
+	var total int
+	countQ := "SELECT COUNT(*) FROM products p WHERE " + whereSQL
+	if err := m.db.GetContext(ctx, &total, countQ, args...); err != nil {
+		core.LogError("search count", map[string]interface{}{
+			"query": countQ,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	res.Total = total
+	if total == 0 {
+		return res, nil
+	}
+
+	listQ := `
+		SELECT p.id, p.category_id, p.name, p.article, p.description, p.price, p.image_alt, p.image_key, p.image_width, p.image_height, p.created_at, p.updated_at,
+		       ` + storage.ProductStockColumns + `,
+		       ` + scoreSQL + ` AS score
+		FROM products p
+		WHERE ` + whereSQL + `
+		ORDER BY score DESC, p.name ASC
+		LIMIT ? OFFSET ?`
+
+	// Аргументы score идут раньше аргументов WHERE — порядок плейсхолдеров в SQL
+	listArgs := append(append(append([]interface{}{}, args...), args...), q.PageSize, (q.Page-1)*q.PageSize)
+
+	var rows []scoredProduct
+	if err := m.db.SelectContext(ctx, &rows, listQ, listArgs...); err != nil {
+		core.LogError("search products", map[string]interface{}{
+			"query": listQ,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	for _, r := range rows {
+		res.Hits = append(res.Hits, newHit(r.Product, r.Score, terms))
+	}
+	return res, nil
+}
+
+// buildMySQLQuery — выражение релевантности и условие WHERE (с одинаковым набором аргументов).
+// Слова короче minFulltextToken ищем через LIKE: FULLTEXT их не индексирует.
+func buildMySQLQuery(text string, terms []string) (scoreSQL, whereSQL string, args []interface{}) {
+	var ft []string
+	var short []string
+	for _, t := range terms {
+		if utf8.RuneCountInString(t) >= minFulltextToken {
+			// Terms уже убрал операторы BOOLEAN MODE, так что экранировать нечего
+			ft = append(ft, "+"+t+"*")
+		} else {
+			short = append(short, t)
+		}
+	}
+	match := strings.Join(ft, " ")
+	article := strings.TrimSpace(text)
+
+	var conds []string
+	var scoreParts []string
+	if match != "" {
+		conds = append(conds, "MATCH(p.name, p.article, p.description) AGAINST (? IN BOOLEAN MODE)")
+		scoreParts = append(scoreParts, "MATCH(p.name, p.article, p.description) AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, match)
+	}
+	for _, s := range short {
+		like := "%" + escapeLike(s) + "%"
+		conds = append(conds, "(p.name LIKE ? OR p.article LIKE ?)")
+		scoreParts = append(scoreParts, "(p.name LIKE ? OR p.article LIKE ?)")
+		args = append(args, like, like)
+	}
+
+	// Все слова обязательны (AND), но точный артикул находит товар в любом случае
+	whereSQL = "((" + strings.Join(conds, " AND ") + ") OR p.article = ?)"
+	scoreSQL = "(" + strings.Join(scoreParts, " + ") + " + (p.article = ?) * 100)"
+	args = append(args, article)
+	return scoreSQL, whereSQL, args
+}
+
+// escapeLike — экранирует спецсимволы LIKE (% и _)
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
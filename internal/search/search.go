@@ -0,0 +1,108 @@
+package search
+
+// search.go — поиск товаров по названию, артикулу и описанию.
+// Движок подключаемый: MySQL FULLTEXT в проде, in-process Memory для тестов и разработки без БД.
+import (
+	"context"
+	"html/template"
+	"strings"
+	"unicode"
+
+	"myApp/internal/storage"
+)
+
+// Ограничения запроса (защита от тяжёлых запросов к FULLTEXT)
+const (
+	MaxQueryLength = 200 // символов в строке запроса
+	MaxTerms       = 10  // слов в запросе
+)
+
+// Engine — интерфейс поискового движка
+type Engine interface {
+	Search(ctx context.Context, q Query) (*Result, error)
+}
+
+// Query — поисковый запрос + страница
+type Query struct {
+	Text     string
+	Page     int
+	PageSize int
+}
+
+// Result — найденные товары текущей страницы, отсортированные по релевантности
+type Result struct {
+	Hits     []Hit    `json:"items"`
+	Total    int      `json:"total"`
+	Page     int      `json:"page"`
+	PageSize int      `json:"page_size"`
+	Terms    []string `json:"terms"` // Слова, по которым подсвечены совпадения
+}
+
+// Hit — найденный товар, его релевантность и подсвеченные фрагменты
+type Hit struct {
+	Product    storage.Product `json:"product"`
+	Score      float64         `json:"score"`
+	Highlights Highlights      `json:"highlights"`
+}
+
+// Highlights — HTML-фрагменты с <mark> вокруг совпадений (остальной текст экранирован)
+type Highlights struct {
+	Name        template.HTML `json:"name"`
+	Article     template.HTML `json:"article"`
+	Description template.HTML `json:"description,omitempty"`
+}
+
+// Normalize — приводит запрос к допустимым значениям (как storage.ProductQuery)
+func (q Query) Normalize() Query {
+	q.Text = strings.TrimSpace(q.Text)
+	if r := []rune(q.Text); len(r) > MaxQueryLength {
+		q.Text = string(r[:MaxQueryLength])
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = storage.DefaultPageSize
+	}
+	if q.PageSize > storage.MaxPageSize {
+		q.PageSize = storage.MaxPageSize
+	}
+	return q
+}
+
+// Terms — слова запроса в нижнем регистре, без дублей и операторов (+, -, *, " и т.п.)
+func Terms(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		terms = append(terms, f)
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// newHit — собирает Hit с подсветкой (общая часть для всех движков)
+func newHit(p storage.Product, score float64, terms []string) Hit {
+	h := Hit{
+		Product: p,
+		Score:   score,
+		Highlights: Highlights{
+			Name:    Highlight(p.Name, terms),
+			Article: Highlight(p.Article, terms),
+		},
+	}
+	if p.Description != nil {
+		h.Highlights.Description = Highlight(Snippet(*p.Description, terms, snippetLength), terms)
+	}
+	return h
+}
@@ -0,0 +1,202 @@
+package storage
+
+// api_tokens_repo.go — API-токены скриптов и интеграций (api_tokens). Как и у токенов из писем,
+// хранится только SHA-256 токена (auth.HashToken): владелец видит токен один раз при создании.
+// Срок действия и отзыв проверяются по часам MySQL.
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Scope — область доступа API-токена. Маршрут /api/v1 требует её через handler.RequireScope;
+// запросы с сессией (браузер) областями не ограничены.
+type Scope string
+
+const (
+	ScopeCart        Scope = "cart"         // Корзина: чтение и изменение
+	ScopeOrdersRead  Scope = "orders:read"  // Заказы пользователя
+	ScopeAccountRead Scope = "account:read" // Учётная запись
+)
+
+// Scopes — все области в порядке показа в форме создания токена
+var Scopes = []Scope{ScopeCart, ScopeOrdersRead, ScopeAccountRead}
+
+// ScopeList — области токена; в БД — через пробел ("cart orders:read")
+type ScopeList []Scope
+
+// Has — есть ли у токена область s
+func (l ScopeList) Has(s Scope) bool {
+	return slices.Contains(l, s)
+}
+
+// Value — запись в колонку scopes (driver.Valuer)
+func (l ScopeList) Value() (driver.Value, error) {
+	parts := make([]string, len(l))
+	for i, s := range l {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// Scan — чтение колонки scopes (sql.Scanner)
+func (l *ScopeList) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("scopes: неподдерживаемый тип %T", src)
+	}
+	*l = nil
+	for _, f := range strings.Fields(s) {
+		*l = append(*l, Scope(f))
+	}
+	return nil
+}
+
+// APIToken — API-токен пользователя. Hash — SHA-256 токена, Hint — его начало для списка.
+type APIToken struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	UserEmail  string     `db:"user_email"` // Email владельца (только в списках)
+	Name       string     `db:"name"`
+	Hint       string     `db:"token_hint"`
+	Hash       string     `db:"token_hash"`
+	Scopes     ScopeList  `db:"scopes"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"` // nil — ещё не использовался
+	RevokedAt  *time.Time `db:"revoked_at"`   // nil — не отозван
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// Active — токен действует: не отозван и не истёк
+func (t *APIToken) Active() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// ErrAPITokenInvalid — токена нет, он истёк или отозван (причина не раскрывается)
+var ErrAPITokenInvalid = &core.AppError{Code: "invalid_token", Status: http.StatusUnauthorized, Message: "Токен недействителен, истёк или отозван"}
+
+// apiTokenTouchInterval — last_used_at обновляется не чаще раза в минуту: частый скрипт
+// не превращает каждое чтение в запись
+const apiTokenTouchInterval = time.Minute
+
+const apiTokenColumns = `t.id, t.user_id, u.email AS user_email, t.name, t.token_hint, t.token_hash, t.scopes,
+	t.expires_at, t.last_used_at, t.revoked_at, t.created_at`
+
+// CreateAPIToken — новый токен со сроком ttl; заполняет t.ID, t.ExpiresAt и t.CreatedAt
+func CreateAPIToken(ctx context.Context, db *sqlx.DB, t *APIToken, ttl time.Duration) error {
+	const q = `
+		INSERT INTO api_tokens (user_id, name, token_hint, token_hash, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP + INTERVAL ? SECOND)`
+	res, err := db.ExecContext(ctx, q, t.UserID, t.Name, t.Hint, t.Hash, t.Scopes, int64(ttl/time.Second))
+	if err != nil {
+		core.LogError("create api token", map[string]interface{}{"user_id": t.UserID, "error": err.Error()})
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	created, err := getAPIToken(ctx, db, `t.id = ?`, id)
+	if err != nil {
+		return err
+	}
+	*t = *created
+	return nil
+}
+
+// ListAPITokens — токены пользователя (userID = "" — всех пользователей), новые первыми
+func ListAPITokens(ctx context.Context, db *sqlx.DB, userID string) ([]APIToken, error) {
+	q := `SELECT ` + apiTokenColumns + ` FROM api_tokens t JOIN users u ON u.id = t.user_id`
+	var args []any
+	if userID != "" {
+		q += ` WHERE t.user_id = ?`
+		args = append(args, userID)
+	}
+	q += ` ORDER BY t.created_at DESC, t.id DESC`
+
+	var list []APIToken
+	if err := db.SelectContext(ctx, &list, q, args...); err != nil {
+		core.LogError("list api tokens", map[string]interface{}{"user_id": userID, "error": err.Error()})
+		return nil, err
+	}
+	return list, nil
+}
+
+// AuthenticateAPIToken — действующий токен по хешу; отмечает время использования.
+// Нет, истёк или отозван — ErrAPITokenInvalid.
+func AuthenticateAPIToken(ctx context.Context, db *sqlx.DB, hash string) (*APIToken, error) {
+	t, err := getAPIToken(ctx, db, `t.token_hash = ? AND t.revoked_at IS NULL AND t.expires_at > CURRENT_TIMESTAMP`, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPITokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL ? SECOND)`
+	if _, err := db.ExecContext(ctx, q, t.ID, int64(apiTokenTouchInterval/time.Second)); err != nil {
+		// Отметка не критична для запроса: токен действует, ошибку только пишем в лог
+		core.LogError("touch api token", map[string]interface{}{"token_id": t.ID, "error": err.Error()})
+	}
+	return t, nil
+}
+
+// RevokeAPIToken — отзывает токен; userID != "" — только токен этого пользователя.
+// Нет токена (или он чужой) — sql.ErrNoRows; повторный отзыв ничего не меняет.
+func RevokeAPIToken(ctx context.Context, db *sqlx.DB, id, userID string) error {
+	t, err := getAPIToken(ctx, db, `t.id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if userID != "" && t.UserID != userID {
+		return sql.ErrNoRows
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL`, id); err != nil {
+		core.LogError("revoke api token", map[string]interface{}{"token_id": id, "error": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// RevokeUserAPITokens — отзывает все действующие токены пользователя (смена пароля по ссылке);
+// возвращает, сколько отозвано
+func RevokeUserAPITokens(ctx context.Context, db *sqlx.DB, userID string) (int, error) {
+	res, err := db.ExecContext(ctx, `UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = ? AND revoked_at IS NULL`, userID)
+	if err != nil {
+		core.LogError("revoke user api tokens", map[string]interface{}{"user_id": userID, "error": err.Error()})
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// getAPIToken — токен по условию where (с алиасом t)
+func getAPIToken(ctx context.Context, db *sqlx.DB, where string, args ...any) (*APIToken, error) {
+	var t APIToken
+	q := `SELECT ` + apiTokenColumns + ` FROM api_tokens t JOIN users u ON u.id = t.user_id WHERE ` + where
+	if err := db.GetContext(ctx, &t, q, args...); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError("get api token", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+// blobs.go — файлы (изображения товаров) отдельно от БД. Обработчики знают только BlobStore:
+// сейчас за ним каталог на диске (UPLOADS_DIR, раздаётся как /uploads/), объектное
+// хранилище (S3 и т.п.) подключается новой реализацией без изменений в обработчиках.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BlobStore — хранилище файлов по ключу вида "products/3f2a…-640.jpg"
+type BlobStore interface {
+	// Put — сохраняет файл целиком (заменяет существующий)
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Delete — удаляет файл; отсутствующий файл — не ошибка
+	Delete(ctx context.Context, key string) error
+	// URL — публичный адрес файла для <img src>
+	URL(key string) string
+}
+
+// ErrInvalidBlobKey — ключ, который нельзя превратить в безопасный путь
+var ErrInvalidBlobKey = errors.New("storage: некорректный ключ файла")
+
+// blobKeyRe — только строчные латинские буквы, цифры, "-", "_", "." и "/" между сегментами
+var blobKeyRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*(/[a-z0-9][a-z0-9_.-]*)*$`)
+
+// LocalBlobStore — файлы в каталоге на диске
+type LocalBlobStore struct {
+	dir       string
+	urlPrefix string
+}
+
+// NewLocalBlobStore — файлы в dir (создаётся при первой записи), адреса — urlPrefix + "/" + key
+func NewLocalBlobStore(dir, urlPrefix string) *LocalBlobStore {
+	return &LocalBlobStore{dir: dir, urlPrefix: strings.TrimRight(urlPrefix, "/")}
+}
+
+// Dir — каталог с файлами (для раздачи статикой)
+func (s *LocalBlobStore) Dir() string { return s.dir }
+
+// Put — запись во временный файл рядом и переименование: читатель не увидит файл наполовину
+func (s *LocalBlobStore) Put(_ context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // после Rename файла уже нет — ошибка игнорируется
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("storage: запись %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Файлы раздаются веб-сервером — нужны права на чтение, а не 0600 от CreateTemp
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete — удаляет файл key
+func (s *LocalBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL — /uploads/products/…
+func (s *LocalBlobStore) URL(key string) string {
+	return s.urlPrefix + "/" + key
+}
+
+// path — путь файла внутри dir; ".." и абсолютные пути отсекает blobKeyRe
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if !blobKeyRe.MatchString(key) || strings.Contains(key, "..") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidBlobKey, key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,306 @@
+package storage
+
+// internal/storage/carts_repo.go
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxCartQuantity — предел количества одного товара в корзине
+const MaxCartQuantity = 99
+
+// Cart — корзина с позициями и итогом по текущим ценам каталога
+type Cart struct {
+	ID        string      `db:"id" json:"id"`
+	UserID    *string     `db:"user_id" json:"-"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time   `db:"updated_at" json:"updated_at"`
+	Lines     []CartLine  `db:"-" json:"lines"`
+	Count     int         `db:"-" json:"count"` // Всего единиц товара
+	Total     money.Money `db:"-" json:"total"` // Сумма по текущим ценам
+}
+
+// CartLine — позиция корзины (товар или его вариант + количество)
+type CartLine struct {
+	ProductID string      `db:"product_id" json:"product_id"`
+	VariantID *string     `db:"variant_id" json:"variant_id,omitempty"` // nil — товар без вариантов
+	Name      string      `db:"name" json:"name"`
+	Variant   *string     `db:"variant" json:"variant,omitempty"` // Опции варианта (Variant.Label)
+	Article   string      `db:"article" json:"article"`           // У варианта — его SKU
+	Price     money.Money `db:"price" json:"price"`               // Текущая цена варианта или товара
+	ImageAlt  *string     `db:"image_alt" json:"image_alt,omitempty"`
+	Quantity  int         `db:"quantity" json:"quantity"`
+	Available int         `db:"available" json:"available"` // Доступно этой корзине: остаток − чужие резервы
+	Subtotal  money.Money `db:"-" json:"subtotal"`
+}
+
+// Short — в наличии меньше, чем в корзине (оформить заказ не получится)
+func (l CartLine) Short() bool {
+	return l.Quantity > l.Available
+}
+
+// Key — идентификатор позиции в корзине (для id полей формы): "5" или "5-2"
+func (l CartLine) Key() string {
+	if l.VariantID == nil {
+		return l.ProductID
+	}
+	return l.ProductID + "-" + *l.VariantID
+}
+
+// cartLinesSQL — позиции корзины по текущим ценам. INNER JOIN: товары, удалённые из каталога,
+// из корзины пропадают; LEFT JOIN + условие — так же пропадают удалённые варианты.
+// Доступно — без резерва самой корзины: она уже держит свои единицы.
+var cartLinesSQL = `
+		SELECT ci.product_id, NULLIF(ci.variant_id, 0) AS variant_id, p.name, ` + variantLabelSQL + ` AS variant,
+		       COALESCE(v.sku, p.article) AS article, COALESCE(v.price, p.price) AS price, p.image_alt, ci.quantity,
+		       GREATEST(COALESCE(v.stock, p.stock) - COALESCE((SELECT SUM(r.quantity) FROM stock_reservations r
+		           WHERE r.product_id = ci.product_id AND r.variant_id = ci.variant_id AND ` + activeReservationSQL + `
+		             AND NOT (r.cart_id <=> ci.cart_id)), 0), 0) AS available
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id AND v.product_id = ci.product_id
+		WHERE ci.cart_id = ? AND (ci.variant_id = 0 OR v.id IS NOT NULL)`
+
+// variantKey — variant_id строки корзины: 0 — товар без вариантов
+func variantKey(variantID string) string {
+	if variantID == "" {
+		return "0"
+	}
+	return variantID
+}
+
+// newCartID — случайный идентификатор корзины (128 бит, hex)
+func newCartID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateCart — новая пустая корзина, возвращает её ID.
+// userID = "" — анонимная корзина (привяжется к пользователю при входе, см. ClaimCart).
+func CreateCart(ctx context.Context, db *sqlx.DB, userID string) (string, error) {
+	id, err := newCartID()
+	if err != nil {
+		return "", err
+	}
+
+	var owner *string
+	if userID != "" {
+		owner = &userID
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO carts (id, user_id) VALUES (?, ?)`, id, owner); err != nil {
+		core.LogError("create cart", map[string]interface{}{"error": err.Error()})
+		return "", err
+	}
+	return id, nil
+}
+
+// CartExists — есть ли корзина с таким ID (без загрузки позиций)
+func CartExists(ctx context.Context, db *sqlx.DB, id string) (bool, error) {
+	var n int
+	if err := db.GetContext(ctx, &n, `SELECT COUNT(*) FROM carts WHERE id = ?`, id); err != nil {
+		core.LogError("cart exists", map[string]interface{}{"error": err.Error()})
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetCartIDByUser — ID корзины пользователя ("" — корзины нет)
+func GetCartIDByUser(ctx context.Context, db *sqlx.DB, userID string) (string, error) {
+	var id string
+	err := db.GetContext(ctx, &id, `SELECT id FROM carts WHERE user_id = ?`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		core.LogError("get cart by user", map[string]interface{}{"error": err.Error()})
+		return "", err
+	}
+	return id, nil
+}
+
+// GetCart — корзина с позициями; цены и названия — текущие из products.
+// sql.ErrNoRows, если корзины нет (например, удалена по сроку).
+func GetCart(ctx context.Context, db *sqlx.DB, id string) (*Cart, error) {
+	var cart Cart
+	const qCart = `SELECT id, user_id, created_at, updated_at FROM carts WHERE id = ?`
+	if err := db.GetContext(ctx, &cart, qCart, id); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError("get cart", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, err
+	}
+
+	qLines := cartLinesSQL + `
+		ORDER BY ci.added_at ASC, p.name ASC, ci.variant_id ASC`
+	if err := db.SelectContext(ctx, &cart.Lines, qLines, id); err != nil {
+		core.LogError("get cart lines", map[string]interface{}{
+			"query": qLines,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	cart.Recalculate()
+	return &cart, nil
+}
+
+// Recalculate — пересчитывает суммы строк, количество и итог (в центах, без округлений)
+func (c *Cart) Recalculate() {
+	c.Count = 0
+	c.Total = money.Money{}
+	for i := range c.Lines {
+		l := &c.Lines[i]
+		l.Subtotal = l.Price.Mul(int64(l.Quantity))
+		c.Count += l.Quantity
+		c.Total = c.Total.Add(l.Subtotal)
+	}
+}
+
+// Short — хотя бы одной позиции не хватает на складе
+func (c *Cart) Short() bool {
+	return slices.ContainsFunc(c.Lines, CartLine.Short)
+}
+
+// AddCartItem — добавляет товар или его вариант (variantID = "" — без варианта);
+// уже лежащая позиция увеличивается, но не больше MaxCartQuantity
+func AddCartItem(ctx context.Context, db *sqlx.DB, cartID, productID, variantID string, qty int) error {
+	const q = `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE quantity = LEAST(quantity + VALUES(quantity), ?)`
+	if _, err := db.ExecContext(ctx, q, cartID, productID, variantKey(variantID), clampQuantity(qty), MaxCartQuantity); err != nil {
+		core.LogError("add cart item", map[string]interface{}{
+			"cart_id":    cartID,
+			"product_id": productID,
+			"variant_id": variantID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+	return touchCart(ctx, db, cartID)
+}
+
+// SetCartItemQuantity — задаёт количество; 0 и меньше — удаляет позицию
+func SetCartItemQuantity(ctx context.Context, db *sqlx.DB, cartID, productID, variantID string, qty int) error {
+	if qty <= 0 {
+		return RemoveCartItem(ctx, db, cartID, productID, variantID)
+	}
+
+	const q = `UPDATE cart_items SET quantity = ? WHERE cart_id = ? AND product_id = ? AND variant_id = ?`
+	if _, err := db.ExecContext(ctx, q, clampQuantity(qty), cartID, productID, variantKey(variantID)); err != nil {
+		core.LogError("set cart item quantity", map[string]interface{}{
+			"cart_id":    cartID,
+			"product_id": productID,
+			"variant_id": variantID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+	return touchCart(ctx, db, cartID)
+}
+
+// RemoveCartItem — удаляет позицию из корзины
+func RemoveCartItem(ctx context.Context, db *sqlx.DB, cartID, productID, variantID string) error {
+	const q = `DELETE FROM cart_items WHERE cart_id = ? AND product_id = ? AND variant_id = ?`
+	if _, err := db.ExecContext(ctx, q, cartID, productID, variantKey(variantID)); err != nil {
+		core.LogError("remove cart item", map[string]interface{}{
+			"cart_id":    cartID,
+			"product_id": productID,
+			"variant_id": variantID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+	return touchCart(ctx, db, cartID)
+}
+
+// touchCart — обновляет updated_at (по нему можно чистить брошенные корзины)
+func touchCart(ctx context.Context, db *sqlx.DB, cartID string) error {
+	_, err := db.ExecContext(ctx, `UPDATE carts SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, cartID)
+	return err
+}
+
+// clampQuantity — количество в пределах 1..MaxCartQuantity
+func clampQuantity(qty int) int {
+	if qty < 1 {
+		return 1
+	}
+	if qty > MaxCartQuantity {
+		return MaxCartQuantity
+	}
+	return qty
+}
+
+// ClaimCart — привязывает корзину к пользователю при входе и возвращает ID его корзины.
+// Анонимная корзина либо становится корзиной пользователя (если своей у него нет),
+// либо сливается в существующую: количества складываются, анонимная удаляется.
+func ClaimCart(ctx context.Context, db *sqlx.DB, anonCartID, userID string) (string, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userCartID string
+	err = tx.GetContext(ctx, &userCartID, `SELECT id FROM carts WHERE user_id = ? FOR UPDATE`, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	switch {
+	case anonCartID == "" || anonCartID == userCartID:
+		// Сливать нечего
+	case userCartID == "":
+		// Своей корзины нет — забираем анонимную (только если она ещё ничья)
+		res, err := tx.ExecContext(ctx, `UPDATE carts SET user_id = ? WHERE id = ? AND user_id IS NULL`, userID, anonCartID)
+		if err != nil {
+			return "", err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			userCartID = anonCartID
+		}
+	default:
+		const qMerge = `
+			INSERT INTO cart_items (cart_id, product_id, variant_id, quantity)
+			SELECT ?, ci.product_id, ci.variant_id, ci.quantity
+			FROM cart_items ci
+			JOIN carts c ON c.id = ci.cart_id AND c.user_id IS NULL
+			WHERE ci.cart_id = ?
+			ON DUPLICATE KEY UPDATE quantity = LEAST(cart_items.quantity + VALUES(quantity), ?)`
+		if _, err := tx.ExecContext(ctx, qMerge, userCartID, anonCartID, MaxCartQuantity); err != nil {
+			return "", err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM carts WHERE id = ? AND user_id IS NULL`, anonCartID); err != nil {
+			return "", err
+		}
+		// Резерв анонимной корзины удалён каскадом
+		if err := touchCatalog(ctx, tx); err != nil {
+			return "", err
+		}
+	}
+
+	if userCartID == "" {
+		// Ни анонимной, ни своей — корзина появится при первом добавлении товара
+		return "", tx.Commit()
+	}
+	if err := tx.Commit(); err != nil {
+		core.LogError("claim cart", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return "", err
+	}
+	return userCartID, nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+// internal/storage/catalog_changes.go — время последнего изменения каталога для Last-Modified
+// /catalog/json. Строку catalog_changes отмечает каждая запись, меняющая товары, остатки или
+// резервы; истечение резерва — не запись, его время берётся из stock_reservations.expires_at.
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// touchCatalog — отмечает изменение каталога. В транзакции — после блокировок остатков, ближе
+// к Commit: строка одна на всех, держать её блокировку дольше незачем.
+func touchCatalog(ctx context.Context, ex sqlx.ExecerContext) error {
+	if _, err := ex.ExecContext(ctx, `UPDATE catalog_changes SET changed_at = CURRENT_TIMESTAMP(3) WHERE id = 1`); err != nil {
+		core.LogError("touch catalog", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// CatalogModified — время последнего изменения товаров, остатков и резервов (с учётом истёкших
+// резервов). Нулевое время — каталог менялся в текущую секунду по часам БД: Last-Modified
+// с точностью до секунды не отличил бы следующее изменение в ту же секунду.
+func CatalogModified(ctx context.Context, db *sqlx.DB) (time.Time, error) {
+	var t time.Time
+	const q = `
+		SELECT m.modified FROM (
+			SELECT GREATEST(c.changed_at, COALESCE(
+				(SELECT MAX(r.expires_at) FROM stock_reservations r WHERE r.expires_at <= CURRENT_TIMESTAMP(3)),
+				c.changed_at)) AS modified
+			FROM catalog_changes c
+			WHERE c.id = 1
+		) m
+		WHERE m.modified < CURRENT_TIMESTAMP`
+	err := db.GetContext(ctx, &t, q)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		core.LogError("catalog modified", map[string]interface{}{"error": err.Error()})
+		return time.Time{}, err
+	}
+	return t, nil
+}
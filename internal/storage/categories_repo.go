@@ -0,0 +1,162 @@
+package storage
+
+// internal/storage/categories_repo.go
+import (
+	"context"
+	"sort"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Category — узел дерева категорий (parent_id = nil → корень)
+type Category struct {
+	ID        string      `db:"id" json:"id"`
+	ParentID  *string     `db:"parent_id" json:"parent_id,omitempty"`
+	Name      string      `db:"name" json:"name"`
+	Slug      string      `db:"slug" json:"slug"`
+	Position  int         `db:"position" json:"position"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
+	Children  []*Category `db:"-" json:"children,omitempty"` // Заполняется BuildCategoryTree
+}
+
+// ReservedCategorySlugs — slug, занятые статическими маршрутами /catalog/<slug>: такую категорию
+// не открыть. Запрещены в БД (migrations/018_categories_reserved_slugs.up.sql).
+var ReservedCategorySlugs = []string{"json"}
+
+// ListAllCategories — плоский список всех категорий (для построения дерева)
+func ListAllCategories(ctx context.Context, db *sqlx.DB) ([]Category, error) {
+	const q = `
+		SELECT id, parent_id, name, slug, position, created_at
+		FROM categories
+		ORDER BY position ASC, name ASC`
+
+	var items []Category
+	if err := db.SelectContext(ctx, &items, q); err != nil {
+		core.LogError("list all categories", map[string]interface{}{
+			"query": q,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetCategoryBySlug — категория по slug из URL (/catalog/:slug)
+func GetCategoryBySlug(ctx context.Context, db *sqlx.DB, slug string) (*Category, error) {
+	var cat Category
+
+	const q = `
+		SELECT id, parent_id, name, slug, position, created_at
+		FROM categories
+		WHERE slug = ?`
+
+	if err := db.GetContext(ctx, &cat, q, slug); err != nil {
+		core.LogError("get category by slug", map[string]interface{}{
+			"slug":  slug,
+			"error": err.Error(),
+			"query": q,
+		})
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// LoadCategoryTree — загружает все категории и собирает из них дерево
+func LoadCategoryTree(ctx context.Context, categories CategoryRepository) ([]*Category, error) {
+	items, err := categories.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCategoryTree(items), nil
+}
+
+// BuildCategoryTree — собирает дерево из плоского списка.
+// Категории с несуществующим родителем становятся корневыми, чтобы не "потеряться" в меню.
+func BuildCategoryTree(items []Category) []*Category {
+	nodes := make(map[string]*Category, len(items))
+	for i := range items {
+		c := items[i]
+		c.Children = nil
+		nodes[c.ID] = &c
+	}
+
+	var roots []*Category
+	for i := range items {
+		node := nodes[items[i].ID]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok && parent != node {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	sortCategories(roots)
+	return roots
+}
+
+// sortCategories — порядок как в админке: position, затем имя (рекурсивно)
+func sortCategories(list []*Category) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Position != list[j].Position {
+			return list[i].Position < list[j].Position
+		}
+		return list[i].Name < list[j].Name
+	})
+	for _, c := range list {
+		sortCategories(c.Children)
+	}
+}
+
+// FindCategoryPath — ищет категорию по slug в дереве и возвращает путь от корня до неё (хлебные крошки).
+// Возвращает nil, если slug не найден.
+func FindCategoryPath(tree []*Category, slug string) []*Category {
+	visited := map[string]bool{}
+
+	var walk func(list []*Category, path []*Category) []*Category
+	walk = func(list []*Category, path []*Category) []*Category {
+		for _, c := range list {
+			if visited[c.ID] {
+				continue
+			}
+			visited[c.ID] = true
+
+			cur := append(append([]*Category(nil), path...), c)
+			if c.Slug == slug {
+				return cur
+			}
+			if found := walk(c.Children, cur); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+
+	return walk(tree, nil)
+}
+
+// DescendantIDs — ID самой категории и всех её потомков (для фильтра товаров).
+// Защищено от циклов в parent_id.
+func (c *Category) DescendantIDs() []string {
+	visited := map[string]bool{}
+	var ids []string
+
+	var walk func(n *Category)
+	walk = func(n *Category) {
+		if n == nil || visited[n.ID] {
+			return
+		}
+		visited[n.ID] = true
+		ids = append(ids, n.ID)
+		for _, ch := range n.Children {
+			walk(ch)
+		}
+	}
+
+	walk(c)
+	return ids
+}
@@ -2,45 +2,39 @@ package storage
 
 import (
 	"context"
-	"strings"
 	"time"
 
 	"myApp/internal/core"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
-// CtxDBKey - ключ для хранения *sqlx.DB в контексте запроса
-type CtxDBKey struct{}
-
-// Константы подключения к MySQL (заменить на продакшн значения)
-const (
-	MySQLUser     = "root"           // Пользователь БД
-	MySQLPassword = "admin"          // Пароль БД (ЗАМЕНИТЬ!)
-	MySQLHost     = "localhost:3306" // Хост и порт MySQL
-	MySQLDatabase = "shop"           // Имя базы данных
-)
-
-// NewDB создаёт пул подключений к MySQL с продакшн-настройками
+// NewDB создаёт пул подключений к MySQL по настройкам из core.Config (DB_*)
 // Инициализирует connection pool и проверяет подключение
-func NewDB() (*sqlx.DB, error) {
-	dsn := getMySQLDSN()
+func NewDB(cfg core.DBConfig) (*sqlx.DB, error) {
+	mc, err := mysqlConfig(cfg)
+	if err != nil {
+		core.LogError("некорректный DB_DSN", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	dsn := mc.FormatDSN()
 
 	// Подключение к MySQL
 	db, err := sqlx.ConnectContext(context.Background(), "mysql", dsn)
 	if err != nil {
 		core.LogError("ошибка подключения к MySQL", map[string]interface{}{
 			"error": err.Error(),
-			"dsn":   getSanitizedDSN(dsn),
+			"dsn":   sanitizedDSN(mc),
 		})
 		return nil, err
 	}
 
-	// Настройка connection pool для продакшена
-	db.SetMaxOpenConns(25)                 // Максимум одновременных подключений
-	db.SetMaxIdleConns(25)                 // Подключения в пуле ожидания
-	db.SetConnMaxLifetime(5 * time.Minute) // Ротация соединений
+	// Настройка connection pool
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	// Проверка подключения
 	if err := db.PingContext(context.Background()); err != nil {
@@ -58,10 +52,10 @@ func NewDB() (*sqlx.DB, error) {
 
 	// Успешное подключение - логируем через LogInfo
 	core.LogInfo("MySQL подключение успешно", map[string]interface{}{
-		"host":     MySQLHost,
-		"database": MySQLDatabase,
-		"max_open": 25,
-		"max_idle": 25,
+		"host":     mc.Addr,
+		"database": mc.DBName,
+		"max_open": cfg.MaxOpenConns,
+		"max_idle": cfg.MaxIdleConns,
 	})
 
 	return db, nil
@@ -85,35 +79,41 @@ func Close(db *sqlx.DB) error {
 	return nil
 }
 
-// GetDBFromContext извлекает *sqlx.DB из контекста HTTP-запроса
-// Возвращает nil если DB не найдена (критическая ошибка)
-func GetDBFromContext(ctx context.Context) *sqlx.DB {
-	if db, ok := ctx.Value(CtxDBKey{}).(*sqlx.DB); ok {
-		return db
+// mysqlConfig — параметры драйвера: DB_DSN как есть или сборка из DB_* переменных.
+// parseTime и запрет multiStatements обязательны в обоих случаях: код сканирует
+// TIMESTAMP в time.Time, а миграции сами делят файлы на statement'ы.
+func mysqlConfig(cfg core.DBConfig) (*mysql.Config, error) {
+	var mc *mysql.Config
+	if cfg.DSN != "" {
+		parsed, err := mysql.ParseDSN(cfg.DSN)
+		if err != nil {
+			return nil, err
+		}
+		mc = parsed
+	} else {
+		mc = mysql.NewConfig()
+		mc.User = cfg.User
+		mc.Passwd = cfg.Password
+		mc.Net = "tcp"
+		mc.Addr = cfg.Host
+		mc.DBName = cfg.Name
+		mc.Timeout = cfg.ConnectTimeout                     // Таймаут подключения
+		mc.ReadTimeout = cfg.ReadTimeout                    // Таймаут чтения
+		mc.WriteTimeout = cfg.WriteTimeout                  // Таймаут записи
+		mc.InterpolateParams = true                         // Prepared statements
+		mc.Loc = time.Local                                 // Локальная временная зона
+		mc.Params = map[string]string{"charset": "utf8mb4"} // Unicode + эмодзи
 	}
-	return nil
+	mc.ParseTime = true        // Парсинг времени
+	mc.MultiStatements = false // Безопасность SQL
+	return mc, nil
 }
 
-// getMySQLDSN формирует строку подключения MySQL из констант
-func getMySQLDSN() string {
-	dsn := MySQLUser + ":" + MySQLPassword + "@tcp(" + MySQLHost + ")/" + MySQLDatabase
-	dsn += "?parseTime=true"         // Парсинг времени
-	dsn += "&charset=utf8mb4"        // Unicode + эмодзи
-	dsn += "&timeout=5s"             // Таймаут подключения
-	dsn += "&readTimeout=5s"         // Таймаут чтения
-	dsn += "&writeTimeout=10s"       // Таймаут записи
-	dsn += "&interpolateParams=true" // Prepared statements
-	dsn += "&multiStatements=false"  // Безопасность SQL
-	dsn += "&loc=Local"              // Локальная временная зона
-	return dsn
-}
-
-// getSanitizedDSN удаляет пароль из DSN для логирования
-func getSanitizedDSN(dsn string) string {
-	if passIdx := strings.Index(dsn, ":"); passIdx > 0 {
-		if atIdx := strings.Index(dsn[passIdx:], "@"); atIdx > 0 {
-			return dsn[:passIdx] + ":***@tcp(" + dsn[passIdx+atIdx+1:]
-		}
+// sanitizedDSN — DSN без пароля для логирования
+func sanitizedDSN(mc *mysql.Config) string {
+	c := mc.Clone()
+	if c.Passwd != "" {
+		c.Passwd = "***"
 	}
-	return dsn
+	return c.FormatDSN()
 }
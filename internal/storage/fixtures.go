@@ -0,0 +1,48 @@
+package storage
+
+// fixtures.go — начальные данные для хранилища в памяти (тесты, APP_STORAGE=memory).
+// DemoFixtures повторяет seeds/*.sql, чтобы оба режима показывали один каталог.
+import "myApp/internal/money"
+
+// Fixtures — начальное содержимое Memory
+type Fixtures struct {
+	Products   []Product
+	Variants   []Variant // Опции — в Variant.Options, цена — PriceOverride (Price считается при чтении)
+	Categories []Category
+}
+
+// DemoFixtures — демо-каталог: дерево категорий, пять товаров и варианты клавиатуры
+func DemoFixtures() Fixtures {
+	str := func(s string) *string { return &s }
+	opts := func(color, sw string) []VariantOption {
+		return []VariantOption{{Type: "color", Title: "Цвет", Value: color}, {Type: "switch", Title: "Переключатели", Value: sw}}
+	}
+	white := money.New(13900, "")
+
+	return Fixtures{
+		Categories: []Category{
+			{ID: "1", Name: "Электроника", Slug: "electronics", Position: 1},
+			{ID: "2", ParentID: str("1"), Name: "Телефоны и планшеты", Slug: "phones", Position: 1},
+			{ID: "3", ParentID: str("1"), Name: "Компьютеры", Slug: "computers", Position: 2},
+			{ID: "4", ParentID: str("3"), Name: "Периферия", Slug: "peripherals", Position: 1},
+			{ID: "5", ParentID: str("1"), Name: "Аудио", Slug: "audio", Position: 3},
+		},
+		Products: []Product{
+			{ID: "1", CategoryID: str("2"), Name: "Смартфон XYZ Pro", Article: "ART-001", Price: money.New(29999, ""), Stock: 25,
+				ImageAlt: str("Смартфон с 128GB"), Description: str("Флагманский смартфон: 128 ГБ памяти, OLED-экран и быстрая зарядка.")},
+			{ID: "2", CategoryID: str("3"), Name: "Ноутбук ABC Ultra", Article: "ART-002", Price: money.New(89900, ""), Stock: 10,
+				ImageAlt: str(`Ноутбук 16" i7`), Description: str("Лёгкий ноутбук с экраном 16 дюймов и процессором i7 для работы и учёбы.")},
+			{ID: "3", CategoryID: str("2"), Name: "Планшет DEF Mini", Article: "ART-003", Price: money.New(19950, ""), Stock: 15,
+				ImageAlt: str(`Планшет 10"`), Description: str("Компактный планшет с экраном 10 дюймов для чтения и видео.")},
+			{ID: "4", CategoryID: str("5"), Name: "Наушники GHI Wireless", Article: "ART-004", Price: money.New(7990, ""), Stock: 40,
+				ImageAlt: str("Беспроводные TWS"), Description: str("Беспроводные TWS-наушники с шумоподавлением и кейсом для зарядки.")},
+			{ID: "5", CategoryID: str("4"), Name: "Клавиатура KLM Mechanical", Article: "ART-005", Price: money.New(12900, ""),
+				Description: str("Механическая клавиатура с подсветкой и тактильными переключателями.")},
+		},
+		Variants: []Variant{
+			{ID: "1", ProductID: "5", SKU: "ART-005-BLK-RED", Stock: 10, Position: 1, Options: opts("Чёрный", "Red")},
+			{ID: "2", ProductID: "5", SKU: "ART-005-BLK-BRN", Stock: 5, Position: 2, Options: opts("Чёрный", "Brown")},
+			{ID: "3", ProductID: "5", SKU: "ART-005-WHT-BRN", PriceOverride: &white, Stock: 0, Position: 3, Options: opts("Белый", "Brown")},
+		},
+	}
+}
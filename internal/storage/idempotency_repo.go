@@ -0,0 +1,128 @@
+package storage
+
+// idempotency_repo.go — ключи Idempotency-Key (idempotency_keys): первый ответ на небезопасный
+// запрос к /api/v1 хранится до истечения срока и отдаётся на повторы того же запроса.
+// Ключ занимается вставкой строки (UNIQUE scope + idem_key), поэтому два параллельных запроса
+// с одним ключом не выполнятся оба.
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// IdempotencyRecord — ключ Idempotency-Key и первый ответ на запрос с ним
+type IdempotencyRecord struct {
+	Scope       string      // Чей ключ: "token:7", "user:3", "session:..."
+	Key         string      // Значение заголовка Idempotency-Key
+	Fingerprint string      // SHA-256 метода, пути и тела запроса
+	Status      int         // Код ответа; 0 — запрос ещё выполняется
+	Header      http.Header // Заголовки, выставленные обработчиком
+	Body        []byte
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// Done — ответ сохранён и его можно повторить
+func (r *IdempotencyRecord) Done() bool {
+	return r.Status != 0
+}
+
+// idempotencyRow — строка idempotency_keys (status и headers могут быть NULL)
+type idempotencyRow struct {
+	Scope       string         `db:"scope"`
+	Key         string         `db:"idem_key"`
+	Fingerprint string         `db:"fingerprint"`
+	Status      sql.NullInt64  `db:"status"`
+	Headers     sql.NullString `db:"headers"`
+	Body        []byte         `db:"body"`
+	ExpiresAt   time.Time      `db:"expires_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+// BeginIdempotency — занимает ключ rec.Scope + rec.Key на ttl. Свободен — prev = nil, запрос
+// выполняется. Занят — prev: сохранённый ответ или запрос, который ещё выполняется.
+// Просроченные ключи и незавершённые дольше stale (процесс упал посреди запроса) освобождаются.
+func BeginIdempotency(ctx context.Context, db *sqlx.DB, rec *IdempotencyRecord, ttl, stale time.Duration) (prev *IdempotencyRecord, err error) {
+	const qPurge = `
+		DELETE FROM idempotency_keys
+		WHERE expires_at <= CURRENT_TIMESTAMP
+		   OR (scope = ? AND idem_key = ? AND status IS NULL AND created_at <= CURRENT_TIMESTAMP - INTERVAL ? SECOND)`
+	if _, err := db.ExecContext(ctx, qPurge, rec.Scope, rec.Key, int64(stale/time.Second)); err != nil {
+		core.LogError("purge idempotency keys", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+
+	const qInsert = `
+		INSERT IGNORE INTO idempotency_keys (scope, idem_key, fingerprint, expires_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP + INTERVAL ? SECOND)`
+	res, err := db.ExecContext(ctx, qInsert, rec.Scope, rec.Key, rec.Fingerprint, int64(ttl/time.Second))
+	if err != nil {
+		core.LogError("begin idempotency key", map[string]interface{}{"scope": rec.Scope, "error": err.Error()})
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, nil
+	}
+
+	var row idempotencyRow
+	const qGet = `
+		SELECT scope, idem_key, fingerprint, status, headers, body, expires_at, created_at
+		FROM idempotency_keys WHERE scope = ? AND idem_key = ?`
+	if err := db.GetContext(ctx, &row, qGet, rec.Scope, rec.Key); err != nil {
+		core.LogError("get idempotency key", map[string]interface{}{"scope": rec.Scope, "error": err.Error()})
+		return nil, err
+	}
+	return row.record()
+}
+
+// CompleteIdempotency — сохраняет ответ rec.Status, rec.Header, rec.Body для повторов
+func CompleteIdempotency(ctx context.Context, db *sqlx.DB, rec *IdempotencyRecord) error {
+	headers, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+	const q = `
+		UPDATE idempotency_keys SET status = ?, headers = ?, body = ?
+		WHERE scope = ? AND idem_key = ? AND fingerprint = ? AND status IS NULL`
+	if _, err := db.ExecContext(ctx, q, rec.Status, string(headers), rec.Body, rec.Scope, rec.Key, rec.Fingerprint); err != nil {
+		core.LogError("complete idempotency key", map[string]interface{}{"scope": rec.Scope, "error": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotency — освобождает незавершённый ключ: ответ не сохраняется, повтор
+// запроса выполнится заново (например, после ошибки сервера)
+func ReleaseIdempotency(ctx context.Context, db *sqlx.DB, scope, key string) error {
+	const q = `DELETE FROM idempotency_keys WHERE scope = ? AND idem_key = ? AND status IS NULL`
+	if _, err := db.ExecContext(ctx, q, scope, key); err != nil {
+		core.LogError("release idempotency key", map[string]interface{}{"scope": scope, "error": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// record — запись из строки таблицы
+func (row idempotencyRow) record() (*IdempotencyRecord, error) {
+	rec := &IdempotencyRecord{
+		Scope:       row.Scope,
+		Key:         row.Key,
+		Fingerprint: row.Fingerprint,
+		Status:      int(row.Status.Int64),
+		Body:        row.Body,
+		ExpiresAt:   row.ExpiresAt,
+		CreatedAt:   row.CreatedAt,
+	}
+	if row.Headers.Valid {
+		if err := json.Unmarshal([]byte(row.Headers.String), &rec.Header); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
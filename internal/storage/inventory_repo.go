@@ -0,0 +1,447 @@
+package storage
+
+// internal/storage/inventory_repo.go — остатки и резервы. Остаток хранится у товара без вариантов
+// (products.stock) или у каждого варианта (product_variants.stock). Доступно = остаток − действующие
+// резервы до expires_at: корзины, которая сейчас оформляется (RESERVATION_TTL), и неоплаченного
+// заказа (PENDING_ORDER_TTL; просроченный заказ отменяет CancelExpiredOrders).
+// Списание — при оплате, условным UPDATE ... WHERE stock >= ?; каждое изменение пишется
+// в журнал inventory_movements.
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MovementReason — причина движения остатка
+type MovementReason string
+
+const (
+	MovementSale   MovementReason = "sale"   // Списание при оплате заказа
+	MovementRefund MovementReason = "refund" // Возврат на склад при возврате денег за неотгруженный заказ
+	MovementAdjust MovementReason = "adjust" // Правка остатка в панели
+)
+
+// Title — причина для журнала в панели
+func (r MovementReason) Title() string {
+	switch r {
+	case MovementSale:
+		return "Продажа"
+	case MovementRefund:
+		return "Возврат"
+	case MovementAdjust:
+		return "Корректировка"
+	}
+	return string(r)
+}
+
+// StockMovement — запись журнала движений остатков
+type StockMovement struct {
+	ID         string         `db:"id" json:"id"`
+	ProductID  string         `db:"product_id" json:"product_id"`
+	VariantID  *string        `db:"variant_id" json:"variant_id,omitempty"` // nil — товар без вариантов
+	Delta      int            `db:"delta" json:"delta"`
+	StockAfter int            `db:"stock_after" json:"stock_after"`
+	Reason     MovementReason `db:"reason" json:"reason"`
+	OrderID    *string        `db:"order_id" json:"order_id,omitempty"` // Заказ (для sale и refund)
+	UserID     *string        `db:"user_id" json:"user_id,omitempty"`   // Кто правил (для adjust)
+	Note       *string        `db:"note" json:"note,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// StockAdjustment — новый остаток товара (VariantID = "") или варианта из панели
+type StockAdjustment struct {
+	ProductID string
+	VariantID string
+	Stock     int
+	UserID    string
+	Note      string
+}
+
+// ErrOutOfStock — товара не хватает: резерв при оформлении, подтверждение или оплата заказа
+var ErrOutOfStock = &core.AppError{Code: "out_of_stock", Status: http.StatusConflict,
+	Message: "Некоторых товаров нет в нужном количестве"}
+
+// errBelowReserved — новый остаток меньше уже зарезервированного
+func errBelowReserved(reserved int) error {
+	return &core.AppError{Code: "validation", Status: http.StatusConflict, Message: "Остаток меньше резерва",
+		Fields: map[string]string{"stock": fmt.Sprintf("Не меньше зарезервированного: %d", reserved)}}
+}
+
+// activeReservationSQL — резерв r действует: корзина или неоплаченный заказ в пределах срока
+const activeReservationSQL = `r.expires_at > CURRENT_TIMESTAMP`
+
+// reservedSQL — сумма действующих резервов позиции (выражения для product_id и variant_id)
+func reservedSQL(productID, variantID string) string {
+	return `COALESCE((SELECT SUM(r.quantity) FROM stock_reservations r
+		WHERE r.product_id = ` + productID + ` AND r.variant_id = ` + variantID + ` AND ` + activeReservationSQL + `), 0)`
+}
+
+// variantAvailableSQL — доступно варианта v
+var variantAvailableSQL = `GREATEST(v.stock - ` + reservedSQL("v.product_id", "v.id") + `, 0)`
+
+// ProductStockColumns — остаток и доступное количество товара p для списка колонок SELECT.
+// У товара с вариантами доступно — сумма по вариантам, products.stock не учитывается.
+var ProductStockColumns = `p.stock, (CASE
+		WHEN EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = p.id)
+		THEN (SELECT COALESCE(SUM(GREATEST(pv.stock - ` + reservedSQL("pv.product_id", "pv.id") + `, 0)), 0)
+		      FROM product_variants pv WHERE pv.product_id = p.id)
+		ELSE GREATEST(p.stock - ` + reservedSQL("p.id", "0") + `, 0)
+	END) AS available`
+
+// stockLine — позиция учёта остатка: variant_id = "0" — товар без вариантов
+type stockLine struct {
+	ProductID string `db:"product_id"`
+	VariantID string `db:"variant_id"`
+	Quantity  int    `db:"quantity"`
+}
+
+// sortStockLines — блокировки берутся в одном порядке (product_id, variant_id): меньше взаимоблокировок
+func sortStockLines(lines []stockLine) {
+	slices.SortFunc(lines, func(a, b stockLine) int {
+		if c := compareIDs(a.ProductID, b.ProductID); c != 0 {
+			return c
+		}
+		return compareIDs(a.VariantID, b.VariantID)
+	})
+}
+
+// lockStockTx — остаток позиции с блокировкой строки (FOR UPDATE); sql.ErrNoRows — нет такой
+func lockStockTx(ctx context.Context, tx *sqlx.Tx, productID, variantID string) (int, error) {
+	var stock int
+	var err error
+	if variantID == "0" {
+		err = tx.GetContext(ctx, &stock, `SELECT stock FROM products WHERE id = ? FOR UPDATE`, productID)
+	} else {
+		err = tx.GetContext(ctx, &stock, `SELECT stock FROM product_variants WHERE id = ? AND product_id = ? FOR UPDATE`,
+			variantID, productID)
+	}
+	return stock, err
+}
+
+// reservedTx — действующие резервы позиции, кроме резерва корзины exceptCart ("" — все).
+// Блокирующее чтение: снимок REPEATABLE READ не увидел бы резервы параллельных транзакций.
+func reservedTx(ctx context.Context, tx *sqlx.Tx, productID, variantID, exceptCart string) (int, error) {
+	var n int
+	err := tx.GetContext(ctx, &n, `
+		SELECT COALESCE(SUM(r.quantity), 0) FROM stock_reservations r
+		WHERE r.product_id = ? AND r.variant_id = ? AND `+activeReservationSQL+` AND NOT (r.cart_id <=> ?)
+		FOR UPDATE`, productID, variantID, exceptCart)
+	return n, err
+}
+
+// checkStockTx — хватает ли остатка на позиции с учётом чужих резервов; иначе ErrOutOfStock
+func checkStockTx(ctx context.Context, tx *sqlx.Tx, lines []stockLine, cartID string) error {
+	for _, l := range lines {
+		stock, err := lockStockTx(ctx, tx, l.ProductID, l.VariantID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOutOfStock
+		}
+		if err != nil {
+			return err
+		}
+		reserved, err := reservedTx(ctx, tx, l.ProductID, l.VariantID, cartID)
+		if err != nil {
+			return err
+		}
+		if stock-reserved < l.Quantity {
+			core.LogInfo("Недостаточно товара", map[string]interface{}{
+				"product_id": l.ProductID,
+				"variant_id": l.VariantID,
+				"stock":      stock,
+				"reserved":   reserved,
+				"wanted":     l.Quantity,
+			})
+			return ErrOutOfStock
+		}
+	}
+	return nil
+}
+
+// insertReservationsTx — резервы позиций на ttl: за корзиной (cartID) или за заказом (orderID)
+func insertReservationsTx(ctx context.Context, tx *sqlx.Tx, cartID, orderID *string, ttl time.Duration, lines []stockLine) error {
+	const q = `
+		INSERT INTO stock_reservations (cart_id, order_id, product_id, variant_id, quantity, expires_at)
+		VALUES (?, ?, ?, ?, ?, DATE_ADD(CURRENT_TIMESTAMP, INTERVAL ? SECOND))`
+	for _, l := range lines {
+		if _, err := tx.ExecContext(ctx, q, cartID, orderID, l.ProductID, l.VariantID, l.Quantity, int(ttl.Seconds())); err != nil {
+			core.LogError("insert stock reservation", map[string]interface{}{"error": err.Error()})
+			return err
+		}
+	}
+	return nil
+}
+
+// ReserveCart — резервирует позиции корзины на ttl (прежний резерв корзины заменяется).
+// Не хватает товара — ErrOutOfStock, резерв не меняется.
+func ReserveCart(ctx context.Context, db *sqlx.DB, cartID string, ttl time.Duration) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Просроченные резервы корзин уже не действуют — заодно убираем их.
+	// Просроченные резервы заказов остаются: по ним CancelExpiredOrders находит заказы для отмены.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stock_reservations WHERE order_id IS NULL AND expires_at <= CURRENT_TIMESTAMP`); err != nil {
+		return err
+	}
+
+	var lines []stockLine
+	const q = `
+		SELECT ci.product_id, ci.variant_id, ci.quantity
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id AND v.product_id = ci.product_id
+		WHERE ci.cart_id = ? AND (ci.variant_id = 0 OR v.id IS NOT NULL)`
+	if err := tx.SelectContext(ctx, &lines, q, cartID); err != nil {
+		core.LogError("reserve cart: load cart", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	sortStockLines(lines)
+
+	if err := checkStockTx(ctx, tx, lines, cartID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stock_reservations WHERE cart_id = ?`, cartID); err != nil {
+		return err
+	}
+	if err := insertReservationsTx(ctx, tx, &cartID, nil, ttl, lines); err != nil {
+		return err
+	}
+	if err := touchCatalog(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReleaseCart — снимает резерв корзины (корзина изменилась или заказ не оформлен)
+func ReleaseCart(ctx context.Context, db *sqlx.DB, cartID string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM stock_reservations WHERE cart_id = ?`, cartID); err != nil {
+		core.LogError("release cart reservation", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	return touchCatalog(ctx, db)
+}
+
+// reserveOrderTx — при оформлении заказа: проверка остатка (без учёта резерва своей корзины),
+// резерв корзины заменяется резервом заказа на ttl
+func reserveOrderTx(ctx context.Context, tx *sqlx.Tx, cartID, orderID string, items []CartLine, ttl time.Duration) error {
+	lines := make([]stockLine, 0, len(items))
+	for _, it := range items {
+		l := stockLine{ProductID: it.ProductID, VariantID: "0", Quantity: it.Quantity}
+		if it.VariantID != nil {
+			l.VariantID = *it.VariantID
+		}
+		lines = append(lines, l)
+	}
+	sortStockLines(lines)
+
+	if err := checkStockTx(ctx, tx, lines, cartID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stock_reservations WHERE cart_id = ?`, cartID); err != nil {
+		return err
+	}
+	if err := insertReservationsTx(ctx, tx, nil, &orderID, ttl, lines); err != nil {
+		return err
+	}
+	return touchCatalog(ctx, tx)
+}
+
+// commitOrderStockTx — при оплате: списывает резерв заказа с остатков. Условный UPDATE
+// (stock >= ?) не даёт уйти в минус даже при рассинхроне; тогда — ErrOutOfStock и откат.
+// Резерв истёк (заказ ещё не отменён) — товар мог уйти в чужие резервы: остаток проверяется
+// заново с их учётом. У заказов, оформленных до учёта остатков, резерва нет — списывать нечего.
+func commitOrderStockTx(ctx context.Context, tx *sqlx.Tx, orderID string) error {
+	var lines []stockLine
+	if err := tx.SelectContext(ctx, &lines, `
+		SELECT product_id, variant_id, quantity FROM stock_reservations
+		WHERE order_id = ? ORDER BY product_id, variant_id FOR UPDATE`, orderID); err != nil {
+		return err
+	}
+
+	var expired bool
+	if err := tx.GetContext(ctx, &expired, `
+		SELECT EXISTS(SELECT 1 FROM stock_reservations WHERE order_id = ? AND expires_at <= CURRENT_TIMESTAMP)`,
+		orderID); err != nil {
+		return err
+	}
+	if expired {
+		if err := checkStockTx(ctx, tx, lines, ""); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range lines {
+		table, id := "products", l.ProductID
+		if l.VariantID != "0" {
+			table, id = "product_variants", l.VariantID
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE `+table+` SET stock = stock - ? WHERE id = ? AND stock >= ?`,
+			l.Quantity, id, l.Quantity)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			core.LogError("Остатка не хватает для оплаченного заказа", map[string]interface{}{
+				"order_id":   orderID,
+				"product_id": l.ProductID,
+				"variant_id": l.VariantID,
+				"quantity":   l.Quantity,
+			})
+			return ErrOutOfStock
+		}
+		if err := touchProductTx(ctx, tx, table, l.ProductID); err != nil {
+			return err
+		}
+		var after int
+		if err := tx.GetContext(ctx, &after, `SELECT stock FROM `+table+` WHERE id = ?`, id); err != nil {
+			return err
+		}
+		if err := insertMovementTx(ctx, tx, l.ProductID, l.VariantID, -l.Quantity, after, MovementSale, orderID, "", ""); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stock_reservations WHERE order_id = ?`, orderID); err != nil {
+		return err
+	}
+	return touchCatalog(ctx, tx)
+}
+
+// releaseOrderStockTx — при отмене: резерв заказа снимается, остатки не меняются
+func releaseOrderStockTx(ctx context.Context, tx *sqlx.Tx, orderID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stock_reservations WHERE order_id = ?`, orderID); err != nil {
+		return err
+	}
+	return touchCatalog(ctx, tx)
+}
+
+// restockOrderTx — при возврате денег за неотгруженный заказ: списанное при оплате (движения sale
+// заказа) возвращается на остатки с движением refund. Удалённые с тех пор товар или вариант пропускаются.
+func restockOrderTx(ctx context.Context, tx *sqlx.Tx, orderID string) error {
+	var lines []stockLine
+	if err := tx.SelectContext(ctx, &lines, `
+		SELECT product_id, variant_id, -SUM(delta) AS quantity FROM inventory_movements
+		WHERE order_id = ? AND reason = ?
+		GROUP BY product_id, variant_id ORDER BY product_id, variant_id`, orderID, MovementSale); err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		table, id := "products", l.ProductID
+		if l.VariantID != "0" {
+			table, id = "product_variants", l.VariantID
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE `+table+` SET stock = stock + ? WHERE id = ?`, l.Quantity, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+		if err := touchProductTx(ctx, tx, table, l.ProductID); err != nil {
+			return err
+		}
+		var after int
+		if err := tx.GetContext(ctx, &after, `SELECT stock FROM `+table+` WHERE id = ?`, id); err != nil {
+			return err
+		}
+		if err := insertMovementTx(ctx, tx, l.ProductID, l.VariantID, l.Quantity, after, MovementRefund, orderID, "", ""); err != nil {
+			return err
+		}
+	}
+	return touchCatalog(ctx, tx)
+}
+
+// touchProductTx — отмечает изменение товара после правки остатка в table: у самого товара
+// updated_at обновляет ON UPDATE, у варианта — этот запрос
+func touchProductTx(ctx context.Context, tx *sqlx.Tx, table, productID string) error {
+	if table == "products" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE products SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, productID)
+	return err
+}
+
+// insertMovementTx — запись журнала ("" в orderID, userID, note — NULL)
+func insertMovementTx(ctx context.Context, tx *sqlx.Tx, productID, variantID string, delta, after int,
+	reason MovementReason, orderID, userID, note string) error {
+	const q = `
+		INSERT INTO inventory_movements (product_id, variant_id, delta, stock_after, reason, order_id, user_id, note)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))`
+	if _, err := tx.ExecContext(ctx, q, productID, variantID, delta, after, reason, orderID, userID, note); err != nil {
+		core.LogError("insert inventory movement", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// AdjustStock — задаёт остаток товара или варианта и пишет движение adjust.
+// Меньше действующего резерва — ошибка валидации; нет такой позиции — sql.ErrNoRows.
+func AdjustStock(ctx context.Context, db *sqlx.DB, a StockAdjustment) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	variantID := variantKey(a.VariantID)
+	cur, err := lockStockTx(ctx, tx, a.ProductID, variantID)
+	if err != nil {
+		return err
+	}
+	reserved, err := reservedTx(ctx, tx, a.ProductID, variantID, "")
+	if err != nil {
+		return err
+	}
+	if a.Stock < reserved {
+		return errBelowReserved(reserved)
+	}
+	if a.Stock == cur {
+		return nil
+	}
+
+	table, id := "products", a.ProductID
+	if variantID != "0" {
+		table, id = "product_variants", variantID
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET stock = ? WHERE id = ?`, a.Stock, id); err != nil {
+		core.LogError("adjust stock", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	if err := touchProductTx(ctx, tx, table, a.ProductID); err != nil {
+		return err
+	}
+	if err := insertMovementTx(ctx, tx, a.ProductID, variantID, a.Stock-cur, a.Stock, MovementAdjust, "", a.UserID, a.Note); err != nil {
+		return err
+	}
+	if err := touchCatalog(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListStockMovements — последние движения по товару и его вариантам (новые первыми)
+func ListStockMovements(ctx context.Context, db *sqlx.DB, productID string, limit int) ([]StockMovement, error) {
+	var list []StockMovement
+	const q = `
+		SELECT id, product_id, NULLIF(variant_id, 0) AS variant_id, delta, stock_after, reason, order_id, user_id, note, created_at
+		FROM inventory_movements
+		WHERE product_id = ?
+		ORDER BY id DESC
+		LIMIT ?`
+	if err := db.SelectContext(ctx, &list, q, productID, limit); err != nil {
+		core.LogError("list inventory movements", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,1491 @@
+package storage
+
+// memory.go — хранилище в памяти: для тестов и запуска без MySQL (APP_STORAGE=memory).
+// Семантика как у MySQL-реализации: "не найдено" — sql.ErrNoRows, заказ оформляется атомарно,
+// события провайдера идемпотентны. Данные живут до перезапуска процесса.
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"myApp/internal/core"
+)
+
+// Memory — потокобезопасное хранилище в памяти. Один мьютекс на всё хранилище:
+// каждая операция целиком выполняется под ним, как транзакция в MySQL.
+type Memory struct {
+	mu          sync.RWMutex
+	products    []Product
+	variants    []Variant // Опции — прямо в Variant.Options; Price и Available пересчитываются при чтении
+	categories  []Category
+	carts       map[string]*memoryCart
+	orders      []*Order
+	payments    []*Payment
+	users       []*User
+	tokens      []*memoryToken
+	apiTokens   []*APIToken
+	idempotency map[string]*IdempotencyRecord // scope + "\n" + ключ
+	reserved    []memoryReservation           // Резервы корзин и заказов (stock_reservations)
+	movements   []StockMovement               // Журнал движений остатков
+	roles       map[string][]Permission       // Права ролей (DefaultRolePermissions)
+	events      map[string]string             // provider + "/" + event_id — обработанные события (значение — conflict)
+	changed     time.Time                     // Последнее изменение товаров, остатков или резервов (catalog_changes)
+	seq         int64                         // Автоинкремент ID заказов, позиций, платежей и пользователей
+}
+
+// memoryCart — корзина; позиции в порядке добавления (как ORDER BY added_at)
+type memoryCart struct {
+	Cart
+	items []memoryCartItem
+}
+
+type memoryCartItem struct {
+	productID string
+	variantID string // "" — товар без вариантов
+	quantity  int
+}
+
+// memoryReservation — резерв позиции до expires за корзиной (cartID) или за заказом (orderID)
+type memoryReservation struct {
+	memoryCartItem
+	cartID  string
+	orderID string
+	expires time.Time
+}
+
+// active — резерв ещё действует (как activeReservationSQL)
+func (r memoryReservation) active(now time.Time) bool {
+	return r.expires.After(now)
+}
+
+// NewMemory — хранилище с начальными данными (например, DemoFixtures())
+func NewMemory(fx Fixtures) *Memory {
+	m := &Memory{
+		products:    append([]Product(nil), fx.Products...),
+		variants:    append([]Variant(nil), fx.Variants...),
+		categories:  append([]Category(nil), fx.Categories...),
+		carts:       map[string]*memoryCart{},
+		events:      map[string]string{},
+		idempotency: map[string]*IdempotencyRecord{},
+		roles:       DefaultRolePermissions(),
+	}
+	now := time.Now()
+	m.changed = now
+	for i := range m.products {
+		if m.products[i].CreatedAt.IsZero() {
+			m.products[i].CreatedAt = now
+		}
+		if m.products[i].UpdatedAt.IsZero() {
+			m.products[i].UpdatedAt = m.products[i].CreatedAt
+		}
+	}
+	for i := range m.categories {
+		m.categories[i].Children = nil
+		if m.categories[i].CreatedAt.IsZero() {
+			m.categories[i].CreatedAt = now
+		}
+	}
+	return m
+}
+
+// Repositories — репозитории поверх этого хранилища
+func (m *Memory) Repositories() Repositories {
+	return Repositories{
+		Products:    memoryProducts{m},
+		Variants:    memoryVariants{m},
+		Inventory:   memoryInventory{m},
+		Categories:  memoryCategories{m},
+		Carts:       memoryCarts{m},
+		Orders:      memoryOrders{m},
+		Payments:    memoryPayments{m},
+		Users:       memoryUsers{m},
+		Tokens:      memoryTokens{m},
+		APITokens:   memoryAPITokens{m},
+		Idempotency: memoryIdempotency{m},
+		Roles:       memoryRoles{m},
+	}
+}
+
+// nextID — следующий автоинкрементный ID (вызывать под m.mu)
+func (m *Memory) nextID() string {
+	m.seq++
+	return strconv.FormatInt(m.seq, 10)
+}
+
+// product — товар по ID (вызывать под m.mu)
+func (m *Memory) product(id string) (*Product, bool) {
+	for i := range m.products {
+		if m.products[i].ID == id {
+			return &m.products[i], true
+		}
+	}
+	return nil, false
+}
+
+// variant — вариант по ID с ценой по текущей цене товара (вызывать под m.mu)
+func (m *Memory) variant(id string) (Variant, bool) {
+	for _, v := range m.variants {
+		if v.ID != id {
+			continue
+		}
+		p, ok := m.product(v.ProductID)
+		if !ok {
+			return Variant{}, false
+		}
+		v.Price = p.Price
+		if v.PriceOverride != nil {
+			v.Price = *v.PriceOverride
+		}
+		v.Options = append([]VariantOption(nil), v.Options...)
+		v.Available = max(v.Stock-m.reservedQty(v.ProductID, v.ID, ""), 0)
+		return v, true
+	}
+	return Variant{}, false
+}
+
+// order — заказ по внутреннему ID (вызывать под m.mu)
+func (m *Memory) order(id string) (*Order, bool) {
+	for _, o := range m.orders {
+		if o.ID == id {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+// compareIDs — числовые ID сравниваются как числа (как BIGINT в MySQL)
+func compareIDs(a, b string) int {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// --- Товары ---
+
+type memoryProducts struct{ m *Memory }
+
+func (r memoryProducts) ListAll(_ context.Context) ([]Product, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	items := append([]Product(nil), r.m.products...)
+	for i := range items {
+		items[i].Available = r.m.productAvailable(items[i].ID)
+	}
+	sortProducts(items, "name")
+	return items, nil
+}
+
+func (r memoryProducts) GetByID(_ context.Context, id int) (*Product, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	p, ok := r.m.product(strconv.Itoa(id))
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	cp := *p
+	cp.Available = r.m.productAvailable(cp.ID)
+	return &cp, nil
+}
+
+func (r memoryProducts) List(_ context.Context, q ProductQuery) (*ProductPage, error) {
+	q = q.Normalize()
+
+	inCategory := make(map[string]bool, len(q.CategoryIDs))
+	for _, id := range q.CategoryIDs {
+		inCategory[id] = true
+	}
+
+	r.m.mu.RLock()
+	var matched []Product
+	for _, p := range r.m.products {
+		if len(inCategory) > 0 && (p.CategoryID == nil || !inCategory[*p.CategoryID]) {
+			continue
+		}
+		if q.MinPrice != nil && p.Price.Cmp(*q.MinPrice) < 0 {
+			continue
+		}
+		if q.MaxPrice != nil && p.Price.Cmp(*q.MaxPrice) > 0 {
+			continue
+		}
+		if text := strings.ToLower(q.Text); text != "" &&
+			!strings.Contains(strings.ToLower(p.Name), text) && !strings.Contains(strings.ToLower(p.Article), text) {
+			continue
+		}
+		p.Available = r.m.productAvailable(p.ID)
+		matched = append(matched, p)
+	}
+	r.m.mu.RUnlock()
+
+	page := &ProductPage{Total: len(matched), Page: q.Page, PageSize: q.PageSize}
+	if page.Total == 0 {
+		return page, nil
+	}
+
+	sortProducts(matched, q.Sort)
+	from := (q.Page - 1) * q.PageSize
+	if from > len(matched) {
+		from = len(matched)
+	}
+	to := from + q.PageSize
+	if to > len(matched) {
+		to = len(matched)
+	}
+	page.Items = matched[from:to]
+	return page, nil
+}
+
+func (r memoryProducts) Create(_ context.Context, p *Product) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if r.m.articleTaken(p.Article, "") {
+		return ErrArticleTaken
+	}
+	// ID товаров — свой автоинкремент (демо-каталог приходит с готовыми ID)
+	var maxID int64
+	for _, x := range r.m.products {
+		if n, _ := strconv.ParseInt(x.ID, 10, 64); n > maxID {
+			maxID = n
+		}
+	}
+	p.ID = strconv.FormatInt(maxID+1, 10)
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	r.m.products = append(r.m.products, *p)
+	r.m.touchCatalog()
+	return nil
+}
+
+func (r memoryProducts) Update(_ context.Context, p *Product) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	cur, ok := r.m.product(p.ID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if r.m.articleTaken(p.Article, p.ID) {
+		return ErrArticleTaken
+	}
+	// Остаток меняется только через InventoryRepository, как и в UpdateProduct
+	p.CreatedAt, p.Stock, p.UpdatedAt = cur.CreatedAt, cur.Stock, time.Now()
+	*cur = *p
+	r.m.touchCatalog()
+	return nil
+}
+
+func (r memoryProducts) Delete(_ context.Context, id int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	pid := strconv.Itoa(id)
+	i := slices.IndexFunc(r.m.products, func(p Product) bool { return p.ID == pid })
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	r.m.products = slices.Delete(r.m.products, i, i+1)
+	// Как ON DELETE CASCADE у product_variants и cart_items
+	r.m.variants = slices.DeleteFunc(r.m.variants, func(v Variant) bool { return v.ProductID == pid })
+	for _, cart := range r.m.carts {
+		cart.items = slices.DeleteFunc(cart.items, func(it memoryCartItem) bool { return it.productID == pid })
+	}
+	r.m.reserved = slices.DeleteFunc(r.m.reserved, func(res memoryReservation) bool { return res.productID == pid })
+	r.m.touchCatalog()
+	return nil
+}
+
+// Modified — как CatalogModified: последнее изменение с учётом истёкших резервов;
+// нулевое время — изменение в текущую секунду
+func (r memoryProducts) Modified(_ context.Context) (time.Time, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	now := time.Now()
+	modified := r.m.changed
+	for _, res := range r.m.reserved {
+		if !res.active(now) && res.expires.After(modified) {
+			modified = res.expires
+		}
+	}
+	if !modified.Before(now.Truncate(time.Second)) {
+		return time.Time{}, nil
+	}
+	return modified, nil
+}
+
+// articleTaken — артикул занят другим товаром (без учёта регистра, как utf8mb4-collation в MySQL)
+func (m *Memory) articleTaken(article, exceptID string) bool {
+	return slices.ContainsFunc(m.products, func(p Product) bool {
+		return p.ID != exceptID && strings.EqualFold(p.Article, article)
+	})
+}
+
+// sortProducts — та же сортировка, что productSorts в SQL (при равенстве — по id)
+func sortProducts(items []Product, sortKey string) {
+	desc := strings.HasPrefix(sortKey, "-")
+	field := strings.TrimPrefix(sortKey, "-")
+
+	slices.SortStableFunc(items, func(a, b Product) int {
+		var c int
+		switch field {
+		case "price":
+			c = a.Price.Cmp(b.Price)
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		default:
+			c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+		if c == 0 {
+			c = compareIDs(a.ID, b.ID)
+		}
+		if desc {
+			c = -c
+		}
+		return c
+	})
+}
+
+// --- Варианты ---
+
+type memoryVariants struct{ m *Memory }
+
+func (r memoryVariants) ByProducts(_ context.Context, productIDs []string) (map[string][]Variant, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	out := map[string][]Variant{}
+	for _, pid := range productIDs {
+		for _, v := range r.m.variants {
+			if v.ProductID != pid {
+				continue
+			}
+			if cp, ok := r.m.variant(v.ID); ok {
+				out[pid] = append(out[pid], cp)
+			}
+		}
+		slices.SortStableFunc(out[pid], func(a, b Variant) int {
+			if a.Position != b.Position {
+				return a.Position - b.Position
+			}
+			return compareIDs(a.ID, b.ID)
+		})
+	}
+	return out, nil
+}
+
+func (r memoryVariants) GetByID(_ context.Context, id int) (*Variant, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	v, ok := r.m.variant(strconv.Itoa(id))
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &v, nil
+}
+
+// --- Остатки ---
+
+type memoryInventory struct{ m *Memory }
+
+func (r memoryInventory) Reserve(_ context.Context, cartID string, ttl time.Duration) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now()
+	// Просроченные резервы заказов остаются до CancelExpired, как и в ReserveCart
+	r.m.reserved = slices.DeleteFunc(r.m.reserved, func(res memoryReservation) bool {
+		return res.orderID == "" && !res.active(now)
+	})
+
+	cart, ok := r.m.carts[cartID]
+	if !ok {
+		return nil
+	}
+	items := stockItems(r.m.cartLines(cart))
+	if err := r.m.checkStock(items, cartID); err != nil {
+		return err
+	}
+	r.m.releaseCart(cartID)
+	for _, it := range items {
+		r.m.reserved = append(r.m.reserved, memoryReservation{memoryCartItem: it, cartID: cartID, expires: now.Add(ttl)})
+	}
+	return nil
+}
+
+func (r memoryInventory) Release(_ context.Context, cartID string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	r.m.releaseCart(cartID)
+	return nil
+}
+
+func (r memoryInventory) Adjust(_ context.Context, a StockAdjustment) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stock, ok := r.m.stock(a.ProductID, a.VariantID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if reserved := r.m.reservedQty(a.ProductID, a.VariantID, ""); a.Stock < reserved {
+		return errBelowReserved(reserved)
+	}
+	if a.Stock == *stock {
+		return nil
+	}
+	delta := a.Stock - *stock
+	*stock = a.Stock
+	r.m.touchCatalog()
+	r.m.addMovement(a.ProductID, a.VariantID, delta, a.Stock, MovementAdjust, "", a.UserID, a.Note)
+	return nil
+}
+
+func (r memoryInventory) Movements(_ context.Context, productID string, limit int) ([]StockMovement, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var list []StockMovement
+	for i := len(r.m.movements) - 1; i >= 0 && len(list) < limit; i-- {
+		if mv := r.m.movements[i]; mv.ProductID == productID {
+			list = append(list, mv)
+		}
+	}
+	return list, nil
+}
+
+// stockItems — позиции корзины как единицы учёта остатка
+func stockItems(lines []CartLine) []memoryCartItem {
+	items := make([]memoryCartItem, 0, len(lines))
+	for _, l := range lines {
+		it := memoryCartItem{productID: l.ProductID, quantity: l.Quantity}
+		if l.VariantID != nil {
+			it.variantID = *l.VariantID
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// stock — остаток товара без вариантов или варианта этого товара (вызывать под m.mu)
+func (m *Memory) stock(productID, variantID string) (*int, bool) {
+	if variantID == "" {
+		if p, ok := m.product(productID); ok {
+			return &p.Stock, true
+		}
+		return nil, false
+	}
+	for i := range m.variants {
+		if v := &m.variants[i]; v.ID == variantID && v.ProductID == productID {
+			return &v.Stock, true
+		}
+	}
+	return nil, false
+}
+
+// reservedQty — действующие резервы позиции, кроме резерва корзины exceptCart (вызывать под m.mu)
+func (m *Memory) reservedQty(productID, variantID, exceptCart string) int {
+	now := time.Now()
+	n := 0
+	for _, r := range m.reserved {
+		if r.same(productID, variantID) && r.active(now) && (exceptCart == "" || r.cartID != exceptCart) {
+			n += r.quantity
+		}
+	}
+	return n
+}
+
+// productAvailable — доступно товара: по вариантам, если они есть (вызывать под m.mu)
+func (m *Memory) productAvailable(productID string) int {
+	n, hasVariants := 0, false
+	for _, v := range m.variants {
+		if v.ProductID == productID {
+			hasVariants = true
+			n += max(v.Stock-m.reservedQty(productID, v.ID, ""), 0)
+		}
+	}
+	if hasVariants {
+		return n
+	}
+	if p, ok := m.product(productID); ok {
+		return max(p.Stock-m.reservedQty(productID, "", ""), 0)
+	}
+	return 0
+}
+
+// checkStock — хватает ли остатка с учётом чужих резервов; иначе ErrOutOfStock (вызывать под m.mu)
+func (m *Memory) checkStock(items []memoryCartItem, cartID string) error {
+	for _, it := range items {
+		stock, ok := m.stock(it.productID, it.variantID)
+		if !ok || *stock-m.reservedQty(it.productID, it.variantID, cartID) < it.quantity {
+			return ErrOutOfStock
+		}
+	}
+	return nil
+}
+
+// releaseCart — снимает резерв корзины (вызывать под m.mu)
+func (m *Memory) releaseCart(cartID string) {
+	m.reserved = slices.DeleteFunc(m.reserved, func(r memoryReservation) bool { return r.cartID == cartID })
+	m.touchCatalog()
+}
+
+// touchCatalog — отмечает изменение каталога, как storage.touchCatalog (вызывать под m.mu)
+func (m *Memory) touchCatalog() {
+	m.changed = time.Now()
+}
+
+// commitOrderStock — списывает резерв заказа с остатков (вызывать под m.mu).
+// Сначала проверка всех позиций: при нехватке ничего не меняется, как при откате транзакции.
+// Истёкший резерв проверяется с учётом чужих действующих резервов, как в commitOrderStockTx.
+func (m *Memory) commitOrderStock(orderID string) error {
+	now := time.Now()
+	var items []memoryReservation
+	expired := false
+	for _, r := range m.reserved {
+		if r.orderID == orderID {
+			items = append(items, r)
+			expired = expired || !r.active(now)
+		}
+	}
+	for _, it := range items {
+		stock, ok := m.stock(it.productID, it.variantID)
+		if !ok || *stock < it.quantity {
+			return ErrOutOfStock
+		}
+		if expired && *stock-m.reservedQty(it.productID, it.variantID, "") < it.quantity {
+			return ErrOutOfStock
+		}
+	}
+	for _, it := range items {
+		stock, _ := m.stock(it.productID, it.variantID)
+		*stock -= it.quantity
+		m.addMovement(it.productID, it.variantID, -it.quantity, *stock, MovementSale, orderID, "", "")
+	}
+	m.reserved = slices.DeleteFunc(m.reserved, func(r memoryReservation) bool { return r.orderID == orderID })
+	m.touchCatalog()
+	return nil
+}
+
+// restockOrder — возвращает на остатки списанное при оплате заказа, как restockOrderTx (вызывать под m.mu)
+func (m *Memory) restockOrder(orderID string) {
+	type line struct{ productID, variantID string }
+	var order []line
+	qty := map[line]int{}
+	for _, mv := range m.movements {
+		if mv.Reason != MovementSale || mv.OrderID == nil || *mv.OrderID != orderID {
+			continue
+		}
+		l := line{productID: mv.ProductID}
+		if mv.VariantID != nil {
+			l.variantID = *mv.VariantID
+		}
+		if _, ok := qty[l]; !ok {
+			order = append(order, l)
+		}
+		qty[l] -= mv.Delta
+	}
+	for _, l := range order {
+		stock, ok := m.stock(l.productID, l.variantID)
+		if !ok {
+			continue
+		}
+		*stock += qty[l]
+		m.addMovement(l.productID, l.variantID, qty[l], *stock, MovementRefund, orderID, "", "")
+	}
+	m.touchCatalog()
+}
+
+// addMovement — запись журнала ("" в orderID, userID, note — нет значения; вызывать под m.mu)
+// Как и в MySQL, изменение остатка (в том числе варианта) отмечает товар изменённым.
+func (m *Memory) addMovement(productID, variantID string, delta, after int, reason MovementReason, orderID, userID, note string) {
+	if p, ok := m.product(productID); ok {
+		p.UpdatedAt = time.Now()
+	}
+	opt := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	m.movements = append(m.movements, StockMovement{
+		ID:         m.nextID(),
+		ProductID:  productID,
+		VariantID:  opt(variantID),
+		Delta:      delta,
+		StockAfter: after,
+		Reason:     reason,
+		OrderID:    opt(orderID),
+		UserID:     opt(userID),
+		Note:       opt(note),
+		CreatedAt:  time.Now(),
+	})
+}
+
+// --- Категории ---
+
+type memoryCategories struct{ m *Memory }
+
+func (r memoryCategories) ListAll(_ context.Context) ([]Category, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	items := append([]Category(nil), r.m.categories...)
+	slices.SortStableFunc(items, func(a, b Category) int {
+		if a.Position != b.Position {
+			return a.Position - b.Position
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return items, nil
+}
+
+func (r memoryCategories) GetBySlug(_ context.Context, slug string) (*Category, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	for _, c := range r.m.categories {
+		if c.Slug == slug {
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// --- Корзины ---
+
+type memoryCarts struct{ m *Memory }
+
+func (r memoryCarts) Create(_ context.Context, userID string) (string, error) {
+	id, err := newCartID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	cart := &memoryCart{Cart: Cart{ID: id, CreatedAt: now, UpdatedAt: now}}
+	if userID != "" {
+		cart.UserID = &userID
+	}
+
+	r.m.mu.Lock()
+	r.m.carts[id] = cart
+	r.m.mu.Unlock()
+	return id, nil
+}
+
+func (r memoryCarts) Exists(_ context.Context, id string) (bool, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	_, ok := r.m.carts[id]
+	return ok, nil
+}
+
+func (r memoryCarts) IDByUser(_ context.Context, userID string) (string, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	for id, cart := range r.m.carts {
+		if cart.UserID != nil && *cart.UserID == userID {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+func (r memoryCarts) Get(_ context.Context, id string) (*Cart, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	cart, ok := r.m.carts[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	out := cart.Cart
+	out.Lines = r.m.cartLines(cart)
+	out.Recalculate()
+	return &out, nil
+}
+
+// cartLines — позиции с текущими ценами; удалённые из каталога товары и варианты пропускаются
+// (как JOIN в cartLinesSQL)
+func (m *Memory) cartLines(cart *memoryCart) []CartLine {
+	var lines []CartLine
+	for _, it := range cart.items {
+		p, ok := m.product(it.productID)
+		if !ok {
+			continue
+		}
+		line := CartLine{
+			ProductID: p.ID,
+			Name:      p.Name,
+			Article:   p.Article,
+			Price:     p.Price,
+			ImageAlt:  p.ImageAlt,
+			Quantity:  it.quantity,
+		}
+		if it.variantID != "" {
+			v, ok := m.variant(it.variantID)
+			if !ok || v.ProductID != p.ID {
+				continue
+			}
+			label := v.Label()
+			line.VariantID, line.Variant = &v.ID, &label
+			line.Article, line.Price = v.SKU, v.Price
+		}
+		if stock, ok := m.stock(it.productID, it.variantID); ok {
+			line.Available = max(*stock-m.reservedQty(it.productID, it.variantID, cart.ID), 0)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (r memoryCarts) AddItem(_ context.Context, cartID, productID, variantID string, qty int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	cart, ok := r.m.carts[cartID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if _, ok := r.m.product(productID); !ok {
+		return sql.ErrNoRows
+	}
+	cart.add(memoryCartItem{productID: productID, variantID: variantID, quantity: clampQuantity(qty)})
+	return nil
+}
+
+// add — как INSERT ... ON DUPLICATE KEY UPDATE quantity = LEAST(quantity + n, MaxCartQuantity)
+func (c *memoryCart) add(item memoryCartItem) {
+	c.UpdatedAt = time.Now()
+	for i := range c.items {
+		if c.items[i].same(item.productID, item.variantID) {
+			c.items[i].quantity = min(c.items[i].quantity+item.quantity, MaxCartQuantity)
+			return
+		}
+	}
+	c.items = append(c.items, item)
+}
+
+// same — позиция того же товара и варианта (первичный ключ cart_items)
+func (it memoryCartItem) same(productID, variantID string) bool {
+	return it.productID == productID && it.variantID == variantID
+}
+
+func (r memoryCarts) SetItemQuantity(ctx context.Context, cartID, productID, variantID string, qty int) error {
+	if qty <= 0 {
+		return r.RemoveItem(ctx, cartID, productID, variantID)
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if cart, ok := r.m.carts[cartID]; ok {
+		for i := range cart.items {
+			if cart.items[i].same(productID, variantID) {
+				cart.items[i].quantity = clampQuantity(qty)
+			}
+		}
+		cart.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r memoryCarts) RemoveItem(_ context.Context, cartID, productID, variantID string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if cart, ok := r.m.carts[cartID]; ok {
+		items := cart.items[:0]
+		for _, it := range cart.items {
+			if !it.same(productID, variantID) {
+				items = append(items, it)
+			}
+		}
+		cart.items = items
+		cart.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r memoryCarts) Claim(_ context.Context, anonCartID, userID string) (string, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var userCart *memoryCart
+	for _, cart := range r.m.carts {
+		if cart.UserID != nil && *cart.UserID == userID {
+			userCart = cart
+			break
+		}
+	}
+
+	anon, ok := r.m.carts[anonCartID]
+	if !ok || anon.UserID != nil || anon == userCart {
+		// Сливать нечего (или корзина уже чья-то)
+		if userCart == nil {
+			return "", nil
+		}
+		return userCart.ID, nil
+	}
+
+	if userCart == nil {
+		// Своей корзины нет — забираем анонимную
+		anon.UserID = &userID
+		return anon.ID, nil
+	}
+
+	for _, it := range anon.items {
+		userCart.add(it)
+	}
+	delete(r.m.carts, anonCartID)
+	r.m.releaseCart(anonCartID)
+	return userCart.ID, nil
+}
+
+// --- Заказы ---
+
+type memoryOrders struct{ m *Memory }
+
+func (r memoryOrders) CreateFromCart(_ context.Context, cartID string, d OrderDraft, ttl time.Duration) (*Order, error) {
+	method, ok := FindShippingMethod(d.ShippingMethod)
+	if !ok {
+		return nil, errUnknownShipping()
+	}
+
+	number, err := newOrderNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	cart, ok := r.m.carts[cartID]
+	if !ok {
+		return nil, ErrEmptyCart
+	}
+	lines := r.m.cartLines(cart)
+	if len(lines) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	items := stockItems(lines)
+	if err := r.m.checkStock(items, cartID); err != nil {
+		return nil, err
+	}
+
+	order := buildOrder(number, method, d, lines)
+	order.ID = r.m.nextID()
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	for i := range order.Items {
+		order.Items[i].ID = r.m.nextID()
+		order.Items[i].OrderID = order.ID
+	}
+	r.m.orders = append(r.m.orders, order)
+	cart.items = nil
+	r.m.releaseCart(cartID)
+	for _, it := range items {
+		r.m.reserved = append(r.m.reserved, memoryReservation{memoryCartItem: it, orderID: order.ID, expires: order.CreatedAt.Add(ttl)})
+	}
+
+	core.LogInfo("Заказ создан", map[string]interface{}{
+		"order":  order.Number,
+		"total":  order.Total.String(),
+		"items":  len(order.Items),
+		"method": order.ShippingMethod,
+	})
+	return copyOrder(order), nil
+}
+
+func (r memoryOrders) GetByNumber(_ context.Context, number string) (*Order, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	for _, o := range r.m.orders {
+		if o.Number == number {
+			return copyOrder(o), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r memoryOrders) GetByID(_ context.Context, id string) (*Order, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	o, ok := r.m.order(id)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return copyOrder(o), nil
+}
+
+func (r memoryOrders) ListByUser(_ context.Context, userID string, page, pageSize int) (*OrderPage, error) {
+	out := &OrderPage{Page: max(page, 1), PageSize: pageSize}
+	if out.PageSize < 1 || out.PageSize > MaxPageSize {
+		out.PageSize = DefaultPageSize
+	}
+
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	// orders — в порядке создания; новые первыми
+	var own []*Order
+	for i := len(r.m.orders) - 1; i >= 0; i-- {
+		if o := r.m.orders[i]; o.UserID != nil && *o.UserID == userID {
+			own = append(own, o)
+		}
+	}
+	out.Total = len(own)
+	from := min((out.Page-1)*out.PageSize, len(own))
+	for _, o := range own[from:min(from+out.PageSize, len(own))] {
+		out.Items = append(out.Items, *copyOrder(o))
+	}
+	return out, nil
+}
+
+func (r memoryOrders) UpdateStatus(_ context.Context, orderID string, to OrderStatus, _ string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.transitionOrder(orderID, to)
+}
+
+func (r memoryOrders) CancelExpired(_ context.Context) (int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for _, o := range r.m.orders {
+		if o.Status != OrderPending {
+			continue
+		}
+		expired := slices.ContainsFunc(r.m.reserved, func(res memoryReservation) bool {
+			return res.orderID == o.ID && !res.active(now)
+		})
+		if !expired {
+			continue
+		}
+		if err := r.m.transitionOrder(o.ID, OrderCancelled); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// transitionOrder — смена статуса по конечному автомату (вызывать под m.mu).
+// Повторный переход в текущий статус — no-op, как и в transitionOrderTx.
+func (m *Memory) transitionOrder(orderID string, to OrderStatus) error {
+	o, ok := m.order(orderID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if o.Status == to {
+		return nil
+	}
+	if err := CheckOrderTransition(o.Status, to); err != nil {
+		return err
+	}
+	switch {
+	case to == OrderPaid:
+		if err := m.commitOrderStock(orderID); err != nil {
+			return err
+		}
+	case to == OrderCancelled:
+		m.reserved = slices.DeleteFunc(m.reserved, func(r memoryReservation) bool { return r.orderID == orderID })
+		m.touchCatalog()
+	case to == OrderRefunded && o.Status == OrderPaid:
+		m.restockOrder(orderID)
+	}
+
+	core.LogInfo("Статус заказа изменён", map[string]interface{}{
+		"order_id": orderID,
+		"from":     o.Status,
+		"to":       to,
+	})
+	o.Status = to
+	o.UpdatedAt = time.Now()
+	return nil
+}
+
+// copyOrder — копия заказа: вызывающий код не должен менять данные хранилища
+func copyOrder(o *Order) *Order {
+	cp := *o
+	cp.Items = append([]OrderItem(nil), o.Items...)
+	return &cp
+}
+
+// --- Платежи ---
+
+type memoryPayments struct{ m *Memory }
+
+func (r memoryPayments) Create(_ context.Context, p Payment) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	p.ID = r.m.nextID()
+	p.Currency = p.Amount.Currency()
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	r.m.payments = append(r.m.payments, &p)
+	return nil
+}
+
+func (r memoryPayments) ListByOrder(_ context.Context, orderID string) ([]Payment, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var list []Payment
+	for i := len(r.m.payments) - 1; i >= 0; i-- {
+		if p := r.m.payments[i]; p.OrderID == orderID {
+			list = append(list, *p)
+		}
+	}
+	return list, nil
+}
+
+func (r memoryPayments) ApplyEvent(_ context.Context, ev PaymentEvent) (PaymentEventResult, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var result PaymentEventResult
+	key := ev.Provider + "/" + ev.EventID
+	if _, ok := r.m.events[key]; ok {
+		return result, nil
+	}
+
+	var p *Payment
+	for _, cand := range r.m.payments {
+		if cand.Provider == ev.Provider && cand.IntentID == ev.IntentID {
+			p = cand
+			break
+		}
+	}
+	if p == nil {
+		return result, ErrUnknownPayment
+	}
+
+	// Сначала заказ: прочая ошибка перехода не должна оставить следов (как откат транзакции),
+	// конфликт — только не переводит заказ (как ROLLBACK TO SAVEPOINT)
+	if ev.OrderStatus != "" {
+		err := r.m.applyOrderEvent(p.OrderID, ev)
+		if conflict, ok := paymentConflict(err); ok {
+			result.Conflict = conflict
+			logPaymentConflict(ev, p.OrderID, conflict)
+		} else if err != nil {
+			return result, err
+		}
+	}
+	p.Status = ev.PaymentStatus
+	p.UpdatedAt = time.Now()
+	r.m.events[key] = result.Conflict
+	result.Applied = true
+	return result, nil
+}
+
+// applyOrderEvent — переход заказа по событию платежа, как applyOrderEventTx (вызывать под m.mu)
+func (m *Memory) applyOrderEvent(orderID string, ev PaymentEvent) error {
+	o, ok := m.order(orderID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	var paidBy string
+	if o.PaidIntentID != nil {
+		paidBy = *o.PaidIntentID
+	}
+	if apply, err := eventAppliesToOrder(paidBy, ev); !apply {
+		return err
+	}
+
+	if err := m.transitionOrder(orderID, ev.OrderStatus); err != nil {
+		return err
+	}
+	if ev.OrderStatus == OrderPaid {
+		intent := ev.IntentID
+		o.PaidIntentID = &intent
+	}
+	return nil
+}
+
+// --- Пользователи ---
+
+type memoryUsers struct{ m *Memory }
+
+func (r memoryUsers) Create(_ context.Context, u *User) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	u.Email = NormalizeEmail(u.Email)
+	if _, ok := r.m.user(func(x *User) bool { return x.Email == u.Email }); ok {
+		return ErrEmailTaken
+	}
+	if u.Role == "" {
+		u.Role = RoleCustomer
+	}
+	if _, ok := r.m.roles[u.Role]; !ok {
+		return ErrUnknownRole
+	}
+	u.ID = r.m.nextID()
+	u.CreatedAt = time.Now()
+	u.LastLoginAt = nil
+	cp := *u
+	r.m.users = append(r.m.users, &cp)
+	return nil
+}
+
+func (r memoryUsers) GetByID(_ context.Context, id string) (*User, error) {
+	return r.get(func(u *User) bool { return u.ID == id })
+}
+
+func (r memoryUsers) GetByEmail(_ context.Context, email string) (*User, error) {
+	email = NormalizeEmail(email)
+	return r.get(func(u *User) bool { return u.Email == email })
+}
+
+func (r memoryUsers) get(match func(*User) bool) (*User, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	u, ok := r.m.user(match)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (r memoryUsers) SetPasswordHash(_ context.Context, id, hash string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if u, ok := r.m.user(func(u *User) bool { return u.ID == id }); ok {
+		u.PasswordHash = hash
+	}
+	return nil
+}
+
+func (r memoryUsers) TouchLogin(_ context.Context, id string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if u, ok := r.m.user(func(u *User) bool { return u.ID == id }); ok {
+		now := time.Now()
+		u.LastLoginAt = &now
+	}
+	return nil
+}
+
+func (r memoryUsers) MarkEmailVerified(_ context.Context, id string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if u, ok := r.m.user(func(u *User) bool { return u.ID == id }); ok && u.VerifiedAt == nil {
+		now := time.Now()
+		u.VerifiedAt = &now
+	}
+	return nil
+}
+
+func (r memoryUsers) SetRole(_ context.Context, id, role string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if _, ok := r.m.roles[role]; !ok {
+		return ErrUnknownRole
+	}
+	u, ok := r.m.user(func(u *User) bool { return u.ID == id })
+	if !ok {
+		return sql.ErrNoRows
+	}
+	u.Role = role
+	return nil
+}
+
+// user — первый пользователь, подходящий под условие (вызывать под m.mu)
+func (m *Memory) user(match func(*User) bool) (*User, bool) {
+	for _, u := range m.users {
+		if match(u) {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// --- API-токены ---
+
+type memoryAPITokens struct{ m *Memory }
+
+func (r memoryAPITokens) Create(_ context.Context, t *APIToken, ttl time.Duration) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now()
+	t.ID = r.m.nextID()
+	t.CreatedAt = now
+	t.ExpiresAt = now.Add(ttl)
+	t.LastUsedAt, t.RevokedAt = nil, nil
+	if u, ok := r.m.user(func(u *User) bool { return u.ID == t.UserID }); ok {
+		t.UserEmail = u.Email
+	}
+	cp := *t
+	cp.Scopes = slices.Clone(t.Scopes)
+	r.m.apiTokens = append(r.m.apiTokens, &cp)
+	return nil
+}
+
+func (r memoryAPITokens) List(_ context.Context, userID string) ([]APIToken, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var list []APIToken
+	for i := len(r.m.apiTokens) - 1; i >= 0; i-- {
+		if t := r.m.apiTokens[i]; userID == "" || t.UserID == userID {
+			list = append(list, copyAPIToken(t))
+		}
+	}
+	return list, nil
+}
+
+func (r memoryAPITokens) Authenticate(_ context.Context, hash string) (*APIToken, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, t := range r.m.apiTokens {
+		if t.Hash != hash || !t.Active() {
+			continue
+		}
+		now := time.Now()
+		if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= apiTokenTouchInterval {
+			t.LastUsedAt = &now
+		}
+		cp := copyAPIToken(t)
+		return &cp, nil
+	}
+	return nil, ErrAPITokenInvalid
+}
+
+func (r memoryAPITokens) Revoke(_ context.Context, id, userID string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, t := range r.m.apiTokens {
+		if t.ID != id || (userID != "" && t.UserID != userID) {
+			continue
+		}
+		if t.RevokedAt == nil {
+			now := time.Now()
+			t.RevokedAt = &now
+		}
+		return nil
+	}
+	return sql.ErrNoRows
+}
+
+func (r memoryAPITokens) RevokeAll(_ context.Context, userID string) (int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	n := 0
+	now := time.Now()
+	for _, t := range r.m.apiTokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			at := now
+			t.RevokedAt = &at
+			n++
+		}
+	}
+	return n, nil
+}
+
+// copyAPIToken — копия токена (указатели на время не разделяются с хранилищем)
+func copyAPIToken(t *APIToken) APIToken {
+	cp := *t
+	cp.Scopes = slices.Clone(t.Scopes)
+	if t.LastUsedAt != nil {
+		at := *t.LastUsedAt
+		cp.LastUsedAt = &at
+	}
+	if t.RevokedAt != nil {
+		at := *t.RevokedAt
+		cp.RevokedAt = &at
+	}
+	return cp
+}
+
+// --- Ключи Idempotency-Key ---
+
+type memoryIdempotency struct{ m *Memory }
+
+func (r memoryIdempotency) Begin(_ context.Context, rec *IdempotencyRecord, ttl, stale time.Duration) (*IdempotencyRecord, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range r.m.idempotency {
+		if !e.ExpiresAt.After(now) {
+			delete(r.m.idempotency, k)
+		}
+	}
+	key := rec.Scope + "\n" + rec.Key
+	if e, ok := r.m.idempotency[key]; ok && (e.Done() || now.Sub(e.CreatedAt) < stale) {
+		cp := copyIdempotencyRecord(e)
+		return &cp, nil
+	}
+
+	cp := copyIdempotencyRecord(rec)
+	cp.Status, cp.Header, cp.Body = 0, nil, nil
+	cp.CreatedAt, cp.ExpiresAt = now, now.Add(ttl)
+	r.m.idempotency[key] = &cp
+	return nil, nil
+}
+
+func (r memoryIdempotency) Complete(_ context.Context, rec *IdempotencyRecord) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	e, ok := r.m.idempotency[rec.Scope+"\n"+rec.Key]
+	if !ok || e.Done() || e.Fingerprint != rec.Fingerprint {
+		return nil
+	}
+	e.Status, e.Header, e.Body = rec.Status, rec.Header.Clone(), slices.Clone(rec.Body)
+	return nil
+}
+
+func (r memoryIdempotency) Release(_ context.Context, scope, key string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if e, ok := r.m.idempotency[scope+"\n"+key]; ok && !e.Done() {
+		delete(r.m.idempotency, scope+"\n"+key)
+	}
+	return nil
+}
+
+// copyIdempotencyRecord — копия записи (заголовки и тело не разделяются с хранилищем)
+func copyIdempotencyRecord(rec *IdempotencyRecord) IdempotencyRecord {
+	cp := *rec
+	cp.Header = rec.Header.Clone()
+	cp.Body = slices.Clone(rec.Body)
+	return cp
+}
+
+// --- Роли ---
+
+type memoryRoles struct{ m *Memory }
+
+func (r memoryRoles) Permissions(_ context.Context, role string) ([]Permission, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	return slices.Clone(r.m.roles[role]), nil
+}
+
+// --- Токены из писем ---
+
+type memoryToken struct {
+	userID  string
+	purpose TokenPurpose
+	hash    string
+	expires time.Time
+	used    bool
+}
+
+type memoryTokens struct{ m *Memory }
+
+func (r memoryTokens) Create(_ context.Context, userID string, purpose TokenPurpose, hash string, ttl time.Duration) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, t := range r.m.tokens {
+		if t.userID == userID && t.purpose == purpose {
+			t.used = true
+		}
+	}
+	r.m.tokens = append(r.m.tokens, &memoryToken{userID: userID, purpose: purpose, hash: hash, expires: time.Now().Add(ttl)})
+	return nil
+}
+
+func (r memoryTokens) Find(_ context.Context, purpose TokenPurpose, hash string) (string, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	t, ok := r.m.token(purpose, hash)
+	if !ok {
+		return "", ErrTokenInvalid
+	}
+	return t.userID, nil
+}
+
+func (r memoryTokens) Consume(_ context.Context, purpose TokenPurpose, hash string) (string, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	t, ok := r.m.token(purpose, hash)
+	if !ok {
+		return "", ErrTokenInvalid
+	}
+	t.used = true
+	return t.userID, nil
+}
+
+func (r memoryTokens) ResetPassword(_ context.Context, hash, passwordHash string) (string, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	t, ok := r.m.token(TokenResetPassword, hash)
+	if !ok {
+		return "", 0, ErrTokenInvalid
+	}
+	t.used = true
+
+	now := time.Now()
+	if u, ok := r.m.user(func(u *User) bool { return u.ID == t.userID }); ok {
+		u.PasswordHash = passwordHash
+		if u.VerifiedAt == nil {
+			at := now
+			u.VerifiedAt = &at
+		}
+	}
+	revoked := 0
+	for _, a := range r.m.apiTokens {
+		if a.UserID == t.userID && a.RevokedAt == nil {
+			at := now
+			a.RevokedAt = &at
+			revoked++
+		}
+	}
+	return t.userID, revoked, nil
+}
+
+// token — действующий токен (вызывать под m.mu)
+func (m *Memory) token(purpose TokenPurpose, hash string) (*memoryToken, bool) {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.hash == hash && t.purpose == purpose && !t.used && now.Before(t.expires) {
+			return t, true
+		}
+	}
+	return nil, false
+}
@@ -1,10 +1,24 @@
 package storage
 
-// migrations.go
+// migrations.go — движок миграций схемы.
+// Файлы NNN_name.up.sql / NNN_name.down.sql из каталога миграций применяются по номеру версии,
+// применённые версии с контрольной суммой хранятся в schema_migrations.
+// Одновременный запуск с нескольких инстансов исключён advisory-блокировкой MySQL (GET_LOCK).
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"myApp/internal/core"
 
@@ -12,76 +26,470 @@ import (
 )
 
 const (
-	EnableMigrations = false                       // true → выполнять миграцию
-	MigrationFile    = "migrations/001_schema.sql" // путь к файлу миграции
+	MigrationDir         = "migrations" // Каталог с файлами миграций по умолчанию
+	migrationsTable      = "schema_migrations"
+	migrationLockName    = "myapp:migrations" // Имя advisory-блокировки (GET_LOCK)
+	migrationLockTimeout = 30                 // Секунд ждать, пока другой инстанс закончит миграцию
 )
 
+// migrationFileRe — 001_create_products.up.sql → версия 1, имя create_products, направление up
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+// Ошибки движка миграций
+var (
+	ErrMigrationLocked   = errors.New("миграции уже выполняются другим инстансом")
+	ErrMigrationDirty    = errors.New("предыдущая миграция завершилась с ошибкой (dirty), нужно исправить схему вручную")
+	ErrMigrationChecksum = errors.New("файл уже применённой миграции изменён")
+	ErrNoDownMigration   = errors.New("у миграции нет down-файла")
+)
+
+// Migration — одна версия схемы
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string // Текст up-файла
+	Down     string // Текст down-файла ("" — откат не предусмотрен)
+	Checksum string // sha256 up-файла (hex)
+}
+
+// MigrationStatus — состояние версии: есть ли на диске, применена ли, совпадает ли checksum
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Dirty     bool // Применение оборвалось на середине
+	Modified  bool // Файл изменён после применения
+	Missing   bool // Версия есть в schema_migrations, но файла нет
+}
+
+// appliedMigration — строка schema_migrations
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	Dirty     bool      `db:"dirty"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
 type Migrations struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	files fs.FS
 }
 
-func NewMigrations(db *sqlx.DB) *Migrations {
-	return &Migrations{db: db}
+// NewMigrations — движок миграций поверх каталога files (обычно os.DirFS(MigrationDir))
+func NewMigrations(db *sqlx.DB, files fs.FS) *Migrations {
+	return &Migrations{db: db, files: files}
+}
+
+// LoadMigrations — читает и упорядочивает файлы миграций.
+// Посторонние файлы (README, .gitkeep) пропускаются, а *.sql без .up/.down — ошибка:
+// такой файл молча не применился бы.
+func LoadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("чтение каталога миграций: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("файл миграции %s: ожидается имя NNN_name.up.sql или NNN_name.down.sql", e.Name())
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("файл миграции %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("версия %d занята двумя миграциями: %s и %s", version, mig.Name, m[2])
+		}
+
+		body, err := fs.ReadFile(files, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("чтение %s: %w", e.Name(), err)
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			sum := sha256.Sum256(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Checksum == "" {
+			return nil, fmt.Errorf("миграция %03d_%s: нет up-файла", mig.Version, mig.Name)
+		}
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
 }
 
-func (m *Migrations) RunMigrations() error {
-	if !EnableMigrations {
-		core.LogInfo("Миграции отключены (EnableMigrations=false)", nil)
+// Up — применяет все ещё не применённые миграции по порядку. Возвращает число применённых.
+func (m *Migrations) Up(ctx context.Context) (int, error) {
+	migs, err := LoadMigrations(m.files)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkApplied(migs, done); err != nil {
+			return err
+		}
+
+		for _, mig := range migs {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := applyMigration(ctx, conn, mig, mig.Up, true); err != nil {
+				return err
+			}
+			applied++
+		}
 		return nil
+	})
+	if err == nil && applied == 0 {
+		core.LogInfo("Схема БД актуальна, новых миграций нет", nil)
+	}
+	return applied, err
+}
+
+// Down — откатывает steps последних применённых миграций (в обратном порядке)
+func (m *Migrations) Down(ctx context.Context, steps int) (int, error) {
+	migs, err := LoadMigrations(m.files)
+	if err != nil {
+		return 0, err
+	}
+	byVersion := make(map[int64]Migration, len(migs))
+	for _, mig := range migs {
+		byVersion[mig.Version] = mig
 	}
 
-	core.LogInfo("Начало выполнения миграции", map[string]interface{}{
-		"file": MigrationFile,
+	reverted := 0
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkApplied(migs, done); err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions {
+			if reverted == steps {
+				break
+			}
+			mig, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("миграция %03d_%s: файла нет на диске", v, done[v].Name)
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("миграция %03d_%s: %w", mig.Version, mig.Name, ErrNoDownMigration)
+			}
+			if err := applyMigration(ctx, conn, mig, mig.Down, false); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
 	})
+	return reverted, err
+}
 
-	content, err := os.ReadFile(MigrationFile)
+// Status — состояние всех известных версий: файлы на диске плюс записи schema_migrations
+func (m *Migrations) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migs, err := LoadMigrations(m.files)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+	done, err := loadApplied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]MigrationStatus, 0, len(migs))
+	for _, mig := range migs {
+		st := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := done[mig.Version]; ok {
+			at := a.AppliedAt
+			st.Applied, st.AppliedAt, st.Dirty = true, &at, a.Dirty
+			st.Modified = a.Checksum != mig.Checksum
+			delete(done, mig.Version)
+		}
+		list = append(list, st)
+	}
+	for _, a := range done {
+		at := a.AppliedAt
+		list = append(list, MigrationStatus{Version: a.Version, Name: a.Name, Applied: true, AppliedAt: &at, Dirty: a.Dirty, Missing: true})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// withLock — выполняет fn на выделенном соединении под advisory-блокировкой.
+// GET_LOCK живёт в сессии MySQL, поэтому блокировка, миграции и RELEASE_LOCK идут через одно соединение.
+func (m *Migrations) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
 	if err != nil {
-		core.LogError("Ошибка чтения файла миграции", map[string]interface{}{
-			"file":  MigrationFile,
-			"error": err.Error(),
-		})
-		fmt.Println("Ошибка применения миграции")
 		return err
 	}
+	defer func() { _ = conn.Close() }()
+
+	var got sql.NullInt64
+	if err := conn.GetContext(ctx, &got, `SELECT GET_LOCK(?, ?)`, migrationLockName, migrationLockTimeout); err != nil {
+		return fmt.Errorf("advisory-блокировка миграций: %w", err)
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return ErrMigrationLocked
+	}
+	defer func() {
+		// Отдельный контекст: блокировку нужно снять, даже если ctx уже отменён
+		if _, err := conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, migrationLockName); err != nil {
+			core.LogError("Ошибка снятия блокировки миграций", map[string]interface{}{"error": err.Error()})
+		}
+	}()
 
-	sqlText := cleanSQL(string(content))
-	stmts := strings.Split(sqlText, ";")
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
 
-	executed := 0
-	for _, raw := range stmts {
-		stmt := strings.TrimSpace(raw)
-		if stmt == "" {
-			continue
+// ensureMigrationsTable — создаёт schema_migrations при первом запуске
+func ensureMigrationsTable(ctx context.Context, db sqlx.ExecerContext) error {
+	const q = `
+		CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
+		 version     BIGINT NOT NULL PRIMARY KEY,
+		 name        VARCHAR(255) NOT NULL,
+		 checksum    CHAR(64) NOT NULL,
+		 dirty       TINYINT(1) NOT NULL DEFAULT 0,
+		 applied_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return fmt.Errorf("создание %s: %w", migrationsTable, err)
+	}
+	return nil
+}
+
+// loadApplied — применённые версии из schema_migrations
+func loadApplied(ctx context.Context, db sqlx.QueryerContext) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := sqlx.SelectContext(ctx, db, &rows, `SELECT version, name, checksum, dirty, applied_at FROM `+migrationsTable); err != nil {
+		return nil, fmt.Errorf("чтение %s: %w", migrationsTable, err)
+	}
+	done := make(map[int64]appliedMigration, len(rows))
+	for _, r := range rows {
+		done[r.Version] = r
+	}
+	return done, nil
+}
+
+// checkApplied — перед любыми изменениями: нет оборванных миграций и изменённых файлов
+func checkApplied(migs []Migration, done map[int64]appliedMigration) error {
+	for _, a := range done {
+		if a.Dirty {
+			return fmt.Errorf("миграция %03d_%s: %w", a.Version, a.Name, ErrMigrationDirty)
 		}
-		if _, err := m.db.Exec(stmt); err != nil {
-			core.LogError("Ошибка SQL", map[string]interface{}{
-				"sql":   stmt,
-				"error": err.Error(),
-			})
-			fmt.Println(" Ошибка применения миграции")
-			return fmt.Errorf("ошибка выполнения SQL: %w", err)
+	}
+	for _, mig := range migs {
+		if a, ok := done[mig.Version]; ok && a.Checksum != mig.Checksum {
+			return fmt.Errorf("миграция %03d_%s: %w (ожидался checksum %s)", mig.Version, mig.Name, ErrMigrationChecksum, a.Checksum)
 		}
-		executed++
+	}
+	return nil
+}
+
+// applyMigration — выполняет up- или down-текст миграции и обновляет schema_migrations.
+// DDL в MySQL коммитится неявно, поэтому миграция с DDL идёт без транзакции: перед началом
+// версия помечается dirty, и если statement упадёт, следующий запуск остановится, а не
+// применит схему поверх частично выполненной миграции. Миграции только с DML
+// выполняются в одной транзакции вместе с записью в schema_migrations.
+func applyMigration(ctx context.Context, conn *sqlx.Conn, mig Migration, body string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	file := fmt.Sprintf("%03d_%s.%s.sql", mig.Version, mig.Name, direction)
+
+	stmts, err := SplitStatements(body)
+	if err != nil {
+		return fmt.Errorf("разбор %s: %w", file, err)
+	}
+
+	core.LogInfo("Начало выполнения миграции", map[string]interface{}{
+		"file":       file,
+		"statements": len(stmts),
+	})
+
+	if hasDDL(stmts) {
+		err = applyNonTx(ctx, conn, mig, stmts, up)
+	} else {
+		err = applyTx(ctx, conn, mig, stmts, up)
+	}
+	if err != nil {
+		core.LogError("Ошибка применения миграции", map[string]interface{}{
+			"file":  file,
+			"error": err.Error(),
+		})
+		return fmt.Errorf("миграция %s: %w", file, err)
 	}
 
 	core.LogInfo("Миграция успешно применена", map[string]interface{}{
-		"file":       MigrationFile,
-		"statements": executed,
+		"file":       file,
+		"statements": len(stmts),
 	})
-	fmt.Println(" Миграция применена")
 	return nil
 }
 
-// cleanSQL — убирает комментарии и пустые строки
-func cleanSQL(sql string) string {
-	lines := strings.Split(sql, "\n")
-	var clean []string
-	for _, ln := range lines {
-		t := strings.TrimSpace(ln)
-		if t == "" || strings.HasPrefix(t, "--") {
-			continue
+// applyTx — DML-миграция целиком в одной транзакции
+func applyTx(ctx context.Context, conn *sqlx.Conn, mig Migration, stmts []string, up bool) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := execStatements(ctx, tx, stmts); err != nil {
+		return err
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO `+migrationsTable+` (version, name, checksum) VALUES (?, ?, ?)`, mig.Version, mig.Name, mig.Checksum)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+migrationsTable+` WHERE version = ?`, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applyNonTx — миграция с DDL: dirty-флаг вокруг выполнения
+func applyNonTx(ctx context.Context, conn *sqlx.Conn, mig Migration, stmts []string, up bool) error {
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, `INSERT INTO `+migrationsTable+` (version, name, checksum, dirty) VALUES (?, ?, ?, 1)`, mig.Version, mig.Name, mig.Checksum)
+	} else {
+		_, err = conn.ExecContext(ctx, `UPDATE `+migrationsTable+` SET dirty = 1 WHERE version = ?`, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := execStatements(ctx, conn, stmts); err != nil {
+		return err
+	}
+
+	if up {
+		_, err = conn.ExecContext(ctx, `UPDATE `+migrationsTable+` SET dirty = 0 WHERE version = ?`, mig.Version)
+	} else {
+		_, err = conn.ExecContext(ctx, `DELETE FROM `+migrationsTable+` WHERE version = ?`, mig.Version)
+	}
+	return err
+}
+
+func execStatements(ctx context.Context, db sqlx.ExecerContext, stmts []string) error {
+	for i, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("statement %d (%s): %w", i+1, abbreviate(stmt, 80), err)
+		}
+	}
+	return nil
+}
+
+// hasDDL — есть ли statement, который MySQL коммитит неявно
+func hasDDL(stmts []string) bool {
+	for _, s := range stmts {
+		word := strings.ToUpper(firstWord(s))
+		switch word {
+		case "CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE", "LOCK", "UNLOCK":
+			return true
+		}
+	}
+	return false
+}
+
+func firstWord(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexFunc(s, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '(' }); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// abbreviate — statement для сообщения об ошибке: одной строкой и не длиннее max рун
+func abbreviate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		return string(r[:max]) + "…"
+	}
+	return s
+}
+
+// migrationNameRe — всё, что не подходит для имени файла миграции
+var migrationNameRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// CreateMigration — создаёт пару пустых файлов следующей версии в каталоге dir.
+// Возвращает пути к up- и down-файлам.
+func CreateMigration(dir, name string) (up, down string, err error) {
+	name = strings.Trim(migrationNameRe.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("имя миграции: нужны латинские буквы или цифры")
+	}
+
+	migs, err := LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	var version int64 = 1
+	if len(migs) > 0 {
+		version = migs[len(migs)-1].Version + 1
+	}
+
+	base := fmt.Sprintf("%03d_%s", version, name)
+	up = filepath.Join(dir, base+".up.sql")
+	down = filepath.Join(dir, base+".down.sql")
+	for _, f := range []struct{ path, comment string }{
+		{up, "-- " + base + ".up.sql — \n"},
+		{down, "-- " + base + ".down.sql — откат " + base + ".up.sql\n"},
+	} {
+		// O_EXCL: не перезаписываем файл, если кто-то успел создать такой же
+		fh, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		_, werr := fh.WriteString(f.comment)
+		if cerr := fh.Close(); werr == nil {
+			werr = cerr
+		}
+		if werr != nil {
+			return "", "", werr
 		}
-		clean = append(clean, t)
 	}
-	return strings.Join(clean, "\n")
+	return up, down, nil
 }
@@ -0,0 +1,93 @@
+package storage
+
+// order_status.go — конечный автомат статусов заказа
+//
+//	pending ──► paid ──► shipped ──► delivered
+//	   │          │         │            │
+//	   ▼          └─────────┴────────────┴──► refunded
+//	cancelled
+import (
+	"net/http"
+
+	"myApp/internal/core"
+)
+
+// OrderStatus — статус заказа
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"   // Создан, ждёт оплаты
+	OrderPaid      OrderStatus = "paid"      // Оплачен
+	OrderShipped   OrderStatus = "shipped"   // Передан в доставку
+	OrderDelivered OrderStatus = "delivered" // Получен покупателем
+	OrderCancelled OrderStatus = "cancelled" // Отменён до оплаты (финальный)
+	OrderRefunded  OrderStatus = "refunded"  // Деньги возвращены (финальный)
+)
+
+// orderTransitions — разрешённые переходы: из статуса → в статусы
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderDelivered, OrderRefunded},
+	OrderDelivered: {OrderRefunded},
+	OrderCancelled: {},
+	OrderRefunded:  {},
+}
+
+// orderStatusTitles — подписи статусов для страниц
+var orderStatusTitles = map[OrderStatus]string{
+	OrderPending:   "Ожидает оплаты",
+	OrderPaid:      "Оплачен",
+	OrderShipped:   "Отправлен",
+	OrderDelivered: "Доставлен",
+	OrderCancelled: "Отменён",
+	OrderRefunded:  "Возврат средств",
+}
+
+// Valid — известен ли статус
+func (s OrderStatus) Valid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+// Final — из статуса больше нет переходов
+func (s OrderStatus) Final() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+// Title — человекочитаемое название статуса
+func (s OrderStatus) Title() string {
+	if t, ok := orderStatusTitles[s]; ok {
+		return t
+	}
+	return string(s)
+}
+
+// CanTransitionTo — разрешён ли переход s → to
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range orderTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrderTransition — nil, если переход разрешён; иначе AppError 409 (или 400 для неизвестного статуса)
+func CheckOrderTransition(from, to OrderStatus) error {
+	if !to.Valid() {
+		return &core.AppError{
+			Code:    "invalid_status",
+			Status:  http.StatusBadRequest,
+			Message: "Неизвестный статус заказа: " + string(to),
+		}
+	}
+	if !from.CanTransitionTo(to) {
+		return &core.AppError{
+			Code:    "invalid_transition",
+			Status:  http.StatusConflict,
+			Message: "Недопустимый переход статуса заказа: " + string(from) + " → " + string(to),
+		}
+	}
+	return nil
+}
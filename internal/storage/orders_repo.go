@@ -0,0 +1,487 @@
+package storage
+
+// internal/storage/orders_repo.go
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ShippingMethod — способ доставки и его стоимость
+type ShippingMethod struct {
+	Code  string
+	Title string
+	Price money.Money
+}
+
+// ShippingMethods — доступные способы доставки (порядок — как на странице оформления).
+// Цены без явной валюты — в валюте магазина (money.DefaultCurrency).
+var ShippingMethods = []ShippingMethod{
+	{Code: "pickup", Title: "Самовывоз (Hamina)", Price: money.New(0, "")},
+	{Code: "post", Title: "Почта Posti", Price: money.New(490, "")},
+	{Code: "courier", Title: "Курьер до двери", Price: money.New(990, "")},
+}
+
+// FindShippingMethod — способ доставки по коду
+func FindShippingMethod(code string) (ShippingMethod, bool) {
+	for _, m := range ShippingMethods {
+		if m.Code == code {
+			return m, true
+		}
+	}
+	return ShippingMethod{}, false
+}
+
+// Order — заказ с позициями
+type Order struct {
+	ID             string      `db:"id" json:"-"`
+	Number         string      `db:"number" json:"number"`
+	UserID         *string     `db:"user_id" json:"-"`
+	Status         OrderStatus `db:"status" json:"status"`
+	PaidIntentID   *string     `db:"paid_intent_id" json:"-"` // Платёж, которым оплачен заказ (nil — не оплачен)
+	Email          string      `db:"email" json:"email"`
+	Name           string      `db:"name" json:"name"`
+	Phone          string      `db:"phone" json:"phone"`
+	Country        string      `db:"country" json:"country"`
+	City           string      `db:"city" json:"city"`
+	PostalCode     string      `db:"postal_code" json:"postal_code"`
+	Address        string      `db:"address" json:"address"`
+	ShippingMethod string      `db:"shipping_method" json:"shipping_method"`
+	Currency       string      `db:"currency" json:"-"`
+	ShippingCost   money.Money `db:"shipping_cost" json:"shipping_cost"`
+	Subtotal       money.Money `db:"subtotal" json:"subtotal"`
+	Total          money.Money `db:"total" json:"total"`
+	CreatedAt      time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time   `db:"updated_at" json:"updated_at"`
+	Items          []OrderItem `db:"-" json:"items"`
+}
+
+// OrderItem — позиция заказа: снимок названия, варианта, артикула и цены на момент покупки
+type OrderItem struct {
+	ID        string      `db:"id" json:"-"`
+	OrderID   string      `db:"order_id" json:"-"`
+	ProductID *string     `db:"product_id" json:"product_id,omitempty"`
+	VariantID *string     `db:"variant_id" json:"variant_id,omitempty"`
+	Name      string      `db:"name" json:"name"`
+	Variant   *string     `db:"variant" json:"variant,omitempty"` // "Цвет: Чёрный, Переключатели: Red"
+	Article   string      `db:"article" json:"article"`           // У варианта — его SKU
+	UnitPrice money.Money `db:"unit_price" json:"unit_price"`
+	Quantity  int         `db:"quantity" json:"quantity"`
+	LineTotal money.Money `db:"line_total" json:"line_total"`
+}
+
+// OrderDraft — данные покупателя, собранные на шагах оформления
+type OrderDraft struct {
+	UserID         string // "" — гостевой заказ
+	Email          string
+	Name           string
+	Phone          string
+	Country        string
+	City           string
+	PostalCode     string
+	Address        string
+	ShippingMethod string
+}
+
+// ErrEmptyCart — в корзине нет позиций (оформлять нечего)
+var ErrEmptyCart = &core.AppError{Code: "empty_cart", Status: http.StatusConflict, Message: "Корзина пуста"}
+
+// orderNumberAlphabet — без похожих символов (0/O, 1/I/L), номер диктуют по телефону
+const orderNumberAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// newOrderNumber — случайный публичный номер заказа (12 символов ≈ 59 бит)
+func newOrderNumber() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = orderNumberAlphabet[int(b[i])%len(orderNumberAlphabet)]
+	}
+	return string(b), nil
+}
+
+// CreateOrderFromCart — оформляет заказ из корзины одной транзакцией:
+// цены берутся текущие и копируются в order_items, товар резервируется за заказом на ttl
+// (не хватает — ErrOutOfStock), корзина очищается.
+func CreateOrderFromCart(ctx context.Context, db *sqlx.DB, cartID string, d OrderDraft, ttl time.Duration) (*Order, error) {
+	method, ok := FindShippingMethod(d.ShippingMethod)
+	if !ok {
+		return nil, errUnknownShipping()
+	}
+
+	number, err := newOrderNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// FOR UPDATE на строках корзины: параллельное "Подтвердить" не создаст второй заказ из той же корзины
+	var lines []CartLine
+	qLines := cartLinesSQL + `
+		ORDER BY ci.added_at ASC, ci.variant_id ASC
+		FOR UPDATE`
+	if err := tx.SelectContext(ctx, &lines, qLines, cartID); err != nil {
+		core.LogError("create order: load cart", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	order := buildOrder(number, method, d, lines)
+
+	const qOrder = `
+		INSERT INTO orders (number, user_id, status, email, name, phone, country, city, postal_code, address,
+		                    shipping_method, currency, shipping_cost, subtotal, total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := tx.ExecContext(ctx, qOrder, order.Number, order.UserID, order.Status, order.Email, order.Name, order.Phone,
+		order.Country, order.City, order.PostalCode, order.Address, order.ShippingMethod, order.Currency, order.ShippingCost,
+		order.Subtotal, order.Total)
+	if err != nil {
+		core.LogError("create order", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	orderID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	const qItem = `
+		INSERT INTO order_items (order_id, product_id, variant_id, name, variant, article, unit_price, quantity, line_total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, it := range order.Items {
+		if _, err := tx.ExecContext(ctx, qItem, orderID, it.ProductID, it.VariantID, it.Name, it.Variant, it.Article,
+			it.UnitPrice, it.Quantity, it.LineTotal); err != nil {
+			core.LogError("create order item", map[string]interface{}{"error": err.Error()})
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO order_status_events (order_id, from_status, to_status, note) VALUES (?, NULL, ?, ?)`,
+		orderID, OrderPending, "Заказ создан"); err != nil {
+		return nil, err
+	}
+
+	// Остаток проверяется под блокировкой и переходит из резерва корзины в резерв заказа
+	if err := reserveOrderTx(ctx, tx, cartID, strconv.FormatInt(orderID, 10), lines, ttl); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE cart_id = ?`, cartID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		core.LogError("create order: commit", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+
+	core.LogInfo("Заказ создан", map[string]interface{}{
+		"order":  order.Number,
+		"total":  order.Total.String(),
+		"items":  len(order.Items),
+		"method": order.ShippingMethod,
+	})
+	return GetOrderByNumber(ctx, db, order.Number)
+}
+
+// errUnknownShipping — ошибка валидации шага "Доставка"
+func errUnknownShipping() error {
+	return &core.AppError{Code: "validation", Status: http.StatusBadRequest, Message: "Неизвестный способ доставки",
+		Fields: map[string]string{"shipping_method": "Выберите способ доставки"}}
+}
+
+// buildOrder — новый заказ из позиций корзины: цены копируются, суммы считаются в минимальных единицах
+func buildOrder(number string, method ShippingMethod, d OrderDraft, lines []CartLine) *Order {
+	order := &Order{
+		Number:         number,
+		Status:         OrderPending,
+		Email:          d.Email,
+		Name:           d.Name,
+		Phone:          d.Phone,
+		Country:        d.Country,
+		City:           d.City,
+		PostalCode:     d.PostalCode,
+		Address:        d.Address,
+		ShippingMethod: method.Code,
+		ShippingCost:   method.Price,
+		Currency:       method.Price.Currency(),
+	}
+	if d.UserID != "" {
+		order.UserID = &d.UserID
+	}
+	for _, l := range lines {
+		productID := l.ProductID
+		item := OrderItem{
+			ProductID: &productID,
+			VariantID: l.VariantID,
+			Name:      l.Name,
+			Variant:   l.Variant,
+			Article:   l.Article,
+			UnitPrice: l.Price,
+			Quantity:  l.Quantity,
+			LineTotal: l.Price.Mul(int64(l.Quantity)),
+		}
+		order.Items = append(order.Items, item)
+		order.Subtotal = order.Subtotal.Add(item.LineTotal)
+	}
+	order.Total = order.Subtotal.Add(order.ShippingCost)
+	return order
+}
+
+// GetOrderByNumber — заказ с позициями по публичному номеру
+func GetOrderByNumber(ctx context.Context, db *sqlx.DB, number string) (*Order, error) {
+	return getOrder(ctx, db, "number = ?", number)
+}
+
+// GetOrderByID — заказ с позициями по внутреннему ID
+func GetOrderByID(ctx context.Context, db *sqlx.DB, id string) (*Order, error) {
+	return getOrder(ctx, db, "id = ?", id)
+}
+
+// getOrder — общая выборка заказа и его позиций
+func getOrder(ctx context.Context, db *sqlx.DB, where string, arg interface{}) (*Order, error) {
+	var o Order
+	q := `
+		SELECT id, number, user_id, status, paid_intent_id, email, name, phone, country, city, postal_code, address,
+		       shipping_method, currency, shipping_cost, subtotal, total, created_at, updated_at
+		FROM orders
+		WHERE ` + where
+	if err := db.GetContext(ctx, &o, q, arg); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError("get order", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, err
+	}
+
+	const qItems = `
+		SELECT id, order_id, product_id, variant_id, name, variant, article, unit_price, quantity, line_total
+		FROM order_items
+		WHERE order_id = ?
+		ORDER BY id ASC`
+	if err := db.SelectContext(ctx, &o.Items, qItems, o.ID); err != nil {
+		core.LogError("get order items", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	o.applyCurrency()
+	return &o, nil
+}
+
+// OrderPage — страница заказов пользователя и их общее число
+type OrderPage struct {
+	Items    []Order
+	Total    int
+	Page     int
+	PageSize int
+}
+
+// ListOrdersByUser — заказы пользователя с позициями, новые первыми.
+// page и pageSize приводятся к допустимым значениям, как в ProductQuery.Normalize.
+func ListOrdersByUser(ctx context.Context, db *sqlx.DB, userID string, page, pageSize int) (*OrderPage, error) {
+	out := &OrderPage{Page: max(page, 1), PageSize: pageSize}
+	if out.PageSize < 1 || out.PageSize > MaxPageSize {
+		out.PageSize = DefaultPageSize
+	}
+
+	if err := db.GetContext(ctx, &out.Total, `SELECT COUNT(*) FROM orders WHERE user_id = ?`, userID); err != nil {
+		core.LogError("count orders", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+
+	const q = `
+		SELECT id, number, user_id, status, paid_intent_id, email, name, phone, country, city, postal_code, address,
+		       shipping_method, currency, shipping_cost, subtotal, total, created_at, updated_at
+		FROM orders
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+	if err := db.SelectContext(ctx, &out.Items, q, userID, out.PageSize, (out.Page-1)*out.PageSize); err != nil {
+		core.LogError("list orders", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	if len(out.Items) == 0 {
+		return out, nil
+	}
+
+	ids := make([]string, len(out.Items))
+	byID := make(map[string]*Order, len(out.Items))
+	for i := range out.Items {
+		ids[i] = out.Items[i].ID
+		byID[out.Items[i].ID] = &out.Items[i]
+	}
+	qItems, args, err := sqlx.In(`
+		SELECT id, order_id, product_id, variant_id, name, variant, article, unit_price, quantity, line_total
+		FROM order_items
+		WHERE order_id IN (?)
+		ORDER BY id ASC`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var items []OrderItem
+	if err := db.SelectContext(ctx, &items, db.Rebind(qItems), args...); err != nil {
+		core.LogError("list order items", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	for _, it := range items {
+		if o, ok := byID[it.OrderID]; ok {
+			o.Items = append(o.Items, it)
+		}
+	}
+	for i := range out.Items {
+		out.Items[i].applyCurrency()
+	}
+	return out, nil
+}
+
+// applyCurrency — суммы из DECIMAL читаются в валюте по умолчанию; у заказа своя колонка currency
+func (o *Order) applyCurrency() {
+	o.ShippingCost = o.ShippingCost.WithCurrency(o.Currency)
+	o.Subtotal = o.Subtotal.WithCurrency(o.Currency)
+	o.Total = o.Total.WithCurrency(o.Currency)
+	for i := range o.Items {
+		o.Items[i].UnitPrice = o.Items[i].UnitPrice.WithCurrency(o.Currency)
+		o.Items[i].LineTotal = o.Items[i].LineTotal.WithCurrency(o.Currency)
+	}
+}
+
+// UpdateOrderStatus — переводит заказ в новый статус с проверкой конечного автомата.
+// Строка заказа блокируется (FOR UPDATE), поэтому параллельные переходы не "перепрыгнут" друг через друга.
+// Недопустимый переход — *core.AppError (409).
+func UpdateOrderStatus(ctx context.Context, db *sqlx.DB, orderID string, to OrderStatus, note string) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := transitionOrderTx(ctx, tx, orderID, to, note); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// orderExpiredNote — комментарий в истории статусов заказа, отменённого по сроку резерва
+const orderExpiredNote = "Не оплачен вовремя: резерв истёк"
+
+// CancelExpiredOrders — отменяет неоплаченные заказы с истёкшим резервом (PENDING_ORDER_TTL),
+// резерв снимается. Каждый заказ — своей транзакцией: статус и срок перепроверяются под блокировкой,
+// заказ, оплаченный между выборкой и отменой, не трогается. Возвращает число отменённых.
+func CancelExpiredOrders(ctx context.Context, db *sqlx.DB) (int, error) {
+	var ids []string
+	const q = `
+		SELECT DISTINCT o.id
+		FROM orders o
+		JOIN stock_reservations r ON r.order_id = o.id
+		WHERE o.status = ? AND r.expires_at <= CURRENT_TIMESTAMP`
+	if err := db.SelectContext(ctx, &ids, q, OrderPending); err != nil {
+		core.LogError("list expired orders", map[string]interface{}{"error": err.Error()})
+		return 0, err
+	}
+
+	n := 0
+	for _, id := range ids {
+		cancelled, err := cancelExpiredOrder(ctx, db, id)
+		if err != nil {
+			return n, err
+		}
+		if cancelled {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// cancelExpiredOrder — отмена одного заказа из CancelExpiredOrders; false — уже не pending или резерв продлён
+func cancelExpiredOrder(ctx context.Context, db *sqlx.DB, orderID string) (bool, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var status OrderStatus
+	if err := tx.GetContext(ctx, &status, `SELECT status FROM orders WHERE id = ? FOR UPDATE`, orderID); err != nil {
+		return false, err
+	}
+	var expired bool
+	if err := tx.GetContext(ctx, &expired, `
+		SELECT EXISTS(SELECT 1 FROM stock_reservations WHERE order_id = ? AND expires_at <= CURRENT_TIMESTAMP)`,
+		orderID); err != nil {
+		return false, err
+	}
+	if status != OrderPending || !expired {
+		return false, nil
+	}
+
+	if err := transitionOrderTx(ctx, tx, orderID, OrderCancelled, orderExpiredNote); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// transitionOrderTx — смена статуса внутри уже открытой транзакции (общая часть
+// UpdateOrderStatus и обработки платёжных событий). Повторный переход в текущий статус — no-op.
+func transitionOrderTx(ctx context.Context, tx *sqlx.Tx, orderID string, to OrderStatus, note string) error {
+	var from OrderStatus
+	if err := tx.GetContext(ctx, &from, `SELECT status FROM orders WHERE id = ? FOR UPDATE`, orderID); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	if err := CheckOrderTransition(from, to); err != nil {
+		core.LogError("Недопустимый переход статуса заказа", map[string]interface{}{
+			"order_id": orderID,
+			"from":     from,
+			"to":       to,
+		})
+		return err
+	}
+
+	// Оплата списывает резерв с остатков, отмена — просто снимает его. Возврат денег до отгрузки
+	// возвращает товар на остатки; после отгрузки товар у покупателя — приход оформляется правкой остатка.
+	switch {
+	case to == OrderPaid:
+		if err := commitOrderStockTx(ctx, tx, orderID); err != nil {
+			return err
+		}
+	case to == OrderCancelled:
+		if err := releaseOrderStockTx(ctx, tx, orderID); err != nil {
+			return err
+		}
+	case to == OrderRefunded && from == OrderPaid:
+		if err := restockOrderTx(ctx, tx, orderID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = ? WHERE id = ?`, to, orderID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO order_status_events (order_id, from_status, to_status, note) VALUES (?, ?, ?, ?)`,
+		orderID, from, to, note); err != nil {
+		return err
+	}
+
+	core.LogInfo("Статус заказа изменён", map[string]interface{}{
+		"order_id": orderID,
+		"from":     from,
+		"to":       to,
+	})
+	return nil
+}
@@ -0,0 +1,208 @@
+package storage
+
+// internal/storage/payments_repo.go
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Payment — попытка оплаты заказа у провайдера
+type Payment struct {
+	ID        string      `db:"id" json:"-"`
+	OrderID   string      `db:"order_id" json:"-"`
+	Provider  string      `db:"provider" json:"provider"`
+	IntentID  string      `db:"intent_id" json:"intent_id"`
+	Status    string      `db:"status" json:"status"`
+	Amount    money.Money `db:"amount" json:"amount"`
+	Currency  string      `db:"currency" json:"-"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time   `db:"updated_at" json:"updated_at"`
+}
+
+// PaymentEvent — проверенное (подпись сошлась) событие провайдера
+type PaymentEvent struct {
+	Provider      string
+	EventID       string
+	IntentID      string
+	Type          string
+	PaymentStatus string      // Новый статус платежа у провайдера
+	OrderStatus   OrderStatus // "" — статус заказа не меняется (например, отказ банка)
+}
+
+// PaymentEventResult — итог применения события провайдера
+type PaymentEventResult struct {
+	Applied  bool   // false — событие уже было обработано (повторная доставка)
+	Conflict string // Не "" — заказ не переведён (отменён, нет остатка): деньги у провайдера, нужен ручной возврат
+}
+
+// ErrPaidByOtherIntent — заказ уже оплачен другим платежом: успешная оплата ещё одним — двойное списание
+var ErrPaidByOtherIntent = &core.AppError{Code: "already_paid", Status: http.StatusConflict,
+	Message: "Заказ уже оплачен другим платежом"}
+
+// ErrUnknownPayment — событие пришло по платежу, которого у нас нет
+var ErrUnknownPayment = &core.AppError{Code: "unknown_payment", Status: http.StatusNotFound, Message: "Платёж не найден"}
+
+// CreatePayment — запоминает созданный у провайдера платёж
+func CreatePayment(ctx context.Context, db *sqlx.DB, p Payment) error {
+	const q = `
+		INSERT INTO payments (order_id, provider, intent_id, status, amount, currency)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.ExecContext(ctx, q, p.OrderID, p.Provider, p.IntentID, p.Status, p.Amount, p.Amount.Currency()); err != nil {
+		core.LogError("create payment", map[string]interface{}{
+			"order_id":  p.OrderID,
+			"intent_id": p.IntentID,
+			"error":     err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+// ListOrderPayments — платежи заказа, последние первыми
+func ListOrderPayments(ctx context.Context, db *sqlx.DB, orderID string) ([]Payment, error) {
+	var list []Payment
+	const q = `
+		SELECT id, order_id, provider, intent_id, status, amount, currency, created_at, updated_at
+		FROM payments
+		WHERE order_id = ?
+		ORDER BY id DESC`
+	if err := db.SelectContext(ctx, &list, q, orderID); err != nil {
+		core.LogError("list order payments", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	for i := range list {
+		list[i].Amount = list[i].Amount.WithCurrency(list[i].Currency)
+	}
+	return list, nil
+}
+
+// ApplyPaymentEvent — применяет событие провайдера одной транзакцией: отмечает событие
+// обработанным, обновляет статус платежа и (если нужно) переводит заказ по конечному автомату.
+// Повторная доставка того же event_id ничего не меняет и возвращает Applied=false.
+// Заказ перевести нельзя (оплата отменённого заказа или другим платежом, не хватает остатка) — откатывается только
+// переход: событие записывается с payment_events.conflict, провайдер получает подтверждение
+// и не повторяет доставку, а платёж ждёт ручного возврата.
+func ApplyPaymentEvent(ctx context.Context, db *sqlx.DB, ev PaymentEvent) (PaymentEventResult, error) {
+	var result PaymentEventResult
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `INSERT IGNORE INTO payment_events (provider, event_id, intent_id, type) VALUES (?, ?, ?, ?)`,
+		ev.Provider, ev.EventID, ev.IntentID, ev.Type)
+	if err != nil {
+		core.LogError("record payment event", map[string]interface{}{"error": err.Error()})
+		return result, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Уже обработано — ответ провайдеру такой же, как в первый раз
+		return result, tx.Commit()
+	}
+
+	var p Payment
+	const qPayment = `SELECT id, order_id FROM payments WHERE provider = ? AND intent_id = ? FOR UPDATE`
+	if err := tx.GetContext(ctx, &p, qPayment, ev.Provider, ev.IntentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, ErrUnknownPayment
+		}
+		return result, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE payments SET status = ? WHERE id = ?`, ev.PaymentStatus, p.ID); err != nil {
+		return result, err
+	}
+
+	if ev.OrderStatus != "" {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT order_transition`); err != nil {
+			return result, err
+		}
+		err := applyOrderEventTx(ctx, tx, p.OrderID, ev)
+		if conflict, ok := paymentConflict(err); ok {
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT order_transition`); err != nil {
+				return result, err
+			}
+			const qConflict = `UPDATE payment_events SET conflict = ? WHERE provider = ? AND event_id = ?`
+			if _, err := tx.ExecContext(ctx, qConflict, conflict, ev.Provider, ev.EventID); err != nil {
+				return result, err
+			}
+			result.Conflict = conflict
+			logPaymentConflict(ev, p.OrderID, conflict)
+		} else if err != nil {
+			return result, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		core.LogError("apply payment event: commit", map[string]interface{}{"error": err.Error()})
+		return result, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// applyOrderEventTx — переход заказа по событию платежа. Заказ оплачивает один платёж
+// (orders.paid_intent_id): успешная оплата другим платежом того же заказа — ErrPaidByOtherIntent,
+// возврат по платежу, которым заказ не оплачен, статус заказа не меняет.
+func applyOrderEventTx(ctx context.Context, tx *sqlx.Tx, orderID string, ev PaymentEvent) error {
+	var paidBy sql.NullString
+	if err := tx.GetContext(ctx, &paidBy, `SELECT paid_intent_id FROM orders WHERE id = ? FOR UPDATE`, orderID); err != nil {
+		return err
+	}
+	if apply, err := eventAppliesToOrder(paidBy.String, ev); !apply {
+		return err
+	}
+
+	if err := transitionOrderTx(ctx, tx, orderID, ev.OrderStatus, "Платёж: "+ev.Type); err != nil {
+		return err
+	}
+	if ev.OrderStatus == OrderPaid {
+		if _, err := tx.ExecContext(ctx, `UPDATE orders SET paid_intent_id = ? WHERE id = ?`, ev.IntentID, orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventAppliesToOrder — переводить ли заказ, оплаченный платежом paidBy ("" — не оплачен), по событию:
+// успешная оплата другим платежом — ErrPaidByOtherIntent, возврат по другому платежу заказ не меняет
+func eventAppliesToOrder(paidBy string, ev PaymentEvent) (bool, error) {
+	switch {
+	case ev.OrderStatus == OrderPaid && paidBy != "" && paidBy != ev.IntentID:
+		return false, ErrPaidByOtherIntent
+	case ev.OrderStatus == OrderRefunded && paidBy != ev.IntentID:
+		return false, nil
+	}
+	return true, nil
+}
+
+// paymentConflict — переход заказа по событию невозможен по данным (409: статус, остаток),
+// а не из-за сбоя: такое событие подтверждается провайдеру, повтор ничего не изменит
+func paymentConflict(err error) (string, bool) {
+	var appErr *core.AppError
+	if errors.As(err, &appErr) && appErr.Status == http.StatusConflict {
+		return appErr.Message, true
+	}
+	return "", false
+}
+
+// logPaymentConflict — событие не применено к заказу: нужен ручной возврат платежа
+func logPaymentConflict(ev PaymentEvent, orderID, conflict string) {
+	core.LogError("Платёж не применён к заказу, нужен ручной возврат", map[string]interface{}{
+		"provider":  ev.Provider,
+		"event_id":  ev.EventID,
+		"intent_id": ev.IntentID,
+		"type":      ev.Type,
+		"order_id":  orderID,
+		"conflict":  conflict,
+	})
+}
@@ -3,25 +3,58 @@ package storage
 // internal/storage/ products_repo.go
 import (
 	"context"
+	"database/sql"
 	"myApp/internal/core"
+	"myApp/internal/money"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Product struct {
-	ID         string    `db:"id" json:"id"`
-	CategoryID *string   `db:"category_id" json:"category_id,omitempty"`
-	Name       string    `db:"name" json:"name"`
-	Article    string    `db:"article" json:"article"`
-	Price      float64   `db:"price" json:"price"`
-	ImageAlt   *string   `db:"image_alt" json:"image_alt,omitempty"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	ID          string      `db:"id" json:"id"`
+	CategoryID  *string     `db:"category_id" json:"category_id,omitempty"`
+	Name        string      `db:"name" json:"name"`
+	Article     string      `db:"article" json:"article"`
+	Description *string     `db:"description" json:"description,omitempty"`
+	Price       money.Money `db:"price" json:"price"`
+	Stock       int         `db:"stock" json:"-"`             // Остаток товара без вариантов (у вариантов — свой)
+	Available   int         `db:"available" json:"available"` // Доступно к покупке: остаток − резервы (по всем вариантам)
+	ImageAlt    *string     `db:"image_alt" json:"image_alt,omitempty"`
+	ImageKey    *string     `db:"image_key" json:"-"`    // Префикс имён миниатюр в BlobStore (nil — нет изображения)
+	ImageWidth  *int        `db:"image_width" json:"-"`  // Ширина самой большой миниатюры
+	ImageHeight *int        `db:"image_height" json:"-"` // Её высота
+	CreatedAt   time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time   `db:"updated_at" json:"updated_at"` // Правка товара или остатка
+	Variants    []Variant   `db:"-" json:"variants,omitempty"`  // Загружаются отдельно (VariantRepository)
+}
+
+// InStock — есть ли товар в наличии (хотя бы у одного варианта)
+func (p Product) InStock() bool {
+	return p.Available > 0
+}
+
+// ProductImage — загруженное изображение товара для шаблонов
+type ProductImage struct {
+	Key           string
+	Width, Height int
+}
+
+// Image — изображение товара; nil — не загружено (шаблон показывает заглушку)
+func (p Product) Image() *ProductImage {
+	if p.ImageKey == nil || p.ImageWidth == nil || p.ImageHeight == nil {
+		return nil
+	}
+	return &ProductImage{Key: *p.ImageKey, Width: *p.ImageWidth, Height: *p.ImageHeight}
 }
 
 func ListAllProducts(ctx context.Context, db *sqlx.DB) ([]Product, error) {
-	const q = `
-		SELECT p.id, p.name, p.price, p.image_alt
+	q := `
+		SELECT p.id, p.category_id, p.name, p.article, p.description, p.price, p.image_alt, p.image_key, p.image_width, p.image_height, p.created_at, p.updated_at,
+		       ` + ProductStockColumns + `
 		FROM products p
 		ORDER BY p.name ASC`
 
@@ -45,10 +78,11 @@ func ListAllProducts(ctx context.Context, db *sqlx.DB) ([]Product, error) {
 func GetProductByID(ctx context.Context, db *sqlx.DB, id int) (*Product, error) {
 	var p Product
 
-	const q = `
-		SELECT id, name, article, price, image_alt
-		FROM products 
-		WHERE id = ?`
+	q := `
+		SELECT p.id, p.category_id, p.name, p.article, p.description, p.price, p.image_alt, p.image_key, p.image_width, p.image_height, p.created_at, p.updated_at,
+		       ` + ProductStockColumns + `
+		FROM products p
+		WHERE p.id = ?`
 
 	if err := db.GetContext(ctx, &p, q, id); err != nil {
 		core.LogError("get product by id", map[string]interface{}{
@@ -60,3 +94,219 @@ func GetProductByID(ctx context.Context, db *sqlx.DB, id int) (*Product, error)
 	}
 	return &p, nil
 }
+
+// Значения по умолчанию и ограничения для постраничного вывода
+const (
+	DefaultPageSize = 24
+	MaxPageSize     = 100
+)
+
+// productSorts — белый список сортировок: значение ?sort= → ORDER BY (никогда не подставляем ввод в SQL напрямую)
+var productSorts = map[string]string{
+	"name":        "p.name ASC, p.id ASC",
+	"-name":       "p.name DESC, p.id DESC",
+	"price":       "p.price ASC, p.id ASC",
+	"-price":      "p.price DESC, p.id DESC",
+	"created_at":  "p.created_at ASC, p.id ASC",
+	"-created_at": "p.created_at DESC, p.id DESC",
+}
+
+// ProductQuery — параметры выборки каталога: страница, сортировка, фильтры
+type ProductQuery struct {
+	Page        int          // Номер страницы (с 1)
+	PageSize    int          // Размер страницы (1..MaxPageSize)
+	Sort        string       // Ключ из productSorts; "" → "name"
+	MinPrice    *money.Money // Нижняя граница цены (включительно)
+	MaxPrice    *money.Money // Верхняя граница цены (включительно)
+	CategoryIDs []string     // Категория и её потомки; пусто — без фильтра
+	Text        string       // Подстрока названия или артикула (список в /admin/products)
+}
+
+// ProductPage — одна страница каталога и общее число подходящих товаров
+type ProductPage struct {
+	Items    []Product
+	Total    int
+	Page     int
+	PageSize int
+}
+
+// IsValidProductSort — поддерживается ли ключ сортировки
+func IsValidProductSort(sort string) bool {
+	_, ok := productSorts[sort]
+	return ok
+}
+
+// Normalize — приводит параметры к допустимым значениям (страница ≥ 1, размер в пределах)
+func (q ProductQuery) Normalize() ProductQuery {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = DefaultPageSize
+	}
+	if q.PageSize > MaxPageSize {
+		q.PageSize = MaxPageSize
+	}
+	if !IsValidProductSort(q.Sort) {
+		q.Sort = "name"
+	}
+	return q
+}
+
+// ListProducts — страница каталога с фильтрами и сортировкой + общее количество
+func ListProducts(ctx context.Context, db *sqlx.DB, q ProductQuery) (*ProductPage, error) {
+	q = q.Normalize()
+
+	var (
+		where []string
+		args  []interface{}
+	)
+	if len(q.CategoryIDs) > 0 {
+		where = append(where, "p.category_id IN (?)")
+		args = append(args, q.CategoryIDs)
+	}
+	if q.MinPrice != nil {
+		where = append(where, "p.price >= ?")
+		args = append(args, *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		where = append(where, "p.price <= ?")
+		args = append(args, *q.MaxPrice)
+	}
+	if q.Text != "" {
+		like := "%" + escapeLike(q.Text) + "%"
+		where = append(where, "(p.name LIKE ? OR p.article LIKE ?)")
+		args = append(args, like, like)
+	}
+
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	// 1) Общее количество — для "страница N из M"
+	countQ, countArgs, err := sqlx.In("SELECT COUNT(*) FROM products p "+whereSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	var total int
+	if err := db.GetContext(ctx, &total, db.Rebind(countQ), countArgs...); err != nil {
+		core.LogError("count products", map[string]interface{}{
+			"query": countQ,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	page := &ProductPage{Total: total, Page: q.Page, PageSize: q.PageSize}
+	if total == 0 {
+		return page, nil
+	}
+
+	// 2) Сама страница
+	listQ, listArgs, err := sqlx.In(`
+		SELECT p.id, p.category_id, p.name, p.article, p.description, p.price, p.image_alt, p.image_key, p.image_width, p.image_height, p.created_at, p.updated_at,
+		       `+ProductStockColumns+`
+		FROM products p
+		`+whereSQL+`
+		ORDER BY `+productSorts[q.Sort]+`
+		LIMIT ? OFFSET ?`, append(args, q.PageSize, (q.Page-1)*q.PageSize)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &page.Items, db.Rebind(listQ), listArgs...); err != nil {
+		core.LogError("list products", map[string]interface{}{
+			"query": listQ,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	return page, nil
+}
+
+// escapeLike — экранирует % и _ (и сам \), чтобы ввод искался буквально в LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ErrArticleTaken — артикул уже занят другим товаром (UNIQUE uq_products_article)
+var ErrArticleTaken = &core.AppError{Code: "article_taken", Status: http.StatusConflict, Message: "Товар с таким артикулом уже есть",
+	Fields: map[string]string{"article": "Товар с таким артикулом уже есть"}}
+
+// CreateProduct — новый товар; заполняет p.ID и p.CreatedAt. Занятый артикул — ErrArticleTaken.
+func CreateProduct(ctx context.Context, db *sqlx.DB, p *Product) error {
+	const q = `
+		INSERT INTO products (category_id, name, article, description, price, image_alt, image_key, image_width, image_height)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := db.ExecContext(ctx, q, p.CategoryID, p.Name, p.Article, p.Description, p.Price, p.ImageAlt,
+		p.ImageKey, p.ImageWidth, p.ImageHeight)
+	if isDuplicateKey(err) {
+		return ErrArticleTaken
+	}
+	if err != nil {
+		core.LogError("create product", map[string]interface{}{"article": p.Article, "error": err.Error()})
+		return err
+	}
+	if err := touchCatalog(ctx, db); err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	created, err := GetProductByID(ctx, db, int(id))
+	if err != nil {
+		return err
+	}
+	*p = *created
+	return nil
+}
+
+// UpdateProduct — сохраняет все поля товара p.ID. Нет товара — sql.ErrNoRows,
+// занятый артикул — ErrArticleTaken.
+func UpdateProduct(ctx context.Context, db *sqlx.DB, p *Product) error {
+	const q = `
+		UPDATE products
+		SET category_id = ?, name = ?, article = ?, description = ?, price = ?, image_alt = ?,
+		    image_key = ?, image_width = ?, image_height = ?
+		WHERE id = ?`
+	_, err := db.ExecContext(ctx, q, p.CategoryID, p.Name, p.Article, p.Description, p.Price, p.ImageAlt,
+		p.ImageKey, p.ImageWidth, p.ImageHeight, p.ID)
+	if isDuplicateKey(err) {
+		return ErrArticleTaken
+	}
+	if err != nil {
+		core.LogError("update product", map[string]interface{}{"id": p.ID, "error": err.Error()})
+		return err
+	}
+	if err := touchCatalog(ctx, db); err != nil {
+		return err
+	}
+
+	// RowsAffected = 0 и для неизменённой строки — существование проверяем отдельно
+	id, err := strconv.Atoi(p.ID)
+	if err != nil {
+		return sql.ErrNoRows
+	}
+	updated, err := GetProductByID(ctx, db, id)
+	if err != nil {
+		return err
+	}
+	*p = *updated
+	return nil
+}
+
+// DeleteProduct — удаляет товар (позиции корзин удаляются каскадом, заказы хранят снимок).
+// Нет товара — sql.ErrNoRows.
+func DeleteProduct(ctx context.Context, db *sqlx.DB, id int) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM products WHERE id = ?`, id)
+	if err != nil {
+		core.LogError("delete product", map[string]interface{}{"id": id, "error": err.Error()})
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return touchCatalog(ctx, db)
+}
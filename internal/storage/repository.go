@@ -0,0 +1,207 @@
+package storage
+
+// repository.go — интерфейсы хранилища. Обработчики работают с ними, а не с *sqlx.DB:
+// в проде за интерфейсами MySQL (repository_mysql.go), в тестах и при APP_STORAGE=memory —
+// память (memory.go). "Не найдено" во всех реализациях — sql.ErrNoRows.
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProductRepository — каталог товаров
+type ProductRepository interface {
+	// ListAll — все товары по имени
+	ListAll(ctx context.Context) ([]Product, error)
+	// GetByID — товар по ID
+	GetByID(ctx context.Context, id int) (*Product, error)
+	// List — страница каталога с фильтрами и сортировкой
+	List(ctx context.Context, q ProductQuery) (*ProductPage, error)
+	// Create — новый товар (заполняет ID и CreatedAt); занятый артикул — ErrArticleTaken
+	Create(ctx context.Context, p *Product) error
+	// Update — сохраняет все поля товара p.ID; занятый артикул — ErrArticleTaken
+	Update(ctx context.Context, p *Product) error
+	// Delete — удаляет товар
+	Delete(ctx context.Context, id int) error
+	// Modified — последнее изменение товаров, остатков или резервов (Last-Modified каталога);
+	// нулевое время — изменение в текущую секунду, валидатора по времени нет
+	Modified(ctx context.Context) (time.Time, error)
+}
+
+// VariantRepository — варианты товаров (размер, цвет)
+type VariantRepository interface {
+	// ByProducts — варианты товаров по position, ключ — ID товара (у товара без вариантов ключа нет)
+	ByProducts(ctx context.Context, productIDs []string) (map[string][]Variant, error)
+	// GetByID — вариант по ID
+	GetByID(ctx context.Context, id int) (*Variant, error)
+}
+
+// InventoryRepository — остатки, резервы и журнал движений (см. inventory_repo.go).
+// Списание и снятие резерва заказа — в OrderRepository.UpdateStatus (оплата и отмена).
+type InventoryRepository interface {
+	// Reserve — резервирует позиции корзины на ttl вместо прежнего резерва; не хватает — ErrOutOfStock
+	Reserve(ctx context.Context, cartID string, ttl time.Duration) error
+	// Release — снимает резерв корзины
+	Release(ctx context.Context, cartID string) error
+	// Adjust — задаёт остаток и пишет движение; меньше резерва — *core.AppError (409)
+	Adjust(ctx context.Context, a StockAdjustment) error
+	// Movements — последние движения товара и его вариантов, новые первыми
+	Movements(ctx context.Context, productID string, limit int) ([]StockMovement, error)
+}
+
+// CategoryRepository — дерево категорий
+type CategoryRepository interface {
+	// ListAll — плоский список (position, затем имя)
+	ListAll(ctx context.Context) ([]Category, error)
+	// GetBySlug — категория по slug из URL
+	GetBySlug(ctx context.Context, slug string) (*Category, error)
+}
+
+// CartRepository — корзины покупателей
+type CartRepository interface {
+	// Create — новая пустая корзина (userID = "" — анонимная)
+	Create(ctx context.Context, userID string) (string, error)
+	// Exists — есть ли корзина с таким ID
+	Exists(ctx context.Context, id string) (bool, error)
+	// IDByUser — ID корзины пользователя ("" — корзины нет)
+	IDByUser(ctx context.Context, userID string) (string, error)
+	// Get — корзина с позициями по текущим ценам каталога
+	Get(ctx context.Context, id string) (*Cart, error)
+	// AddItem — добавляет товар (variantID = "" — без варианта) или увеличивает количество
+	// (не больше MaxCartQuantity)
+	AddItem(ctx context.Context, cartID, productID, variantID string, qty int) error
+	// SetItemQuantity — задаёт количество; 0 и меньше — удаляет позицию
+	SetItemQuantity(ctx context.Context, cartID, productID, variantID string, qty int) error
+	// RemoveItem — удаляет позицию
+	RemoveItem(ctx context.Context, cartID, productID, variantID string) error
+	// Claim — привязывает анонимную корзину к пользователю при входе (см. ClaimCart)
+	Claim(ctx context.Context, anonCartID, userID string) (string, error)
+}
+
+// OrderRepository — заказы
+type OrderRepository interface {
+	// CreateFromCart — оформляет заказ из корзины, резервирует товар за заказом на ttl и очищает корзину;
+	// не хватает товара — ErrOutOfStock
+	CreateFromCart(ctx context.Context, cartID string, d OrderDraft, ttl time.Duration) (*Order, error)
+	// GetByNumber — заказ по публичному номеру
+	GetByNumber(ctx context.Context, number string) (*Order, error)
+	// GetByID — заказ по внутреннему ID
+	GetByID(ctx context.Context, id string) (*Order, error)
+	// ListByUser — страница заказов пользователя с позициями, новые первыми
+	ListByUser(ctx context.Context, userID string, page, pageSize int) (*OrderPage, error)
+	// UpdateStatus — переход по конечному автомату (недопустимый — *core.AppError 409);
+	// оплата списывает резерв заказа с остатков, отмена снимает его
+	UpdateStatus(ctx context.Context, orderID string, to OrderStatus, note string) error
+	// CancelExpired — отменяет неоплаченные заказы с истёкшим резервом и снимает резерв;
+	// возвращает число отменённых
+	CancelExpired(ctx context.Context) (int, error)
+}
+
+// PaymentRepository — платежи и события провайдера
+type PaymentRepository interface {
+	// Create — запоминает созданный у провайдера платёж
+	Create(ctx context.Context, p Payment) error
+	// ListByOrder — платежи заказа, последние первыми
+	ListByOrder(ctx context.Context, orderID string) ([]Payment, error)
+	// ApplyEvent — идемпотентно применяет событие; Applied=false — уже было обработано,
+	// Conflict — заказ не переведён, событие записано (нужен ручной возврат)
+	ApplyEvent(ctx context.Context, ev PaymentEvent) (PaymentEventResult, error)
+}
+
+// UserRepository — учётные записи покупателей
+type UserRepository interface {
+	// Create — новая учётная запись (заполняет ID и CreatedAt); занятый email — ErrEmailTaken
+	Create(ctx context.Context, u *User) error
+	// GetByID — пользователь по ID
+	GetByID(ctx context.Context, id string) (*User, error)
+	// GetByEmail — пользователь по email (без учёта регистра)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	// SetPasswordHash — заменяет хеш пароля
+	SetPasswordHash(ctx context.Context, id, hash string) error
+	// TouchLogin — отмечает время успешного входа
+	TouchLogin(ctx context.Context, id string) error
+	// MarkEmailVerified — email подтверждён
+	MarkEmailVerified(ctx context.Context, id string) error
+	// SetRole — назначает роль; неизвестная роль — ErrUnknownRole, нет пользователя — sql.ErrNoRows
+	SetRole(ctx context.Context, id, role string) error
+}
+
+// RoleRepository — права ролей (role_permissions)
+type RoleRepository interface {
+	// Permissions — права роли (неизвестная роль — пустой список)
+	Permissions(ctx context.Context, role string) ([]Permission, error)
+}
+
+// TokenRepository — одноразовые токены из писем (хранится только хеш). Недействительный
+// токен — ErrTokenInvalid.
+type TokenRepository interface {
+	// Create — новый токен; прежние неиспользованные токены того же назначения гаснут
+	Create(ctx context.Context, userID string, purpose TokenPurpose, hash string, ttl time.Duration) error
+	// Find — пользователь действующего токена (токен остаётся действующим)
+	Find(ctx context.Context, purpose TokenPurpose, hash string) (userID string, err error)
+	// Consume — гасит токен и возвращает пользователя; второй вызов — ErrTokenInvalid
+	Consume(ctx context.Context, purpose TokenPurpose, hash string) (userID string, err error)
+	// ResetPassword — гасит токен сброса пароля и атомарно с этим задаёт хеш пароля, подтверждает
+	// email и отзывает API-токены пользователя (revoked — их число); при ошибке не меняется ничего
+	ResetPassword(ctx context.Context, hash, passwordHash string) (userID string, revoked int, err error)
+}
+
+// APITokenRepository — API-токены скриптов и интеграций (хранится только хеш)
+type APITokenRepository interface {
+	// Create — новый токен со сроком ttl (заполняет ID, ExpiresAt и CreatedAt)
+	Create(ctx context.Context, t *APIToken, ttl time.Duration) error
+	// List — токены пользователя (userID = "" — всех) с email владельца, новые первыми
+	List(ctx context.Context, userID string) ([]APIToken, error)
+	// Authenticate — действующий токен по хешу с отметкой last_used_at; иначе ErrAPITokenInvalid
+	Authenticate(ctx context.Context, hash string) (*APIToken, error)
+	// Revoke — отзывает токен (userID != "" — только свой); нет токена — sql.ErrNoRows
+	Revoke(ctx context.Context, id, userID string) error
+	// RevokeAll — отзывает все действующие токены пользователя; возвращает их число
+	RevokeAll(ctx context.Context, userID string) (int, error)
+}
+
+// IdempotencyRepository — ключи Idempotency-Key и сохранённые ответы на запросы с ними
+type IdempotencyRepository interface {
+	// Begin — занимает ключ на ttl; занят — возвращает его запись (ответ или незавершённый запрос).
+	// Незавершённый дольше stale ключ считается брошенным и занимается заново.
+	Begin(ctx context.Context, rec *IdempotencyRecord, ttl, stale time.Duration) (prev *IdempotencyRecord, err error)
+	// Complete — сохраняет ответ занятого ключа
+	Complete(ctx context.Context, rec *IdempotencyRecord) error
+	// Release — освобождает незавершённый ключ без ответа
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Repositories — набор репозиториев приложения
+type Repositories struct {
+	Products    ProductRepository
+	Variants    VariantRepository
+	Inventory   InventoryRepository
+	Categories  CategoryRepository
+	Carts       CartRepository
+	Orders      OrderRepository
+	Payments    PaymentRepository
+	Users       UserRepository
+	Tokens      TokenRepository
+	APITokens   APITokenRepository
+	Idempotency IdempotencyRepository
+	Roles       RoleRepository
+}
+
+// NewMySQLRepositories — репозитории поверх MySQL
+func NewMySQLRepositories(db *sqlx.DB) Repositories {
+	return Repositories{
+		Products:    mysqlProducts{db},
+		Variants:    mysqlVariants{db},
+		Inventory:   mysqlInventory{db},
+		Categories:  mysqlCategories{db},
+		Carts:       mysqlCarts{db},
+		Orders:      mysqlOrders{db},
+		Payments:    mysqlPayments{db},
+		Users:       mysqlUsers{db},
+		Tokens:      mysqlTokens{db},
+		APITokens:   mysqlAPITokens{db},
+		Idempotency: mysqlIdempotency{db},
+		Roles:       mysqlRoles{db},
+	}
+}
@@ -0,0 +1,241 @@
+package storage
+
+// repository_mysql.go — MySQL-реализация репозиториев: тонкие обёртки над функциями *_repo.go
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type mysqlProducts struct{ db *sqlx.DB }
+
+func (r mysqlProducts) ListAll(ctx context.Context) ([]Product, error) {
+	return ListAllProducts(ctx, r.db)
+}
+
+func (r mysqlProducts) GetByID(ctx context.Context, id int) (*Product, error) {
+	return GetProductByID(ctx, r.db, id)
+}
+
+func (r mysqlProducts) List(ctx context.Context, q ProductQuery) (*ProductPage, error) {
+	return ListProducts(ctx, r.db, q)
+}
+
+func (r mysqlProducts) Create(ctx context.Context, p *Product) error {
+	return CreateProduct(ctx, r.db, p)
+}
+
+func (r mysqlProducts) Update(ctx context.Context, p *Product) error {
+	return UpdateProduct(ctx, r.db, p)
+}
+
+func (r mysqlProducts) Delete(ctx context.Context, id int) error {
+	return DeleteProduct(ctx, r.db, id)
+}
+
+func (r mysqlProducts) Modified(ctx context.Context) (time.Time, error) {
+	return CatalogModified(ctx, r.db)
+}
+
+type mysqlVariants struct{ db *sqlx.DB }
+
+func (r mysqlVariants) ByProducts(ctx context.Context, productIDs []string) (map[string][]Variant, error) {
+	return ListVariantsByProducts(ctx, r.db, productIDs)
+}
+
+func (r mysqlVariants) GetByID(ctx context.Context, id int) (*Variant, error) {
+	return GetVariantByID(ctx, r.db, id)
+}
+
+type mysqlInventory struct{ db *sqlx.DB }
+
+func (r mysqlInventory) Reserve(ctx context.Context, cartID string, ttl time.Duration) error {
+	return ReserveCart(ctx, r.db, cartID, ttl)
+}
+
+func (r mysqlInventory) Release(ctx context.Context, cartID string) error {
+	return ReleaseCart(ctx, r.db, cartID)
+}
+
+func (r mysqlInventory) Adjust(ctx context.Context, a StockAdjustment) error {
+	return AdjustStock(ctx, r.db, a)
+}
+
+func (r mysqlInventory) Movements(ctx context.Context, productID string, limit int) ([]StockMovement, error) {
+	return ListStockMovements(ctx, r.db, productID, limit)
+}
+
+type mysqlCategories struct{ db *sqlx.DB }
+
+func (r mysqlCategories) ListAll(ctx context.Context) ([]Category, error) {
+	return ListAllCategories(ctx, r.db)
+}
+
+func (r mysqlCategories) GetBySlug(ctx context.Context, slug string) (*Category, error) {
+	return GetCategoryBySlug(ctx, r.db, slug)
+}
+
+type mysqlCarts struct{ db *sqlx.DB }
+
+func (r mysqlCarts) Create(ctx context.Context, userID string) (string, error) {
+	return CreateCart(ctx, r.db, userID)
+}
+
+func (r mysqlCarts) Exists(ctx context.Context, id string) (bool, error) {
+	return CartExists(ctx, r.db, id)
+}
+
+func (r mysqlCarts) IDByUser(ctx context.Context, userID string) (string, error) {
+	return GetCartIDByUser(ctx, r.db, userID)
+}
+
+func (r mysqlCarts) Get(ctx context.Context, id string) (*Cart, error) {
+	return GetCart(ctx, r.db, id)
+}
+
+func (r mysqlCarts) AddItem(ctx context.Context, cartID, productID, variantID string, qty int) error {
+	return AddCartItem(ctx, r.db, cartID, productID, variantID, qty)
+}
+
+func (r mysqlCarts) SetItemQuantity(ctx context.Context, cartID, productID, variantID string, qty int) error {
+	return SetCartItemQuantity(ctx, r.db, cartID, productID, variantID, qty)
+}
+
+func (r mysqlCarts) RemoveItem(ctx context.Context, cartID, productID, variantID string) error {
+	return RemoveCartItem(ctx, r.db, cartID, productID, variantID)
+}
+
+func (r mysqlCarts) Claim(ctx context.Context, anonCartID, userID string) (string, error) {
+	return ClaimCart(ctx, r.db, anonCartID, userID)
+}
+
+type mysqlOrders struct{ db *sqlx.DB }
+
+func (r mysqlOrders) CreateFromCart(ctx context.Context, cartID string, d OrderDraft, ttl time.Duration) (*Order, error) {
+	return CreateOrderFromCart(ctx, r.db, cartID, d, ttl)
+}
+
+func (r mysqlOrders) GetByNumber(ctx context.Context, number string) (*Order, error) {
+	return GetOrderByNumber(ctx, r.db, number)
+}
+
+func (r mysqlOrders) GetByID(ctx context.Context, id string) (*Order, error) {
+	return GetOrderByID(ctx, r.db, id)
+}
+
+func (r mysqlOrders) ListByUser(ctx context.Context, userID string, page, pageSize int) (*OrderPage, error) {
+	return ListOrdersByUser(ctx, r.db, userID, page, pageSize)
+}
+
+func (r mysqlOrders) UpdateStatus(ctx context.Context, orderID string, to OrderStatus, note string) error {
+	return UpdateOrderStatus(ctx, r.db, orderID, to, note)
+}
+
+func (r mysqlOrders) CancelExpired(ctx context.Context) (int, error) {
+	return CancelExpiredOrders(ctx, r.db)
+}
+
+type mysqlPayments struct{ db *sqlx.DB }
+
+func (r mysqlPayments) Create(ctx context.Context, p Payment) error {
+	return CreatePayment(ctx, r.db, p)
+}
+
+func (r mysqlPayments) ListByOrder(ctx context.Context, orderID string) ([]Payment, error) {
+	return ListOrderPayments(ctx, r.db, orderID)
+}
+
+func (r mysqlPayments) ApplyEvent(ctx context.Context, ev PaymentEvent) (PaymentEventResult, error) {
+	return ApplyPaymentEvent(ctx, r.db, ev)
+}
+
+type mysqlUsers struct{ db *sqlx.DB }
+
+func (r mysqlUsers) Create(ctx context.Context, u *User) error {
+	return CreateUser(ctx, r.db, u)
+}
+
+func (r mysqlUsers) GetByID(ctx context.Context, id string) (*User, error) {
+	return GetUserByID(ctx, r.db, id)
+}
+
+func (r mysqlUsers) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return GetUserByEmail(ctx, r.db, email)
+}
+
+func (r mysqlUsers) SetPasswordHash(ctx context.Context, id, hash string) error {
+	return SetUserPasswordHash(ctx, r.db, id, hash)
+}
+
+func (r mysqlUsers) TouchLogin(ctx context.Context, id string) error {
+	return TouchUserLogin(ctx, r.db, id)
+}
+
+func (r mysqlUsers) MarkEmailVerified(ctx context.Context, id string) error {
+	return MarkUserEmailVerified(ctx, r.db, id)
+}
+
+func (r mysqlUsers) SetRole(ctx context.Context, id, role string) error {
+	return SetUserRole(ctx, r.db, id, role)
+}
+
+type mysqlTokens struct{ db *sqlx.DB }
+
+func (r mysqlTokens) Create(ctx context.Context, userID string, purpose TokenPurpose, hash string, ttl time.Duration) error {
+	return CreateUserToken(ctx, r.db, userID, purpose, hash, ttl)
+}
+
+func (r mysqlTokens) Find(ctx context.Context, purpose TokenPurpose, hash string) (string, error) {
+	return FindUserToken(ctx, r.db, purpose, hash)
+}
+
+func (r mysqlTokens) Consume(ctx context.Context, purpose TokenPurpose, hash string) (string, error) {
+	return ConsumeUserToken(ctx, r.db, purpose, hash)
+}
+
+func (r mysqlTokens) ResetPassword(ctx context.Context, hash, passwordHash string) (string, int, error) {
+	return ResetUserPassword(ctx, r.db, hash, passwordHash)
+}
+
+type mysqlAPITokens struct{ db *sqlx.DB }
+
+func (r mysqlAPITokens) Create(ctx context.Context, t *APIToken, ttl time.Duration) error {
+	return CreateAPIToken(ctx, r.db, t, ttl)
+}
+
+func (r mysqlAPITokens) List(ctx context.Context, userID string) ([]APIToken, error) {
+	return ListAPITokens(ctx, r.db, userID)
+}
+
+func (r mysqlAPITokens) Authenticate(ctx context.Context, hash string) (*APIToken, error) {
+	return AuthenticateAPIToken(ctx, r.db, hash)
+}
+
+func (r mysqlAPITokens) Revoke(ctx context.Context, id, userID string) error {
+	return RevokeAPIToken(ctx, r.db, id, userID)
+}
+
+func (r mysqlAPITokens) RevokeAll(ctx context.Context, userID string) (int, error) {
+	return RevokeUserAPITokens(ctx, r.db, userID)
+}
+
+type mysqlIdempotency struct{ db *sqlx.DB }
+
+func (r mysqlIdempotency) Begin(ctx context.Context, rec *IdempotencyRecord, ttl, stale time.Duration) (*IdempotencyRecord, error) {
+	return BeginIdempotency(ctx, r.db, rec, ttl, stale)
+}
+
+func (r mysqlIdempotency) Complete(ctx context.Context, rec *IdempotencyRecord) error {
+	return CompleteIdempotency(ctx, r.db, rec)
+}
+
+func (r mysqlIdempotency) Release(ctx context.Context, scope, key string) error {
+	return ReleaseIdempotency(ctx, r.db, scope, key)
+}
+
+type mysqlRoles struct{ db *sqlx.DB }
+
+func (r mysqlRoles) Permissions(ctx context.Context, role string) ([]Permission, error) {
+	return RolePermissions(ctx, r.db, role)
+}
@@ -0,0 +1,88 @@
+package storage
+
+// roles_repo.go — роли пользователей и их права (roles, role_permissions).
+// Обработчики проверяют права, а не роли: handler.RequirePermission(app, PermProductsWrite).
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"myApp/internal/core"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// Permission — право доступа ("область.действие")
+type Permission string
+
+// Права, которые проверяет код. Выдаются ролям в role_permissions (migrations/010_roles.up.sql и далее).
+const (
+	PermAdminAccess   Permission = "admin.access"   // Вход в /admin
+	PermProductsWrite Permission = "products.write" // Изменение каталога
+	PermDebugView     Permission = "debug.view"     // /debug: заголовки, cookie, пул БД
+	PermTokensManage  Permission = "tokens.manage"  // /admin/tokens: API-токены всех пользователей
+)
+
+// Роли из таблицы roles
+const (
+	RoleCustomer = "customer" // Покупатель (по умолчанию при регистрации)
+	RoleStaff    = "staff"    // Сотрудник магазина
+	RoleAdmin    = "admin"    // Администратор
+)
+
+// DefaultRolePermissions повторяет migrations/010_roles.up.sql и 015_api_tokens.up.sql — права ролей хранилища в памяти
+func DefaultRolePermissions() map[string][]Permission {
+	return map[string][]Permission{
+		RoleCustomer: nil,
+		RoleStaff:    {PermAdminAccess, PermProductsWrite},
+		RoleAdmin:    {PermAdminAccess, PermProductsWrite, PermDebugView, PermTokensManage},
+	}
+}
+
+// ErrUnknownRole — роли нет в таблице roles
+var ErrUnknownRole = &core.AppError{Code: "unknown_role", Status: http.StatusBadRequest, Message: "Неизвестная роль"}
+
+// isForeignKeyViolation — ошибка MySQL 1452 (нет строки, на которую ссылается внешний ключ)
+func isForeignKeyViolation(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == 1452
+}
+
+// RolePermissions — права роли (пустой список — у роли нет прав или роли нет)
+func RolePermissions(ctx context.Context, db *sqlx.DB, role string) ([]Permission, error) {
+	var perms []Permission
+	if err := db.SelectContext(ctx, &perms, `SELECT permission FROM role_permissions WHERE role = ? ORDER BY permission`, role); err != nil {
+		core.LogError("role permissions", map[string]interface{}{"role": role, "error": err.Error()})
+		return nil, err
+	}
+	return perms, nil
+}
+
+// SetUserRole — назначает роль пользователю; роли нет в roles — ErrUnknownRole,
+// пользователя нет — sql.ErrNoRows
+func SetUserRole(ctx context.Context, db *sqlx.DB, id, role string) error {
+	res, err := db.ExecContext(ctx, `UPDATE users SET role = ? WHERE id = ?`, role, id)
+	if isForeignKeyViolation(err) {
+		return ErrUnknownRole
+	}
+	if err != nil {
+		core.LogError("set user role", map[string]interface{}{"user_id": id, "role": role, "error": err.Error()})
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	// RowsAffected = 0 и для той же роли — существование проверяем отдельно
+	var exists bool
+	if err := db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)`, id); err != nil {
+		core.LogError("set user role: check user", map[string]interface{}{"user_id": id, "error": err.Error()})
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+// seeds.go — демо-данные отдельно от схемы (`app seed`).
+// Файлы seeds/*.sql выполняются по порядку имён, каждый в своей транзакции;
+// сиды должны быть повторяемыми (INSERT IGNORE, UPDATE), журнала применения у них нет.
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SeedDir — каталог с демо-данными по умолчанию
+const SeedDir = "seeds"
+
+// Seed — выполняет все *.sql из files. Возвращает число выполненных файлов.
+func Seed(ctx context.Context, db *sqlx.DB, files fs.FS) (int, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return 0, fmt.Errorf("чтение каталога сидов: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".sql") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		body, err := fs.ReadFile(files, name)
+		if err != nil {
+			return i, fmt.Errorf("чтение %s: %w", name, err)
+		}
+		stmts, err := SplitStatements(string(body))
+		if err != nil {
+			return i, fmt.Errorf("разбор %s: %w", name, err)
+		}
+		if hasDDL(stmts) {
+			return i, fmt.Errorf("сид %s: изменения схемы оформляются миграцией", name)
+		}
+		if err := seedFile(ctx, db, stmts); err != nil {
+			core.LogError("Ошибка выполнения сида", map[string]interface{}{"file": name, "error": err.Error()})
+			return i, fmt.Errorf("сид %s: %w", name, err)
+		}
+		core.LogInfo("Сид выполнен", map[string]interface{}{"file": name, "statements": len(stmts)})
+	}
+	if len(names) > 0 {
+		if err := touchCatalog(ctx, db); err != nil {
+			return len(names), err
+		}
+	}
+	return len(names), nil
+}
+
+func seedFile(ctx context.Context, db *sqlx.DB, stmts []string) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := execStatements(ctx, tx, stmts); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,153 @@
+package storage
+
+// sqlsplit.go — разбор файла миграции на отдельные statement'ы.
+// Драйвер работает с multiStatements=false, поэтому каждый statement выполняется отдельно.
+// Учитываются строки ('...', "...", `...`), комментарии (--, #, /* */) и блоки DELIMITER
+// (процедуры и триггеры, внутри которых ";" не завершает statement).
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitStatements — делит SQL-текст на statement'ы. Обычные комментарии отбрасываются,
+// исполняемые комментарии MySQL (/*! ... */) сохраняются.
+func SplitStatements(sqlText string) ([]string, error) {
+	var (
+		stmts []string
+		cur   strings.Builder
+		delim = ";"
+		line  = 1
+	)
+
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		cur.Reset()
+	}
+
+	n := len(sqlText)
+	for i := 0; i < n; {
+		// DELIMITER — команда клиента mysql: действует с начала строки и до её конца
+		if atLineStart(sqlText, i) && strings.TrimSpace(cur.String()) == "" {
+			if d, next, ok := parseDelimiter(sqlText, i); ok {
+				if d == "" {
+					return nil, fmt.Errorf("строка %d: пустой DELIMITER", line)
+				}
+				delim = d
+				line++
+				i = next
+				continue
+			}
+		}
+
+		ch := sqlText[i]
+		switch {
+		case ch == '\n':
+			line++
+			cur.WriteByte(ch)
+			i++
+
+		case ch == '\'' || ch == '"' || ch == '`':
+			end, err := skipQuoted(sqlText, i)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %w", line, err)
+			}
+			line += strings.Count(sqlText[i:end], "\n")
+			cur.WriteString(sqlText[i:end])
+			i = end
+
+		case ch == '#' || (ch == '-' && strings.HasPrefix(sqlText[i:], "--") && (i+2 == n || isSpace(sqlText[i+2]))):
+			// Однострочный комментарий: до конца строки (перевод строки оставляем)
+			end := strings.IndexByte(sqlText[i:], '\n')
+			if end < 0 {
+				end = n - i
+			}
+			i += end
+
+		case ch == '/' && strings.HasPrefix(sqlText[i:], "/*"):
+			end := strings.Index(sqlText[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("строка %d: незакрытый комментарий /*", line)
+			}
+			end += i + 4
+			if strings.HasPrefix(sqlText[i:], "/*!") {
+				cur.WriteString(sqlText[i:end])
+			} else {
+				cur.WriteByte(' ')
+			}
+			line += strings.Count(sqlText[i:end], "\n")
+			i = end
+
+		case strings.HasPrefix(sqlText[i:], delim):
+			flush()
+			i += len(delim)
+
+		default:
+			cur.WriteByte(ch)
+			i++
+		}
+	}
+	flush()
+	return stmts, nil
+}
+
+// atLineStart — позиция i — первый непробельный символ строки
+func atLineStart(s string, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch s[j] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+			continue
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseDelimiter — разбирает "DELIMITER xx" в позиции i; next — начало следующей строки
+func parseDelimiter(s string, i int) (delim string, next int, ok bool) {
+	const kw = "DELIMITER"
+	if len(s)-i < len(kw) || !strings.EqualFold(s[i:i+len(kw)], kw) {
+		return "", 0, false
+	}
+	rest := s[i+len(kw):]
+	if rest != "" && !isSpace(rest[0]) {
+		return "", 0, false
+	}
+	end := strings.IndexByte(rest, '\n')
+	if end < 0 {
+		end = len(rest)
+		next = len(s)
+	} else {
+		next = i + len(kw) + end + 1
+	}
+	return strings.TrimSpace(rest[:end]), next, true
+}
+
+// skipQuoted — конец строкового литерала/идентификатора, начинающегося в позиции i.
+// Поддерживает удвоение кавычки внутри литерала и экранирование обратным слэшем (кроме `...`).
+func skipQuoted(s string, i int) (int, error) {
+	q := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			if q != '`' {
+				j++
+			}
+		case q:
+			if j+1 < len(s) && s[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("незакрытая кавычка %c", q)
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
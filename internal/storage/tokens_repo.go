@@ -0,0 +1,148 @@
+package storage
+
+// tokens_repo.go — одноразовые токены из писем (user_tokens). Хранится только SHA-256 токена
+// (auth.HashToken); срок действия считается по часам MySQL, чтобы не зависеть от часового пояса.
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TokenPurpose — назначение токена: токен сброса пароля не подтвердит email и наоборот
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenResetPassword TokenPurpose = "reset_password"
+)
+
+// ErrTokenInvalid — токена нет, он истёк или уже использован (причина не раскрывается)
+var ErrTokenInvalid = &core.AppError{Code: "token_invalid", Status: http.StatusBadRequest, Message: "Ссылка недействительна или устарела"}
+
+// CreateUserToken — новый токен пользователя; неиспользованные токены того же назначения гаснут
+func CreateUserToken(ctx context.Context, db *sqlx.DB, userID string, purpose TokenPurpose, hash string, ttl time.Duration) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = ? AND purpose = ? AND used_at IS NULL`,
+		userID, purpose); err != nil {
+		core.LogError("revoke user tokens", map[string]interface{}{"user_id": userID, "error": err.Error()})
+		return err
+	}
+	const q = `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP + INTERVAL ? SECOND)`
+	if _, err := tx.ExecContext(ctx, q, userID, purpose, hash, int64(ttl/time.Second)); err != nil {
+		core.LogError("create user token", map[string]interface{}{"user_id": userID, "error": err.Error()})
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindUserToken — пользователь действующего токена, токен не гасится (страница ввода нового пароля)
+func FindUserToken(ctx context.Context, db *sqlx.DB, purpose TokenPurpose, hash string) (string, error) {
+	var userID string
+	const q = `
+		SELECT user_id FROM user_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+	err := db.GetContext(ctx, &userID, q, hash, purpose)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTokenInvalid
+	}
+	if err != nil {
+		core.LogError("find user token", map[string]interface{}{"error": err.Error()})
+		return "", err
+	}
+	return userID, nil
+}
+
+// ConsumeUserToken — гасит действующий токен и возвращает его пользователя.
+// Одновременные запросы с одним токеном: успешен ровно один (UPDATE ... AND used_at IS NULL).
+func ConsumeUserToken(ctx context.Context, db *sqlx.DB, purpose TokenPurpose, hash string) (string, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	userID, err := consumeUserTokenTx(ctx, tx, purpose, hash)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		core.LogError("consume user token: commit", map[string]interface{}{"error": err.Error()})
+		return "", err
+	}
+	return userID, nil
+}
+
+// ResetUserPassword — по токену сброса одной транзакцией: гасит токен, меняет хеш пароля,
+// подтверждает email и отзывает API-токены пользователя (возвращает их число). Любая ошибка —
+// откат: пароль не сменится без отзыва токенов, а токен сброса останется действующим.
+func ResetUserPassword(ctx context.Context, db *sqlx.DB, hash, passwordHash string) (string, int, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	userID, err := consumeUserTokenTx(ctx, tx, TokenResetPassword, hash)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, userID); err != nil {
+		core.LogError("reset user password", map[string]interface{}{"user_id": userID, "error": err.Error()})
+		return "", 0, err
+	}
+	const qVerify = `UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ? AND email_verified_at IS NULL`
+	if _, err := tx.ExecContext(ctx, qVerify, userID); err != nil {
+		core.LogError("reset user password: verify email", map[string]interface{}{"user_id": userID, "error": err.Error()})
+		return "", 0, err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = ? AND revoked_at IS NULL`, userID)
+	if err != nil {
+		core.LogError("reset user password: revoke api tokens", map[string]interface{}{"user_id": userID, "error": err.Error()})
+		return "", 0, err
+	}
+	revoked, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		core.LogError("reset user password: commit", map[string]interface{}{"error": err.Error()})
+		return "", 0, err
+	}
+	return userID, int(revoked), nil
+}
+
+// consumeUserTokenTx — гасит действующий токен внутри открытой транзакции; иначе ErrTokenInvalid
+func consumeUserTokenTx(ctx context.Context, tx *sqlx.Tx, purpose TokenPurpose, hash string) (string, error) {
+	var userID string
+	const qFind = `
+		SELECT user_id FROM user_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		FOR UPDATE`
+	if err := tx.GetContext(ctx, &userID, qFind, hash, purpose); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrTokenInvalid
+		}
+		core.LogError("consume user token", map[string]interface{}{"error": err.Error()})
+		return "", err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE token_hash = ? AND used_at IS NULL`, hash)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", ErrTokenInvalid
+	}
+	return userID, nil
+}
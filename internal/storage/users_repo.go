@@ -0,0 +1,139 @@
+package storage
+
+// users_repo.go — учётные записи покупателей
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// User — учётная запись. PasswordHash не покидает сервер (json:"-").
+// Permissions — права роли; заполняет handler.LoadUser (RoleRepository.Permissions).
+type User struct {
+	ID           string     `db:"id" json:"id"`
+	Email        string     `db:"email" json:"email"`
+	Name         string     `db:"name" json:"name"`
+	Role         string     `db:"role" json:"role"`
+	PasswordHash string     `db:"password_hash" json:"-"`
+	VerifiedAt   *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"` // nil — email не подтверждён
+	LastLoginAt  *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+
+	Permissions []Permission `db:"-" json:"-"`
+}
+
+// Can — есть ли у пользователя право (в шаблонах: {{if .User.Can "admin.access"}})
+func (u *User) Can(p Permission) bool {
+	return u != nil && slices.Contains(u.Permissions, p)
+}
+
+// ErrEmailTaken — email уже зарегистрирован (UNIQUE uq_users_email)
+var ErrEmailTaken = &core.AppError{Code: "email_taken", Status: http.StatusConflict, Message: "Этот email уже зарегистрирован",
+	Fields: map[string]string{"email": "Этот email уже зарегистрирован"}}
+
+// NormalizeEmail — email для хранения и поиска: без пробелов по краям, в нижнем регистре
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// isDuplicateKey — ошибка MySQL 1062 (нарушение UNIQUE)
+func isDuplicateKey(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == 1062
+}
+
+const userColumns = `id, email, name, role, password_hash, email_verified_at, last_login_at, created_at`
+
+// CreateUser — новая учётная запись (пустая роль — RoleCustomer); заполняет u.ID и u.CreatedAt.
+// Занятый email — ErrEmailTaken, неизвестная роль — ErrUnknownRole.
+func CreateUser(ctx context.Context, db *sqlx.DB, u *User) error {
+	u.Email = NormalizeEmail(u.Email)
+	if u.Role == "" {
+		u.Role = RoleCustomer
+	}
+	res, err := db.ExecContext(ctx, `INSERT INTO users (email, name, role, password_hash) VALUES (?, ?, ?, ?)`,
+		u.Email, u.Name, u.Role, u.PasswordHash)
+	if isDuplicateKey(err) {
+		return ErrEmailTaken
+	}
+	if isForeignKeyViolation(err) {
+		return ErrUnknownRole
+	}
+	if err != nil {
+		core.LogError("create user", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	created, err := GetUserByID(ctx, db, strconv.FormatInt(id, 10))
+	if err != nil {
+		return err
+	}
+	*u = *created
+	return nil
+}
+
+// GetUserByID — пользователь по ID (sql.ErrNoRows — нет такого)
+func GetUserByID(ctx context.Context, db *sqlx.DB, id string) (*User, error) {
+	var u User
+	if err := db.GetContext(ctx, &u, `SELECT `+userColumns+` FROM users WHERE id = ?`, id); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError("get user by id", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUserByEmail — пользователь по email (регистр не важен; sql.ErrNoRows — нет такого)
+func GetUserByEmail(ctx context.Context, db *sqlx.DB, email string) (*User, error) {
+	var u User
+	if err := db.GetContext(ctx, &u, `SELECT `+userColumns+` FROM users WHERE email = ?`, NormalizeEmail(email)); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogError("get user by email", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SetUserPasswordHash — заменяет хеш пароля (перехеширование при входе, смена пароля)
+func SetUserPasswordHash(ctx context.Context, db *sqlx.DB, id, hash string) error {
+	if _, err := db.ExecContext(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, hash, id); err != nil {
+		core.LogError("set user password hash", map[string]interface{}{"user_id": id, "error": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// TouchUserLogin — отмечает время успешного входа
+func TouchUserLogin(ctx context.Context, db *sqlx.DB, id string) error {
+	if _, err := db.ExecContext(ctx, `UPDATE users SET last_login_at = CURRENT_TIMESTAMP WHERE id = ?`, id); err != nil {
+		core.LogError("touch user login", map[string]interface{}{"user_id": id, "error": err.Error()})
+		return err
+	}
+	return nil
+}
+
+// MarkUserEmailVerified — email подтверждён (ссылкой из письма); повторный вызов не меняет дату
+func MarkUserEmailVerified(ctx context.Context, db *sqlx.DB, id string) error {
+	const q = `UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE id = ? AND email_verified_at IS NULL`
+	if _, err := db.ExecContext(ctx, q, id); err != nil {
+		core.LogError("mark user email verified", map[string]interface{}{"user_id": id, "error": err.Error()})
+		return err
+	}
+	return nil
+}
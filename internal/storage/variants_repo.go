@@ -0,0 +1,141 @@
+package storage
+
+// internal/storage/variants_repo.go — варианты товаров: у каждого свой SKU, цена (или цена
+// товара) и остаток; опции ("Цвет: Чёрный") — значения общих для каталога типов опций.
+import (
+	"context"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Variant — вариант товара
+type Variant struct {
+	ID            string          `db:"id" json:"id"`
+	ProductID     string          `db:"product_id" json:"-"`
+	SKU           string          `db:"sku" json:"sku"`
+	PriceOverride *money.Money    `db:"price" json:"-"`               // nil — цена товара
+	Price         money.Money     `db:"effective_price" json:"price"` // Цена варианта с учётом PriceOverride
+	Stock         int             `db:"stock" json:"-"`
+	Available     int             `db:"available" json:"available"` // Остаток − действующие резервы
+	Position      int             `db:"position" json:"-"`
+	Options       []VariantOption `db:"-" json:"options"`
+}
+
+// VariantOption — значение опции варианта
+type VariantOption struct {
+	VariantID string `db:"variant_id" json:"-"`
+	Type      string `db:"type" json:"type"`   // Код типа опции ("color")
+	Title     string `db:"title" json:"title"` // Название типа ("Цвет")
+	Value     string `db:"value" json:"value"` // Значение ("Чёрный")
+}
+
+// Label — опции варианта одной строкой: "Цвет: Чёрный, Переключатели: Red"
+func (v Variant) Label() string {
+	parts := make([]string, 0, len(v.Options))
+	for _, o := range v.Options {
+		parts = append(parts, o.Title+": "+o.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// InStock — есть ли вариант в наличии
+func (v Variant) InStock() bool {
+	return v.Available > 0
+}
+
+// variantLabelSQL — та же строка, что Variant.Label, для варианта v (NULL — без варианта)
+const variantLabelSQL = `(
+	SELECT GROUP_CONCAT(CONCAT(ot.title, ': ', ov.value) ORDER BY ot.position, ot.id SEPARATOR ', ')
+	FROM variant_option_values vov
+	JOIN option_values ov ON ov.id = vov.option_value_id
+	JOIN option_types ot ON ot.id = ov.option_type_id
+	WHERE vov.variant_id = v.id)`
+
+// ListVariantsByProducts — варианты товаров (по position), ключ — ID товара
+func ListVariantsByProducts(ctx context.Context, db *sqlx.DB, productIDs []string) (map[string][]Variant, error) {
+	out := map[string][]Variant{}
+	if len(productIDs) == 0 {
+		return out, nil
+	}
+
+	q, args, err := sqlx.In(`
+		SELECT v.id, v.product_id, v.sku, v.price, COALESCE(v.price, p.price) AS effective_price, v.stock,
+		       `+variantAvailableSQL+` AS available, v.position
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.product_id IN (?)
+		ORDER BY v.product_id, v.position, v.id`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	var variants []Variant
+	if err := db.SelectContext(ctx, &variants, db.Rebind(q), args...); err != nil {
+		core.LogError("list variants", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	if err := loadVariantOptions(ctx, db, variants); err != nil {
+		return nil, err
+	}
+	for _, v := range variants {
+		out[v.ProductID] = append(out[v.ProductID], v)
+	}
+	return out, nil
+}
+
+// GetVariantByID — вариант по ID (sql.ErrNoRows — нет такого)
+func GetVariantByID(ctx context.Context, db *sqlx.DB, id int) (*Variant, error) {
+	var v Variant
+	q := `
+		SELECT v.id, v.product_id, v.sku, v.price, COALESCE(v.price, p.price) AS effective_price, v.stock,
+		       ` + variantAvailableSQL + ` AS available, v.position
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.id = ?`
+	if err := db.GetContext(ctx, &v, q, id); err != nil {
+		return nil, err
+	}
+	variants := []Variant{v}
+	if err := loadVariantOptions(ctx, db, variants); err != nil {
+		return nil, err
+	}
+	return &variants[0], nil
+}
+
+// loadVariantOptions — опции вариантов одним запросом (порядок — position типа опции)
+func loadVariantOptions(ctx context.Context, db *sqlx.DB, variants []Variant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	ids := make([]string, len(variants))
+	byID := make(map[string]*Variant, len(variants))
+	for i := range variants {
+		ids[i] = variants[i].ID
+		byID[variants[i].ID] = &variants[i]
+	}
+
+	q, args, err := sqlx.In(`
+		SELECT vov.variant_id, ot.code AS type, ot.title, ov.value
+		FROM variant_option_values vov
+		JOIN option_values ov ON ov.id = vov.option_value_id
+		JOIN option_types ot ON ot.id = ov.option_type_id
+		WHERE vov.variant_id IN (?)
+		ORDER BY ot.position, ot.id`, ids)
+	if err != nil {
+		return err
+	}
+	var opts []VariantOption
+	if err := db.SelectContext(ctx, &opts, db.Rebind(q), args...); err != nil {
+		core.LogError("list variant options", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	for _, o := range opts {
+		if v, ok := byID[o.VariantID]; ok {
+			v.Options = append(v.Options, o)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package view
+
+// funcs.go — функции, доступные во всех шаблонах (template.FuncMap)
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"myApp/internal/images"
+	"myApp/internal/money"
+)
+
+// funcMap — регистрируется в layout до парсинга страниц (см. New)
+var funcMap = template.FuncMap{
+	"dict":  dict,
+	"money": formatMoney,
+}
+
+// dict — собирает map из пар ключ/значение, чтобы передать несколько значений в partial:
+//
+//	{{template "product-card" dict "Product" . "Nonce" $.Nonce}}
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: нечётное число аргументов")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: ключ %v не строка", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// formatMoney — сумма для показа с учётом локали (по умолчанию — money.DefaultLocale):
+//
+//	{{money .Price}}        → 1 234,50 €
+//	{{money .Price "en"}}   → €1,234.50
+func formatMoney(m money.Money, locale ...string) string {
+	if len(locale) > 0 {
+		return m.Format(locale[0])
+	}
+	return m.Format(money.DefaultLocale)
+}
+
+// srcWidth — ширина миниатюры для src у <img> (браузеры без srcset)
+const srcWidth = 640
+
+// imageFuncs — адреса миниатюр изображения товара; url — адрес файла в BlobStore.
+// До SetBlobURL файлы ищутся под /uploads/.
+//
+//	{{srcset .Key .Width "webp"}}  → /uploads/products/…-320.webp 320w, /uploads/products/…-640.webp 640w
+//	{{imageSrc .Key .Width "jpg"}} → /uploads/products/…-640.jpg
+func imageFuncs(url func(key string) string) template.FuncMap {
+	return template.FuncMap{
+		"srcset": func(key string, maxWidth int, format string) string {
+			var parts []string
+			for _, w := range images.WidthsFor(maxWidth) {
+				parts = append(parts, url(images.Name(key, w, format))+" "+strconv.Itoa(w)+"w")
+			}
+			return strings.Join(parts, ", ")
+		},
+		"imageSrc": func(key string, maxWidth int, format string) string {
+			widths := images.WidthsFor(maxWidth)
+			w := widths[0]
+			for _, x := range widths {
+				if x <= srcWidth {
+					w = x
+				}
+			}
+			return url(images.Name(key, w, format))
+		},
+	}
+}
+
+// defaultBlobURL — адрес файла в UPLOADS_DIR (LocalBlobStore с префиксом /uploads)
+func defaultBlobURL(key string) string {
+	return "/uploads/" + key
+}
@@ -11,38 +11,73 @@ import (
 
 type Templates struct {
 	templates map[string]*template.Template // Хранилище готовых шаблонов: ключ — имя страницы ("home"), значение — скомпилированный шаблон (layout + page)
+	menu      MenuFunc                      // Источник данных для меню в блоке "nav" (может быть nil)
+	user      MenuFunc                      // Текущий пользователь для блока "nav" (может быть nil)
 }
 
+// MenuFunc — возвращает данные для меню навигации (например, дерево категорий).
+// Вызывается только при рендере HTML-страницы, JSON-ответы его не трогают.
+type MenuFunc func(c *gin.Context) any
+
 type PageData struct {
 	Title     string        // Заголовок страницы: используется в <title>{{.Title}}</title> в layout
-	CSRFField template.HTML // Готовый HTML для скрытого CSRF-поля: <input type="hidden" name="csrf_token" value="..."> (template.HTML — чтобы не эскейпить HTML)
+	CSRFField template.HTML // Готовый HTML для скрытого CSRF-поля: <input type="hidden" name="_csrf" value="..."> (template.HTML — чтобы не эскейпить HTML)
 	Nonce     string        // CSP-nonce: случайная строка для защиты скриптов/стилей ({{.Nonce}} в шаблоне)
 	Data      any           // Гибкие данные для страницы: struct, map и т.д. (передаётся в {{.Data}} в page-шаблоне)
+	Menu      any           // Данные меню для блока "nav" (дерево категорий), см. SetMenu
+	User      any           // Вошедший пользователь (nil — аноним), см. SetUser
 }
 
-const layoutFile = "web/templates/layouts/layout.html"
+const (
+	layoutFile   = "web/templates/layouts/layout.html"
+	partialsGlob = "web/templates/partials/*.html" // Общие куски разметки (pagination и т.п.), доступны всем страницам
+)
 
 func New() (*Templates, error) {
 	// Фиксированная map страниц — как в оригинале: ключи — имена для рендера ("home"), значения — пути к page-файлам
 	// Почему map? Быстрый поиск по строке (O(1)). Легко добавлять/удалять страницы без сканирования FS.
 	pages := map[string]string{
-		"home":     "web/templates/pages/home.html",         // Главная страница
-		"about":    "web/templates/pages/about.html",        // О проекте
-		"form":     "web/templates/pages/form.html",         // Форма (с CSRF)
-		"catalog":  "web/templates/pages/catalog.html",      // Каталог (список продуктов)
-		"product":  "web/templates/pages/show_product.html", // Страница продукта (с data)
-		"notfound": "web/templates/pages/404.html",          // 404-страница
+		"home":                 "web/templates/pages/home.html",                 // Главная страница
+		"about":                "web/templates/pages/about.html",                // О проекте
+		"form":                 "web/templates/pages/form.html",                 // Форма (с CSRF)
+		"catalog":              "web/templates/pages/catalog.html",              // Каталог (список продуктов)
+		"product":              "web/templates/pages/show_product.html",         // Страница продукта (с data)
+		"search":               "web/templates/pages/search.html",               // Поиск товаров (/search?q=)
+		"cart":                 "web/templates/pages/cart.html",                 // Корзина (с CSRF-формами)
+		"register":             "web/templates/pages/register.html",             // Регистрация
+		"login":                "web/templates/pages/login.html",                // Вход
+		"reset_request":        "web/templates/pages/reset_request.html",        // Восстановление пароля: email
+		"reset_confirm":        "web/templates/pages/reset_confirm.html",        // Восстановление пароля: новый пароль
+		"account_message":      "web/templates/pages/account_message.html",      // Результат перехода по ссылке из письма
+		"checkout":             "web/templates/pages/checkout.html",             // Оформление заказа (шаги по .Data.Step)
+		"order":                "web/templates/pages/order.html",                // Страница заказа
+		"payment_fake":         "web/templates/pages/payment_fake.html",         // Страница оплаты фейкового провайдера
+		"admin_dashboard":      "web/templates/pages/admin_dashboard.html",      // Панель управления /admin
+		"admin_products":       "web/templates/pages/admin_products.html",       // Товары: список с поиском
+		"admin_product_form":   "web/templates/pages/admin_product_form.html",   // Товар: создание и редактирование
+		"admin_product_delete": "web/templates/pages/admin_product_delete.html", // Товар: подтверждение удаления
+		"admin_product_stock":  "web/templates/pages/admin_product_stock.html",  // Товар: остатки и журнал движений
+		"api_docs":             "web/templates/pages/api_docs.html",             // Документация JSON API (по openapi.json)
+		"api_tokens":           "web/templates/pages/api_tokens.html",           // API-токены: свои и (в /admin) всех пользователей
+		"forbidden":            "web/templates/pages/403.html",                  // 403-страница (нет прав)
+		"notfound":             "web/templates/pages/404.html",                  // 404-страница
 	}
 
 	// Шаг 1: Парсим layout ОДИН РАЗ (оптимизация!)
 	// template.New("layout") — создаёт новый шаблон с именем "layout" (не влияет на рендер, только внутреннее).
 	// ParseFiles(layoutFile) — читает файл, парсит в AST (абстрактное дерево), компилирует в исполняемый план.
 	// Если layout сломан (синтаксис {{ }} неверный) — ошибка сразу.
-	layoutTpl, err := template.New("layout").ParseFiles(layoutFile)
+	// Funcs(funcMap) — до ParseFiles: функции должны быть известны парсеру
+	layoutTpl, err := template.New("layout").Funcs(funcMap).Funcs(imageFuncs(defaultBlobURL)).ParseFiles(layoutFile)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка парсинга layout: %w", err) // %w — "wrap" ошибки: сохраняет оригинальный стек для дебага
 	}
 
+	// Partials парсим в layout до клонирования — так они попадут в каждую страницу
+	if _, err := layoutTpl.ParseGlob(partialsGlob); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга partials: %w", err)
+	}
+
 	// Инициализируем структуру: пустая map для хранения готовых шаблонов (layout + page)
 	t := &Templates{templates: make(map[string]*template.Template)}
 	for name, pagePath := range pages {
@@ -72,6 +107,23 @@ func New() (*Templates, error) {
 	return t, nil
 }
 
+// SetMenu — подключает источник данных для меню в layout (вызывается один раз в main.go)
+func (t *Templates) SetMenu(fn MenuFunc) {
+	t.menu = fn
+}
+
+// SetUser — подключает источник текущего пользователя для layout ("Войти" / имя и "Выйти")
+func (t *Templates) SetUser(fn MenuFunc) {
+	t.user = fn
+}
+
+// SetBlobURL — адреса загруженных файлов для srcset/imageSrc (BlobStore.URL; вызывается один раз в server.New)
+func (t *Templates) SetBlobURL(url func(key string) string) {
+	for _, tpl := range t.templates {
+		tpl.Funcs(imageFuncs(url))
+	}
+}
+
 // Render — метод структуры Templates: рендерит страницу в HTTP-ответ (c.Writer)
 func (t *Templates) Render(c *gin.Context, templateName string, title string, data any) error {
 	// Шаг 1: Ищем шаблон в map по имени (напр., "home")
@@ -82,10 +134,8 @@ func (t *Templates) Render(c *gin.Context, templateName string, title string, da
 		return fmt.Errorf("шаблон не найден: %s", templateName)
 	}
 
-	// Шаг 2: Извлекаем CSP-nonce из Gin-контекста (middleware добавил ранее, напр., c.Set(core.CtxNonce, "random123"))
-	// Context().Value() — безопасно берёт значение по ключу (core.CtxNonce — твой константный ключ)
-	nonceVal := c.Request.Context().Value(core.CtxNonce)
-	nonce, _ := nonceVal.(string) // Приводим к string (если не string — паника, но middleware гарантирует)
+	// Шаг 2: Извлекаем CSP-nonce из контекста запроса (middleware кладёт его через core.WithNonce)
+	nonce := core.Nonce(c.Request.Context())
 	if nonce == "" {
 		// Если nonce пуст — лог + ошибка (защита: без nonce CSP заблокирует скрипты)
 		core.LogError("CSP Nonce не найден в контексте запроса", nil)
@@ -102,7 +152,7 @@ func (t *Templates) Render(c *gin.Context, templateName string, title string, da
 	if token != "" {
 		// Формируем безопасный HTML: эскейпим только value (HTMLEscapeString), но весь input не эскейпим (template.HTML)
 		// Почему вручную? utrack/gin-csrf не рендерит поле — только токен; мы добавляем <input>
-		csrfField = template.HTML(fmt.Sprintf(`<input type="hidden" name="csrf_token" value="%s">`,
+		csrfField = template.HTML(fmt.Sprintf(`<input type="hidden" name="_csrf" value="%s">`,
 			template.HTMLEscapeString(token)))
 	} else {
 		// Если токен пуст (редко, если CSRF отключён) — лог, но продолжаем (не критично для GET-страниц)
@@ -116,6 +166,12 @@ func (t *Templates) Render(c *gin.Context, templateName string, title string, da
 		Nonce:     nonce,     // Для CSP в скриптах/стилях
 		Data:      data,      // Твои данные: напр., gin.H{"Products": []Product{...}}
 	}
+	if t.menu != nil {
+		page.Menu = t.menu(c)
+	}
+	if t.user != nil {
+		page.User = t.user(c)
+	}
 
 	// Шаг 6: Рендерим! ExecuteTemplate(c.Writer, "base", page) — выполняет блок "base" из шаблона,
 	// пишет HTML в HTTP-ответ (c.Writer — io.Writer). Layout + page сливаются динамически.
@@ -129,12 +185,12 @@ func (t *Templates) Render(c *gin.Context, templateName string, title string, da
 //    (В main.go: templates, _ := view.New(); r.SetTemplates(templates) или глобально)
 //
 // 2) Каждый Gin-хендлер вызывает tpl.Render(c, "имя", "заголовок", data).
-//    Render получает nonce из Gin-контекста (кладётся middleware) и подготавливает PageData.
+//    Render получает nonce из контекста запроса (core.Nonce) и подготавливает PageData.
 //
 // 3) CSRF: токен берём из utrack/gin-csrf: token := csrf.GetToken(c).
 //    Скрытое поле собираем вручную:
-//       <input type="hidden" name="csrf_token" value="...">
-//    (имя параметра — "csrf_token" по умолчанию у utrack/gin-csrf).
+//       <input type="hidden" name="_csrf" value="...">
+//    (utrack/gin-csrf читает токен из поля "_csrf" или заголовка X-CSRF-TOKEN).
 //
 // 4) CSP: nonce пробрасывается в PageData.Nonce и используется в шаблоне:
 //       <script nonce="{{ .Nonce }}">...</script>
@@ -0,0 +1,3 @@
+-- 001_schema.down.sql — откат каталога товаров
+
+DROP TABLE IF EXISTS products;
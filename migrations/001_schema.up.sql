@@ -0,0 +1,13 @@
+-- 001_schema.up.sql — каталог товаров (демо-данные — seeds/)
+
+-- Создаём таблицу с нужными полями (индекс только под фильтр по категории)
+CREATE TABLE products (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ category_id INT NULL,
+ name        VARCHAR(255) NOT NULL,
+ article     VARCHAR(100) NOT NULL,
+ price       DECIMAL(10,2) NOT NULL,
+ image_alt   VARCHAR(255),
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ KEY idx_products_category (category_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,6 @@
+-- 002_categories.down.sql — откат дерева категорий (товары остаются, category_id не трогаем)
+
+ALTER TABLE products
+ DROP FOREIGN KEY fk_products_category;
+
+DROP TABLE IF EXISTS categories;
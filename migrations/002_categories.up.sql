@@ -0,0 +1,18 @@
+-- 002_categories.up.sql — дерево категорий (parent/child)
+
+-- parent_id = NULL — корневая категория; slug используется в URL /catalog/:slug
+CREATE TABLE categories (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ parent_id   INT NULL,
+ name        VARCHAR(255) NOT NULL,
+ slug        VARCHAR(150) NOT NULL,
+ position    INT NOT NULL DEFAULT 0,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_categories_slug (slug),
+ KEY idx_categories_parent (parent_id),
+ CONSTRAINT fk_categories_parent FOREIGN KEY (parent_id) REFERENCES categories (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Товар ссылается на категорию; при удалении категории товар остаётся "без категории"
+ALTER TABLE products
+ ADD CONSTRAINT fk_products_category FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE SET NULL;
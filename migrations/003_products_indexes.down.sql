@@ -0,0 +1,6 @@
+-- 003_products_indexes.down.sql — откат индексов каталога
+
+ALTER TABLE products
+ DROP KEY idx_products_name,
+ DROP KEY idx_products_price,
+ DROP KEY idx_products_created;
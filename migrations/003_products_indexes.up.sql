@@ -0,0 +1,6 @@
+-- 003_products_indexes.up.sql — индексы под сортировку и фильтры каталога (?sort=, ?min_price=)
+
+ALTER TABLE products
+ ADD KEY idx_products_name (name),
+ ADD KEY idx_products_price (price),
+ ADD KEY idx_products_created (created_at);
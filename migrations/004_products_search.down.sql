@@ -0,0 +1,7 @@
+-- 004_products_search.down.sql — откат поиска: FULLTEXT-индекс и описание товара
+
+ALTER TABLE products
+ DROP KEY ft_products_search;
+
+ALTER TABLE products
+ DROP COLUMN description;
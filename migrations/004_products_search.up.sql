@@ -0,0 +1,8 @@
+-- 004_products_search.up.sql — описание товара и FULLTEXT-индекс для поиска (/search, /api/v1/search)
+
+ALTER TABLE products
+ ADD COLUMN description TEXT NULL AFTER article;
+
+-- InnoDB FULLTEXT: ищем сразу по названию, артикулу и описанию
+ALTER TABLE products
+ ADD FULLTEXT KEY ft_products_search (name, article, description);
@@ -0,0 +1,4 @@
+-- 005_carts.down.sql — откат корзин (сначала строки, потом сами корзины)
+
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS carts;
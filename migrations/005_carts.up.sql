@@ -0,0 +1,22 @@
+-- 005_carts.up.sql — корзины покупателей (в сессии хранится только carts.id)
+
+-- id — случайный токен (32 hex), а не AUTO_INCREMENT: чужую корзину нельзя подобрать перебором (IDOR)
+CREATE TABLE IF NOT EXISTS carts (
+ id          CHAR(32) NOT NULL PRIMARY KEY,
+ user_id     INT NULL,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ updated_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_carts_user (user_id),
+ KEY idx_carts_updated (updated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Строка корзины: только товар и количество, цена всегда берётся текущая из products
+CREATE TABLE IF NOT EXISTS cart_items (
+ cart_id     CHAR(32) NOT NULL,
+ product_id  INT NOT NULL,
+ quantity    INT NOT NULL,
+ added_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ PRIMARY KEY (cart_id, product_id),
+ CONSTRAINT fk_cart_items_cart FOREIGN KEY (cart_id) REFERENCES carts (id) ON DELETE CASCADE,
+ CONSTRAINT fk_cart_items_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+-- 006_orders.down.sql — откат заказов (зависимые таблицы первыми)
+
+DROP TABLE IF EXISTS order_status_events;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,53 @@
+-- 006_orders.up.sql — заказы, позиции заказа (снимок цен) и история статусов
+
+-- number — публичный номер заказа (случайный, используется в URL /orders/:number)
+CREATE TABLE IF NOT EXISTS orders (
+ id               INT AUTO_INCREMENT PRIMARY KEY,
+ number           VARCHAR(20) NOT NULL,
+ user_id          INT NULL,
+ status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+ email            VARCHAR(255) NOT NULL,
+ name             VARCHAR(100) NOT NULL,
+ phone            VARCHAR(30) NOT NULL,
+ country          VARCHAR(60) NOT NULL,
+ city             VARCHAR(100) NOT NULL,
+ postal_code      VARCHAR(20) NOT NULL,
+ address          VARCHAR(255) NOT NULL,
+ shipping_method  VARCHAR(20) NOT NULL,
+ currency         CHAR(3) NOT NULL DEFAULT 'EUR',
+ shipping_cost    DECIMAL(10,2) NOT NULL,
+ subtotal         DECIMAL(10,2) NOT NULL,
+ total            DECIMAL(10,2) NOT NULL,
+ created_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ updated_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_orders_number (number),
+ KEY idx_orders_user (user_id),
+ KEY idx_orders_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Позиция заказа — снимок товара на момент покупки: правки каталога не меняют историю.
+-- product_id без внешнего ключа: товар можно удалить, заказ останется.
+CREATE TABLE IF NOT EXISTS order_items (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ order_id    INT NOT NULL,
+ product_id  INT NULL,
+ name        VARCHAR(255) NOT NULL,
+ article     VARCHAR(100) NOT NULL,
+ unit_price  DECIMAL(10,2) NOT NULL,
+ quantity    INT NOT NULL,
+ line_total  DECIMAL(10,2) NOT NULL,
+ KEY idx_order_items_order (order_id),
+ CONSTRAINT fk_order_items_order FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Журнал переходов статусов (кто/когда/почему)
+CREATE TABLE IF NOT EXISTS order_status_events (
+ id           INT AUTO_INCREMENT PRIMARY KEY,
+ order_id     INT NOT NULL,
+ from_status  VARCHAR(20) NULL,
+ to_status    VARCHAR(20) NOT NULL,
+ note         VARCHAR(255) NOT NULL DEFAULT '',
+ created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ KEY idx_order_status_events_order (order_id),
+ CONSTRAINT fk_order_status_events_order FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
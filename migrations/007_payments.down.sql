@@ -0,0 +1,4 @@
+-- 007_payments.down.sql — откат платежей и журнала webhook-событий
+
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,28 @@
+-- 007_payments.up.sql — платежи по заказам и журнал обработанных webhook-событий
+
+-- Платёж — попытка оплаты заказа у провайдера (intent_id — ID на стороне провайдера)
+CREATE TABLE IF NOT EXISTS payments (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ order_id    INT NOT NULL,
+ provider    VARCHAR(30) NOT NULL,
+ intent_id   VARCHAR(64) NOT NULL,
+ status      VARCHAR(30) NOT NULL,
+ amount      DECIMAL(10,2) NOT NULL,
+ currency    CHAR(3) NOT NULL DEFAULT 'EUR',
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ updated_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_payments_intent (provider, intent_id),
+ KEY idx_payments_order (order_id),
+ CONSTRAINT fk_payments_order FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Обработанные события webhook: PRIMARY KEY по event_id делает повторную доставку no-op
+CREATE TABLE IF NOT EXISTS payment_events (
+ provider     VARCHAR(30) NOT NULL,
+ event_id     VARCHAR(64) NOT NULL,
+ intent_id    VARCHAR(64) NOT NULL,
+ type         VARCHAR(40) NOT NULL,
+ received_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ PRIMARY KEY (provider, event_id),
+ KEY idx_payment_events_intent (intent_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
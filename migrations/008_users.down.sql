@@ -0,0 +1,5 @@
+-- 008_users.down.sql — откат учётных записей (сначала внешние ключи на users)
+
+ALTER TABLE orders DROP FOREIGN KEY fk_orders_user;
+ALTER TABLE carts DROP FOREIGN KEY fk_carts_user;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,21 @@
+-- 008_users.up.sql — учётные записи покупателей
+
+-- password_hash — PHC-строка argon2id (или устаревший bcrypt до первого входа, см. internal/auth)
+CREATE TABLE IF NOT EXISTS users (
+ id             INT AUTO_INCREMENT PRIMARY KEY,
+ email          VARCHAR(255) NOT NULL,
+ name           VARCHAR(100) NOT NULL,
+ password_hash  VARCHAR(255) NOT NULL,
+ last_login_at  TIMESTAMP NULL DEFAULT NULL,
+ created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ updated_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_users_email (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- carts.user_id и orders.user_id появились раньше пользователей — теперь это внешние ключи.
+-- Удалённый пользователь теряет корзину, а его заказы остаются (user_id = NULL).
+ALTER TABLE carts
+ ADD CONSTRAINT fk_carts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE orders
+ ADD CONSTRAINT fk_orders_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+-- 009_user_tokens.down.sql — откат токенов и подтверждения email
+
+DROP TABLE IF EXISTS user_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,20 @@
+-- 009_user_tokens.up.sql — подтверждение email и одноразовые токены из писем
+
+ALTER TABLE users
+ ADD COLUMN email_verified_at TIMESTAMP NULL DEFAULT NULL AFTER password_hash;
+
+-- token_hash — SHA-256 токена из ссылки (сам токен не хранится).
+-- Токен действует до expires_at и только один раз (used_at); новый токен того же
+-- назначения гасит предыдущие неиспользованные.
+CREATE TABLE IF NOT EXISTS user_tokens (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ user_id     INT NOT NULL,
+ purpose     VARCHAR(20) NOT NULL,
+ token_hash  CHAR(64) NOT NULL,
+ expires_at  TIMESTAMP NOT NULL,
+ used_at     TIMESTAMP NULL DEFAULT NULL,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_user_tokens_hash (token_hash),
+ KEY idx_user_tokens_user (user_id, purpose),
+ CONSTRAINT fk_user_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,7 @@
+-- 010_roles.down.sql — откат ролей (сначала внешний ключ users → roles)
+
+ALTER TABLE users
+ DROP FOREIGN KEY fk_users_role,
+ DROP COLUMN role;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,32 @@
+-- 010_roles.up.sql — роли и права доступа (RBAC)
+
+-- Права роли — строки вида "область.действие" (storage.Perm*). Новое право добавляется
+-- миграцией вместе с кодом, который его проверяет.
+CREATE TABLE IF NOT EXISTS roles (
+ name   VARCHAR(20) PRIMARY KEY,
+ title  VARCHAR(100) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+ role        VARCHAR(20) NOT NULL,
+ permission  VARCHAR(64) NOT NULL,
+ PRIMARY KEY (role, permission),
+ CONSTRAINT fk_role_permissions_role FOREIGN KEY (role) REFERENCES roles (name) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO roles (name, title) VALUES
+ ('customer', 'Покупатель'),
+ ('staff',    'Сотрудник'),
+ ('admin',    'Администратор');
+
+INSERT INTO role_permissions (role, permission) VALUES
+ ('staff', 'admin.access'),
+ ('staff', 'products.write'),
+ ('admin', 'admin.access'),
+ ('admin', 'products.write'),
+ ('admin', 'debug.view');
+
+-- Все существующие пользователи — покупатели
+ALTER TABLE users
+ ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'customer' AFTER name,
+ ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles (name);
@@ -0,0 +1,4 @@
+-- 011_products_article_unique.down.sql — снова допускаем повторяющиеся артикулы
+
+ALTER TABLE products
+ DROP KEY uq_products_article;
@@ -0,0 +1,6 @@
+-- 011_products_article_unique.up.sql — артикул уникален (товары теперь заводятся через /admin/products)
+
+-- Если в каталоге уже есть повторяющиеся артикулы, миграция упадёт: их нужно исправить вручную
+-- (SELECT article, COUNT(*) FROM products GROUP BY article HAVING COUNT(*) > 1).
+ALTER TABLE products
+ ADD UNIQUE KEY uq_products_article (article);
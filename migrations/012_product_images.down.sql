@@ -0,0 +1,6 @@
+-- 012_product_images.down.sql — товары снова без изображений (файлы в UPLOADS_DIR остаются)
+
+ALTER TABLE products
+ DROP COLUMN image_height,
+ DROP COLUMN image_width,
+ DROP COLUMN image_key;
@@ -0,0 +1,9 @@
+-- 012_product_images.up.sql — загруженное изображение товара (файлы — в BlobStore, в БД только ключ)
+
+-- image_key — префикс имён миниатюр ("products/<id>" → products/<id>-320.jpg, ...-320.webp, ...),
+-- image_width / image_height — размер самой большой миниатюры: по нему строится srcset
+-- и задаются width/height у <img> (без сдвига вёрстки при загрузке).
+ALTER TABLE products
+ ADD COLUMN image_key    VARCHAR(64) NULL AFTER image_alt,
+ ADD COLUMN image_width  SMALLINT UNSIGNED NULL AFTER image_key,
+ ADD COLUMN image_height SMALLINT UNSIGNED NULL AFTER image_width;
@@ -0,0 +1,16 @@
+-- 013_product_variants.down.sql — товары снова без вариантов (строки корзин с вариантами удаляются)
+
+ALTER TABLE order_items
+ DROP COLUMN variant,
+ DROP COLUMN variant_id;
+
+DELETE FROM cart_items WHERE variant_id <> 0;
+ALTER TABLE cart_items
+ DROP PRIMARY KEY,
+ ADD PRIMARY KEY (cart_id, product_id),
+ DROP COLUMN variant_id;
+
+DROP TABLE IF EXISTS variant_option_values;
+DROP TABLE IF EXISTS product_variants;
+DROP TABLE IF EXISTS option_values;
+DROP TABLE IF EXISTS option_types;
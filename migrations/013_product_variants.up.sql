@@ -0,0 +1,55 @@
+-- 013_product_variants.up.sql — варианты товаров (размер, цвет, ...): свой SKU, цена и остаток
+
+-- Типы опций ("Цвет", "Размер") и их значения — общие для всего каталога
+CREATE TABLE IF NOT EXISTS option_types (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ code        VARCHAR(50)  NOT NULL,
+ title       VARCHAR(100) NOT NULL,
+ position    INT NOT NULL DEFAULT 0,
+ UNIQUE KEY uq_option_types_code (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS option_values (
+ id              INT AUTO_INCREMENT PRIMARY KEY,
+ option_type_id  INT NOT NULL,
+ value           VARCHAR(100) NOT NULL,
+ position        INT NOT NULL DEFAULT 0,
+ UNIQUE KEY uq_option_values_type_value (option_type_id, value),
+ CONSTRAINT fk_option_values_type FOREIGN KEY (option_type_id) REFERENCES option_types (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Вариант товара: price = NULL — цена товара; stock — остаток именно этого варианта
+CREATE TABLE IF NOT EXISTS product_variants (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ product_id  INT NOT NULL,
+ sku         VARCHAR(100) NOT NULL,
+ price       DECIMAL(10,2) NULL,
+ stock       INT NOT NULL DEFAULT 0,
+ position    INT NOT NULL DEFAULT 0,
+ UNIQUE KEY uq_product_variants_sku (sku),
+ KEY idx_product_variants_product (product_id, position),
+ CONSTRAINT fk_product_variants_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Значения опций варианта (по одному на тип: "Цвет: Чёрный", "Размер: M")
+CREATE TABLE IF NOT EXISTS variant_option_values (
+ variant_id       INT NOT NULL,
+ option_value_id  INT NOT NULL,
+ PRIMARY KEY (variant_id, option_value_id),
+ CONSTRAINT fk_variant_option_values_variant FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE,
+ CONSTRAINT fk_variant_option_values_value FOREIGN KEY (option_value_id) REFERENCES option_values (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Строка корзины — товар и вариант. variant_id = 0 — товар без вариантов: NULL в первичном
+-- ключе недопустим, а внешний ключ на вариант не нужен — строки удалённых вариантов
+-- отсекает JOIN при чтении корзины (как удалённые товары).
+ALTER TABLE cart_items
+ ADD COLUMN variant_id INT NOT NULL DEFAULT 0 AFTER product_id,
+ DROP PRIMARY KEY,
+ ADD PRIMARY KEY (cart_id, product_id, variant_id);
+
+-- Позиция заказа: вариант и снимок его опций ("Цвет: Чёрный, Переключатели: Red");
+-- article у таких позиций — SKU варианта
+ALTER TABLE order_items
+ ADD COLUMN variant_id INT NULL AFTER product_id,
+ ADD COLUMN variant    VARCHAR(255) NULL AFTER name;
@@ -0,0 +1,7 @@
+-- 014_inventory.down.sql — откат учёта остатков (журнал движений теряется)
+
+DROP TABLE IF EXISTS inventory_movements;
+DROP TABLE IF EXISTS stock_reservations;
+
+ALTER TABLE products
+ DROP COLUMN stock;
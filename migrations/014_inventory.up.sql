@@ -0,0 +1,43 @@
+-- 014_inventory.up.sql — остатки товаров, резервы на время оформления и журнал движений
+
+-- Остаток товара без вариантов (у товара с вариантами — product_variants.stock).
+-- Существующие товары получают 0 ("нет в наличии"): остатки заводятся в /admin/products/:id/stock.
+ALTER TABLE products
+ ADD COLUMN stock INT NOT NULL DEFAULT 0 AFTER price;
+
+-- Резерв: единицы, отложенные для корзины (пока покупатель оформляет заказ, до expires_at)
+-- или для заказа (expires_at = NULL — до оплаты или отмены). Доступно = stock − действующие резервы.
+-- variant_id = 0 — товар без вариантов (как в cart_items).
+CREATE TABLE IF NOT EXISTS stock_reservations (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ cart_id     CHAR(32) NULL,
+ order_id    INT NULL,
+ product_id  INT NOT NULL,
+ variant_id  INT NOT NULL DEFAULT 0,
+ quantity    INT NOT NULL,
+ expires_at  TIMESTAMP NULL,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ KEY idx_stock_reservations_item (product_id, variant_id),
+ KEY idx_stock_reservations_cart (cart_id),
+ KEY idx_stock_reservations_order (order_id),
+ CONSTRAINT fk_stock_reservations_cart FOREIGN KEY (cart_id) REFERENCES carts (id) ON DELETE CASCADE,
+ CONSTRAINT fk_stock_reservations_order FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE,
+ CONSTRAINT fk_stock_reservations_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Журнал движений остатков для аудита: только добавление, без внешних ключей —
+-- записи переживают удаление товара. reason: sale (оплата заказа), adjust (правка в панели).
+CREATE TABLE IF NOT EXISTS inventory_movements (
+ id           INT AUTO_INCREMENT PRIMARY KEY,
+ product_id   INT NOT NULL,
+ variant_id   INT NOT NULL DEFAULT 0,
+ delta        INT NOT NULL,
+ stock_after  INT NOT NULL,
+ reason       VARCHAR(20) NOT NULL,
+ order_id     INT NULL,
+ user_id      INT NULL,
+ note         VARCHAR(255) NULL,
+ created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ KEY idx_inventory_movements_product (product_id, created_at),
+ KEY idx_inventory_movements_order (order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+-- 015_api_tokens.down.sql — откат API-токенов (все выданные токены перестают действовать)
+
+DELETE FROM role_permissions WHERE permission = 'tokens.manage';
+DROP TABLE IF EXISTS api_tokens;
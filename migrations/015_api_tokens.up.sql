@@ -0,0 +1,24 @@
+-- 015_api_tokens.up.sql — API-токены для скриптов и интеграций (Authorization: Bearer)
+
+-- token_hash — SHA-256 токена (сам токен показывается владельцу один раз при создании),
+-- token_hint — его начало, чтобы узнать токен в списке. scopes — области доступа через пробел
+-- (storage.Scope). Токен действует до expires_at, если не отозван (revoked_at).
+CREATE TABLE IF NOT EXISTS api_tokens (
+ id            INT AUTO_INCREMENT PRIMARY KEY,
+ user_id       INT NOT NULL,
+ name          VARCHAR(100) NOT NULL,
+ token_hint    VARCHAR(20) NOT NULL,
+ token_hash    CHAR(64) NOT NULL,
+ scopes        VARCHAR(255) NOT NULL,
+ expires_at    TIMESTAMP NOT NULL,
+ last_used_at  TIMESTAMP NULL DEFAULT NULL,
+ revoked_at    TIMESTAMP NULL DEFAULT NULL,
+ created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_api_tokens_hash (token_hash),
+ KEY idx_api_tokens_user (user_id, created_at),
+ CONSTRAINT fk_api_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Токены всех пользователей в /admin/tokens
+INSERT INTO role_permissions (role, permission) VALUES
+ ('admin', 'tokens.manage');
@@ -0,0 +1,3 @@
+-- 016_idempotency_keys.down.sql — откат ключей Idempotency-Key (сохранённые ответы теряются)
+
+DROP TABLE IF EXISTS idempotency_keys;
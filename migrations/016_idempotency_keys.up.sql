@@ -0,0 +1,19 @@
+-- 016_idempotency_keys.up.sql — ключи Idempotency-Key запросов POST/PUT/PATCH к /api/v1
+
+-- scope — чей ключ (API-токен, пользователь или сессия): одинаковые ключи разных клиентов
+-- не пересекаются. fingerprint — SHA-256 метода, пути и тела первого запроса. status = NULL —
+-- запрос ещё выполняется; после ответа в строке лежат его код, заголовки (JSON) и тело
+-- для повторов. Просроченные строки удаляются при следующих запросах с ключами.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+ id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+ scope        VARCHAR(100) NOT NULL,
+ idem_key     VARCHAR(255) NOT NULL,
+ fingerprint  CHAR(64) NOT NULL,
+ status       SMALLINT NULL DEFAULT NULL,
+ headers      TEXT NULL,
+ body         MEDIUMBLOB NULL,
+ expires_at   TIMESTAMP NOT NULL,
+ created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_idempotency_keys_key (scope, idem_key),
+ KEY idx_idempotency_keys_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
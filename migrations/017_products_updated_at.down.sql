@@ -0,0 +1,3 @@
+-- 017_products_updated_at.down.sql — откат времени изменения товара
+
+ALTER TABLE products DROP COLUMN updated_at;
@@ -0,0 +1,8 @@
+-- 017_products_updated_at.up.sql — время правки товара (поле updated_at в JSON товара)
+
+-- ON UPDATE отмечает правки самого товара; изменение остатка варианта отмечает
+-- товар явно (storage.touchProductTx). Существующим товарам — время создания.
+ALTER TABLE products
+ ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP AFTER created_at;
+
+UPDATE products SET updated_at = created_at;
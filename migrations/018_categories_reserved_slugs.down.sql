@@ -0,0 +1,3 @@
+-- 018_categories_reserved_slugs.down.sql — откат запрета зарезервированных slug
+
+ALTER TABLE categories DROP CHECK chk_categories_slug_reserved;
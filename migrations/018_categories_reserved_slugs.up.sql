@@ -0,0 +1,8 @@
+-- 018_categories_reserved_slugs.up.sql — slug, занятые статическими маршрутами /catalog/<slug>
+
+-- /catalog/json — JSON каталога: категория с таким slug недостижима (storage.ReservedCategorySlugs).
+-- Уже заведённой категории slug меняется на slug-id.
+UPDATE categories SET slug = CONCAT(slug, '-', id) WHERE slug IN ('json');
+
+ALTER TABLE categories
+ ADD CONSTRAINT chk_categories_slug_reserved CHECK (slug NOT IN ('json'));
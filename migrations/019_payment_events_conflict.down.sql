@@ -0,0 +1,3 @@
+-- 019_payment_events_conflict.down.sql — откат причины конфликта события
+
+ALTER TABLE payment_events DROP COLUMN conflict;
@@ -0,0 +1,7 @@
+-- 019_payment_events_conflict.up.sql — события, не применённые к заказу
+
+-- Событие провайдера записывается и подтверждается, даже если заказ перевести нельзя
+-- (оплата отменённого заказа, не хватает остатка): иначе провайдер повторял бы его бесконечно.
+-- conflict — причина; такой платёж ждёт ручного возврата.
+ALTER TABLE payment_events
+ ADD COLUMN conflict VARCHAR(255) NULL AFTER type;
@@ -0,0 +1,5 @@
+-- 020_order_reservations_expiry.down.sql — откат: резерв заказа снова бессрочный
+
+ALTER TABLE stock_reservations DROP KEY idx_stock_reservations_expires;
+
+UPDATE stock_reservations SET expires_at = NULL WHERE order_id IS NOT NULL;
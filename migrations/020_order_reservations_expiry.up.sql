@@ -0,0 +1,12 @@
+-- 020_order_reservations_expiry.up.sql — срок резерва неоплаченного заказа
+
+-- Резерв заказа раньше был бессрочным: брошенный заказ держал товар навсегда. Теперь у него
+-- есть expires_at (PENDING_ORDER_TTL), по истечении заказ отменяется. Уже оформленным
+-- неоплаченным заказам даётся час на оплату с момента миграции.
+UPDATE stock_reservations
+SET expires_at = DATE_ADD(CURRENT_TIMESTAMP, INTERVAL 1 HOUR)
+WHERE order_id IS NOT NULL AND expires_at IS NULL;
+
+-- Поиск просроченных резервов для отмены заказов
+ALTER TABLE stock_reservations
+ ADD KEY idx_stock_reservations_expires (expires_at);
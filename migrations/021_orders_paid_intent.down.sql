@@ -0,0 +1,3 @@
+-- 021_orders_paid_intent.down.sql — откат платежа заказа
+
+ALTER TABLE orders DROP COLUMN paid_intent_id;
@@ -0,0 +1,16 @@
+-- 021_orders_paid_intent.up.sql — платёж, которым оплачен заказ
+
+-- Пока заказ не оплачен, покупатель может начать несколько платежей. Заказ оплачивает первый
+-- успешный; успешная оплата другим платежом — двойное списание: событие записывается
+-- с payment_events.conflict и ждёт ручного возврата.
+ALTER TABLE orders
+ ADD COLUMN paid_intent_id VARCHAR(64) NULL AFTER status;
+
+-- Уже оплаченные заказы: первый успешный (или возвращённый) платёж
+UPDATE orders o
+JOIN (SELECT order_id, MIN(intent_id) AS intent_id
+      FROM payments
+      WHERE status IN ('succeeded', 'refunded')
+      GROUP BY order_id) p ON p.order_id = o.id
+SET o.paid_intent_id = p.intent_id
+WHERE o.status IN ('paid', 'shipped', 'delivered', 'refunded');
@@ -0,0 +1,3 @@
+-- 022_catalog_changes.down.sql — откат времени изменения каталога
+
+DROP TABLE IF EXISTS catalog_changes;
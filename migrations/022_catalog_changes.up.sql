@@ -0,0 +1,10 @@
+-- 022_catalog_changes.up.sql — время последнего изменения каталога (Last-Modified /catalog/json)
+
+-- Одна строка: её отмечает каждая запись, меняющая товары, остатки или резервы
+-- (storage.touchCatalog). updated_at товаров этого не покрывает: наличие меняют резервы.
+CREATE TABLE IF NOT EXISTS catalog_changes (
+ id          TINYINT PRIMARY KEY,
+ changed_at  TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO catalog_changes (id) VALUES (1);
@@ -0,0 +1,30 @@
+-- 001_demo_catalog.sql — демо-каталог: категории, товары и описания для поиска.
+-- Повторный запуск безопасен: явные id + INSERT IGNORE, UPDATE по артикулу.
+
+-- Демо-дерево: Электроника → (Телефоны и планшеты, Компьютеры → Периферия, Аудио)
+INSERT IGNORE INTO categories (id, parent_id, name, slug, position) VALUES
+(1, NULL, 'Электроника',          'electronics', 1),
+(2, 1,    'Телефоны и планшеты',  'phones',      1),
+(3, 1,    'Компьютеры',           'computers',   2),
+(4, 3,    'Периферия',            'peripherals', 1),
+(5, 1,    'Аудио',                'audio',       3);
+
+-- Демо-товары (одним INSERT с несколькими значениями)
+INSERT IGNORE INTO products (id, name, article, price, image_alt) VALUES
+(1, 'Смартфон XYZ Pro',          'ART-001', 299.99, 'Смартфон с 128GB'),
+(2, 'Ноутбук ABC Ultra',         'ART-002', 899.00, 'Ноутбук 16" i7'),
+(3, 'Планшет DEF Mini',          'ART-003', 199.50, 'Планшет 10"'),
+(4, 'Наушники GHI Wireless',     'ART-004',  79.90, 'Беспроводные TWS'),
+(5, 'Клавиатура KLM Mechanical', 'ART-005', 129.00, NULL);
+
+UPDATE products SET category_id = 2 WHERE article IN ('ART-001', 'ART-003');
+UPDATE products SET category_id = 3 WHERE article = 'ART-002';
+UPDATE products SET category_id = 4 WHERE article = 'ART-005';
+UPDATE products SET category_id = 5 WHERE article = 'ART-004';
+
+-- Демо-описания
+UPDATE products SET description = 'Флагманский смартфон: 128 ГБ памяти, OLED-экран и быстрая зарядка.' WHERE article = 'ART-001';
+UPDATE products SET description = 'Лёгкий ноутбук с экраном 16 дюймов и процессором i7 для работы и учёбы.' WHERE article = 'ART-002';
+UPDATE products SET description = 'Компактный планшет с экраном 10 дюймов для чтения и видео.' WHERE article = 'ART-003';
+UPDATE products SET description = 'Беспроводные TWS-наушники с шумоподавлением и кейсом для зарядки.' WHERE article = 'ART-004';
+UPDATE products SET description = 'Механическая клавиатура с подсветкой и тактильными переключателями.' WHERE article = 'ART-005';
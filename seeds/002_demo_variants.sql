@@ -0,0 +1,23 @@
+-- 002_demo_variants.sql — варианты демо-клавиатуры (ART-005): цвет корпуса и переключатели.
+-- Повторный запуск безопасен: явные id + INSERT IGNORE.
+
+INSERT IGNORE INTO option_types (id, code, title, position) VALUES
+(1, 'color',  'Цвет',          1),
+(2, 'switch', 'Переключатели', 2);
+
+INSERT IGNORE INTO option_values (id, option_type_id, value, position) VALUES
+(1, 1, 'Чёрный', 1),
+(2, 1, 'Белый',  2),
+(3, 2, 'Red',    1),
+(4, 2, 'Brown',  2);
+
+-- price = NULL — цена товара (129.00); белая версия дороже
+INSERT IGNORE INTO product_variants (id, product_id, sku, price, stock, position) VALUES
+(1, 5, 'ART-005-BLK-RED', NULL,   10, 1),
+(2, 5, 'ART-005-BLK-BRN', NULL,    5, 2),
+(3, 5, 'ART-005-WHT-BRN', 139.00,  0, 3);
+
+INSERT IGNORE INTO variant_option_values (variant_id, option_value_id) VALUES
+(1, 1), (1, 3),
+(2, 1), (2, 4),
+(3, 2), (3, 4);
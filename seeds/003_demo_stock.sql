@@ -0,0 +1,7 @@
+-- 003_demo_stock.sql — остатки демо-товаров (варианты клавиатуры — в 002_demo_variants.sql).
+-- Остаток задаётся, только если он ещё 0: повторный запуск не затирает ненулевые остатки.
+
+UPDATE products SET stock = 25 WHERE article = 'ART-001' AND stock = 0;
+UPDATE products SET stock = 10 WHERE article = 'ART-002' AND stock = 0;
+UPDATE products SET stock = 15 WHERE article = 'ART-003' AND stock = 0;
+UPDATE products SET stock = 40 WHERE article = 'ART-004' AND stock = 0;